@@ -82,12 +82,21 @@ func (c *Client) initService(lggr pkglogger.Logger, batchSvc *ChipIngressBatchEm
 // NewClient creates a new Client with initialized OpenTelemetry components
 // To handle OpenTelemetry errors use [otel.SetErrorHandler](https://pkg.go.dev/go.opentelemetry.io/otel#SetErrorHandler)
 func NewClient(cfg Config) (*Client, error) {
-	if cfg.OtelExporterGRPCEndpoint != "" && cfg.OtelExporterHTTPEndpoint != "" {
+	var endpoints int
+	for _, endpoint := range []string{cfg.OtelExporterGRPCEndpoint, cfg.OtelExporterHTTPEndpoint, cfg.OtelExporterFileDir} {
+		if endpoint != "" {
+			endpoints++
+		}
+	}
+	if endpoints > 1 {
 		return nil, errors.New("only one exporter endpoint should be set")
 	}
-	if cfg.OtelExporterGRPCEndpoint == "" && cfg.OtelExporterHTTPEndpoint == "" {
+	if endpoints == 0 {
 		return nil, errors.New("at least one exporter endpoint should be set")
 	}
+	if cfg.OtelExporterFileDir != "" {
+		return NewFileClient(cfg)
+	}
 	if cfg.OtelExporterHTTPEndpoint != "" {
 		factory := func(options ...otlploghttp.Option) (sdklog.Exporter, error) {
 			// note: context is unused internally
@@ -526,18 +535,9 @@ func newTracerProvider(config Config, resource *sdkresource.Resource, auth Auth,
 	if config.TraceBatchTimeout > 0 {
 		batcherOpts = append(batcherOpts, sdktrace.WithBatchTimeout(config.TraceBatchTimeout)) // Default is 5s
 	}
-	opts := []sdktrace.TracerProviderOption{
-		sdktrace.WithBatcher(metered, batcherOpts...),
+	opts := append(config.tracerProviderOptions(metered, batcherOpts...),
 		sdktrace.WithResource(resource),
-		sdktrace.WithSampler(
-			sdktrace.ParentBased(
-				sdktrace.TraceIDRatioBased(config.TraceSampleRatio),
-			),
-		),
-	}
-	if config.TraceSpanExporter != nil {
-		opts = append(opts, sdktrace.WithBatcher(config.TraceSpanExporter))
-	}
+	)
 	return sdktrace.NewTracerProvider(opts...), metered, nil
 }
 
@@ -597,7 +597,7 @@ func newMeterProvider(cfg Config, resource *sdkresource.Resource, auth Auth, cre
 	}
 
 	mpOpts := append(cfg.metricOptions(),
-		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(metered, readerOpts...)),
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(cfg.metricExporter(metered), readerOpts...)),
 		sdkmetric.WithResource(resource),
 	)
 	return sdkmetric.NewMeterProvider(mpOpts...), metered, nil
@@ -684,7 +684,7 @@ func newLoggerProviderOpts(cfg Config, baseResource *sdkresource.Resource, share
 
 	return []sdklog.LoggerProviderOption{
 		sdklog.WithResource(loggerResource),
-		sdklog.WithProcessor(loggerProcessor),
+		sdklog.WithProcessor(cfg.logProcessor(loggerProcessor)),
 	}, nil
 }
 
@@ -286,6 +286,25 @@ func TestNewClient(t *testing.T) {
 		assert.IsType(t, &beholder.Client{}, client)
 	})
 
+	t.Run("file dir set", func(t *testing.T) {
+		client, err := beholder.NewClient(beholder.Config{
+			OtelExporterFileDir: t.TempDir(),
+		})
+		require.NoError(t, err)
+		assert.NotNil(t, client)
+		assert.IsType(t, &beholder.Client{}, client)
+	})
+
+	t.Run("file dir and endpoint set", func(t *testing.T) {
+		client, err := beholder.NewClient(beholder.Config{
+			OtelExporterGRPCEndpoint: "grpc-endpoint",
+			OtelExporterFileDir:      t.TempDir(),
+		})
+		require.Error(t, err)
+		assert.Nil(t, client)
+		assert.Equal(t, "only one exporter endpoint should be set", err.Error())
+	})
+
 	t.Run("emitter is dual source when ChipIngress is enabled", func(t *testing.T) {
 		client, err := beholder.NewClient(beholder.Config{
 			OtelExporterGRPCEndpoint:       "grpc-endpoint",
@@ -0,0 +1,89 @@
+// Command replay forwards the OTLP-JSON telemetry files written by a beholder client in local file mode (see
+// beholder.NewFileClient) to an OTel collector over OTLP/gRPC.
+//
+//	go run ./pkg/beholder/cmd/replay -dir ./telemetry -endpoint collector:4317 -header authorization=token
+//
+// Files are forwarded oldest first per signal. The progress of each file is recorded next to it, so a rerun after a
+// failure resumes where it left off. With -delete, each file is removed once it has been forwarded.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/beholder"
+)
+
+type headers []string
+
+func (h *headers) String() string { return strings.Join(*h, ",") }
+
+func (h *headers) Set(s string) error {
+	if !strings.Contains(s, "=") {
+		return fmt.Errorf("header must be key=value: %s", s)
+	}
+	*h = append(*h, s)
+	return nil
+}
+
+var (
+	dir        = flag.String("dir", "", "Directory containing the telemetry files")
+	endpoint   = flag.String("endpoint", "localhost:4317", "OTLP/gRPC endpoint of the collector")
+	insecureTx = flag.Bool("insecure", false, "Disable TLS")
+	caCertFile = flag.String("ca-cert", "", "CA certificate file for TLS (defaults to the system roots)")
+	remove     = flag.Bool("delete", false, "Delete each file once it has been forwarded")
+	header     headers
+)
+
+func main() {
+	flag.Var(&header, "header", "Header to send with each request, as key=value (repeatable)")
+	flag.Parse()
+	if err := run(context.Background(), os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, w io.Writer) error {
+	if *dir == "" {
+		return fmt.Errorf("-dir is required")
+	}
+	creds := insecure.NewCredentials()
+	if !*insecureTx {
+		var err error
+		creds = credentials.NewClientTLSFromCert(nil, "")
+		if *caCertFile != "" {
+			if creds, err = credentials.NewClientTLSFromFile(*caCertFile, ""); err != nil {
+				return err
+			}
+		}
+	}
+	conn, err := grpc.NewClient(*endpoint, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	for _, h := range header {
+		k, v, _ := strings.Cut(h, "=")
+		ctx = metadata.AppendToOutgoingContext(ctx, k, v)
+	}
+	results, err := beholder.NewFileReplayer(conn).Replay(ctx, *dir)
+	for _, res := range results {
+		fmt.Fprintf(w, "%s: %d requests, %d rejected\n", res.Path, res.Requests, res.Rejected)
+		if *remove {
+			err = errors.Join(err, beholder.RemoveReplayedFile(res.Path))
+		}
+	}
+	return err
+}
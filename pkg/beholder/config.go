@@ -16,6 +16,11 @@ type Config struct {
 	CACertFile               string
 	OtelExporterGRPCEndpoint string
 	OtelExporterHTTPEndpoint string
+	// OtelExporterFileDir selects the local mode, which writes rotating OTLP-JSON files to this directory instead of
+	// exporting to a collector. See NewFileClient.
+	OtelExporterFileDir string
+	FileExportMaxBytes  int64 // Max size of each file before rotating (default 100 MiB)
+	FileExportMaxFiles  int   // Max files kept per signal, removing the oldest (0 = unlimited)
 
 	// OTel Resource
 	ResourceAttributes []attribute.KeyValue
@@ -33,6 +38,8 @@ type Config struct {
 	TraceBatchTimeout time.Duration
 	TraceSpanExporter trace.SpanExporter // optional additional exporter
 	TraceRetryConfig  *RetryConfig
+	// TraceTailSampling enables tail-based sampling, which keeps every span of an errored trace (nil = head sampling).
+	TraceTailSampling *TailSamplingConfig
 	// TraceCompressor sets the gRPC compressor for traces. Valid values: "gzip" (default), "none".
 	TraceCompressor string
 
@@ -88,6 +95,9 @@ type Config struct {
 	// LogCompressor sets the gRPC compressor for logs. Valid values: "gzip" (default), "none".
 	LogCompressor string
 
+	// TenantBudgets enables per-tenant budgets of logs, metrics and traces (nil = disabled).
+	TenantBudgets *TenantBudgetConfig
+
 	// Auth
 	// AuthHeaders serves two purposes:
 	// 1. Static mode: When AuthKeySigner is nil, these headers are used as-is and never change
@@ -0,0 +1,194 @@
+package beholder
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	collogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	// maxReplayLineBytes bounds a line of a telemetry file, i.e. one encoded export request.
+	maxReplayLineBytes = 64 << 20
+	// replayProgressExt is the extension of the file recording how much of a telemetry file has been forwarded.
+	replayProgressExt = ".replayed"
+)
+
+// FileReplayer forwards the telemetry files written by NewFileClient to an OTel collector over OTLP/gRPC.
+type FileReplayer struct {
+	logs    collogs.LogsServiceClient
+	metrics colmetrics.MetricsServiceClient
+	traces  coltrace.TraceServiceClient
+}
+
+// NewFileReplayer creates a FileReplayer exporting to the collector of conn. Auth headers can be attached to the
+// contexts passed to Replay and ReplayFile as outgoing gRPC metadata.
+func NewFileReplayer(conn grpc.ClientConnInterface) *FileReplayer {
+	return &FileReplayer{
+		logs:    collogs.NewLogsServiceClient(conn),
+		metrics: colmetrics.NewMetricsServiceClient(conn),
+		traces:  coltrace.NewTraceServiceClient(conn),
+	}
+}
+
+// ReplayResult describes a replayed file.
+type ReplayResult struct {
+	Path string
+	// Requests is the number of export requests forwarded.
+	Requests int
+	// Rejected is the number of log records, data points or spans the collector reported as rejected.
+	Rejected int64
+}
+
+// Replay forwards every telemetry file of dir, oldest first per signal, and returns the results of the replayed files.
+// It stops at the first failure. Files should not be replayed while a client is still writing to them.
+func (r *FileReplayer) Replay(ctx context.Context, dir string) ([]ReplayResult, error) {
+	var results []ReplayResult
+	for _, signal := range fileSignals {
+		files, err := signalFiles(dir, signal)
+		if err != nil {
+			return results, err
+		}
+		for _, path := range files {
+			res, err := r.ReplayFile(ctx, path)
+			if err != nil {
+				return results, err
+			}
+			results = append(results, res)
+		}
+	}
+	return results, nil
+}
+
+// ReplayFile forwards the export requests of a single telemetry file. Its signal is given by its name.
+//
+// The progress is recorded next to the file after each forwarded request, so a file is resumed where a failed replay
+// left off, and a replayed file is not forwarded again. Use RemoveReplayedFile to remove both.
+func (r *FileReplayer) ReplayFile(ctx context.Context, path string) (ReplayResult, error) {
+	res := ReplayResult{Path: path}
+	export, err := r.exporter(filepath.Base(path))
+	if err != nil {
+		return res, err
+	}
+	offset, err := readReplayProgress(path)
+	if err != nil {
+		return res, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return res, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return res, err
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, maxReplayLineBytes)
+	for scanner.Scan() {
+		next := offset + int64(len(scanner.Bytes())) + 1
+		if len(scanner.Bytes()) > 0 {
+			rejected, err := export(ctx, scanner.Bytes())
+			if err != nil {
+				return res, fmt.Errorf("failed to replay %s at byte %d: %w", path, offset, err)
+			}
+			res.Requests++
+			res.Rejected += rejected
+		}
+		if err := writeReplayProgress(path, next); err != nil {
+			return res, err
+		}
+		offset = next
+	}
+	if err := scanner.Err(); err != nil {
+		return res, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return res, nil
+}
+
+// RemoveReplayedFile removes a telemetry file along with its replay progress.
+func RemoveReplayedFile(path string) error {
+	err := os.Remove(path)
+	if perr := os.Remove(path + replayProgressExt); perr != nil && !errors.Is(perr, os.ErrNotExist) {
+		err = errors.Join(err, perr)
+	}
+	return err
+}
+
+// readReplayProgress returns the offset of path up to which it has been forwarded.
+func readReplayProgress(path string) (int64, error) {
+	b, err := os.ReadFile(path + replayProgressExt)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("failed to read replay progress of %s: %w", path, err)
+	}
+	offset, err := strconv.ParseInt(strings.TrimSpace(string(b)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid replay progress of %s: %w", path, err)
+	}
+	return offset, nil
+}
+
+// writeReplayProgress atomically records that path has been forwarded up to offset.
+func writeReplayProgress(path string, offset int64) error {
+	tmp := path + replayProgressExt + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strconv.FormatInt(offset, 10)), 0o640); err != nil {
+		return fmt.Errorf("failed to record replay progress of %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path+replayProgressExt); err != nil {
+		return fmt.Errorf("failed to record replay progress of %s: %w", path, err)
+	}
+	return nil
+}
+
+type replayExportFunc func(ctx context.Context, line []byte) (rejected int64, err error)
+
+func (r *FileReplayer) exporter(name string) (replayExportFunc, error) {
+	signal, _, ok := strings.Cut(name, "-")
+	if !ok || !strings.HasSuffix(name, fileExportExt) {
+		return nil, fmt.Errorf("not a telemetry file: %s", name)
+	}
+	switch signal {
+	case signalLogs, signalMessages:
+		return func(ctx context.Context, line []byte) (int64, error) {
+			var req collogs.ExportLogsServiceRequest
+			if err := protojson.Unmarshal(line, &req); err != nil {
+				return 0, err
+			}
+			resp, err := r.logs.Export(ctx, &req)
+			return resp.GetPartialSuccess().GetRejectedLogRecords(), err
+		}, nil
+	case signalMetrics:
+		return func(ctx context.Context, line []byte) (int64, error) {
+			var req colmetrics.ExportMetricsServiceRequest
+			if err := protojson.Unmarshal(line, &req); err != nil {
+				return 0, err
+			}
+			resp, err := r.metrics.Export(ctx, &req)
+			return resp.GetPartialSuccess().GetRejectedDataPoints(), err
+		}, nil
+	case signalTraces:
+		return func(ctx context.Context, line []byte) (int64, error) {
+			var req coltrace.ExportTraceServiceRequest
+			if err := protojson.Unmarshal(line, &req); err != nil {
+				return 0, err
+			}
+			resp, err := r.traces.Export(ctx, &req)
+			return resp.GetPartialSuccess().GetRejectedSpans(), err
+		}, nil
+	default:
+		return nil, errors.New("unknown signal of telemetry file: " + name)
+	}
+}
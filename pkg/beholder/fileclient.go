@@ -0,0 +1,332 @@
+package beholder
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	collogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/chipingress"
+	pkglogger "github.com/smartcontractkit/chainlink-common/pkg/logger"
+)
+
+const (
+	// signalMessages is the file signal of custom messages, which are exported as logs.
+	signalMessages = "messages"
+
+	defaultFileExportMaxBytes = 100 << 20
+
+	fileExportExt        = ".jsonl"
+	fileExportTimeLayout = "20060102T150405.000000000Z"
+)
+
+// fileSignals are the signals written by NewFileClient, each to its own files.
+var fileSignals = []string{signalLogs, signalMessages, signalMetrics, signalTraces}
+
+// NewFileClient creates a beholder Client which writes logs, metrics, traces and custom messages to rotating
+// OTLP-JSON files in Config.OtelExporterFileDir, instead of exporting them to an OTel collector. Each line of a file
+// holds the JSON encoding of the OTLP export request a collector would have received, as written by the collector's
+// file exporter. The files can be forwarded to a collector later with FileReplayer, or the replay command.
+//
+// Chip Ingress is not used in this mode, so custom messages are only written to the files.
+func NewFileClient(cfg Config) (_ *Client, err error) {
+	if cfg.OtelExporterFileDir == "" {
+		return nil, errors.New("file exporter directory is not set")
+	}
+	if err = os.MkdirAll(cfg.OtelExporterFileDir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create file exporter directory: %w", err)
+	}
+	baseResource, err := newOtelResource(cfg)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]*rotatingFile, len(fileSignals))
+	for _, signal := range fileSignals {
+		files[signal] = newRotatingFile(cfg.OtelExporterFileDir, signal, cfg.FileExportMaxBytes, cfg.FileExportMaxFiles)
+	}
+	closeFiles := func() (err error) {
+		for _, signal := range fileSignals {
+			err = errors.Join(err, files[signal].Close())
+		}
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = closeFiles()
+		}
+	}()
+	// note: context is unused internally
+	ctx := context.Background()
+
+	// Tracer
+	traceExporter, err := otlptracehttp.New(ctx,
+		otlptracehttp.WithEndpoint("localhost"),
+		otlptracehttp.WithInsecure(),
+		otlptracehttp.WithHTTPClient(newFileHTTPClient(files[signalTraces], func() proto.Message { return &coltrace.ExportTraceServiceRequest{} })),
+	)
+	if err != nil {
+		return nil, err
+	}
+	batcherOpts := []sdktrace.BatchSpanProcessorOption{}
+	if cfg.TraceBatchTimeout > 0 {
+		batcherOpts = append(batcherOpts, sdktrace.WithBatchTimeout(cfg.TraceBatchTimeout)) // Default is 5s
+	}
+	tracerProvider := sdktrace.NewTracerProvider(append(cfg.tracerProviderOptions(traceExporter, batcherOpts...),
+		sdktrace.WithResource(baseResource),
+	)...)
+
+	// Meter
+	metricExporter, err := otlpmetrichttp.New(ctx,
+		otlpmetrichttp.WithEndpoint("localhost"),
+		otlpmetrichttp.WithInsecure(),
+		otlpmetrichttp.WithHTTPClient(newFileHTTPClient(files[signalMetrics], func() proto.Message { return &colmetrics.ExportMetricsServiceRequest{} })),
+	)
+	if err != nil {
+		return nil, err
+	}
+	readerOpts := []sdkmetric.PeriodicReaderOption{
+		sdkmetric.WithInterval(cfg.MetricReaderInterval), // Default is 10s
+	}
+	for _, p := range cfg.MetricProducers {
+		readerOpts = append(readerOpts, sdkmetric.WithProducer(p))
+	}
+	meterProvider := sdkmetric.NewMeterProvider(append(cfg.metricOptions(),
+		sdkmetric.WithReader(sdkmetric.NewPeriodicReader(cfg.metricExporter(metricExporter), readerOpts...)),
+		sdkmetric.WithResource(baseResource),
+	)...)
+	meter := meterProvider.Meter(defaultPackageName)
+
+	// Logger
+	newLogExporter := func(signal string) (sdklog.Exporter, error) {
+		return otlploghttp.New(ctx,
+			otlploghttp.WithEndpoint("localhost"),
+			otlploghttp.WithInsecure(),
+			otlploghttp.WithHTTPClient(newFileHTTPClient(files[signal], func() proto.Message { return &collogs.ExportLogsServiceRequest{} })),
+		)
+	}
+	var loggerProvider *sdklog.LoggerProvider
+	if !cfg.LogStreamingEnabled {
+		loggerProvider = BeholderNoopLoggerProvider()
+	} else {
+		logExporter, err := newLogExporter(signalLogs)
+		if err != nil {
+			return nil, err
+		}
+		loggerOpts, err := newLoggerProviderOpts(cfg, baseResource, logExporter)
+		if err != nil {
+			return nil, err
+		}
+		loggerProvider = sdklog.NewLoggerProvider(loggerOpts...)
+	}
+
+	// Message emitter
+	messageExporter, err := newLogExporter(signalMessages)
+	if err != nil {
+		return nil, err
+	}
+	messageLoggerOpts, err := newMessageLoggerProviderOpts(cfg, baseResource, messageExporter)
+	if err != nil {
+		return nil, err
+	}
+	messageLoggerProvider := sdklog.NewLoggerProvider(messageLoggerOpts...)
+
+	onClose := func() (err error) {
+		for _, provider := range []shutdowner{messageLoggerProvider, loggerProvider, tracerProvider, meterProvider} {
+			err = errors.Join(err, provider.Shutdown(context.Background()))
+		}
+		// the providers flush to the files when they shut down
+		return errors.Join(err, closeFiles())
+	}
+	c := &Client{
+		Config:                cfg,
+		Logger:                loggerProvider.Logger(defaultPackageName),
+		Tracer:                tracerProvider.Tracer(defaultPackageName),
+		Meter:                 meter,
+		Emitter:               NewMessageEmitter(messageLoggerProvider.Logger(defaultPackageName)),
+		Chip:                  &chipingress.NoopClient{},
+		LoggerProvider:        loggerProvider,
+		TracerProvider:        tracerProvider,
+		MeterProvider:         meterProvider,
+		MessageLoggerProvider: messageLoggerProvider,
+		OnClose:               onClose,
+	}
+	lggr := cfg.ChipIngressLogger
+	if lggr == nil {
+		lggr = pkglogger.Nop()
+	}
+	c.initService(lggr, nil)
+	return c, nil
+}
+
+// newFileHTTPClient returns the http.Client of an OTLP/HTTP exporter which appends the export requests to f, instead
+// of sending them, so that the files hold exactly what a collector would have received.
+func newFileHTTPClient(f *rotatingFile, newRequest func() proto.Message) *http.Client {
+	return &http.Client{Transport: &fileTransport{file: f, newRequest: newRequest}}
+}
+
+type fileTransport struct {
+	file       *rotatingFile
+	newRequest func() proto.Message
+}
+
+func (t *fileTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	b, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	msg := t.newRequest()
+	if err = proto.Unmarshal(b, msg); err != nil {
+		return nil, fmt.Errorf("failed to decode OTLP request: %w", err)
+	}
+	line, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode OTLP request as JSON: %w", err)
+	}
+	if err = t.file.WriteLine(line); err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		Status:     http.StatusText(http.StatusOK),
+		StatusCode: http.StatusOK,
+		Proto:      req.Proto,
+		ProtoMajor: req.ProtoMajor,
+		ProtoMinor: req.ProtoMinor,
+		Header:     http.Header{"Content-Type": []string{"application/x-protobuf"}},
+		Body:       io.NopCloser(bytes.NewReader(nil)),
+		Request:    req,
+	}, nil
+}
+
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	defer req.Body.Close()
+	var r io.Reader = req.Body
+	if req.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return io.ReadAll(r)
+}
+
+// rotatingFile appends lines to the files of a signal, named "<signal>-<UTC creation time>.jsonl" so that they sort
+// from oldest to newest. A new file is started by each client, and whenever the current one would exceed maxBytes.
+type rotatingFile struct {
+	dir      string
+	signal   string
+	maxBytes int64
+	maxFiles int
+
+	mu   sync.Mutex
+	f    *os.File
+	size int64
+}
+
+func newRotatingFile(dir, signal string, maxBytes int64, maxFiles int) *rotatingFile {
+	if maxBytes <= 0 {
+		maxBytes = defaultFileExportMaxBytes
+	}
+	return &rotatingFile{dir: dir, signal: signal, maxBytes: maxBytes, maxFiles: maxFiles}
+}
+
+// WriteLine appends line and a newline to the current file. The file is created lazily, so that signals which are
+// never exported leave no empty files behind.
+func (r *rotatingFile) WriteLine(line []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := int64(len(line)) + 1
+	if r.f != nil && r.size > 0 && r.size+n > r.maxBytes {
+		if err := r.f.Close(); err != nil {
+			return err
+		}
+		r.f = nil
+	}
+	if r.f == nil {
+		if err := r.open(); err != nil {
+			return err
+		}
+	}
+	if _, err := r.f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write %s file: %w", r.signal, err)
+	}
+	r.size += n
+	return nil
+}
+
+// open starts a new file and removes the oldest ones beyond maxFiles. Must be called with r.mu held.
+func (r *rotatingFile) open() error {
+	name := filepath.Join(r.dir, r.signal+"-"+time.Now().UTC().Format(fileExportTimeLayout)+fileExportExt)
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return fmt.Errorf("failed to create %s file: %w", r.signal, err)
+	}
+	r.f, r.size = f, 0
+	if r.maxFiles <= 0 {
+		return nil
+	}
+	files, err := signalFiles(r.dir, r.signal)
+	if err != nil {
+		return err
+	}
+	for len(files) > r.maxFiles {
+		if err := RemoveReplayedFile(files[0]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove rotated %s file: %w", r.signal, err)
+		}
+		files = files[1:]
+	}
+	return nil
+}
+
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	err := errors.Join(r.f.Sync(), r.f.Close())
+	r.f = nil
+	return err
+}
+
+// signalFiles returns the files of signal in dir, oldest first.
+func signalFiles(dir, signal string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		name := e.Name()
+		if !e.IsDir() && strings.HasPrefix(name, signal+"-") && strings.HasSuffix(name, fileExportExt) {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	slices.Sort(files)
+	return files, nil
+}
@@ -0,0 +1,300 @@
+package beholder_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	otellog "go.opentelemetry.io/otel/log"
+	collogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	colmetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	coltrace "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/beholder"
+)
+
+var testMessageAttrs = []any{
+	beholder.AttrKeyDataSchema, "/test/v1",
+	beholder.AttrKeyDomain, "platform",
+	beholder.AttrKeyEntity, "TestEvent",
+}
+
+func newTestFileClient(t *testing.T, dir string, modify func(*beholder.Config)) *beholder.Client {
+	t.Helper()
+	cfg := beholder.TestDefaultConfig()
+	cfg.OtelExporterGRPCEndpoint = ""
+	cfg.OtelExporterFileDir = dir
+	cfg.LogStreamingEnabled = true
+	if modify != nil {
+		modify(&cfg)
+	}
+	client, err := beholder.NewClient(cfg)
+	require.NoError(t, err)
+	require.NoError(t, client.Start(t.Context()))
+	return client
+}
+
+// readRequests decodes the lines of the files of signal in dir.
+func readRequests[M proto.Message](t *testing.T, dir, signal string, newMsg func() M) []M {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, signal+"-*.jsonl"))
+	require.NoError(t, err)
+	var msgs []M
+	for _, path := range files {
+		f, err := os.Open(path)
+		require.NoError(t, err)
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			m := newMsg()
+			require.NoError(t, protojson.Unmarshal(scanner.Bytes(), m))
+			msgs = append(msgs, m)
+		}
+		require.NoError(t, scanner.Err())
+		require.NoError(t, f.Close())
+	}
+	return msgs
+}
+
+func emitTestTelemetry(t *testing.T, client *beholder.Client) {
+	t.Helper()
+	ctx := t.Context()
+	require.NoError(t, client.Emitter.Emit(ctx, []byte("custom message"), testMessageAttrs...))
+
+	var record otellog.Record
+	record.SetBody(otellog.StringValue("log line"))
+	client.Logger.Emit(ctx, record)
+
+	_, span := client.Tracer.Start(ctx, "test-span")
+	span.End()
+
+	counter, err := client.Meter.Int64Counter("test_counter")
+	require.NoError(t, err)
+	counter.Add(ctx, 1)
+}
+
+func TestNewFileClient(t *testing.T) {
+	dir := t.TempDir()
+	client := newTestFileClient(t, dir, nil)
+	emitTestTelemetry(t, client)
+	require.NoError(t, client.Close())
+
+	messages := readRequests(t, dir, "messages", func() *collogs.ExportLogsServiceRequest { return &collogs.ExportLogsServiceRequest{} })
+	require.Len(t, messages, 1)
+	assert.Equal(t, []byte("custom message"), messages[0].ResourceLogs[0].ScopeLogs[0].LogRecords[0].Body.GetBytesValue())
+
+	logs := readRequests(t, dir, "logs", func() *collogs.ExportLogsServiceRequest { return &collogs.ExportLogsServiceRequest{} })
+	require.Len(t, logs, 1)
+	assert.Equal(t, "log line", logs[0].ResourceLogs[0].ScopeLogs[0].LogRecords[0].Body.GetStringValue())
+
+	traces := readRequests(t, dir, "traces", func() *coltrace.ExportTraceServiceRequest { return &coltrace.ExportTraceServiceRequest{} })
+	require.Len(t, traces, 1)
+	assert.Equal(t, "test-span", traces[0].ResourceSpans[0].ScopeSpans[0].Spans[0].Name)
+
+	metrics := readRequests(t, dir, "metrics", func() *colmetrics.ExportMetricsServiceRequest { return &colmetrics.ExportMetricsServiceRequest{} })
+	var names []string
+	for _, req := range metrics {
+		for _, sm := range req.ResourceMetrics[0].ScopeMetrics {
+			for _, m := range sm.Metrics {
+				names = append(names, m.Name)
+			}
+		}
+	}
+	assert.Contains(t, names, "test_counter")
+}
+
+func TestNewFileClient_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	client := newTestFileClient(t, dir, func(cfg *beholder.Config) {
+		cfg.FileExportMaxBytes = 1
+		cfg.FileExportMaxFiles = 2
+	})
+	for range 5 {
+		require.NoError(t, client.Emitter.Emit(t.Context(), []byte("custom message"), testMessageAttrs...))
+	}
+	require.NoError(t, client.Close())
+
+	files, err := filepath.Glob(filepath.Join(dir, "messages-*.jsonl"))
+	require.NoError(t, err)
+	assert.Len(t, files, 2, "oldest files are removed")
+	assert.Len(t, readRequests(t, dir, "messages", func() *collogs.ExportLogsServiceRequest { return &collogs.ExportLogsServiceRequest{} }), 2)
+}
+
+type testCollector struct {
+	collogs.UnimplementedLogsServiceServer
+	colmetrics.UnimplementedMetricsServiceServer
+	coltrace.UnimplementedTraceServiceServer
+
+	mu      sync.Mutex
+	logs    []*collogs.ExportLogsServiceRequest
+	metrics []*colmetrics.ExportMetricsServiceRequest
+	traces  []*coltrace.ExportTraceServiceRequest
+	auth    []string
+}
+
+func (c *testCollector) recordAuth(ctx context.Context) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	c.auth = append(c.auth, md.Get("authorization")...)
+}
+
+func (c *testCollector) Export(ctx context.Context, req *collogs.ExportLogsServiceRequest) (*collogs.ExportLogsServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.recordAuth(ctx)
+	c.logs = append(c.logs, req)
+	return &collogs.ExportLogsServiceResponse{}, nil
+}
+
+type testMetricsCollector struct{ *testCollector }
+
+func (c testMetricsCollector) Export(ctx context.Context, req *colmetrics.ExportMetricsServiceRequest) (*colmetrics.ExportMetricsServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.recordAuth(ctx)
+	c.metrics = append(c.metrics, req)
+	return &colmetrics.ExportMetricsServiceResponse{}, nil
+}
+
+type testTraceCollector struct{ *testCollector }
+
+func (c testTraceCollector) Export(ctx context.Context, req *coltrace.ExportTraceServiceRequest) (*coltrace.ExportTraceServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.recordAuth(ctx)
+	c.traces = append(c.traces, req)
+	return &coltrace.ExportTraceServiceResponse{
+		PartialSuccess: &coltrace.ExportTracePartialSuccess{RejectedSpans: 1},
+	}, nil
+}
+
+func TestFileReplayer(t *testing.T) {
+	dir := t.TempDir()
+	client := newTestFileClient(t, dir, nil)
+	emitTestTelemetry(t, client)
+	require.NoError(t, client.Close())
+
+	collector := &testCollector{}
+	srv := grpc.NewServer()
+	collogs.RegisterLogsServiceServer(srv, collector)
+	colmetrics.RegisterMetricsServiceServer(srv, testMetricsCollector{collector})
+	coltrace.RegisterTraceServiceServer(srv, testTraceCollector{collector})
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, conn.Close()) })
+
+	ctx, cancel := context.WithTimeout(metadata.AppendToOutgoingContext(t.Context(), "authorization", "token"), 10*time.Second)
+	defer cancel()
+	results, err := beholder.NewFileReplayer(conn).Replay(ctx, dir)
+	require.NoError(t, err)
+
+	var signals []string
+	for _, res := range results {
+		signal, _, _ := strings.Cut(filepath.Base(res.Path), "-")
+		signals = append(signals, signal)
+		if signal == "traces" {
+			assert.Equal(t, int64(1), res.Rejected)
+		}
+	}
+	assert.Equal(t, []string{"logs", "messages", "metrics", "traces"}, signals)
+
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	require.Len(t, collector.logs, 2)
+	assert.Equal(t, "log line", collector.logs[0].ResourceLogs[0].ScopeLogs[0].LogRecords[0].Body.GetStringValue())
+	assert.NotEmpty(t, collector.metrics)
+	require.Len(t, collector.traces, 1)
+	assert.Equal(t, "test-span", collector.traces[0].ResourceSpans[0].ScopeSpans[0].Spans[0].Name)
+	assert.NotEmpty(t, collector.auth)
+	for _, auth := range collector.auth {
+		assert.Equal(t, "token", auth)
+	}
+}
+
+func TestFileReplayer_ReplayFile_NotTelemetry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	require.NoError(t, os.WriteFile(path, []byte("{}"), 0o600))
+	_, err := beholder.NewFileReplayer(nil).ReplayFile(t.Context(), path)
+	require.ErrorContains(t, err, "not a telemetry file")
+}
+
+type flakyLogsCollector struct {
+	*testCollector
+	failAt int
+}
+
+func (c *flakyLogsCollector) Export(ctx context.Context, req *collogs.ExportLogsServiceRequest) (*collogs.ExportLogsServiceResponse, error) {
+	if c.failAt--; c.failAt == 0 {
+		return nil, errors.New("unavailable")
+	}
+	return c.testCollector.Export(ctx, req)
+}
+
+func TestFileReplayer_ReplayFile_Resume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs-20260101T000000.000000000Z.jsonl")
+	var lines []string
+	for _, body := range []string{"a", "b", "c"} {
+		b, err := protojson.Marshal(&collogs.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{{
+			ScopeLogs: []*logspb.ScopeLogs{{LogRecords: []*logspb.LogRecord{{
+				Body: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: body}},
+			}}}},
+		}}})
+		require.NoError(t, err)
+		lines = append(lines, string(b))
+	}
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600))
+
+	collector := &flakyLogsCollector{testCollector: &testCollector{}, failAt: 2}
+	srv := grpc.NewServer()
+	collogs.RegisterLogsServiceServer(srv, collector)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, conn.Close()) })
+	replayer := beholder.NewFileReplayer(conn)
+
+	res, err := replayer.ReplayFile(t.Context(), path)
+	require.ErrorContains(t, err, "unavailable")
+	assert.Equal(t, 1, res.Requests)
+
+	res, err = replayer.ReplayFile(t.Context(), path)
+	require.NoError(t, err)
+	assert.Equal(t, 2, res.Requests, "the replay resumes after the forwarded requests")
+
+	res, err = replayer.ReplayFile(t.Context(), path)
+	require.NoError(t, err)
+	assert.Equal(t, 0, res.Requests, "a replayed file is not forwarded again")
+
+	var bodies []string
+	for _, req := range collector.logs {
+		bodies = append(bodies, req.ResourceLogs[0].ScopeLogs[0].LogRecords[0].Body.GetStringValue())
+	}
+	assert.Equal(t, []string{"a", "b", "c"}, bodies)
+
+	require.NoError(t, beholder.RemoveReplayedFile(path))
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
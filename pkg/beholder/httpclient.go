@@ -113,7 +113,7 @@ func NewHTTPClient(cfg Config, otlploghttpNew otlploghttpFactory) (*Client, erro
 	}
 	loggerProvider := sdklog.NewLoggerProvider(
 		sdklog.WithResource(loggerResource),
-		sdklog.WithProcessor(loggerProcessor),
+		sdklog.WithProcessor(cfg.logProcessor(loggerProcessor)),
 	)
 
 	// If log streaming is disabled, use a noop logger provider
@@ -243,18 +243,9 @@ func newHTTPTracerProvider(config Config, resource *sdkresource.Resource, tlsCon
 	if config.TraceBatchTimeout > 0 {
 		batcherOpts = append(batcherOpts, sdktrace.WithBatchTimeout(config.TraceBatchTimeout)) // Default is 5s
 	}
-	opts := []sdktrace.TracerProviderOption{
-		sdktrace.WithBatcher(exporter, batcherOpts...),
+	opts := append(config.tracerProviderOptions(exporter, batcherOpts...),
 		sdktrace.WithResource(resource),
-		sdktrace.WithSampler(
-			sdktrace.ParentBased(
-				sdktrace.TraceIDRatioBased(config.TraceSampleRatio),
-			),
-		),
-	}
-	if config.TraceSpanExporter != nil {
-		opts = append(opts, sdktrace.WithBatcher(config.TraceSpanExporter))
-	}
+	)
 	return sdktrace.NewTracerProvider(opts...), nil
 }
 
@@ -288,7 +279,7 @@ func newHTTPMeterProvider(config Config, resource *sdkresource.Resource, tlsConf
 	mpOpts := append(config.metricOptions(),
 		sdkmetric.WithReader(
 			sdkmetric.NewPeriodicReader(
-				exporter,
+				config.metricExporter(exporter),
 				sdkmetric.WithInterval(config.MetricReaderInterval), // Default is 10s
 			)),
 		sdkmetric.WithResource(resource),
@@ -3,6 +3,7 @@ package beholder
 
 import (
 	"context"
+	"errors"
 
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
@@ -33,8 +34,22 @@ type ProtoProcessor interface {
 	Process(ctx context.Context, m proto.Message, attrKVs ...any) error
 }
 
-func NewProtoEmitter(lggr logger.Logger, client *Client, schemaBasePath string) ProtoEmitter {
-	return &protoEmitter{lggr, client, schemaBasePath}
+// ProtoEmitterOpt configures a ProtoEmitter.
+type ProtoEmitterOpt func(*protoEmitter)
+
+// WithSchemaManager registers the schema of each message type with schemas before its first emit, and refuses to
+// emit messages whose schema is incompatible, with ErrIncompatibleSchema. Messages whose schema could not be
+// registered for other reasons, e.g. an unavailable registry, are emitted unregistered.
+func WithSchemaManager(schemas *SchemaManager) ProtoEmitterOpt {
+	return func(e *protoEmitter) { e.schemas = schemas }
+}
+
+func NewProtoEmitter(lggr logger.Logger, client *Client, schemaBasePath string, opts ...ProtoEmitterOpt) ProtoEmitter {
+	e := &protoEmitter{lggr: lggr, client: client, schemaBasePath: schemaBasePath}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
 }
 
 // protoEmitter is a ProtoEmitter implementation
@@ -44,6 +59,7 @@ type protoEmitter struct {
 	lggr           logger.Logger
 	client         *Client
 	schemaBasePath string
+	schemas        *SchemaManager
 }
 
 func (e *protoEmitter) Emit(ctx context.Context, m proto.Message, attrKVs ...any) error {
@@ -59,6 +75,15 @@ func (e *protoEmitter) Emit(ctx context.Context, m proto.Message, attrKVs ...any
 		attrKVs = e.appendAttrsRequired(attrKVs, m)
 	}
 
+	if e.schemas != nil {
+		if err = e.registerSchema(ctx, m, attrKVs); errors.Is(err, ErrIncompatibleSchema) {
+			e.lggr.Errorw("[Beholder] Refused to emit incompatible schema", "err", err)
+			return err
+		} else if err != nil {
+			e.lggr.Warnw("[Beholder] Failed to register schema, emitting unregistered", "err", err)
+		}
+	}
+
 	// Emit the message with attributes
 	err = e.client.Emitter.Emit(ctx, payload, attrKVs...)
 	if err != nil {
@@ -87,6 +112,16 @@ func (e *protoEmitter) EmitWithLog(ctx context.Context, m proto.Message, attrKVs
 	return e.Emit(ctx, m, attrKVs...)
 }
 
+// registerSchema registers the schema of m under the domain and entity it is emitted with
+func (e *protoEmitter) registerSchema(ctx context.Context, m proto.Message, attrKVs []any) error {
+	domain, entity, err := ExtractSourceAndType(attrKVs...)
+	if err != nil {
+		return err
+	}
+	_, err = e.schemas.Register(ctx, m, domain, entity)
+	return err
+}
+
 // appendAttrsRequired appends required attributes to the attribute key-value list
 func (e *protoEmitter) appendAttrsRequired(attrKVs []any, m proto.Message) []any {
 	attrKVs = appendRequiredAttrDataSchema(attrKVs, toSchemaPath(m, e.schemaBasePath))
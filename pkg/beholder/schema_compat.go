@@ -0,0 +1,189 @@
+package beholder
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ErrIncompatibleSchema is returned when a schema change breaks the configured SchemaCompatibility.
+var ErrIncompatibleSchema = errors.New("incompatible schema change")
+
+// SchemaCompatibility is the guarantee which schema changes must keep, following the schema registry modes.
+type SchemaCompatibility int
+
+const (
+	// SchemaCompatibilityNone allows any change.
+	SchemaCompatibilityNone SchemaCompatibility = iota
+	// SchemaCompatibilityBackward requires that data written with the previous schema can be read with the new one.
+	SchemaCompatibilityBackward
+	// SchemaCompatibilityForward requires that data written with the new schema can be read with the previous one.
+	SchemaCompatibilityForward
+	// SchemaCompatibilityFull requires both SchemaCompatibilityBackward and SchemaCompatibilityForward.
+	SchemaCompatibilityFull
+)
+
+func (c SchemaCompatibility) String() string {
+	switch c {
+	case SchemaCompatibilityNone:
+		return "NONE"
+	case SchemaCompatibilityBackward:
+		return "BACKWARD"
+	case SchemaCompatibilityForward:
+		return "FORWARD"
+	case SchemaCompatibilityFull:
+		return "FULL"
+	default:
+		return fmt.Sprintf("SchemaCompatibility(%d)", int(c))
+	}
+}
+
+func (c SchemaCompatibility) backward() bool {
+	return c == SchemaCompatibilityBackward || c == SchemaCompatibilityFull
+}
+
+func (c SchemaCompatibility) forward() bool {
+	return c == SchemaCompatibilityForward || c == SchemaCompatibilityFull
+}
+
+// CheckSchemaCompatibility checks that changing the message prev to next keeps compatibility c, including the
+// message and enum types of their fields. Besides the wire format, it requires the numbers of removed fields and
+// enum values to be reserved, so that they cannot be reused by a later change.
+//
+// The returned error wraps ErrIncompatibleSchema and lists every violation.
+func CheckSchemaCompatibility(prev, next protoreflect.MessageDescriptor, c SchemaCompatibility) error {
+	if c == SchemaCompatibilityNone {
+		return nil
+	}
+	cc := &compatChecker{mode: c, seen: make(map[protoreflect.FullName]bool)}
+	cc.message(prev, next)
+	if len(cc.violations) > 0 {
+		return fmt.Errorf("%w (%s) of %s: %s", ErrIncompatibleSchema, c, next.FullName(), strings.Join(cc.violations, "; "))
+	}
+	return nil
+}
+
+type compatChecker struct {
+	mode       SchemaCompatibility
+	seen       map[protoreflect.FullName]bool
+	violations []string
+}
+
+func (cc *compatChecker) violation(format string, args ...any) {
+	cc.violations = append(cc.violations, fmt.Sprintf(format, args...))
+}
+
+func (cc *compatChecker) message(prev, next protoreflect.MessageDescriptor) {
+	if cc.seen[next.FullName()] {
+		return
+	}
+	cc.seen[next.FullName()] = true
+
+	prevFields, nextFields := prev.Fields(), next.Fields()
+	for i := 0; i < prevFields.Len(); i++ {
+		pf := prevFields.Get(i)
+		nf := nextFields.ByNumber(pf.Number())
+		if nf == nil {
+			if !next.ReservedRanges().Has(pf.Number()) {
+				cc.violation("field %s = %d was removed without reserving its number", pf.FullName(), pf.Number())
+			}
+			if cc.mode.forward() && pf.Cardinality() == protoreflect.Required {
+				cc.violation("required field %s was removed", pf.FullName())
+			}
+			continue
+		}
+		cc.field(pf, nf)
+	}
+	if cc.mode.backward() {
+		for i := 0; i < nextFields.Len(); i++ {
+			nf := nextFields.Get(i)
+			if nf.Cardinality() == protoreflect.Required && prevFields.ByNumber(nf.Number()) == nil {
+				cc.violation("required field %s was added", nf.FullName())
+			}
+		}
+	}
+}
+
+func (cc *compatChecker) field(pf, nf protoreflect.FieldDescriptor) {
+	if pf.IsMap() != nf.IsMap() || (pf.Cardinality() == protoreflect.Repeated) != (nf.Cardinality() == protoreflect.Repeated) {
+		cc.violation("field %s = %d changed between singular, repeated and map", nf.FullName(), nf.Number())
+		return
+	}
+	if pf.IsMap() {
+		cc.fieldType(nf, pf.MapKey(), nf.MapKey())
+		cc.fieldType(nf, pf.MapValue(), nf.MapValue())
+	} else {
+		cc.fieldType(nf, pf, nf)
+	}
+	if oneofName(pf) != oneofName(nf) {
+		cc.violation("field %s = %d moved from oneof %q to %q", nf.FullName(), nf.Number(), oneofName(pf), oneofName(nf))
+	}
+	if pf.Cardinality() != nf.Cardinality() {
+		if cc.mode.backward() && nf.Cardinality() == protoreflect.Required {
+			cc.violation("field %s became required", nf.FullName())
+		}
+		if cc.mode.forward() && pf.Cardinality() == protoreflect.Required {
+			cc.violation("field %s is no longer required", nf.FullName())
+		}
+	}
+}
+
+// fieldType checks the types of the field f, or of its map key or value.
+func (cc *compatChecker) fieldType(f, prev, next protoreflect.FieldDescriptor) {
+	if prev.Kind() != next.Kind() {
+		cc.violation("field %s = %d changed type from %s to %s", f.FullName(), f.Number(), prev.Kind(), next.Kind())
+		return
+	}
+	switch next.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		if prev.Message().FullName() != next.Message().FullName() {
+			cc.violation("field %s = %d changed type from %s to %s", f.FullName(), f.Number(), prev.Message().FullName(), next.Message().FullName())
+			return
+		}
+		cc.message(prev.Message(), next.Message())
+	case protoreflect.EnumKind:
+		if prev.Enum().FullName() != next.Enum().FullName() {
+			cc.violation("field %s = %d changed type from %s to %s", f.FullName(), f.Number(), prev.Enum().FullName(), next.Enum().FullName())
+			return
+		}
+		cc.enum(prev.Enum(), next.Enum())
+	}
+}
+
+func (cc *compatChecker) enum(prev, next protoreflect.EnumDescriptor) {
+	if cc.seen[next.FullName()] {
+		return
+	}
+	cc.seen[next.FullName()] = true
+
+	for i := 0; i < prev.Values().Len(); i++ {
+		pv := prev.Values().Get(i)
+		if next.Values().ByNumber(pv.Number()) != nil {
+			continue
+		}
+		if !next.ReservedRanges().Has(pv.Number()) {
+			cc.violation("enum value %s = %d was removed without reserving its number", pv.FullName(), pv.Number())
+		}
+		// readers of closed enums treat unknown values as unknown fields
+		if cc.mode.backward() && next.IsClosed() {
+			cc.violation("value %s of closed enum %s was removed", pv.Name(), next.FullName())
+		}
+	}
+	if cc.mode.forward() && prev.IsClosed() {
+		for i := 0; i < next.Values().Len(); i++ {
+			if nv := next.Values().Get(i); prev.Values().ByNumber(nv.Number()) == nil {
+				cc.violation("value %s was added to closed enum %s", nv.Name(), next.FullName())
+			}
+		}
+	}
+}
+
+// oneofName returns the name of the real oneof containing fd, or "".
+func oneofName(fd protoreflect.FieldDescriptor) protoreflect.Name {
+	if od := fd.ContainingOneof(); od != nil && !od.IsSynthetic() {
+		return od.Name()
+	}
+	return ""
+}
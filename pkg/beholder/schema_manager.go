@@ -0,0 +1,295 @@
+package beholder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/chipingress/pb"
+)
+
+// SchemaRegistrar registers schemas with the Chip Ingress schema registry, returning the version of each subject.
+// chipingress.Client implements it.
+type SchemaRegistrar interface {
+	RegisterSchemas(ctx context.Context, schemas ...*pb.Schema) (map[string]int, error)
+}
+
+// SchemaSubjectFunc returns the schema subject of messages emitted with a domain and entity.
+type SchemaSubjectFunc func(domain, entity string) string
+
+// DefaultSchemaSubject returns "<domain>-<entity>", the subject Chip Ingress looks up for an event of that source and
+// type.
+func DefaultSchemaSubject(domain, entity string) string {
+	return domain + "-" + entity
+}
+
+// SchemaManagerConfig holds configuration for creating a SchemaManager.
+type SchemaManagerConfig struct {
+	// Compatibility is required of every change of the schema of a subject. Zero allows any change.
+	Compatibility SchemaCompatibility
+	// Baseline optionally holds the files of the schemas registered by previous releases, e.g. from a
+	// FileDescriptorSet committed with them. The first registration of a message in this process is checked against
+	// the message of the same full name in Baseline, so an incompatible change is refused before it is registered.
+	Baseline *protoregistry.Files
+	// Subject defaults to DefaultSchemaSubject.
+	Subject SchemaSubjectFunc
+	// RetryBackoff is how long a failed registration is cached before the registry is tried again. It doubles with
+	// each consecutive failure, up to MaxSchemaRetryBackoff. Defaults to DefaultSchemaRetryBackoff.
+	RetryBackoff time.Duration
+}
+
+const (
+	// DefaultSchemaRetryBackoff is the default SchemaManagerConfig.RetryBackoff.
+	DefaultSchemaRetryBackoff = time.Second
+	// MaxSchemaRetryBackoff is the longest a failed registration is cached.
+	MaxSchemaRetryBackoff = time.Minute
+)
+
+// New creates a SchemaManager from the config.
+func (c SchemaManagerConfig) New(registrar SchemaRegistrar) (*SchemaManager, error) {
+	if registrar == nil {
+		return nil, errors.New("schema registrar is nil")
+	}
+	subject := c.Subject
+	if subject == nil {
+		subject = DefaultSchemaSubject
+	}
+	retryBackoff := c.RetryBackoff
+	if retryBackoff <= 0 {
+		retryBackoff = DefaultSchemaRetryBackoff
+	}
+	return &SchemaManager{
+		registrar:     registrar,
+		compatibility: c.Compatibility,
+		baseline:      c.Baseline,
+		subject:       subject,
+		retryBackoff:  retryBackoff,
+		subjects:      make(map[string]*registeredSchema),
+		files:         make(map[string]*registeredSchema),
+		failures:      make(map[string]*registrationFailure),
+	}, nil
+}
+
+// SchemaManager registers the schemas of emitted messages with the Chip Ingress schema registry. A message is
+// registered the first time it is emitted under a subject, together with the files it imports, and the returned
+// versions are cached. Registering a different descriptor under a subject, e.g. after a dynamic schema update, is
+// checked against the previously registered one first.
+//
+// The registry is called without holding locks, and concurrent registrations of the same subject or imported file
+// share a single call. A failed registration is cached, and returned without calling the registry again until its
+// backoff has passed.
+type SchemaManager struct {
+	registrar     SchemaRegistrar
+	compatibility SchemaCompatibility
+	baseline      *protoregistry.Files
+	subject       SchemaSubjectFunc
+	retryBackoff  time.Duration
+	group         singleflight.Group
+
+	mu sync.RWMutex
+	// subjects holds the message schemas by subject.
+	subjects map[string]*registeredSchema
+	// files holds the imported files by path, which is their subject.
+	files map[string]*registeredSchema
+	// failures holds the last failed registration of subjects and files, by singleflight key.
+	failures map[string]*registrationFailure
+}
+
+type registrationFailure struct {
+	err     error
+	retryAt time.Time
+	backoff time.Duration
+}
+
+type registeredSchema struct {
+	message protoreflect.MessageDescriptor // nil for imported files
+	file    protoreflect.FileDescriptor
+	version int
+}
+
+// Register registers the schema of m emitted with domain and entity, unless it already was, and returns its
+// version. It fails with ErrIncompatibleSchema if the schema is not compatible with the previous one of the subject.
+func (s *SchemaManager) Register(ctx context.Context, m proto.Message, domain, entity string) (int, error) {
+	md := m.ProtoReflect().Descriptor()
+	subject := s.subject(domain, entity)
+	for {
+		s.mu.RLock()
+		reg, ok := s.subjects[subject]
+		s.mu.RUnlock()
+		if ok && reg.message == md {
+			return reg.version, nil
+		}
+
+		v, err, _ := s.group.Do("subject:"+subject, func() (any, error) {
+			return s.registerMessage(ctx, subject, md)
+		})
+		if err != nil {
+			return 0, err
+		}
+		// A concurrent registration of another descriptor of the subject may have been shared, so check again.
+		if reg := v.(*registeredSchema); reg.message == md {
+			return reg.version, nil
+		}
+	}
+}
+
+// registerMessage registers md under subject, after checking it is compatible with the previous schema of subject.
+func (s *SchemaManager) registerMessage(ctx context.Context, subject string, md protoreflect.MessageDescriptor) (*registeredSchema, error) {
+	s.mu.RLock()
+	reg, ok := s.subjects[subject]
+	s.mu.RUnlock()
+	if ok {
+		if reg.message == md {
+			return reg, nil
+		}
+		if err := CheckSchemaCompatibility(reg.message, md, s.compatibility); err != nil {
+			return nil, fmt.Errorf("subject %s: %w", subject, err)
+		}
+	} else if prev := s.baselineMessage(md.FullName()); prev != nil {
+		if err := CheckSchemaCompatibility(prev, md, s.compatibility); err != nil {
+			return nil, fmt.Errorf("subject %s: %w", subject, err)
+		}
+	}
+
+	refs, err := s.registerImports(ctx, md.ParentFile())
+	if err != nil {
+		return nil, err
+	}
+	version, err := s.registerFile(ctx, "subject:"+subject, subject, md.ParentFile(), refs)
+	if err != nil {
+		return nil, err
+	}
+	reg = &registeredSchema{message: md, file: md.ParentFile(), version: version}
+	s.mu.Lock()
+	s.subjects[subject] = reg
+	s.mu.Unlock()
+	return reg, nil
+}
+
+// Version returns the cached version of subject, if it was registered.
+func (s *SchemaManager) Version(subject string) (int, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	reg, ok := s.subjects[subject]
+	if !ok {
+		return 0, false
+	}
+	return reg.version, true
+}
+
+func (s *SchemaManager) baselineMessage(name protoreflect.FullName) protoreflect.MessageDescriptor {
+	if s.baseline == nil {
+		return nil
+	}
+	d, err := s.baseline.FindDescriptorByName(name)
+	if err != nil {
+		return nil
+	}
+	md, _ := d.(protoreflect.MessageDescriptor)
+	return md
+}
+
+// registerImports registers the files imported by fd, depth first, and returns the references to them. Well-known
+// types are assumed to be known to the registry.
+func (s *SchemaManager) registerImports(ctx context.Context, fd protoreflect.FileDescriptor) ([]*pb.SchemaReference, error) {
+	var refs []*pb.SchemaReference
+	imports := fd.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		imp := imports.Get(i).FileDescriptor
+		path := imp.Path()
+		if strings.HasPrefix(path, "google/protobuf/") {
+			continue
+		}
+		s.mu.RLock()
+		reg, ok := s.files[path]
+		s.mu.RUnlock()
+		if !ok || reg.file != imp {
+			v, err, _ := s.group.Do("file:"+path, func() (any, error) {
+				return s.registerImport(ctx, path, imp)
+			})
+			if err != nil {
+				return nil, err
+			}
+			reg = v.(*registeredSchema)
+		}
+		refs = append(refs, &pb.SchemaReference{Name: path, Subject: path, Version: int32(reg.version)})
+	}
+	return refs, nil
+}
+
+// registerImport registers the imported file imp, and the files it imports, under its path.
+func (s *SchemaManager) registerImport(ctx context.Context, path string, imp protoreflect.FileDescriptor) (*registeredSchema, error) {
+	s.mu.RLock()
+	reg, ok := s.files[path]
+	s.mu.RUnlock()
+	if ok && reg.file == imp {
+		return reg, nil
+	}
+	refs, err := s.registerImports(ctx, imp)
+	if err != nil {
+		return nil, err
+	}
+	version, err := s.registerFile(ctx, "file:"+path, path, imp, refs)
+	if err != nil {
+		return nil, err
+	}
+	reg = &registeredSchema{file: imp, version: version}
+	s.mu.Lock()
+	s.files[path] = reg
+	s.mu.Unlock()
+	return reg, nil
+}
+
+// registerFile registers fd under subject. Failures are cached by key until their backoff has passed.
+func (s *SchemaManager) registerFile(ctx context.Context, key, subject string, fd protoreflect.FileDescriptor, refs []*pb.SchemaReference) (int, error) {
+	s.mu.RLock()
+	failure := s.failures[key]
+	s.mu.RUnlock()
+	if failure != nil && time.Now().Before(failure.retryAt) {
+		return 0, failure.err
+	}
+
+	version, err := s.callRegistrar(ctx, subject, fd, refs)
+
+	if err != nil && ctx.Err() != nil {
+		// The caller gave up, which says nothing about the registry.
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		delete(s.failures, key)
+		return version, nil
+	}
+	backoff := s.retryBackoff
+	if failure != nil {
+		backoff = min(2*failure.backoff, MaxSchemaRetryBackoff)
+	}
+	s.failures[key] = &registrationFailure{err: err, retryAt: time.Now().Add(backoff), backoff: backoff}
+	return 0, err
+}
+
+func (s *SchemaManager) callRegistrar(ctx context.Context, subject string, fd protoreflect.FileDescriptor, refs []*pb.SchemaReference) (int, error) {
+	versions, err := s.registrar.RegisterSchemas(ctx, &pb.Schema{
+		Subject:    subject,
+		Format:     pb.SchemaType_PROTOBUF,
+		Schema:     protoSource(fd),
+		References: refs,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to register schema of subject %s: %w", subject, err)
+	}
+	version, ok := versions[subject]
+	if !ok {
+		return 0, fmt.Errorf("schema registry did not return a version for subject %s", subject)
+	}
+	return version, nil
+}
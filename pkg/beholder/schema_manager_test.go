@@ -0,0 +1,450 @@
+package beholder_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/beholder"
+	"github.com/smartcontractkit/chainlink-common/pkg/chipingress/pb"
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+)
+
+// fakeRegistrar records registered schemas, and bumps the version of a subject whenever its schema changes.
+type fakeRegistrar struct {
+	mu       sync.Mutex
+	schemas  []*pb.Schema
+	versions map[string][]string
+	err      error
+	calls    int
+	// block, if set, is received from before registering.
+	block chan struct{}
+}
+
+func (r *fakeRegistrar) RegisterSchemas(_ context.Context, schemas ...*pb.Schema) (map[string]int, error) {
+	if r.block != nil {
+		<-r.block
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls++
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.versions == nil {
+		r.versions = make(map[string][]string)
+	}
+	registered := make(map[string]int)
+	for _, s := range schemas {
+		r.schemas = append(r.schemas, s)
+		versions := r.versions[s.Subject]
+		if n := len(versions); n == 0 || versions[n-1] != s.Schema {
+			r.versions[s.Subject] = append(versions, s.Schema)
+		}
+		registered[s.Subject] = len(r.versions[s.Subject])
+	}
+	return registered, nil
+}
+
+func (r *fakeRegistrar) setErr(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.err = err
+}
+
+func (r *fakeRegistrar) callCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls
+}
+
+func (r *fakeRegistrar) registered() []*pb.Schema {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*pb.Schema(nil), r.schemas...)
+}
+
+func field(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, label descriptorpb.FieldDescriptorProto_Label, typeName string) *descriptorpb.FieldDescriptorProto {
+	f := &descriptorpb.FieldDescriptorProto{Name: proto.String(name), Number: proto.Int32(number), Type: typ.Enum(), Label: label.Enum()}
+	if typeName != "" {
+		f.TypeName = proto.String(typeName)
+	}
+	return f
+}
+
+const (
+	optional = descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	repeated = descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+	required = descriptorpb.FieldDescriptorProto_LABEL_REQUIRED
+)
+
+// commonFile is imported by the test messages.
+func commonFile() *descriptorpb.FileDescriptorProto {
+	return &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("common/status.proto"),
+		Package: proto.String("common"),
+		Syntax:  proto.String("proto3"),
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name: proto.String("Status"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{
+				{Name: proto.String("STATUS_UNSPECIFIED"), Number: proto.Int32(0)},
+				{Name: proto.String("STATUS_OK"), Number: proto.Int32(1)},
+			},
+		}},
+	}
+}
+
+// eventFile returns a file declaring platform.test.ReportProcessed with fields, and reserving reserved.
+func eventFile(fields []*descriptorpb.FieldDescriptorProto, reserved ...int32) *descriptorpb.FileDescriptorProto {
+	msg := &descriptorpb.DescriptorProto{Name: proto.String("ReportProcessed"), Field: fields}
+	for _, n := range reserved {
+		msg.ReservedRange = append(msg.ReservedRange, &descriptorpb.DescriptorProto_ReservedRange{Start: proto.Int32(n), End: proto.Int32(n + 1)})
+	}
+	return &descriptorpb.FileDescriptorProto{
+		Name:        proto.String("platform/test/report_processed.proto"),
+		Package:     proto.String("platform.test"),
+		Syntax:      proto.String("proto3"),
+		Dependency:  []string{"common/status.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{msg},
+	}
+}
+
+func baseFields() []*descriptorpb.FieldDescriptorProto {
+	return []*descriptorpb.FieldDescriptorProto{
+		field("workflow_id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional, ""),
+		field("status", 2, descriptorpb.FieldDescriptorProto_TYPE_ENUM, optional, ".common.Status"),
+		field("tags", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING, repeated, ""),
+	}
+}
+
+func buildFiles(t *testing.T, files ...*descriptorpb.FileDescriptorProto) *protoregistry.Files {
+	t.Helper()
+	reg, err := protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: files})
+	require.NoError(t, err)
+	return reg
+}
+
+func reportProcessed(t *testing.T, fields []*descriptorpb.FieldDescriptorProto, reserved ...int32) protoreflect.MessageDescriptor {
+	t.Helper()
+	files := buildFiles(t, commonFile(), eventFile(fields, reserved...))
+	d, err := files.FindDescriptorByName("platform.test.ReportProcessed")
+	require.NoError(t, err)
+	return d.(protoreflect.MessageDescriptor)
+}
+
+func TestSchemaManager_RegistersOnce(t *testing.T) {
+	r := &fakeRegistrar{}
+	sm, err := beholder.SchemaManagerConfig{}.New(r)
+	require.NoError(t, err)
+	m := dynamicpb.NewMessage(reportProcessed(t, baseFields()))
+
+	version, err := sm.Register(t.Context(), m, "platform", "ReportProcessed")
+	require.NoError(t, err)
+	assert.Equal(t, 1, version)
+	version, err = sm.Register(t.Context(), m, "platform", "ReportProcessed")
+	require.NoError(t, err)
+	assert.Equal(t, 1, version)
+
+	schemas := r.registered()
+	require.Len(t, schemas, 2)
+	assert.Equal(t, "common/status.proto", schemas[0].Subject)
+	assert.Equal(t, "platform-ReportProcessed", schemas[1].Subject)
+	assert.Equal(t, pb.SchemaType_PROTOBUF, schemas[1].Format)
+	require.Len(t, schemas[1].References, 1)
+	assert.Equal(t, "common/status.proto", schemas[1].References[0].Subject)
+	assert.Equal(t, int32(1), schemas[1].References[0].Version)
+	assert.Equal(t, `syntax = "proto3";
+
+package platform.test;
+
+import "common/status.proto";
+
+message ReportProcessed {
+  string workflow_id = 1;
+  .common.Status status = 2;
+  repeated string tags = 3;
+}
+`, schemas[1].Schema)
+
+	v, ok := sm.Version("platform-ReportProcessed")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+	_, ok = sm.Version("platform-Other")
+	assert.False(t, ok)
+}
+
+func TestSchemaManager_RegistrationError(t *testing.T) {
+	r := &fakeRegistrar{err: errors.New("unavailable")}
+	sm, err := beholder.SchemaManagerConfig{RetryBackoff: 50 * time.Millisecond}.New(r)
+	require.NoError(t, err)
+	m := dynamicpb.NewMessage(reportProcessed(t, baseFields()))
+
+	_, err = sm.Register(t.Context(), m, "platform", "ReportProcessed")
+	require.ErrorContains(t, err, "unavailable")
+	assert.Equal(t, 1, r.callCount())
+
+	// failures are cached until the backoff has passed
+	r.setErr(nil)
+	_, err = sm.Register(t.Context(), m, "platform", "ReportProcessed")
+	require.ErrorContains(t, err, "unavailable")
+	assert.Equal(t, 1, r.callCount())
+
+	require.Eventually(t, func() bool {
+		version, err := sm.Register(t.Context(), m, "platform", "ReportProcessed")
+		return err == nil && version == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSchemaManager_ConcurrentRegistration(t *testing.T) {
+	r := &fakeRegistrar{}
+	sm, err := beholder.SchemaManagerConfig{}.New(r)
+	require.NoError(t, err)
+	ctx := t.Context()
+	m := dynamicpb.NewMessage(reportProcessed(t, baseFields()))
+	_, err = sm.Register(ctx, m, "platform", "ReportProcessed")
+	require.NoError(t, err)
+
+	r.block = make(chan struct{})
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			version, err := sm.Register(ctx, m, "platform", "Other")
+			assert.NoError(t, err)
+			assert.Equal(t, 1, version)
+		}()
+	}
+
+	// a pending registration does not block registered subjects
+	version, err := sm.Register(ctx, m, "platform", "ReportProcessed")
+	require.NoError(t, err)
+	assert.Equal(t, 1, version)
+
+	close(r.block)
+	wg.Wait()
+	// the subject is registered once, by concurrent callers
+	assert.Equal(t, 3, r.callCount())
+}
+
+func TestSchemaManager_SchemaChanges(t *testing.T) {
+	r := &fakeRegistrar{}
+	sm, err := beholder.SchemaManagerConfig{Compatibility: beholder.SchemaCompatibilityFull}.New(r)
+	require.NoError(t, err)
+	ctx := t.Context()
+	_, err = sm.Register(ctx, dynamicpb.NewMessage(reportProcessed(t, baseFields())), "platform", "ReportProcessed")
+	require.NoError(t, err)
+
+	changed := baseFields()
+	changed[0] = field("workflow_id", 1, descriptorpb.FieldDescriptorProto_TYPE_BYTES, optional, "")
+	_, err = sm.Register(ctx, dynamicpb.NewMessage(reportProcessed(t, changed)), "platform", "ReportProcessed")
+	require.ErrorIs(t, err, beholder.ErrIncompatibleSchema)
+	assert.Len(t, r.registered(), 2, "incompatible schema must not be registered")
+
+	added := append(baseFields(), field("chain_id", 4, descriptorpb.FieldDescriptorProto_TYPE_UINT64, optional, ""))
+	version, err := sm.Register(ctx, dynamicpb.NewMessage(reportProcessed(t, added)), "platform", "ReportProcessed")
+	require.NoError(t, err)
+	assert.Equal(t, 2, version)
+}
+
+func TestSchemaManager_Baseline(t *testing.T) {
+	removed := baseFields()[:2]
+	for name, tt := range map[string]struct {
+		fields   []*descriptorpb.FieldDescriptorProto
+		reserved []int32
+		wantErr  bool
+	}{
+		"unchanged":         {fields: baseFields()},
+		"removed":           {fields: removed, wantErr: true},
+		"removed reserving": {fields: removed, reserved: []int32{3}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			baseline := buildFiles(t, commonFile(), eventFile(baseFields()))
+			sm, err := beholder.SchemaManagerConfig{Compatibility: beholder.SchemaCompatibilityBackward, Baseline: baseline}.New(&fakeRegistrar{})
+			require.NoError(t, err)
+			_, err = sm.Register(t.Context(), dynamicpb.NewMessage(reportProcessed(t, tt.fields, tt.reserved...)), "platform", "ReportProcessed")
+			if tt.wantErr {
+				require.ErrorIs(t, err, beholder.ErrIncompatibleSchema)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func proto2Message(t *testing.T, fields ...*descriptorpb.FieldDescriptorProto) protoreflect.MessageDescriptor {
+	t.Helper()
+	files := buildFiles(t, &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("legacy.proto"),
+		Package: proto.String("legacy"),
+		Syntax:  proto.String("proto2"),
+		EnumType: []*descriptorpb.EnumDescriptorProto{{
+			Name:  proto.String("Kind"),
+			Value: []*descriptorpb.EnumValueDescriptorProto{{Name: proto.String("KIND_A"), Number: proto.Int32(0)}},
+		}},
+		MessageType: []*descriptorpb.DescriptorProto{{Name: proto.String("Legacy"), Field: fields}},
+	})
+	d, err := files.FindDescriptorByName("legacy.Legacy")
+	require.NoError(t, err)
+	return d.(protoreflect.MessageDescriptor)
+}
+
+func TestCheckSchemaCompatibility(t *testing.T) {
+	id := field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional, "")
+	requiredName := field("name", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, required, "")
+	for name, tt := range map[string]struct {
+		prev, next        protoreflect.MessageDescriptor
+		backward, forward bool
+	}{
+		"field added": {
+			prev:     reportProcessed(t, baseFields()[:2]),
+			next:     reportProcessed(t, baseFields()),
+			backward: true, forward: true,
+		},
+		"type changed": {
+			prev: reportProcessed(t, baseFields()),
+			next: reportProcessed(t, append(baseFields()[:2], field("tags", 3, descriptorpb.FieldDescriptorProto_TYPE_INT64, repeated, ""))),
+		},
+		"became singular": {
+			prev: reportProcessed(t, baseFields()),
+			next: reportProcessed(t, append(baseFields()[:2], field("tags", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional, ""))),
+		},
+		"required added": {
+			prev:    proto2Message(t, id),
+			next:    proto2Message(t, id, requiredName),
+			forward: true,
+		},
+		"required removed": {
+			// the number of the removed field is not reserved
+			prev: proto2Message(t, id, requiredName),
+			next: proto2Message(t, id),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			for c, want := range map[beholder.SchemaCompatibility]bool{
+				beholder.SchemaCompatibilityNone:     true,
+				beholder.SchemaCompatibilityBackward: tt.backward,
+				beholder.SchemaCompatibilityForward:  tt.forward,
+				beholder.SchemaCompatibilityFull:     tt.backward && tt.forward,
+			} {
+				err := beholder.CheckSchemaCompatibility(tt.prev, tt.next, c)
+				if want {
+					assert.NoError(t, err, c)
+				} else {
+					assert.ErrorIs(t, err, beholder.ErrIncompatibleSchema, c)
+				}
+			}
+		})
+	}
+}
+
+func TestProtoEmitter_WithSchemaManager(t *testing.T) {
+	r := &fakeRegistrar{}
+	sm, err := beholder.SchemaManagerConfig{Compatibility: beholder.SchemaCompatibilityFull}.New(r)
+	require.NoError(t, err)
+	var emitted int
+	client := &beholder.Client{Emitter: &mockEmitter{emitFunc: func(context.Context, []byte, ...any) error {
+		emitted++
+		return nil
+	}}}
+	emitter := beholder.NewProtoEmitter(logger.Test(t), client, "/schemas", beholder.WithSchemaManager(sm))
+
+	require.NoError(t, emitter.Emit(t.Context(), dynamicpb.NewMessage(reportProcessed(t, baseFields()))))
+	require.NoError(t, emitter.Emit(t.Context(), dynamicpb.NewMessage(reportProcessed(t, baseFields()))))
+	_, ok := sm.Version("platform-ReportProcessed")
+	assert.True(t, ok)
+
+	err = emitter.Emit(t.Context(), dynamicpb.NewMessage(reportProcessed(t, baseFields()[:2])))
+	require.ErrorIs(t, err, beholder.ErrIncompatibleSchema)
+	assert.Equal(t, 2, emitted)
+
+	t.Run("registry unavailable", func(t *testing.T) {
+		r := &fakeRegistrar{err: errors.New("unavailable")}
+		sm, err := beholder.SchemaManagerConfig{}.New(r)
+		require.NoError(t, err)
+		emitter := beholder.NewProtoEmitter(logger.Test(t), client, "/schemas", beholder.WithSchemaManager(sm))
+
+		require.NoError(t, emitter.Emit(t.Context(), dynamicpb.NewMessage(reportProcessed(t, baseFields()))))
+		assert.Equal(t, 3, emitted, "emitted unregistered")
+		_, ok := sm.Version("platform-ReportProcessed")
+		assert.False(t, ok)
+	})
+}
+
+func TestSchemaManager_GroupsAndEditions(t *testing.T) {
+	group := field("result", 2, descriptorpb.FieldDescriptorProto_TYPE_GROUP, optional, ".legacy.Legacy.Result")
+	nested := &descriptorpb.DescriptorProto{Name: proto.String("Result"), Field: []*descriptorpb.FieldDescriptorProto{
+		field("code", 1, descriptorpb.FieldDescriptorProto_TYPE_INT32, optional, ""),
+	}}
+	delimited := field("result", 2, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, optional, ".modern.Modern.Result")
+	delimited.Options = &descriptorpb.FieldOptions{Features: &descriptorpb.FeatureSet{
+		MessageEncoding: descriptorpb.FeatureSet_DELIMITED.Enum(),
+	}}
+	files := buildFiles(t, &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("legacy.proto"),
+		Package: proto.String("legacy"),
+		Syntax:  proto.String("proto2"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name:       proto.String("Legacy"),
+			Field:      []*descriptorpb.FieldDescriptorProto{field("id", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional, ""), group},
+			NestedType: []*descriptorpb.DescriptorProto{nested},
+		}},
+	}, &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("modern.proto"),
+		Package: proto.String("modern"),
+		Syntax:  proto.String("editions"),
+		Edition: descriptorpb.Edition_EDITION_2023.Enum(),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name:       proto.String("Modern"),
+			Field:      []*descriptorpb.FieldDescriptorProto{delimited},
+			NestedType: []*descriptorpb.DescriptorProto{proto.CloneOf(nested)},
+		}},
+	})
+
+	r := &fakeRegistrar{}
+	sm, err := beholder.SchemaManagerConfig{}.New(r)
+	require.NoError(t, err)
+	for _, name := range []protoreflect.FullName{"legacy.Legacy", "modern.Modern"} {
+		d, err := files.FindDescriptorByName(name)
+		require.NoError(t, err)
+		_, err = sm.Register(t.Context(), dynamicpb.NewMessage(d.(protoreflect.MessageDescriptor)), "platform", string(name))
+		require.NoError(t, err)
+	}
+
+	schemas := r.registered()
+	require.Len(t, schemas, 2)
+	assert.Equal(t, `syntax = "proto2";
+
+package legacy;
+
+message Legacy {
+  optional string id = 1;
+  optional group Result = 2 {
+    optional int32 code = 1;
+  }
+}
+`, schemas[0].Schema)
+	assert.Equal(t, `edition = "2023";
+
+package modern;
+
+message Modern {
+
+  message Result {
+    int32 code = 1;
+  }
+  .modern.Modern.Result result = 2 [features.message_encoding = DELIMITED];
+}
+`, schemas[1].Schema)
+}
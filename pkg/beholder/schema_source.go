@@ -0,0 +1,231 @@
+package beholder
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// protoSource renders the messages and enums of a file descriptor as .proto source, the schema format expected by
+// the Chip Ingress schema registry. Options, services and extensions are not rendered, since they do not affect how
+// the data of a message is decoded.
+func protoSource(fd protoreflect.FileDescriptor) string {
+	p := &protoPrinter{file: fd}
+	switch fd.Syntax() {
+	case protoreflect.Proto2:
+		p.line(`syntax = "proto2";`)
+	case protoreflect.Proto3:
+		p.line(`syntax = "proto3";`)
+	default:
+		edition := protodesc.ToFileDescriptorProto(fd).GetEdition()
+		p.line("edition = %q;", strings.TrimPrefix(edition.String(), "EDITION_"))
+	}
+	if pkg := fd.Package(); pkg != "" {
+		p.line("")
+		p.line("package %s;", pkg)
+	}
+	if imports := fd.Imports(); imports.Len() > 0 {
+		p.line("")
+		for i := 0; i < imports.Len(); i++ {
+			p.line("import %q;", imports.Get(i).Path())
+		}
+	}
+	p.enums(fd.Enums())
+	p.messages(fd.Messages())
+	return p.b.String()
+}
+
+type protoPrinter struct {
+	file   protoreflect.FileDescriptor
+	b      strings.Builder
+	indent int
+}
+
+func (p *protoPrinter) line(format string, args ...any) {
+	if format != "" {
+		p.b.WriteString(strings.Repeat("  ", p.indent))
+		fmt.Fprintf(&p.b, format, args...)
+	}
+	p.b.WriteByte('\n')
+}
+
+func (p *protoPrinter) messages(mds protoreflect.MessageDescriptors) {
+	for i := 0; i < mds.Len(); i++ {
+		if md := mds.Get(i); !md.IsMapEntry() && !p.isGroup(md) {
+			p.line("")
+			p.message(md)
+		}
+	}
+}
+
+// isGroup reports whether md is the type of a proto2 group field, which is declared by the field itself.
+func (p *protoPrinter) isGroup(md protoreflect.MessageDescriptor) bool {
+	parent, ok := md.Parent().(protoreflect.MessageDescriptor)
+	if !ok || p.file.Syntax() != protoreflect.Proto2 {
+		return false
+	}
+	fields := parent.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if fd := fields.Get(i); fd.Kind() == protoreflect.GroupKind && fd.Message().FullName() == md.FullName() {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *protoPrinter) message(md protoreflect.MessageDescriptor) {
+	p.line("message %s {", md.Name())
+	p.messageBody(md)
+	p.line("}")
+}
+
+func (p *protoPrinter) messageBody(md protoreflect.MessageDescriptor) {
+	p.indent++
+	p.reserved(fieldRanges(md.ReservedRanges()), md.ReservedNames())
+	p.enums(md.Enums())
+	p.messages(md.Messages())
+	oneofs := md.Oneofs()
+	for i := 0; i < oneofs.Len(); i++ {
+		od := oneofs.Get(i)
+		if od.IsSynthetic() {
+			continue
+		}
+		p.line("oneof %s {", od.Name())
+		p.indent++
+		for j := 0; j < od.Fields().Len(); j++ {
+			p.field(od.Fields().Get(j), false)
+		}
+		p.indent--
+		p.line("}")
+	}
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		if fd := fields.Get(i); fd.ContainingOneof() == nil || fd.ContainingOneof().IsSynthetic() {
+			p.field(fd, true)
+		}
+	}
+	p.indent--
+}
+
+func (p *protoPrinter) field(fd protoreflect.FieldDescriptor, labelled bool) {
+	var label string
+	switch {
+	case !labelled || fd.IsMap():
+	case fd.Cardinality() == protoreflect.Repeated:
+		label = "repeated "
+	case fd.Cardinality() == protoreflect.Required && p.file.Syntax() == protoreflect.Proto2:
+		label = "required "
+	case fd.HasPresence() && (p.file.Syntax() == protoreflect.Proto2 || fd.HasOptionalKeyword()):
+		label = "optional "
+	}
+	typ := p.fieldType(fd)
+	if fd.IsMap() {
+		typ = fmt.Sprintf("map<%s, %s>", p.fieldType(fd.MapKey()), p.fieldType(fd.MapValue()))
+	}
+	var opts []string
+	if fd.HasDefault() && p.file.Syntax() == protoreflect.Proto2 {
+		opts = append(opts, "default = "+defaultValue(fd))
+	}
+	if p.file.Syntax() == protoreflect.Editions && fd.Cardinality() == protoreflect.Required {
+		opts = append(opts, "features.field_presence = LEGACY_REQUIRED")
+	}
+	if p.file.Syntax() == protoreflect.Editions && fd.Kind() == protoreflect.GroupKind {
+		opts = append(opts, "features.message_encoding = DELIMITED")
+	}
+	if p.file.Syntax() == protoreflect.Proto2 && fd.Kind() == protoreflect.GroupKind {
+		p.line("%sgroup %s = %d {", label, fd.Message().Name(), fd.Number())
+		p.messageBody(fd.Message())
+		p.line("}")
+		return
+	}
+	if len(opts) > 0 {
+		p.line("%s%s %s = %d [%s];", label, typ, fd.Name(), fd.Number(), strings.Join(opts, ", "))
+		return
+	}
+	p.line("%s%s %s = %d;", label, typ, fd.Name(), fd.Number())
+}
+
+// fieldType returns the type of fd, with message and enum types fully qualified.
+func (p *protoPrinter) fieldType(fd protoreflect.FieldDescriptor) string {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return "." + string(fd.Message().FullName())
+	case protoreflect.EnumKind:
+		return "." + string(fd.Enum().FullName())
+	default:
+		return fd.Kind().String()
+	}
+}
+
+func defaultValue(fd protoreflect.FieldDescriptor) string {
+	v := fd.Default()
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		return string(fd.DefaultEnumValue().Name())
+	case protoreflect.StringKind:
+		return strconv.Quote(v.String())
+	case protoreflect.BytesKind:
+		return strconv.Quote(string(v.Bytes()))
+	default:
+		return v.String()
+	}
+}
+
+func (p *protoPrinter) enums(eds protoreflect.EnumDescriptors) {
+	for i := 0; i < eds.Len(); i++ {
+		ed := eds.Get(i)
+		p.line("")
+		p.line("enum %s {", ed.Name())
+		p.indent++
+		p.reserved(enumRanges(ed.ReservedRanges()), ed.ReservedNames())
+		for j := 0; j < ed.Values().Len(); j++ {
+			v := ed.Values().Get(j)
+			p.line("%s = %d;", v.Name(), v.Number())
+		}
+		p.indent--
+		p.line("}")
+	}
+}
+
+func (p *protoPrinter) reserved(ranges [][2]int64, names protoreflect.Names) {
+	if len(ranges) > 0 {
+		rs := make([]string, len(ranges))
+		for i, r := range ranges {
+			if r[0] == r[1] {
+				rs[i] = strconv.FormatInt(r[0], 10)
+			} else {
+				rs[i] = fmt.Sprintf("%d to %d", r[0], r[1])
+			}
+		}
+		p.line("reserved %s;", strings.Join(rs, ", "))
+	}
+	if names.Len() > 0 {
+		ns := make([]string, names.Len())
+		for i := range ns {
+			ns[i] = strconv.Quote(string(names.Get(i)))
+		}
+		p.line("reserved %s;", strings.Join(ns, ", "))
+	}
+}
+
+// fieldRanges returns the inclusive reserved ranges of a message, whose end is exclusive in protoreflect.
+func fieldRanges(ranges protoreflect.FieldRanges) [][2]int64 {
+	rs := make([][2]int64, ranges.Len())
+	for i := range rs {
+		r := ranges.Get(i)
+		rs[i] = [2]int64{int64(r[0]), int64(r[1]) - 1}
+	}
+	return rs
+}
+
+func enumRanges(ranges protoreflect.EnumRanges) [][2]int64 {
+	rs := make([][2]int64, ranges.Len())
+	for i := range rs {
+		r := ranges.Get(i)
+		rs[i] = [2]int64{int64(r[0]), int64(r[1])}
+	}
+	return rs
+}
@@ -0,0 +1,274 @@
+package beholder
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/contexts"
+	"github.com/smartcontractkit/chainlink-common/pkg/services"
+)
+
+const (
+	defaultTailSamplingDecisionWait = 10 * time.Second
+	defaultTailSamplingMaxTraces    = 10_000
+	// minTailSamplingTick bounds how often expired traces are decided, for short DecisionWaits.
+	minTailSamplingTick = 10 * time.Millisecond
+)
+
+// TailSamplingConfig configures tail-based sampling of traces. Every span is recorded and buffered until the local
+// spans of its trace have ended, and then the trace is sampled as a whole: a trace with an errored span is always
+// kept, and any other trace is sampled by TenantBudgets.SpanSampleRatio or TraceSampleRatio.
+//
+// The spans are recorded without the sampled flag, so the decision propagated downstream stays deferred. Spans with a
+// sampled parent follow it, and are exported without being buffered.
+type TailSamplingConfig struct {
+	// DecisionWait bounds how long a trace is buffered for its spans to end. Spans ending after the decision follow
+	// it, except errored ones, which are always kept. Defaults to 10s.
+	DecisionWait time.Duration
+	// MaxTraces bounds the buffered traces. The oldest trace is decided early when it is exceeded. Defaults to 10000.
+	MaxTraces int
+}
+
+// tailSamplingProcessor buffers the spans of each trace and passes the spans of sampled traces on to next.
+type tailSamplingProcessor struct {
+	next         []sdktrace.SpanProcessor
+	sampler      sdktrace.Sampler
+	decisionWait time.Duration
+	maxTraces    int
+
+	mu sync.Mutex
+	// traces holds the buffered traces, and order their IDs from oldest to newest.
+	traces map[trace.TraceID]*tailTrace
+	order  *list.List
+	// decided holds the recent decisions, for spans ending after them.
+	decided map[trace.TraceID]tailDecision
+
+	stopCh   services.StopChan
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+type tailTrace struct {
+	elem    *list.Element
+	cre     contexts.CRE
+	started time.Time
+	open    int
+	errored bool
+	spans   []sdktrace.ReadOnlySpan
+}
+
+type tailDecision struct {
+	sampled bool
+	expires time.Time
+}
+
+func newTailSamplingProcessor(cfg TailSamplingConfig, sampler sdktrace.Sampler, next ...sdktrace.SpanProcessor) *tailSamplingProcessor {
+	p := &tailSamplingProcessor{
+		next:         next,
+		sampler:      sampler,
+		decisionWait: cfg.DecisionWait,
+		maxTraces:    cfg.MaxTraces,
+		traces:       make(map[trace.TraceID]*tailTrace),
+		order:        list.New(),
+		decided:      make(map[trace.TraceID]tailDecision),
+		stopCh:       make(services.StopChan),
+	}
+	if p.decisionWait <= 0 {
+		p.decisionWait = defaultTailSamplingDecisionWait
+	}
+	if p.maxTraces <= 0 {
+		p.maxTraces = defaultTailSamplingMaxTraces
+	}
+	p.wg.Add(1)
+	go p.run()
+	return p
+}
+
+func (p *tailSamplingProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	for _, n := range p.next {
+		n.OnStart(parent, s)
+	}
+	if s.SpanContext().IsSampled() {
+		return
+	}
+
+	id := s.SpanContext().TraceID()
+	now := time.Now()
+	var export []sdktrace.ReadOnlySpan
+	p.mu.Lock()
+	if _, ok := p.decided[id]; ok {
+		p.mu.Unlock()
+		return
+	}
+	t, ok := p.traces[id]
+	if !ok {
+		if p.order.Len() >= p.maxTraces {
+			export = p.decide(p.order.Front().Value.(trace.TraceID), now)
+		}
+		t = &tailTrace{started: now}
+		t.elem = p.order.PushBack(id)
+		p.traces[id] = t
+	}
+	t.open++
+	if t.cre == (contexts.CRE{}) {
+		t.cre = contexts.CREValue(parent)
+	}
+	p.mu.Unlock()
+	p.export(export)
+}
+
+func (p *tailSamplingProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if s.SpanContext().IsSampled() {
+		p.export([]sdktrace.ReadOnlySpan{s})
+		return
+	}
+	id := s.SpanContext().TraceID()
+	errored := s.Status().Code == codes.Error
+	var export []sdktrace.ReadOnlySpan
+	p.mu.Lock()
+	t, ok := p.traces[id]
+	if !ok {
+		d := p.decided[id]
+		p.mu.Unlock()
+		if d.sampled || errored {
+			p.export([]sdktrace.ReadOnlySpan{s})
+		}
+		return
+	}
+	t.spans = append(t.spans, s)
+	t.errored = t.errored || errored
+	if t.open--; t.open == 0 {
+		export = p.decide(id, time.Now())
+	}
+	p.mu.Unlock()
+	p.export(export)
+}
+
+// decide samples the buffered trace id, and returns its spans if it was sampled. Must be called with p.mu held.
+func (p *tailSamplingProcessor) decide(id trace.TraceID, now time.Time) []sdktrace.ReadOnlySpan {
+	t := p.traces[id]
+	delete(p.traces, id)
+	p.order.Remove(t.elem)
+
+	sampled := t.errored
+	if !sampled {
+		res := p.sampler.ShouldSample(sdktrace.SamplingParameters{
+			ParentContext: contexts.WithCRE(context.Background(), t.cre),
+			TraceID:       id,
+		})
+		sampled = res.Decision == sdktrace.RecordAndSample
+	}
+	p.decided[id] = tailDecision{sampled: sampled, expires: now.Add(p.decisionWait)}
+	if !sampled {
+		return nil
+	}
+	return t.spans
+}
+
+// export passes spans on to next as sampled, since they were recorded unsampled and processors such as the
+// sdktrace.BatchSpanProcessor drop unsampled spans.
+func (p *tailSamplingProcessor) export(spans []sdktrace.ReadOnlySpan) {
+	for _, s := range spans {
+		for _, n := range p.next {
+			n.OnEnd(tailSampledSpan{s})
+		}
+	}
+}
+
+// tailSampledSpan is a span sampled by the tailSamplingProcessor.
+type tailSampledSpan struct {
+	sdktrace.ReadOnlySpan
+}
+
+func (s tailSampledSpan) SpanContext() trace.SpanContext {
+	sc := s.ReadOnlySpan.SpanContext()
+	return sc.WithTraceFlags(sc.TraceFlags().WithSampled(true))
+}
+
+// deferredSampler records every span, but only samples the spans with a sampled parent, so that the decision
+// propagated downstream is left to the tailSamplingProcessor.
+type deferredSampler struct{}
+
+func (deferredSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	psc := trace.SpanContextFromContext(p.ParentContext)
+	decision := sdktrace.RecordOnly
+	if psc.IsSampled() {
+		decision = sdktrace.RecordAndSample
+	}
+	return sdktrace.SamplingResult{Decision: decision, Tracestate: psc.TraceState()}
+}
+
+func (deferredSampler) Description() string { return "DeferredSampler" }
+
+func (p *tailSamplingProcessor) run() {
+	defer p.wg.Done()
+	ticker := time.NewTicker(max(p.decisionWait/10, minTailSamplingTick))
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stopCh:
+			return
+		case now := <-ticker.C:
+			p.export(p.decideExpired(now))
+		}
+	}
+}
+
+// decideExpired decides the traces buffered for longer than DecisionWait, and forgets the expired decisions.
+func (p *tailSamplingProcessor) decideExpired(now time.Time) []sdktrace.ReadOnlySpan {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var export []sdktrace.ReadOnlySpan
+	for e := p.order.Front(); e != nil; e = p.order.Front() {
+		id := e.Value.(trace.TraceID)
+		if now.Sub(p.traces[id].started) < p.decisionWait {
+			break
+		}
+		export = append(export, p.decide(id, now)...)
+	}
+	for id, d := range p.decided {
+		if now.After(d.expires) {
+			delete(p.decided, id)
+		}
+	}
+	return export
+}
+
+// Shutdown decides the buffered traces and shuts down the next processors.
+func (p *tailSamplingProcessor) Shutdown(ctx context.Context) error {
+	p.stopOnce.Do(func() {
+		close(p.stopCh)
+		p.wg.Wait()
+	})
+	p.mu.Lock()
+	var export []sdktrace.ReadOnlySpan
+	now := time.Now()
+	for e := p.order.Front(); e != nil; e = p.order.Front() {
+		export = append(export, p.decide(e.Value.(trace.TraceID), now)...)
+	}
+	p.mu.Unlock()
+	p.export(export)
+
+	var err error
+	for _, n := range p.next {
+		err = errors.Join(err, n.Shutdown(ctx))
+	}
+	return err
+}
+
+// ForceFlush flushes the next processors. Buffered traces are not decided early, since their remaining spans could
+// change the decision.
+func (p *tailSamplingProcessor) ForceFlush(ctx context.Context) error {
+	var err error
+	for _, n := range p.next {
+		err = errors.Join(err, n.ForceFlush(ctx))
+	}
+	return err
+}
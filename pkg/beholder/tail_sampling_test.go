@@ -0,0 +1,162 @@
+package beholder
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTailSamplingTracer(t *testing.T, cfg Config) (trace.Tracer, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(deferredSampler{}),
+		sdktrace.WithSpanProcessor(newTailSamplingProcessor(*cfg.TraceTailSampling, cfg.spanSampler(), recorder)),
+	}
+	tp := sdktrace.NewTracerProvider(opts...)
+	t.Cleanup(func() { assert.NoError(t, tp.Shutdown(context.Background())) })
+	return tp.Tracer("test"), recorder
+}
+
+func endedNames(r *tracetest.SpanRecorder) []string {
+	var names []string
+	for _, s := range r.Ended() {
+		names = append(names, s.Name())
+	}
+	return names
+}
+
+func TestTailSampling_KeepsErroredTraces(t *testing.T) {
+	tracer, recorder := newTailSamplingTracer(t, Config{TraceSampleRatio: 0, TraceTailSampling: &TailSamplingConfig{}})
+
+	ctx, root := tracer.Start(t.Context(), "ok")
+	_, child := tracer.Start(ctx, "ok.child")
+	child.End()
+	root.End()
+	assert.Empty(t, recorder.Ended(), "unsampled trace is dropped")
+
+	ctx, root = tracer.Start(t.Context(), "failed")
+	_, child = tracer.Start(ctx, "failed.child")
+	child.SetStatus(codes.Error, "boom")
+	child.End()
+	assert.Empty(t, recorder.Ended(), "spans are buffered until the trace ends")
+	root.End()
+	assert.ElementsMatch(t, []string{"failed", "failed.child"}, endedNames(recorder))
+}
+
+func TestTailSampling_SampleRatio(t *testing.T) {
+	tracer, recorder := newTailSamplingTracer(t, Config{TraceSampleRatio: 1, TraceTailSampling: &TailSamplingConfig{}})
+
+	ctx, root := tracer.Start(t.Context(), "root")
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+	root.End()
+	assert.ElementsMatch(t, []string{"root", "child"}, endedNames(recorder))
+}
+
+func TestTailSampling_TenantRatio(t *testing.T) {
+	tracer, recorder := newTailSamplingTracer(t, Config{
+		TraceSampleRatio:  0,
+		TraceTailSampling: &TailSamplingConfig{},
+		TenantBudgets:     testTenantBudgetConfig(t),
+	})
+
+	for _, wf := range []string{"wf1", "wf2"} {
+		_, span := tracer.Start(workflowContext(t.Context(), wf), wf)
+		span.End()
+	}
+	assert.Equal(t, []string{"wf2"}, endedNames(recorder))
+}
+
+func TestTailSampling_DecisionWait(t *testing.T) {
+	tracer, recorder := newTailSamplingTracer(t, Config{
+		TraceTailSampling: &TailSamplingConfig{DecisionWait: 50 * time.Millisecond},
+	})
+
+	ctx, root := tracer.Start(t.Context(), "root")
+	_, child := tracer.Start(ctx, "child")
+	child.SetStatus(codes.Error, "boom")
+	child.End()
+	require.Eventually(t, func() bool { return len(recorder.Ended()) == 1 }, time.Second, 10*time.Millisecond)
+
+	// late spans follow the decision
+	root.End()
+	assert.ElementsMatch(t, []string{"root", "child"}, endedNames(recorder))
+}
+
+func TestTailSampling_ShortDecisionWait(t *testing.T) {
+	tracer, recorder := newTailSamplingTracer(t, Config{
+		TraceTailSampling: &TailSamplingConfig{DecisionWait: time.Nanosecond},
+	})
+
+	_, span := tracer.Start(t.Context(), "span")
+	span.SetStatus(codes.Error, "boom")
+	span.End()
+	assert.Equal(t, []string{"span"}, endedNames(recorder))
+}
+
+func TestTailSampling_MaxTraces(t *testing.T) {
+	tracer, recorder := newTailSamplingTracer(t, Config{
+		TraceTailSampling: &TailSamplingConfig{MaxTraces: 1},
+	})
+
+	_, first := tracer.Start(t.Context(), "first")
+	_, second := tracer.Start(t.Context(), "second")
+	// the first trace was decided early and dropped, but its errored spans are still kept
+	first.SetStatus(codes.Error, "boom")
+	first.End()
+	assert.Equal(t, []string{"first"}, endedNames(recorder))
+	second.End()
+	assert.Equal(t, []string{"first"}, endedNames(recorder))
+}
+
+func TestConfig_tracerProviderOptions_TailSampling(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	exporter := tracetest.NewInMemoryExporter()
+	cfg := Config{TraceSampleRatio: 0, TraceTailSampling: &TailSamplingConfig{}}
+	tp := sdktrace.NewTracerProvider(append(cfg.tracerProviderOptions(exporter), sdktrace.WithSpanProcessor(recorder))...)
+
+	_, span := tp.Tracer("test").Start(t.Context(), "failed")
+	span.SetStatus(codes.Error, "boom")
+	span.End()
+	_, span = tp.Tracer("test").Start(t.Context(), "ok")
+	span.End()
+	require.NoError(t, tp.ForceFlush(t.Context()))
+	t.Cleanup(func() { assert.NoError(t, tp.Shutdown(context.Background())) })
+
+	assert.Len(t, recorder.Ended(), 2, "every span is recorded")
+	require.Len(t, exporter.GetSpans(), 1)
+	assert.Equal(t, "failed", exporter.GetSpans()[0].Name)
+}
+
+func TestTailSampling_DeferredDecision(t *testing.T) {
+	tracer, recorder := newTailSamplingTracer(t, Config{TraceSampleRatio: 1, TraceTailSampling: &TailSamplingConfig{}})
+
+	_, root := tracer.Start(t.Context(), "root")
+	assert.True(t, root.IsRecording())
+	assert.False(t, root.SpanContext().IsSampled(), "the decision propagated downstream is deferred")
+	root.End()
+	require.Len(t, recorder.Ended(), 1)
+	assert.True(t, recorder.Ended()[0].SpanContext().IsSampled())
+
+	// a sampled remote parent is followed, and not buffered
+	parent := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{1},
+		TraceFlags: trace.FlagsSampled,
+		Remote:     true,
+	})
+	ctx, remote := tracer.Start(trace.ContextWithRemoteSpanContext(t.Context(), parent), "remote")
+	_, child := tracer.Start(ctx, "remote.child")
+	assert.True(t, child.SpanContext().IsSampled())
+	child.End()
+	assert.Equal(t, []string{"root", "remote.child"}, endedNames(recorder))
+	remote.End()
+}
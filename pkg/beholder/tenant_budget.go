@@ -0,0 +1,404 @@
+package beholder
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"golang.org/x/time/rate"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/config"
+	"github.com/smartcontractkit/chainlink-common/pkg/contexts"
+	"github.com/smartcontractkit/chainlink-common/pkg/settings"
+)
+
+const (
+	defaultTenantBudgetRefreshInterval = time.Minute
+	defaultTenantBudgetMaxTenants      = 10_000
+)
+
+// TenantBudgetConfig configures per-tenant budgets of the exported telemetry, so that a single chatty workflow cannot
+// dominate the export. The tenant of a log record or span is the contexts.CRE of the context it is emitted with, and
+// the tenant of a metric data point is given by its "org", "owner" and "workflow" attributes. Telemetry without a
+// tenant is only subject to the global configuration.
+//
+// The settings are usually those of cresettings.Default.PerWorkflow.Telemetry.
+type TenantBudgetConfig struct {
+	// Getter resolves the settings for each tenant, e.g. a settings.Registry. Nil uses their default values.
+	Getter settings.Getter
+	// LogRate limits the log records per second of each tenant. Records beyond it are dropped. Custom messages
+	// emitted with the Emitter are not limited. Nil disables the limit.
+	LogRate *settings.Setting[config.Rate]
+	// MetricCardinalityLimit limits the attribute sets of each metric of a tenant. Data points of new attribute sets
+	// beyond it are dropped. Nil disables the limit.
+	MetricCardinalityLimit *settings.Setting[int]
+	// SpanSampleRatio is the ratio of the traces of each tenant which are sampled, in place of
+	// Config.TraceSampleRatio. Nil disables it.
+	SpanSampleRatio *settings.Setting[float64]
+	// RefreshInterval is how long the settings of a tenant are cached, and how long an idle tenant is kept.
+	// Defaults to 1m.
+	RefreshInterval time.Duration
+	// MaxTenants bounds the tenants tracked by each signal. Defaults to 10000.
+	MaxTenants int
+}
+
+// tenantBudgets holds the budget state of each tenant of one signal, and refreshes it from the settings after
+// RefreshInterval. Tenants are locked individually, and their settings are read without holding any lock, so that
+// neither slow settings nor busy tenants hold up the others.
+type tenantBudgets[S any] struct {
+	getter          settings.Getter
+	refreshInterval time.Duration
+	maxTenants      int
+	newState        func() *S
+	// load reads the settings of a tenant, and returns the function to apply them to its state.
+	load func(ctx context.Context, g settings.Getter) func(*S)
+
+	tenants sync.Map // of contexts.CRE to *tenantBudget[S]
+	count   atomic.Int64
+	evictMu sync.Mutex
+}
+
+type tenantBudget[S any] struct {
+	used atomic.Int64 // unix nanoseconds
+
+	mu        sync.Mutex
+	state     *S
+	refreshAt time.Time
+}
+
+func newTenantBudgets[S any](cfg *TenantBudgetConfig, newState func() *S, load func(context.Context, settings.Getter) func(*S)) *tenantBudgets[S] {
+	b := &tenantBudgets[S]{
+		getter:          cfg.Getter,
+		refreshInterval: cfg.RefreshInterval,
+		maxTenants:      cfg.MaxTenants,
+		newState:        newState,
+		load:            load,
+	}
+	if b.refreshInterval <= 0 {
+		b.refreshInterval = defaultTenantBudgetRefreshInterval
+	}
+	if b.maxTenants <= 0 {
+		b.maxTenants = defaultTenantBudgetMaxTenants
+	}
+	return b
+}
+
+// with calls f with the state of tenant cre. The state is locked while f runs, so it must not block.
+func (b *tenantBudgets[S]) with(cre contexts.CRE, f func(*S)) {
+	now := time.Now()
+	t := b.tenant(cre, now)
+	t.used.Store(now.UnixNano())
+
+	t.mu.Lock()
+	refresh := !now.Before(t.refreshAt)
+	if refresh {
+		// other callers keep using the current settings meanwhile
+		t.refreshAt = now.Add(b.refreshInterval)
+	}
+	t.mu.Unlock()
+	var apply func(*S)
+	if refresh {
+		apply = b.loadSettings(cre)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if apply != nil {
+		apply(t.state)
+	}
+	f(t.state)
+}
+
+// loadSettings reads the settings of tenant cre. Errors fall back to the default values.
+func (b *tenantBudgets[S]) loadSettings(cre contexts.CRE) func(*S) {
+	return b.load(contexts.WithCRE(context.Background(), cre), b.getter)
+}
+
+// tenant returns the budget of cre, adding it with its current settings if it is new.
+func (b *tenantBudgets[S]) tenant(cre contexts.CRE, now time.Time) *tenantBudget[S] {
+	if v, ok := b.tenants.Load(cre); ok {
+		return v.(*tenantBudget[S])
+	}
+	t := &tenantBudget[S]{state: b.newState(), refreshAt: now.Add(b.refreshInterval)}
+	b.loadSettings(cre)(t.state)
+	if v, loaded := b.tenants.LoadOrStore(cre, t); loaded {
+		return v.(*tenantBudget[S])
+	}
+	if b.count.Add(1) > int64(b.maxTenants) {
+		b.evict(now, cre)
+	}
+	return t
+}
+
+// evict makes room for new tenants by dropping the idle ones, or arbitrary ones other than keep if there are none.
+func (b *tenantBudgets[S]) evict(now time.Time, keep contexts.CRE) {
+	b.evictMu.Lock()
+	defer b.evictMu.Unlock()
+	remove := func(cre any) {
+		if _, ok := b.tenants.LoadAndDelete(cre); ok {
+			b.count.Add(-1)
+		}
+	}
+	idleBefore := now.Add(-b.refreshInterval).UnixNano()
+	b.tenants.Range(func(cre, v any) bool {
+		if v.(*tenantBudget[S]).used.Load() < idleBefore && cre != keep {
+			remove(cre)
+		}
+		return true
+	})
+	b.tenants.Range(func(cre, _ any) bool {
+		if b.count.Load() <= int64(b.maxTenants) {
+			return false
+		}
+		if cre != keep {
+			remove(cre)
+		}
+		return true
+	})
+}
+
+// logProcessor wraps p to enforce TenantBudgets.LogRate, if configured.
+func (cfg Config) logProcessor(p sdklog.Processor) sdklog.Processor {
+	if cfg.TenantBudgets == nil || cfg.TenantBudgets.LogRate == nil {
+		return p
+	}
+	return newTenantLogProcessor(p, cfg.TenantBudgets)
+}
+
+// tenantLogProcessor drops the log records of tenants exceeding their rate.
+type tenantLogProcessor struct {
+	sdklog.Processor
+	budgets *tenantBudgets[tenantLogBudget]
+}
+
+type tenantLogBudget struct {
+	limiter *rate.Limiter
+}
+
+func newTenantLogProcessor(p sdklog.Processor, cfg *TenantBudgetConfig) *tenantLogProcessor {
+	setting := cfg.LogRate
+	return &tenantLogProcessor{
+		Processor: p,
+		budgets: newTenantBudgets(cfg,
+			func() *tenantLogBudget { return &tenantLogBudget{} },
+			func(ctx context.Context, g settings.Getter) func(*tenantLogBudget) {
+				r, _ := setting.GetOrDefault(ctx, g)
+				return func(b *tenantLogBudget) {
+					if b.limiter == nil {
+						// a new limiter starts with a full burst, unlike one whose burst is raised
+						b.limiter = rate.NewLimiter(r.Limit, r.Burst)
+						return
+					}
+					b.limiter.SetLimit(r.Limit)
+					b.limiter.SetBurst(r.Burst)
+				}
+			}),
+	}
+}
+
+func (p *tenantLogProcessor) OnEmit(ctx context.Context, record *sdklog.Record) error {
+	cre := contexts.CREValue(ctx)
+	if cre == (contexts.CRE{}) {
+		return p.Processor.OnEmit(ctx, record)
+	}
+	var allowed bool
+	p.budgets.with(cre, func(b *tenantLogBudget) { allowed = b.limiter.Allow() })
+	if !allowed {
+		return nil
+	}
+	return p.Processor.OnEmit(ctx, record)
+}
+
+// metricExporter wraps e to enforce TenantBudgets.MetricCardinalityLimit, if configured.
+func (cfg Config) metricExporter(e sdkmetric.Exporter) sdkmetric.Exporter {
+	if cfg.TenantBudgets == nil || cfg.TenantBudgets.MetricCardinalityLimit == nil {
+		return e
+	}
+	return newTenantMetricExporter(e, cfg.TenantBudgets)
+}
+
+// tenantMetricExporter drops the data points of the attribute sets of a tenant beyond its cardinality limit. Admitted
+// attribute sets stay admitted while the tenant is tracked, so lowering the limit only affects new ones.
+type tenantMetricExporter struct {
+	sdkmetric.Exporter
+	budgets *tenantBudgets[tenantMetricBudget]
+}
+
+type tenantMetricBudget struct {
+	limit int
+	// sets holds the admitted attribute sets by metric name.
+	sets map[string]map[attribute.Distinct]struct{}
+}
+
+func newTenantMetricExporter(e sdkmetric.Exporter, cfg *TenantBudgetConfig) *tenantMetricExporter {
+	setting := cfg.MetricCardinalityLimit
+	return &tenantMetricExporter{
+		Exporter: e,
+		budgets: newTenantBudgets(cfg,
+			func() *tenantMetricBudget {
+				return &tenantMetricBudget{sets: make(map[string]map[attribute.Distinct]struct{})}
+			},
+			func(ctx context.Context, g settings.Getter) func(*tenantMetricBudget) {
+				limit, _ := setting.GetOrDefault(ctx, g)
+				return func(b *tenantMetricBudget) { b.limit = limit }
+			}),
+	}
+}
+
+func (e *tenantMetricExporter) Export(ctx context.Context, rm *metricdata.ResourceMetrics) error {
+	return e.Exporter.Export(ctx, e.filter(rm))
+}
+
+// filter returns a copy of rm without the data points over budget. rm itself is reused by the reader, so it is left
+// unmodified.
+func (e *tenantMetricExporter) filter(rm *metricdata.ResourceMetrics) *metricdata.ResourceMetrics {
+	out := &metricdata.ResourceMetrics{
+		Resource:     rm.Resource,
+		ScopeMetrics: make([]metricdata.ScopeMetrics, len(rm.ScopeMetrics)),
+	}
+	for i, sm := range rm.ScopeMetrics {
+		metrics := make([]metricdata.Metrics, len(sm.Metrics))
+		for j, m := range sm.Metrics {
+			m.Data = e.filterData(m.Name, m.Data)
+			metrics[j] = m
+		}
+		out.ScopeMetrics[i] = metricdata.ScopeMetrics{Scope: sm.Scope, Metrics: metrics}
+	}
+	return out
+}
+
+func (e *tenantMetricExporter) filterData(name string, data metricdata.Aggregation) metricdata.Aggregation {
+	switch d := data.(type) {
+	case metricdata.Gauge[int64]:
+		d.DataPoints = admitPoints(e, name, d.DataPoints, func(p metricdata.DataPoint[int64]) attribute.Set { return p.Attributes })
+		return d
+	case metricdata.Gauge[float64]:
+		d.DataPoints = admitPoints(e, name, d.DataPoints, func(p metricdata.DataPoint[float64]) attribute.Set { return p.Attributes })
+		return d
+	case metricdata.Sum[int64]:
+		d.DataPoints = admitPoints(e, name, d.DataPoints, func(p metricdata.DataPoint[int64]) attribute.Set { return p.Attributes })
+		return d
+	case metricdata.Sum[float64]:
+		d.DataPoints = admitPoints(e, name, d.DataPoints, func(p metricdata.DataPoint[float64]) attribute.Set { return p.Attributes })
+		return d
+	case metricdata.Histogram[int64]:
+		d.DataPoints = admitPoints(e, name, d.DataPoints, func(p metricdata.HistogramDataPoint[int64]) attribute.Set { return p.Attributes })
+		return d
+	case metricdata.Histogram[float64]:
+		d.DataPoints = admitPoints(e, name, d.DataPoints, func(p metricdata.HistogramDataPoint[float64]) attribute.Set { return p.Attributes })
+		return d
+	case metricdata.ExponentialHistogram[int64]:
+		d.DataPoints = admitPoints(e, name, d.DataPoints, func(p metricdata.ExponentialHistogramDataPoint[int64]) attribute.Set { return p.Attributes })
+		return d
+	case metricdata.ExponentialHistogram[float64]:
+		d.DataPoints = admitPoints(e, name, d.DataPoints, func(p metricdata.ExponentialHistogramDataPoint[float64]) attribute.Set { return p.Attributes })
+		return d
+	case metricdata.Summary:
+		d.DataPoints = admitPoints(e, name, d.DataPoints, func(p metricdata.SummaryDataPoint) attribute.Set { return p.Attributes })
+		return d
+	default:
+		return data
+	}
+}
+
+// admitPoints returns the points of metric name which are within the budget of their tenant.
+func admitPoints[P any](e *tenantMetricExporter, name string, points []P, attrs func(P) attribute.Set) []P {
+	admitted := make([]P, 0, len(points))
+	for _, p := range points {
+		if e.admit(name, attrs(p)) {
+			admitted = append(admitted, p)
+		}
+	}
+	return admitted
+}
+
+func (e *tenantMetricExporter) admit(name string, attrs attribute.Set) bool {
+	cre := metricTenant(attrs)
+	if cre == (contexts.CRE{}) {
+		return true
+	}
+	var admitted bool
+	e.budgets.with(cre, func(b *tenantMetricBudget) {
+		sets := b.sets[name]
+		if _, admitted = sets[attrs.Equivalent()]; admitted {
+			return
+		}
+		if admitted = len(sets) < b.limit; admitted {
+			if sets == nil {
+				sets = make(map[attribute.Distinct]struct{})
+				b.sets[name] = sets
+			}
+			sets[attrs.Equivalent()] = struct{}{}
+		}
+	})
+	return admitted
+}
+
+// metricTenant returns the tenant of a data point from the attributes of the settings scopes, as recorded by
+// pkg/settings/limits.
+func metricTenant(attrs attribute.Set) contexts.CRE {
+	value := func(scope settings.Scope) string {
+		if v, ok := attrs.Value(attribute.Key(scope.String())); ok {
+			return v.Emit()
+		}
+		return ""
+	}
+	return contexts.CRE{
+		Org:      value(settings.ScopeOrg),
+		Owner:    value(settings.ScopeOwner),
+		Workflow: value(settings.ScopeWorkflow),
+	}.Normalized()
+}
+
+// spanSampler returns the sampler of the traces of a local root span, which applies TenantBudgets.SpanSampleRatio if
+// configured, and TraceSampleRatio otherwise.
+func (cfg Config) spanSampler() sdktrace.Sampler {
+	global := sdktrace.TraceIDRatioBased(cfg.TraceSampleRatio)
+	if cfg.TenantBudgets == nil || cfg.TenantBudgets.SpanSampleRatio == nil {
+		return global
+	}
+	return newTenantSampler(global, cfg.TenantBudgets)
+}
+
+// tenantSampler samples the traces of each tenant by its ratio.
+type tenantSampler struct {
+	global  sdktrace.Sampler
+	budgets *tenantBudgets[sdktrace.Sampler]
+}
+
+func newTenantSampler(global sdktrace.Sampler, cfg *TenantBudgetConfig) *tenantSampler {
+	setting := cfg.SpanSampleRatio
+	return &tenantSampler{
+		global: global,
+		budgets: newTenantBudgets(cfg,
+			func() *sdktrace.Sampler {
+				s := global
+				return &s
+			},
+			func(ctx context.Context, g settings.Getter) func(*sdktrace.Sampler) {
+				ratio, _ := setting.GetOrDefault(ctx, g)
+				sampler := sdktrace.TraceIDRatioBased(ratio)
+				return func(s *sdktrace.Sampler) { *s = sampler }
+			}),
+	}
+}
+
+func (s *tenantSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	cre := contexts.CREValue(p.ParentContext)
+	if cre == (contexts.CRE{}) {
+		return s.global.ShouldSample(p)
+	}
+	var sampler sdktrace.Sampler
+	s.budgets.with(cre, func(ts *sdktrace.Sampler) { sampler = *ts })
+	return sampler.ShouldSample(p)
+}
+
+func (s *tenantSampler) Description() string {
+	return "TenantSampler{" + s.global.Description() + "}"
+}
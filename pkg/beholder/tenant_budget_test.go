@@ -0,0 +1,174 @@
+package beholder
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/contexts"
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+	"github.com/smartcontractkit/chainlink-common/pkg/settings"
+)
+
+const tenantBudgetsJSON = `{
+	"workflow": {
+		"wf2": {
+			"LogRate": "0rps:3",
+			"MetricCardinalityLimit": "3",
+			"SpanSampleRatio": "1"
+		}
+	}
+}`
+
+func testTenantBudgetConfig(t *testing.T) *TenantBudgetConfig {
+	g, err := settings.GetterConfig{Logger: logger.Test(t)}.NewJSONGetter([]byte(tenantBudgetsJSON))
+	require.NoError(t, err)
+	logRate := settings.Rate(0, 1)
+	logRate.Key, logRate.Scope = "LogRate", settings.ScopeWorkflow
+	cardinality := settings.Int(1)
+	cardinality.Key, cardinality.Scope = "MetricCardinalityLimit", settings.ScopeWorkflow
+	ratio := settings.Float64(0)
+	ratio.Key, ratio.Scope = "SpanSampleRatio", settings.ScopeWorkflow
+	return &TenantBudgetConfig{
+		Getter:                 g,
+		LogRate:                &logRate,
+		MetricCardinalityLimit: &cardinality,
+		SpanSampleRatio:        &ratio,
+	}
+}
+
+func workflowContext(ctx context.Context, workflow string) context.Context {
+	return contexts.WithCRE(ctx, contexts.CRE{Org: "org", Owner: "owner", Workflow: workflow})
+}
+
+type countingLogProcessor struct {
+	emitted atomic.Int64
+}
+
+func (p *countingLogProcessor) Enabled(context.Context, sdklog.EnabledParameters) bool { return true }
+
+func (p *countingLogProcessor) OnEmit(context.Context, *sdklog.Record) error {
+	p.emitted.Add(1)
+	return nil
+}
+
+func (p *countingLogProcessor) Shutdown(context.Context) error { return nil }
+
+func (p *countingLogProcessor) ForceFlush(context.Context) error { return nil }
+
+func TestTenantLogProcessor(t *testing.T) {
+	emitCount := func(t *testing.T, ctx context.Context, n int) int64 {
+		next := &countingLogProcessor{}
+		p := Config{TenantBudgets: testTenantBudgetConfig(t)}.logProcessor(next)
+		for range n {
+			var r sdklog.Record
+			require.NoError(t, p.OnEmit(ctx, &r))
+		}
+		return next.emitted.Load()
+	}
+
+	assert.Equal(t, int64(1), emitCount(t, workflowContext(t.Context(), "wf1"), 5))
+	assert.Equal(t, int64(3), emitCount(t, workflowContext(t.Context(), "wf2"), 5))
+	assert.Equal(t, int64(5), emitCount(t, t.Context(), 5), "telemetry without a tenant is not limited")
+
+	next := &countingLogProcessor{}
+	assert.Same(t, next, Config{}.logProcessor(next), "disabled without budgets")
+}
+
+func sumOf(points ...metricdata.DataPoint[int64]) *metricdata.ResourceMetrics {
+	return &metricdata.ResourceMetrics{ScopeMetrics: []metricdata.ScopeMetrics{{
+		Metrics: []metricdata.Metrics{{
+			Name: "requests",
+			Data: metricdata.Sum[int64]{DataPoints: points, Temporality: metricdata.CumulativeTemporality},
+		}},
+	}}}
+}
+
+func point(kvs ...attribute.KeyValue) metricdata.DataPoint[int64] {
+	return metricdata.DataPoint[int64]{Attributes: attribute.NewSet(kvs...), Value: 1}
+}
+
+func pointAttrs(rm *metricdata.ResourceMetrics) []attribute.Set {
+	var sets []attribute.Set
+	for _, p := range rm.ScopeMetrics[0].Metrics[0].Data.(metricdata.Sum[int64]).DataPoints {
+		sets = append(sets, p.Attributes)
+	}
+	return sets
+}
+
+func TestTenantMetricExporter_CardinalityLimit(t *testing.T) {
+	e := newTenantMetricExporter(nil, testTenantBudgetConfig(t))
+
+	wf1a := point(attribute.String("workflow", "wf1"), attribute.String("step", "a"))
+	wf1b := point(attribute.String("workflow", "wf1"), attribute.String("step", "b"))
+	wf2a := point(attribute.String("workflow", "wf2"), attribute.String("owner", "owner"), attribute.String("org", "org"), attribute.String("step", "a"))
+	wf2b := point(attribute.String("workflow", "wf2"), attribute.String("owner", "owner"), attribute.String("org", "org"), attribute.String("step", "b"))
+	platform1 := point(attribute.String("step", "a"))
+	platform2 := point(attribute.String("step", "b"))
+
+	in := sumOf(wf1a, wf1b, wf2a, wf2b, platform1, platform2)
+	got := pointAttrs(e.filter(in))
+	assert.Equal(t, []attribute.Set{wf1a.Attributes, wf2a.Attributes, wf2b.Attributes, platform1.Attributes, platform2.Attributes}, got)
+	assert.Len(t, pointAttrs(in), 6, "input is not modified")
+
+	// admitted attribute sets stay admitted
+	got = pointAttrs(e.filter(sumOf(wf1b, wf1a)))
+	assert.Equal(t, []attribute.Set{wf1a.Attributes}, got)
+}
+
+func TestTenantSampler(t *testing.T) {
+	s := Config{TraceSampleRatio: 1, TenantBudgets: testTenantBudgetConfig(t)}.spanSampler()
+	params := func(ctx context.Context) sdktrace.SamplingParameters {
+		return sdktrace.SamplingParameters{ParentContext: ctx, TraceID: trace.TraceID{0xff}}
+	}
+
+	assert.Equal(t, sdktrace.Drop, s.ShouldSample(params(workflowContext(t.Context(), "wf1"))).Decision)
+	assert.Equal(t, sdktrace.RecordAndSample, s.ShouldSample(params(workflowContext(t.Context(), "wf2"))).Decision)
+	assert.Equal(t, sdktrace.RecordAndSample, s.ShouldSample(params(t.Context())).Decision, "global ratio without a tenant")
+}
+
+func TestTenantBudgets_Evict(t *testing.T) {
+	cfg := &TenantBudgetConfig{MaxTenants: 2}
+	var refreshed int
+	b := newTenantBudgets(cfg, func() *int { return new(int) }, func(context.Context, settings.Getter) func(*int) {
+		refreshed++
+		return func(*int) {}
+	})
+
+	for _, wf := range []string{"wf1", "wf2", "wf1", "wf3"} {
+		b.with(contexts.CRE{Workflow: wf}, func(*int) {})
+	}
+	assert.Equal(t, int64(2), b.count.Load())
+	assert.Equal(t, 3, refreshed, "cached settings are reused")
+	_, ok := b.tenants.Load(contexts.CRE{Workflow: "wf3"})
+	assert.True(t, ok)
+}
+
+func TestTenantBudgets_SlowSettings(t *testing.T) {
+	unblock := make(chan struct{})
+	b := newTenantBudgets(&TenantBudgetConfig{}, func() *int { return new(int) }, func(ctx context.Context, _ settings.Getter) func(*int) {
+		if contexts.CREValue(ctx).Workflow == "slow" {
+			<-unblock
+		}
+		return func(n *int) { *n = 1 }
+	})
+
+	done := make(chan int)
+	go b.with(contexts.CRE{Workflow: "slow"}, func(n *int) { done <- *n })
+
+	// other tenants are not held up
+	var got int
+	b.with(contexts.CRE{Workflow: "fast"}, func(n *int) { got = *n })
+	assert.Equal(t, 1, got)
+
+	close(unblock)
+	assert.Equal(t, 1, <-done)
+}
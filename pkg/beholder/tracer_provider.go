@@ -0,0 +1,32 @@
+package beholder
+
+import (
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// tracerProviderOptions returns cfg-derived sdktrace.TracerProviderOption values: the sampler, and the span
+// processors exporting to exporter and the optional TraceSpanExporter. Callers append the resource option at the call
+// site.
+//
+// With TraceTailSampling, every span is recorded, but only spans with a sampled parent are marked sampled, so the
+// decision propagated downstream is deferred. The sampler is applied by the tail sampling processor once the trace
+// has ended.
+func (cfg Config) tracerProviderOptions(exporter sdktrace.SpanExporter, batcherOpts ...sdktrace.BatchSpanProcessorOption) []sdktrace.TracerProviderOption {
+	processors := []sdktrace.SpanProcessor{sdktrace.NewBatchSpanProcessor(exporter, batcherOpts...)}
+	if cfg.TraceSpanExporter != nil {
+		processors = append(processors, sdktrace.NewBatchSpanProcessor(cfg.TraceSpanExporter))
+	}
+	if cfg.TraceTailSampling != nil {
+		return []sdktrace.TracerProviderOption{
+			sdktrace.WithSampler(deferredSampler{}),
+			sdktrace.WithSpanProcessor(newTailSamplingProcessor(*cfg.TraceTailSampling, cfg.spanSampler(), processors...)),
+		}
+	}
+	opts := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sdktrace.ParentBased(cfg.spanSampler())),
+	}
+	for _, p := range processors {
+		opts = append(opts, sdktrace.WithSpanProcessor(p))
+	}
+	return opts
+}
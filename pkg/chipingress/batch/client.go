@@ -1,9 +1,11 @@
 package batch
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -29,6 +31,7 @@ var (
 type messageWithCallback struct {
 	event    *chipingress.CloudEventPb
 	callback func(error)
+	seqnum   uint64
 }
 
 type seqnumKey struct {
@@ -78,6 +81,12 @@ func ErrorCodeFor(err error) string {
 	if errors.Is(err, ErrClientShutdown) {
 		return ErrClientShutdown.Error()
 	}
+	if errors.Is(err, chipingress.ErrEventRejected) {
+		return "event_rejected"
+	}
+	if errors.Is(err, chipingress.ErrStreamClosed) {
+		return chipingress.ErrStreamClosed.Error()
+	}
 	if st, ok := status.FromError(err); ok {
 		return st.Code().String()
 	}
@@ -115,6 +124,10 @@ type Client struct {
 	metrics batchClientMetrics
 
 	transactionEnabled bool
+
+	useStream  bool
+	streamOpts []chipingress.StreamOpt
+	stream     *chipingress.EventStream // set by Start when useStream
 }
 
 type batchClientMetrics struct {
@@ -180,6 +193,10 @@ func (b *Client) Start(ctx context.Context) {
 	// This avoids retaining a startup context whose cancellation we don't control.
 	batcherCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
 
+	if b.useStream {
+		b.stream = chipingress.NewEventStream(b.client, b.streamOpts...)
+	}
+
 	go func() {
 		defer close(b.batcherDone)
 
@@ -198,6 +215,10 @@ func (b *Client) Start(ctx context.Context) {
 			func(batch []*messageWithCallback) {
 				// Detach from cancellation so final flush can still publish during shutdown.
 				// sendBatch still enforces maxPublishTimeout for each publish call.
+				if b.stream != nil {
+					b.streamBatch(context.WithoutCancel(batcherCtx), batch)
+					return
+				}
 				b.sendBatch(context.WithoutCancel(batcherCtx), batch)
 			},
 		)
@@ -224,6 +245,12 @@ func (b *Client) Stop() {
 			done := make(chan struct{})
 			go func() {
 				<-b.batcherDone
+				if b.stream != nil {
+					// wait for acknowledgements, then fail any remaining events
+					if err := b.stream.Close(ctx); err != nil {
+						b.log.Warnw("failed to close event stream", "error", err)
+					}
+				}
 				for range cap(b.maxConcurrentSends) {
 					b.maxConcurrentSends <- struct{}{}
 				}
@@ -310,6 +337,7 @@ func (b *Client) QueueMessage(event *chipingress.CloudEventPb, callback func(err
 	msg := &messageWithCallback{
 		event:    eventToQueue,
 		callback: callback,
+		seqnum:   seq,
 	}
 
 	select {
@@ -365,6 +393,31 @@ func (b *Client) sendBatch(ctx context.Context, messages []*messageWithCallback)
 	}()
 }
 
+// streamBatch publishes messages on the event stream, blocking while it is at its in-flight limit. Messages are sent
+// in seqnum order, so each (source, type) pair is delivered in the order it was stamped by seqnumFor, even when
+// concurrent QueueMessage calls reached the buffer out of order. Each callback is invoked when its event is
+// acknowledged.
+func (b *Client) streamBatch(ctx context.Context, messages []*messageWithCallback) {
+	slices.SortStableFunc(messages, func(x, y *messageWithCallback) int {
+		return cmp.Compare(x.seqnum, y.seqnum)
+	})
+	for _, msg := range messages {
+		done := func(err error) {
+			if msg.callback != nil {
+				// callbacks run in their own goroutines to not block the stream's acknowledgements
+				b.callbackWg.Go(func() { msg.callback(err) })
+			}
+		}
+		ctxTimeout, cancel := context.WithTimeout(ctx, b.maxPublishTimeout)
+		err := b.stream.Publish(ctxTimeout, msg.event, done)
+		cancel()
+		if err != nil {
+			b.log.Errorw("failed to publish event on stream", "error", err)
+			done(err)
+		}
+	}
+}
+
 func (b *Client) completeBatchCallbacks(messages []*messageWithCallback, err error) {
 	callbackMessages, callbackErr := messages, err
 	// the callbacks are placed in their own goroutine to not block releasing the semaphore
@@ -486,6 +539,16 @@ func newBatchRequest(messages []*messageWithCallback, transactionEnabled bool) (
 	return batchReq, proto.Size(batchReq)
 }
 
+// WithStreamEvents publishes events over a chipingress.EventStream instead of unary PublishBatch calls. The stream
+// is opened by Start, and reconnects after transport errors, resending unacknowledged events. Each callback receives
+// the outcome of its own event once acknowledged.
+func WithStreamEvents(opts ...chipingress.StreamOpt) Opt {
+	return func(c *Client) {
+		c.useStream = true
+		c.streamOpts = opts
+	}
+}
+
 // WithBatchSize sets the number of messages to accumulate before sending a batch
 func WithBatchSize(batchSize int) Opt {
 	return func(c *Client) {
@@ -704,6 +767,7 @@ func (m *batchClientMetrics) recordConfig(ctx context.Context, c *Client) {
 		attribute.Int64("shutdown_timeout_ms", c.shutdownTimeout.Milliseconds()),
 		attribute.Bool("clone_event", c.cloneEvent),
 		attribute.Bool("transaction_enabled", c.transactionEnabled),
+		attribute.Bool("stream_events", c.useStream),
 		attribute.Int("max_grpc_request_size_bytes", c.maxGRPCRequestSize),
 	)))
 }
@@ -3,6 +3,8 @@ package batch
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net"
 	"slices"
 	"strconv"
 	"strings"
@@ -18,12 +20,14 @@ import (
 	"go.opentelemetry.io/otel/attribute"
 	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/smartcontractkit/chainlink-common/pkg/chipingress"
 	"github.com/smartcontractkit/chainlink-common/pkg/chipingress/mocks"
+	"github.com/smartcontractkit/chainlink-common/pkg/chipingress/pb"
 )
 
 func TestNewBatchClient(t *testing.T) {
@@ -2397,6 +2401,16 @@ func TestErrorCodeFor(t *testing.T) {
 			err:  ErrClientShutdown,
 			want: ErrClientShutdown.Error(),
 		},
+		{
+			name: "stream rejected event",
+			err:  fmt.Errorf("%w: id: error", chipingress.ErrEventRejected),
+			want: "event_rejected",
+		},
+		{
+			name: "stream closed",
+			err:  chipingress.ErrStreamClosed,
+			want: chipingress.ErrStreamClosed.Error(),
+		},
 		{
 			name: "unknown error",
 			err:  errors.New("something else"),
@@ -2416,3 +2430,89 @@ func TestErrorCodeFor_nil(t *testing.T) {
 	t.Parallel()
 	require.Empty(t, ErrorCodeFor(nil))
 }
+
+// ackingServer acknowledges every streamed event, and records the seqnum of each by source and type.
+type ackingServer struct {
+	pb.UnimplementedChipIngressServer
+
+	mu      sync.Mutex
+	seqnums map[string][]uint64
+}
+
+func (s *ackingServer) StreamEvents(stream grpc.BidiStreamingServer[pb.StreamEventsRequest, pb.StreamEventsResponse]) error {
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			return nil
+		}
+		seq, err := strconv.ParseUint(req.Event.Attributes["seqnum"].GetCeString(), 10, 64)
+		if err != nil {
+			return err
+		}
+		s.mu.Lock()
+		key := req.Event.Source + "/" + req.Event.Type
+		s.seqnums[key] = append(s.seqnums[key], seq)
+		s.mu.Unlock()
+		if err := stream.Send(&pb.StreamEventsResponse{EventId: req.Event.Id, Status: chipingress.StreamAckSuccess}); err != nil {
+			return err
+		}
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	lis, err := (&net.ListenConfig{}).Listen(t.Context(), "tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := grpc.NewServer()
+	acking := &ackingServer{seqnums: make(map[string][]uint64)}
+	pb.RegisterChipIngressServer(srv, acking)
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	client, err := chipingress.NewClient(lis.Addr().String(), chipingress.WithInsecureConnection())
+	require.NoError(t, err)
+
+	batchClient, err := NewBatchClient(client,
+		WithStreamEvents(chipingress.WithMaxInFlight(5)),
+		WithBatchSize(10),
+		WithBatchInterval(10*time.Millisecond),
+	)
+	require.NoError(t, err)
+	batchClient.Start(t.Context())
+
+	const perKey = 25
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*perKey)
+	for _, source := range []string{"domain-a", "domain-b"} {
+		wg.Go(func() {
+			for i := range perKey {
+				event := &chipingress.CloudEventPb{Id: fmt.Sprintf("%s-%d", source, i), Source: source, Type: "entity"}
+				for {
+					err := batchClient.QueueMessage(event, func(err error) { errs <- err })
+					if !errors.Is(err, ErrMessageBufferFull) {
+						assert.NoError(t, err)
+						break
+					}
+					time.Sleep(time.Millisecond)
+				}
+			}
+		})
+	}
+	wg.Wait()
+	for range 2 * perKey {
+		select {
+		case err := <-errs:
+			require.NoError(t, err)
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for callbacks")
+		}
+	}
+	batchClient.Stop()
+
+	acking.mu.Lock()
+	defer acking.mu.Unlock()
+	require.Len(t, acking.seqnums, 2)
+	for key, seqnums := range acking.seqnums {
+		assert.Len(t, seqnums, perKey, key)
+		assert.True(t, slices.IsSorted(seqnums), "%s delivered out of order: %v", key, seqnums)
+	}
+}
@@ -0,0 +1,452 @@
+// Package chipingresstest provides an in-process Chip Ingress server for end-to-end tests of clients and emitters.
+package chipingresstest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"text/scanner"
+	"time"
+
+	cepb "github.com/cloudevents/sdk-go/binding/format/protobuf/v2/pb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/chipingress"
+	"github.com/smartcontractkit/chainlink-common/pkg/chipingress/pb"
+)
+
+// DefaultWaitTimeout bounds WaitForEvents.
+const DefaultWaitTimeout = 10 * time.Second
+
+// SubjectFunc derives the schema registry subject of an event.
+type SubjectFunc func(event *cepb.CloudEvent) string
+
+// DefaultSubject returns "<source>-<type>".
+func DefaultSubject(event *cepb.CloudEvent) string {
+	return event.Source + "-" + event.Type
+}
+
+// ResultErrorFunc returns the error to report for an event, or nil to produce it.
+type ResultErrorFunc func(event *cepb.CloudEvent) *pb.PublishError
+
+// Opt configures a Server.
+type Opt func(*Server)
+
+// WithSchemaValidation rejects events whose subject has no registered schema, with
+// PUBLISH_ERROR_CODE_SCHEMA_MISSING, and events whose data does not match it, with PUBLISH_ERROR_CODE_ENCODE_ERROR.
+//
+// Since the server does not parse schemas, matching is approximate: for PROTOBUF schemas the event type must be the
+// full name of a message in protoregistry.GlobalTypes which is declared by the schema, and the data must unmarshal as
+// that message. For JSON schemas the data must be valid JSON. AVRO data is not checked.
+func WithSchemaValidation() Opt {
+	return func(s *Server) { s.validateSchemas = true }
+}
+
+// WithSubjectFunc overrides DefaultSubject.
+func WithSubjectFunc(fn SubjectFunc) Opt {
+	return func(s *Server) { s.subject = fn }
+}
+
+// WithSchemas registers schemas, as if by RegisterSchema.
+func WithSchemas(schemas ...*pb.Schema) Opt {
+	return func(s *Server) {
+		for _, schema := range schemas {
+			s.register(schema)
+		}
+	}
+}
+
+// Server is an in-process Chip Ingress server. It implements Publish, PublishBatch, StreamEvents, Ping and
+// RegisterSchema, records every produced event, and supports injecting failures, latency and per-event errors.
+type Server struct {
+	pb.UnimplementedChipIngressServer
+
+	addr            string
+	validateSchemas bool
+	subject         SubjectFunc
+
+	mu           sync.Mutex
+	events       []*cepb.CloudEvent
+	publishCalls int
+	schemas      map[string][]*pb.Schema // versions by subject
+	failures     []error
+	latency      time.Duration
+	resultErrors ResultErrorFunc
+	produced     chan struct{} // closed and replaced whenever events are produced
+}
+
+// NewServer starts a Server on a local port, which is stopped when the test completes.
+func NewServer(t testing.TB, opts ...Opt) *Server {
+	t.Helper()
+	s := &Server{
+		subject:  DefaultSubject,
+		schemas:  make(map[string][]*pb.Schema),
+		produced: make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	lis, err := (&net.ListenConfig{}).Listen(t.Context(), "tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s.addr = lis.Addr().String()
+	srv := grpc.NewServer()
+	pb.RegisterChipIngressServer(srv, s)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	return s
+}
+
+// Addr returns the address of the server, for chipingress.NewClient.
+func (s *Server) Addr() string {
+	return s.addr
+}
+
+// NewClient returns an insecure client for the server, which is closed when the test completes.
+func (s *Server) NewClient(t testing.TB, opts ...chipingress.Opt) chipingress.Client {
+	t.Helper()
+	client, err := chipingress.NewClient(s.addr, append([]chipingress.Opt{chipingress.WithInsecureConnection()}, opts...)...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+// FailNext fails the next n Publish, PublishBatch or StreamEvents calls with err, e.g. a status.Error. Failures
+// accumulate, so FailNext can be called again to inject different errors in order.
+func (s *Server) FailNext(n int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for range n {
+		s.failures = append(s.failures, err)
+	}
+}
+
+// SetLatency delays the handling of every publish call, and of every event on a stream, by d.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// SetResultErrors reports the error returned by fn for each event, instead of producing it. A nil fn clears it.
+// fn is called with the server locked, so it must not call the Server.
+func (s *Server) SetResultErrors(fn ResultErrorFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resultErrors = fn
+}
+
+// Events returns clones of the produced events, in order.
+func (s *Server) Events() []*cepb.CloudEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	events := make([]*cepb.CloudEvent, len(s.events))
+	for i, e := range s.events {
+		events[i] = proto.Clone(e).(*cepb.CloudEvent)
+	}
+	return events
+}
+
+// PublishCalls returns the number of Publish, PublishBatch and StreamEvents calls, including failed ones.
+func (s *Server) PublishCalls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.publishCalls
+}
+
+// Schemas returns the registered versions of subject, starting with version 1.
+func (s *Server) Schemas(subject string) []*pb.Schema {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*pb.Schema(nil), s.schemas[subject]...)
+}
+
+// WaitForEvents waits until at least n events have been produced, and returns them. The test fails if that takes
+// longer than DefaultWaitTimeout.
+func (s *Server) WaitForEvents(t testing.TB, n int) []*cepb.CloudEvent {
+	t.Helper()
+	timeout := time.After(DefaultWaitTimeout)
+	for {
+		s.mu.Lock()
+		count, produced := len(s.events), s.produced
+		s.mu.Unlock()
+		if count >= n {
+			return s.Events()
+		}
+		select {
+		case <-produced:
+		case <-timeout:
+			require.FailNowf(t, "timed out waiting for events", "got %d of %d", count, n)
+		case <-t.Context().Done():
+			require.FailNow(t, "test finished while waiting for events")
+		}
+	}
+}
+
+// Ping responds with "pong".
+func (s *Server) Ping(context.Context, *pb.EmptyRequest) (*pb.PingResponse, error) {
+	return &pb.PingResponse{Message: "pong"}, nil
+}
+
+// Publish produces a single event, or fails with codes.InvalidArgument if it is rejected.
+func (s *Server) Publish(ctx context.Context, event *cepb.CloudEvent) (*pb.PublishResponse, error) {
+	if err := s.begin(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if pubErr := s.check(event); pubErr != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "%s: %s", pubErr.ErrorCode, pubErr.Reason)
+	}
+	s.produce(event)
+	return &pb.PublishResponse{Results: []*pb.PublishResult{{EventId: event.Id}}}, nil
+}
+
+// PublishBatch produces a batch, with positional results. If transactions are enabled, a single rejected event fails
+// the whole batch with codes.InvalidArgument.
+func (s *Server) PublishBatch(ctx context.Context, batch *pb.CloudEventBatch) (*pb.PublishResponse, error) {
+	if err := s.begin(ctx); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	results := make([]*pb.PublishResult, len(batch.Events))
+	var failed bool
+	for i, event := range batch.Events {
+		results[i] = &pb.PublishResult{EventId: event.GetId(), Error: s.check(event)}
+		failed = failed || results[i].Error != nil
+	}
+	if failed && batch.GetOptions().GetTransactionEnabled() {
+		return nil, status.Error(codes.InvalidArgument, "batch rejected: transaction enabled and at least one event failed")
+	}
+	for i, event := range batch.Events {
+		if results[i].Error == nil {
+			s.produce(event)
+		}
+	}
+	return &pb.PublishResponse{Results: results}, nil
+}
+
+// StreamEvents acknowledges each event as it is produced, with status chipingress.StreamAckSuccess, or "error" if it
+// is rejected.
+func (s *Server) StreamEvents(stream grpc.BidiStreamingServer[pb.StreamEventsRequest, pb.StreamEventsResponse]) error {
+	if err := s.begin(stream.Context()); err != nil {
+		return err
+	}
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			// the client has finished, or gone away
+			return nil
+		}
+		if err := s.delay(stream.Context()); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		ack := &pb.StreamEventsResponse{EventId: req.GetEvent().GetId(), Status: chipingress.StreamAckSuccess}
+		if pubErr := s.check(req.GetEvent()); pubErr != nil {
+			ack.Status = "error"
+		} else {
+			s.produce(req.GetEvent())
+		}
+		s.mu.Unlock()
+		if err := stream.Send(ack); err != nil {
+			return err
+		}
+	}
+}
+
+// RegisterSchema registers each schema as the next version of its subject, unless it is identical to the latest
+// version, which is returned instead.
+func (s *Server) RegisterSchema(_ context.Context, req *pb.RegisterSchemaRequest) (*pb.RegisterSchemaResponse, error) {
+	resp := &pb.RegisterSchemaResponse{}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, schema := range req.Schemas {
+		if schema.GetSubject() == "" {
+			return nil, status.Error(codes.InvalidArgument, "schema subject is required")
+		}
+		resp.Registered = append(resp.Registered, &pb.RegisteredSchema{Subject: schema.Subject, Version: s.register(schema)})
+	}
+	return resp, nil
+}
+
+// register stores schema, and returns its version. Must be called with s.mu held, or before the server is started.
+func (s *Server) register(schema *pb.Schema) int32 {
+	versions := s.schemas[schema.Subject]
+	if n := len(versions); n > 0 && versions[n-1].Schema == schema.Schema && versions[n-1].Format == schema.Format {
+		return int32(n)
+	}
+	s.schemas[schema.Subject] = append(versions, proto.Clone(schema).(*pb.Schema))
+	return int32(len(s.schemas[schema.Subject]))
+}
+
+// begin counts a publish call, and applies any injected failure and latency.
+func (s *Server) begin(ctx context.Context) error {
+	s.mu.Lock()
+	s.publishCalls++
+	var err error
+	if len(s.failures) > 0 {
+		err, s.failures = s.failures[0], s.failures[1:]
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.delay(ctx)
+}
+
+func (s *Server) delay(ctx context.Context) error {
+	s.mu.Lock()
+	latency := s.latency
+	s.mu.Unlock()
+	if latency <= 0 {
+		return nil
+	}
+	select {
+	case <-time.After(latency):
+		return nil
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+// produce records event. Must be called with s.mu held.
+func (s *Server) produce(event *cepb.CloudEvent) {
+	s.events = append(s.events, proto.Clone(event).(*cepb.CloudEvent))
+	close(s.produced)
+	s.produced = make(chan struct{})
+}
+
+// check returns the error for event, if it should not be produced. Must be called with s.mu held.
+func (s *Server) check(event *cepb.CloudEvent) *pb.PublishError {
+	if event == nil {
+		return publishError(pb.PublishErrorCode_PUBLISH_ERROR_CODE_VALIDATION_FAILED, "event is required")
+	}
+	for _, required := range []struct{ name, value string }{
+		{"id", event.Id},
+		{"source", event.Source},
+		{"type", event.Type},
+		{"specversion", event.SpecVersion},
+	} {
+		if required.value == "" {
+			return publishError(pb.PublishErrorCode_PUBLISH_ERROR_CODE_VALIDATION_FAILED, required.name+" is required")
+		}
+	}
+	for name, value := range event.Attributes {
+		if value == nil || value.Attr == nil {
+			return publishError(pb.PublishErrorCode_PUBLISH_ERROR_CODE_VALIDATION_FAILED, "nil value for attribute "+name)
+		}
+	}
+	if s.resultErrors != nil {
+		if pubErr := s.resultErrors(event); pubErr != nil {
+			return pubErr
+		}
+	}
+	if s.validateSchemas {
+		return s.checkSchema(event)
+	}
+	return nil
+}
+
+func (s *Server) checkSchema(event *cepb.CloudEvent) *pb.PublishError {
+	subject := s.subject(event)
+	versions := s.schemas[subject]
+	if len(versions) == 0 {
+		return publishError(pb.PublishErrorCode_PUBLISH_ERROR_CODE_SCHEMA_MISSING, "no schema registered for subject "+subject)
+	}
+	schema := versions[len(versions)-1]
+	switch schema.Format {
+	case pb.SchemaType_PROTOBUF:
+		mt, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(event.Type))
+		if errors.Is(err, protoregistry.NotFound) {
+			return publishError(pb.PublishErrorCode_PUBLISH_ERROR_CODE_ENCODE_ERROR,
+				"event type "+event.Type+" is not a registered message type")
+		} else if err != nil {
+			return publishError(pb.PublishErrorCode_PUBLISH_ERROR_CODE_ENCODE_ERROR, "invalid event type: "+err.Error())
+		}
+		if !declaredMessages(schema.Schema)[mt.Descriptor().FullName()] {
+			return publishError(pb.PublishErrorCode_PUBLISH_ERROR_CODE_ENCODE_ERROR,
+				"event type "+event.Type+" does not match a message in the schema of subject "+subject)
+		}
+		if err := proto.Unmarshal(eventData(event), mt.New().Interface()); err != nil {
+			return publishError(pb.PublishErrorCode_PUBLISH_ERROR_CODE_ENCODE_ERROR, "invalid data: "+err.Error())
+		}
+	case pb.SchemaType_JSON:
+		if !json.Valid(eventData(event)) {
+			return publishError(pb.PublishErrorCode_PUBLISH_ERROR_CODE_ENCODE_ERROR, "data is not valid JSON")
+		}
+	}
+	return nil
+}
+
+// declaredMessages returns the full names of the messages declared by the raw proto schema, including nested ones.
+// Comments and string literals are skipped, so only declarations are matched.
+func declaredMessages(schema string) map[protoreflect.FullName]bool {
+	var sc scanner.Scanner
+	sc.Init(strings.NewReader(schema))
+	sc.Mode = scanner.ScanIdents | scanner.ScanInts | scanner.ScanFloats | scanner.ScanStrings | scanner.ScanRawStrings |
+		scanner.ScanComments | scanner.SkipComments
+	sc.Error = func(*scanner.Scanner, string) {} // tolerate syntax the Go scanner does not know
+
+	declared := make(map[protoreflect.FullName]bool)
+	var pkg protoreflect.FullName
+	var scopes []string // message name of each open block, or "" for other blocks
+	var pending string  // message name awaiting its block
+	for tok := sc.Scan(); tok != scanner.EOF; tok = sc.Scan() {
+		switch {
+		case tok == scanner.Ident && sc.TokenText() == "package" && len(scopes) == 0:
+			var name strings.Builder
+			for tok = sc.Scan(); tok == scanner.Ident || tok == '.'; tok = sc.Scan() {
+				name.WriteString(sc.TokenText())
+			}
+			pkg = protoreflect.FullName(name.String())
+		case tok == scanner.Ident && sc.TokenText() == "message":
+			if sc.Scan() == scanner.Ident {
+				pending = sc.TokenText()
+			}
+		case tok == '{':
+			scopes = append(scopes, pending)
+			if pending != "" {
+				name := pkg
+				for _, scope := range scopes {
+					if scope != "" {
+						name = name.Append(protoreflect.Name(scope))
+					}
+				}
+				declared[name] = true
+			}
+			pending = ""
+		case tok == '}' && len(scopes) > 0:
+			scopes = scopes[:len(scopes)-1]
+		}
+	}
+	return declared
+}
+
+// eventData returns the data of event, which chipingress.NewEvent sets as proto_data.
+func eventData(event *cepb.CloudEvent) []byte {
+	switch data := event.Data.(type) {
+	case *cepb.CloudEvent_BinaryData:
+		return data.BinaryData
+	case *cepb.CloudEvent_TextData:
+		return []byte(data.TextData)
+	case *cepb.CloudEvent_ProtoData:
+		return data.ProtoData.GetValue()
+	}
+	return nil
+}
+
+func publishError(code pb.PublishErrorCode, reason string) *pb.PublishError {
+	return &pb.PublishError{ErrorCode: code, Reason: reason}
+}
@@ -0,0 +1,250 @@
+package chipingresstest_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	cepb "github.com/cloudevents/sdk-go/binding/format/protobuf/v2/pb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/chipingress"
+	"github.com/smartcontractkit/chainlink-common/pkg/chipingress/batch"
+	"github.com/smartcontractkit/chainlink-common/pkg/chipingress/chipingresstest"
+	"github.com/smartcontractkit/chainlink-common/pkg/chipingress/pb"
+)
+
+// pingSchema declares pb.PingResponse, which is used as the payload of schema validated events.
+var pingSchema = &pb.Schema{
+	Subject: "test-chipingress.pb.PingResponse",
+	Format:  pb.SchemaType_PROTOBUF,
+	Schema: `syntax = "proto3";
+package chipingress.pb;
+message PingResponse { string message = 1; }`,
+}
+
+func newEvent(t *testing.T, source, typ string, payload []byte) *cepb.CloudEvent {
+	t.Helper()
+	event, err := chipingress.NewEvent(source, typ, payload, nil)
+	require.NoError(t, err)
+	eventPb, err := chipingress.EventToProto(event)
+	require.NoError(t, err)
+	return eventPb
+}
+
+func pingPayload(t *testing.T) []byte {
+	t.Helper()
+	b, err := proto.Marshal(&pb.PingResponse{Message: "hi"})
+	require.NoError(t, err)
+	return b
+}
+
+func TestServer_Publish(t *testing.T) {
+	srv := chipingresstest.NewServer(t)
+	client := srv.NewClient(t)
+
+	pong, err := client.Ping(t.Context(), &pb.EmptyRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "pong", pong.Message)
+
+	event := newEvent(t, "test", "entity", []byte("data"))
+	resp, err := client.Publish(t.Context(), event)
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 1)
+	assert.Equal(t, event.Id, resp.Results[0].EventId)
+
+	events := srv.Events()
+	require.Len(t, events, 1)
+	assert.True(t, proto.Equal(event, events[0]))
+
+	t.Run("invalid event", func(t *testing.T) {
+		_, err := client.Publish(t.Context(), &cepb.CloudEvent{Id: "1", Source: "test", SpecVersion: "1.0"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Len(t, srv.Events(), 1)
+	})
+}
+
+func TestServer_FailNext(t *testing.T) {
+	srv := chipingresstest.NewServer(t)
+	client := srv.NewClient(t)
+
+	srv.FailNext(2, status.Error(codes.Unavailable, "unavailable"))
+	for range 2 {
+		_, err := client.Publish(t.Context(), newEvent(t, "test", "entity", nil))
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	}
+	_, err := client.Publish(t.Context(), newEvent(t, "test", "entity", nil))
+	require.NoError(t, err)
+	assert.Equal(t, 3, srv.PublishCalls())
+
+	srv.FailNext(1, status.Error(codes.Internal, "boom"))
+	_, err = client.PublishBatch(t.Context(), &pb.CloudEventBatch{Events: []*cepb.CloudEvent{newEvent(t, "test", "entity", nil)}})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Len(t, srv.Events(), 1)
+}
+
+func TestServer_Latency(t *testing.T) {
+	srv := chipingresstest.NewServer(t)
+	client := srv.NewClient(t)
+	srv.SetLatency(time.Second)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	_, err := client.Publish(ctx, newEvent(t, "test", "entity", nil))
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.Empty(t, srv.Events())
+}
+
+func TestServer_PublishBatch(t *testing.T) {
+	rejected := func(event *cepb.CloudEvent) *pb.PublishError {
+		if event.Type == "bad" {
+			return &pb.PublishError{ErrorCode: pb.PublishErrorCode_PUBLISH_ERROR_CODE_DOMAIN_MISCONFIGURATION, Reason: "bad"}
+		}
+		return nil
+	}
+
+	t.Run("partial delivery", func(t *testing.T) {
+		srv := chipingresstest.NewServer(t)
+		srv.SetResultErrors(rejected)
+		batchClient, err := batch.NewBatchClient(srv.NewClient(t), batch.WithBatchSize(3), batch.WithBatchInterval(10*time.Millisecond))
+		require.NoError(t, err)
+		batchClient.Start(t.Context())
+		defer batchClient.Stop()
+
+		var wg sync.WaitGroup
+		errs := make(map[string]error)
+		var mu sync.Mutex
+		for _, typ := range []string{"good", "bad", "good"} {
+			event := newEvent(t, "test", typ, nil)
+			wg.Add(1)
+			require.NoError(t, batchClient.QueueMessage(event, func(err error) {
+				defer wg.Done()
+				mu.Lock()
+				errs[event.Id] = err
+				mu.Unlock()
+			}))
+		}
+		wg.Wait()
+
+		events := srv.WaitForEvents(t, 2)
+		assert.Len(t, events, 2)
+		mu.Lock()
+		defer mu.Unlock()
+		var failed int
+		for _, err := range errs {
+			if err == nil {
+				continue
+			}
+			failed++
+			pubErr, ok := errors.AsType[*batch.PublishError](err)
+			require.True(t, ok, err)
+			assert.Equal(t, pb.PublishErrorCode_PUBLISH_ERROR_CODE_DOMAIN_MISCONFIGURATION, pubErr.Code)
+		}
+		assert.Equal(t, 1, failed)
+	})
+
+	t.Run("transaction", func(t *testing.T) {
+		srv := chipingresstest.NewServer(t)
+		srv.SetResultErrors(rejected)
+		client := srv.NewClient(t)
+
+		b, err := chipingress.EventsToBatchWithOpts(nil, chipingress.WithTransactionEnabled(true))
+		require.NoError(t, err)
+		b.Events = []*cepb.CloudEvent{newEvent(t, "test", "good", nil), newEvent(t, "test", "bad", nil)}
+		_, err = client.PublishBatch(t.Context(), b)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Empty(t, srv.Events())
+	})
+}
+
+func TestServer_SchemaValidation(t *testing.T) {
+	srv := chipingresstest.NewServer(t, chipingresstest.WithSchemaValidation())
+	client := srv.NewClient(t)
+
+	registered, err := client.RegisterSchemas(t.Context(), pingSchema)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{pingSchema.Subject: 1}, registered)
+	// identical schemas are not new versions
+	registered, err = client.RegisterSchemas(t.Context(), pingSchema)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{pingSchema.Subject: 1}, registered)
+	assert.Len(t, srv.Schemas(pingSchema.Subject), 1)
+
+	resp, err := client.PublishBatch(t.Context(), &pb.CloudEventBatch{Events: []*cepb.CloudEvent{
+		newEvent(t, "test", "chipingress.pb.PingResponse", pingPayload(t)),
+		newEvent(t, "test", "chipingress.pb.PingResponse", []byte{0xff}),
+		newEvent(t, "test", "chipingress.pb.EmptyRequest", nil),
+	}})
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 3)
+	assert.Nil(t, resp.Results[0].Error)
+	assert.Equal(t, pb.PublishErrorCode_PUBLISH_ERROR_CODE_ENCODE_ERROR, resp.Results[1].Error.GetErrorCode())
+	assert.Equal(t, pb.PublishErrorCode_PUBLISH_ERROR_CODE_SCHEMA_MISSING, resp.Results[2].Error.GetErrorCode())
+	assert.Len(t, srv.Events(), 1)
+
+	t.Run("declarations", func(t *testing.T) {
+		for _, tc := range []struct {
+			name   string
+			schema string
+			ok     bool
+		}{
+			{"nested", `package chipingress; message pb { message PingResponse { string message = 1; } }`, true},
+			{"prefix", `package chipingress.pb; message PingResponseV2 { string message = 1; }`, false},
+			{"comment", `package chipingress.pb; // message PingResponse { }
+message Other { string message = 1; }`, false},
+			{"string", `package chipingress.pb; message Other { string s = 1 [json_name = "message PingResponse {"]; }`, false},
+			{"package", `package chipingress.pbx; message PingResponse { string message = 1; }`, false},
+		} {
+			t.Run(tc.name, func(t *testing.T) {
+				srv := chipingresstest.NewServer(t, chipingresstest.WithSchemaValidation())
+				client := srv.NewClient(t)
+				_, err := client.RegisterSchemas(t.Context(), &pb.Schema{Subject: pingSchema.Subject, Format: pb.SchemaType_PROTOBUF, Schema: tc.schema})
+				require.NoError(t, err)
+				resp, err := client.Publish(t.Context(), newEvent(t, "test", "chipingress.pb.PingResponse", pingPayload(t)))
+				if tc.ok {
+					require.NoError(t, err)
+					assert.Len(t, resp.Results, 1)
+					return
+				}
+				assert.Equal(t, codes.InvalidArgument, status.Code(err))
+				assert.ErrorContains(t, err, "does not match a message in the schema")
+			})
+		}
+	})
+}
+
+func TestServer_StreamEvents(t *testing.T) {
+	srv := chipingresstest.NewServer(t)
+	// the first stream fails, so events are only delivered after a reconnect
+	srv.FailNext(1, status.Error(codes.Unavailable, "unavailable"))
+	batchClient, err := batch.NewBatchClient(srv.NewClient(t),
+		batch.WithStreamEvents(chipingress.WithReconnectBackoff(time.Millisecond, 10*time.Millisecond)),
+		batch.WithBatchInterval(10*time.Millisecond),
+	)
+	require.NoError(t, err)
+	batchClient.Start(t.Context())
+
+	const n = 20
+	errs := make(chan error, n)
+	for range n {
+		require.NoError(t, batchClient.QueueMessage(newEvent(t, "test", "entity", nil), func(err error) { errs <- err }))
+	}
+	for range n {
+		select {
+		case err := <-errs:
+			require.NoError(t, err)
+		case <-time.After(chipingresstest.DefaultWaitTimeout):
+			t.Fatal("timed out waiting for callbacks")
+		}
+	}
+	batchClient.Stop()
+
+	assert.Len(t, srv.Events(), n)
+	assert.GreaterOrEqual(t, srv.PublishCalls(), 2)
+}
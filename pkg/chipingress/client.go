@@ -113,22 +113,24 @@ func NewClient(address string, opts ...Opt) (Client, error) {
 	if cfg.perRPCCredentials != nil {
 		grpcOpts = append(grpcOpts, grpc.WithPerRPCCredentials(cfg.perRPCCredentials))
 	}
-	// Add headers as unary interceptors, use for non-auth headers.
+	// Add headers as unary and stream interceptors, use for non-auth headers.
 	// WithChainUnaryInterceptor is used (rather than WithUnaryInterceptor) so that
 	// headerProvider and nopInfoHeaderProvider compose instead of the second call
 	// silently overriding the first (grpc.WithUnaryInterceptor is last-one-wins).
 	var unaryInterceptors []grpc.UnaryClientInterceptor
-	if cfg.headerProvider != nil {
-		unaryInterceptors = append(unaryInterceptors, newHeaderInterceptor(cfg.headerProvider))
-		// NOTE: not supporting streaming interceptors
-	}
-
-	if cfg.nopInfoHeaderProvider != nil {
-		unaryInterceptors = append(unaryInterceptors, newHeaderInterceptor(cfg.nopInfoHeaderProvider))
+	var streamInterceptors []grpc.StreamClientInterceptor
+	for _, provider := range []HeaderProvider{cfg.headerProvider, cfg.nopInfoHeaderProvider} {
+		if provider != nil {
+			unaryInterceptors = append(unaryInterceptors, newHeaderInterceptor(provider))
+			streamInterceptors = append(streamInterceptors, newHeaderStreamInterceptor(provider))
+		}
 	}
 
 	if len(unaryInterceptors) > 0 {
-		grpcOpts = append(grpcOpts, grpc.WithChainUnaryInterceptor(unaryInterceptors...))
+		grpcOpts = append(grpcOpts,
+			grpc.WithChainUnaryInterceptor(unaryInterceptors...),
+			grpc.WithChainStreamInterceptor(streamInterceptors...),
+		)
 	}
 
 	conn, err := grpc.NewClient(address, grpcOpts...)
@@ -151,8 +153,9 @@ func (c *client) PublishBatch(ctx context.Context, in *CloudEventBatch, opts ...
 }
 
 // StreamEvents - Experimental, this API is subject to change.
-func (c *client) StreamEvents(_ context.Context, _ ...grpc.CallOption) (grpc.BidiStreamingClient[StreamEventsRequest, StreamEventsResponse], error) {
-	return nil, fmt.Errorf("not implemented: StreamEvents is experimental and not supported yet")
+// Prefer NewEventStream, which adds flow control, acknowledgements and reconnects.
+func (c *client) StreamEvents(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[StreamEventsRequest, StreamEventsResponse], error) {
+	return c.client.StreamEvents(ctx, opts...)
 }
 
 func (c *client) RegisterSchema(ctx context.Context, in *pb.RegisterSchemaRequest, opts ...grpc.CallOption) (*pb.RegisterSchemaResponse, error) {
@@ -283,6 +286,20 @@ func newHeaderInterceptor(provider HeaderProvider) grpc.UnaryClientInterceptor {
 	}
 }
 
+// newHeaderStreamInterceptor creates a stream interceptor that adds headers from a HeaderProvider
+func newHeaderStreamInterceptor(provider HeaderProvider) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		headers, err := provider.Headers(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get headers: %w", err)
+		}
+		for k, v := range headers {
+			ctx = metadata.AppendToOutgoingContext(ctx, k, v)
+		}
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+}
+
 // EventOpt configures a CloudEvent after its well-known attributes have been set by NewEvent.
 type EventOpt func(*ce.Event)
 
@@ -0,0 +1,345 @@
+package chipingress
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StreamAckSuccess is the StreamEventsResponse.Status of an event which was accepted by the server.
+const StreamAckSuccess = "success"
+
+var (
+	// ErrStreamClosed is returned for events which were not acknowledged before the EventStream was closed.
+	ErrStreamClosed = errors.New("event stream is closed")
+	// ErrEventRejected is wrapped by the error returned for events which were acknowledged with a non-success status.
+	ErrEventRejected = errors.New("event rejected")
+)
+
+type eventStream = grpc.BidiStreamingClient[StreamEventsRequest, StreamEventsResponse]
+
+// StreamOpt configures an EventStream.
+type StreamOpt func(*streamConfig)
+
+type streamConfig struct {
+	maxInFlight    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+// WithMaxInFlight sets the maximum number of unacknowledged events, after which Publish blocks. Defaults to 100.
+func WithMaxInFlight(n int) StreamOpt {
+	return func(c *streamConfig) { c.maxInFlight = max(n, 1) }
+}
+
+// WithReconnectBackoff sets the exponential backoff between reconnect attempts. Defaults to 100ms up to 5s.
+func WithReconnectBackoff(initial, maximum time.Duration) StreamOpt {
+	return func(c *streamConfig) {
+		c.initialBackoff = initial
+		c.maxBackoff = max(initial, maximum)
+	}
+}
+
+type pendingEvent struct {
+	event    *CloudEventPb
+	callback func(error)
+}
+
+// EventStream publishes events over a StreamEvents stream, with flow control and per-event acknowledgements.
+//
+// Events are sent in the order they are published, and each callback is invoked once the server acknowledges the
+// event, by its ID. After a transport error the stream is reopened, and all unacknowledged events are resent in their
+// original order, ahead of any new events. Delivery is therefore at-least-once: an event may be received again if
+// the stream failed before its acknowledgement arrived.
+type EventStream struct {
+	client ChipIngressClient
+	cfg    streamConfig
+
+	window chan struct{} // a slot is held by every queued or unacknowledged event
+	queue  chan *pendingEvent
+	stop   context.CancelFunc
+	done   chan struct{}
+
+	closeMu sync.RWMutex
+	closed  bool
+
+	mu      sync.Mutex
+	pending *list.List // of *pendingEvent, in send order
+	byID    map[string]*list.Element
+}
+
+// NewEventStream starts an EventStream on client, which must be closed with Close.
+func NewEventStream(client ChipIngressClient, opts ...StreamOpt) *EventStream {
+	cfg := streamConfig{
+		maxInFlight:    100,
+		initialBackoff: 100 * time.Millisecond,
+		maxBackoff:     5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &EventStream{
+		client:  client,
+		cfg:     cfg,
+		window:  make(chan struct{}, cfg.maxInFlight),
+		queue:   make(chan *pendingEvent, cfg.maxInFlight),
+		stop:    cancel,
+		done:    make(chan struct{}),
+		pending: list.New(),
+		byID:    make(map[string]*list.Element),
+	}
+	go s.run(ctx)
+	return s
+}
+
+// Publish queues event to be sent, blocking while the maximum number of events are in flight, or until ctx is done.
+// The callback, if any, is invoked with the outcome of the event: nil once it is acknowledged, an error wrapping
+// ErrEventRejected if the server rejected it, or ErrStreamClosed. Callbacks are invoked from the receiving goroutine,
+// so they must not block. The event must have an ID, and must not be modified after it is published.
+func (s *EventStream) Publish(ctx context.Context, event *CloudEventPb, callback func(error)) error {
+	if event == nil {
+		return errors.New("nil event")
+	}
+	if event.Id == "" {
+		return errors.New("event must have an id")
+	}
+	if s.isClosed() {
+		return ErrStreamClosed
+	}
+	select {
+	case s.window <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	s.closeMu.RLock()
+	defer s.closeMu.RUnlock()
+	if s.closed {
+		<-s.window
+		return ErrStreamClosed
+	}
+	// never blocks, since the queue is as large as the window
+	s.queue <- &pendingEvent{event: event, callback: callback}
+	return nil
+}
+
+func (s *EventStream) isClosed() bool {
+	s.closeMu.RLock()
+	defer s.closeMu.RUnlock()
+	return s.closed
+}
+
+// Close stops accepting events, and waits for those in flight to be acknowledged until ctx is done. Any events still
+// unacknowledged fail with ErrStreamClosed.
+func (s *EventStream) Close(ctx context.Context) error {
+	s.closeMu.Lock()
+	alreadyClosed := s.closed
+	s.closed = true
+	s.closeMu.Unlock()
+	if alreadyClosed {
+		<-s.done
+		return nil
+	}
+
+	// the window is drained once all of its slots can be taken
+	var err error
+	for range cap(s.window) {
+		select {
+		case s.window <- struct{}{}:
+		case <-ctx.Done():
+			err = fmt.Errorf("timed out waiting for acknowledgements: %w", ctx.Err())
+		}
+		if err != nil {
+			break
+		}
+	}
+	s.stop()
+	<-s.done
+	return err
+}
+
+// run maintains the stream until stopped, reconnecting after errors.
+func (s *EventStream) run(ctx context.Context) {
+	defer close(s.done)
+	defer s.failAll(ErrStreamClosed)
+
+	backoff := s.cfg.initialBackoff
+	for ctx.Err() == nil {
+		err := s.serve(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if !isRetryableStreamError(err) {
+			// the events were not accepted, and resending them would fail the same way
+			s.failAll(err)
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		backoff = min(2*backoff, s.cfg.maxBackoff)
+		if err == nil {
+			backoff = s.cfg.initialBackoff
+		}
+	}
+}
+
+// serve opens a stream, resends unacknowledged events, and then sends queued events until the stream fails. A nil
+// error is returned if the server ended a stream which had been delivering acknowledgements.
+func (s *EventStream) serve(ctx context.Context) error {
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := s.client.StreamEvents(streamCtx)
+	if err != nil {
+		return err
+	}
+	if stream == nil {
+		return status.Error(codes.Unavailable, "no stream returned")
+	}
+
+	recvErr := make(chan error, 1)
+	go func() { recvErr <- s.receive(stream) }()
+	// sendErr prefers the status of the stream, since Send only returns io.EOF once the stream has ended
+	sendErr := func(err error) error {
+		if errors.Is(err, io.EOF) {
+			return <-recvErr
+		}
+		return err
+	}
+
+	s.mu.Lock()
+	var resend []*CloudEventPb
+	for e := s.pending.Front(); e != nil; e = e.Next() {
+		resend = append(resend, e.Value.(*pendingEvent).event)
+	}
+	s.mu.Unlock()
+	for _, event := range resend {
+		if err := stream.Send(&StreamEventsRequest{Event: event}); err != nil {
+			return sendErr(err)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			_ = stream.CloseSend()
+			return ctx.Err()
+		case err := <-recvErr:
+			return err
+		case pe := <-s.queue:
+			if !s.track(pe) {
+				continue
+			}
+			if err := stream.Send(&StreamEventsRequest{Event: pe.event}); err != nil {
+				// the event is pending, so it is resent after reconnecting
+				return sendErr(err)
+			}
+		}
+	}
+}
+
+// track adds pe to the pending events, or fails it if an event with the same ID is already pending.
+func (s *EventStream) track(pe *pendingEvent) bool {
+	s.mu.Lock()
+	_, dup := s.byID[pe.event.Id]
+	if !dup {
+		s.byID[pe.event.Id] = s.pending.PushBack(pe)
+	}
+	s.mu.Unlock()
+	if dup {
+		s.complete(pe, fmt.Errorf("event %s is already in flight", pe.event.Id))
+		return false
+	}
+	return true
+}
+
+// receive handles acknowledgements until the stream fails. Since the server only ends the stream cleanly after
+// acknowledging everything it accepted, io.EOF is returned as nil when there was progress.
+func (s *EventStream) receive(stream eventStream) error {
+	var acked bool
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			if errors.Is(err, io.EOF) && acked {
+				return nil
+			}
+			return err
+		}
+		acked = s.ack(resp) || acked
+	}
+}
+
+// ack completes the pending event acknowledged by resp, and reports whether there was one.
+func (s *EventStream) ack(resp *StreamEventsResponse) bool {
+	s.mu.Lock()
+	e, ok := s.byID[resp.EventId]
+	if ok {
+		delete(s.byID, resp.EventId)
+		s.pending.Remove(e)
+	}
+	s.mu.Unlock()
+	if !ok {
+		// duplicate acknowledgement of a resent event
+		return false
+	}
+	var err error
+	if resp.Status != StreamAckSuccess {
+		err = fmt.Errorf("%w: %s: %s", ErrEventRejected, resp.EventId, resp.Status)
+	}
+	s.complete(e.Value.(*pendingEvent), err)
+	return true
+}
+
+// failAll completes all pending and queued events with err.
+func (s *EventStream) failAll(err error) {
+	s.mu.Lock()
+	var failed []*pendingEvent
+	for e := s.pending.Front(); e != nil; e = e.Next() {
+		failed = append(failed, e.Value.(*pendingEvent))
+	}
+	s.pending.Init()
+	clear(s.byID)
+	s.mu.Unlock()
+	for _, pe := range failed {
+		s.complete(pe, err)
+	}
+	if !errors.Is(err, ErrStreamClosed) {
+		return
+	}
+	for {
+		select {
+		case pe := <-s.queue:
+			s.complete(pe, err)
+		default:
+			return
+		}
+	}
+}
+
+func (s *EventStream) complete(pe *pendingEvent, err error) {
+	<-s.window
+	if pe.callback != nil {
+		pe.callback(err)
+	}
+}
+
+// isRetryableStreamError reports whether err is a transport error, after which events should be resent.
+func isRetryableStreamError(err error) bool {
+	if err == nil || errors.Is(err, io.EOF) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted, codes.Internal, codes.Unknown, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
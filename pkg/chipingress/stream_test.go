@@ -0,0 +1,296 @@
+package chipingress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gp "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/chipingress/pb"
+)
+
+// streamingServer is a ChipIngressServer whose StreamEvents behaviour is set per stream by handle, which is passed
+// the index of the stream. By default every event is acknowledged.
+type streamingServer struct {
+	pb.UnimplementedChipIngressServer
+	handle func(n int, stream gp.BidiStreamingServer[pb.StreamEventsRequest, pb.StreamEventsResponse]) error
+
+	mu       sync.Mutex
+	streams  int
+	received [][]string // event IDs received on each stream
+	lastMD   metadata.MD
+}
+
+func (s *streamingServer) StreamEvents(stream gp.BidiStreamingServer[pb.StreamEventsRequest, pb.StreamEventsResponse]) error {
+	s.mu.Lock()
+	n := s.streams
+	s.streams++
+	s.received = append(s.received, nil)
+	s.lastMD, _ = metadata.FromIncomingContext(stream.Context())
+	s.mu.Unlock()
+	if s.handle != nil {
+		return s.handle(n, stream)
+	}
+	return s.ackAll(n, stream, StreamAckSuccess)
+}
+
+// recv receives the next event on stream n and records its ID.
+func (s *streamingServer) recv(n int, stream gp.BidiStreamingServer[pb.StreamEventsRequest, pb.StreamEventsResponse]) (string, error) {
+	req, err := stream.Recv()
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	s.received[n] = append(s.received[n], req.Event.Id)
+	s.mu.Unlock()
+	return req.Event.Id, nil
+}
+
+func (s *streamingServer) ackAll(n int, stream gp.BidiStreamingServer[pb.StreamEventsRequest, pb.StreamEventsResponse], status string) error {
+	for {
+		id, err := s.recv(n, stream)
+		if err != nil {
+			return nil
+		}
+		if err := stream.Send(&pb.StreamEventsResponse{EventId: id, Status: status}); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *streamingServer) receivedOn(n int) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if n >= len(s.received) {
+		return nil
+	}
+	return append([]string(nil), s.received[n]...)
+}
+
+func newStreamingClient(t *testing.T, srv *streamingServer, opts ...Opt) Client {
+	t.Helper()
+	lis, err := (&net.ListenConfig{}).Listen(t.Context(), "tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := gp.NewServer()
+	pb.RegisterChipIngressServer(server, srv)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	client, err := NewClient(lis.Addr().String(), append([]Opt{WithInsecureConnection()}, opts...)...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+// results collects the outcome of each event by ID.
+type results struct {
+	mu   sync.Mutex
+	wg   sync.WaitGroup
+	errs map[string]error
+}
+
+func newResults() *results {
+	return &results{errs: make(map[string]error)}
+}
+
+func (r *results) callback(id string) func(error) {
+	r.wg.Add(1)
+	return func(err error) {
+		r.mu.Lock()
+		r.errs[id] = err
+		r.mu.Unlock()
+		r.wg.Done()
+	}
+}
+
+func (r *results) wait(t *testing.T) map[string]error {
+	t.Helper()
+	done := make(chan struct{})
+	go func() { r.wg.Wait(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for callbacks")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.errs
+}
+
+func publishN(t *testing.T, s *EventStream, r *results, prefix string, n int) []string {
+	t.Helper()
+	var ids []string
+	for i := range n {
+		id := fmt.Sprintf("%s-%d", prefix, i)
+		require.NoError(t, s.Publish(t.Context(), &CloudEventPb{Id: id, Source: "src", Type: "typ"}, r.callback(id)))
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+func TestEventStream(t *testing.T) {
+	t.Run("acknowledges events in order", func(t *testing.T) {
+		srv := &streamingServer{}
+		s := NewEventStream(newStreamingClient(t, srv, WithNOPLookup()))
+
+		r := newResults()
+		ids := publishN(t, s, r, "e", 20)
+		for id, err := range r.wait(t) {
+			assert.NoError(t, err, id)
+		}
+		require.NoError(t, s.Close(t.Context()))
+
+		assert.Equal(t, ids, srv.receivedOn(0))
+		assert.Equal(t, []string{"true"}, srv.lastMD.Get("x-include-nop-info"), "headers are sent on streams")
+	})
+
+	t.Run("rejected events", func(t *testing.T) {
+		srv := &streamingServer{}
+		srv.handle = func(n int, stream gp.BidiStreamingServer[pb.StreamEventsRequest, pb.StreamEventsResponse]) error {
+			return srv.ackAll(n, stream, "error")
+		}
+		s := NewEventStream(newStreamingClient(t, srv))
+
+		r := newResults()
+		publishN(t, s, r, "e", 3)
+		for _, err := range r.wait(t) {
+			assert.ErrorIs(t, err, ErrEventRejected)
+		}
+		require.NoError(t, s.Close(t.Context()))
+	})
+
+	t.Run("resumes unacknowledged events after transport errors", func(t *testing.T) {
+		srv := &streamingServer{}
+		srv.handle = func(n int, stream gp.BidiStreamingServer[pb.StreamEventsRequest, pb.StreamEventsResponse]) error {
+			if n > 0 {
+				return srv.ackAll(n, stream, StreamAckSuccess)
+			}
+			// acknowledge only the first of three events, then fail
+			for i := range 3 {
+				id, err := srv.recv(n, stream)
+				if err != nil {
+					return err
+				}
+				if i == 0 {
+					if err := stream.Send(&pb.StreamEventsResponse{EventId: id, Status: StreamAckSuccess}); err != nil {
+						return err
+					}
+				}
+			}
+			return status.Error(codes.Unavailable, "going away")
+		}
+		s := NewEventStream(newStreamingClient(t, srv), WithReconnectBackoff(time.Millisecond, 10*time.Millisecond))
+
+		r := newResults()
+		ids := publishN(t, s, r, "e", 3)
+		require.Eventually(t, func() bool { return len(srv.receivedOn(1)) == 2 }, 5*time.Second, 10*time.Millisecond)
+		ids = append(ids, publishN(t, s, r, "f", 2)...)
+		for id, err := range r.wait(t) {
+			assert.NoError(t, err, id)
+		}
+		require.NoError(t, s.Close(t.Context()))
+
+		assert.Equal(t, ids[:3], srv.receivedOn(0))
+		// the unacknowledged events are resent in order, ahead of new events
+		assert.Equal(t, append(ids[1:3:3], ids[3:]...), srv.receivedOn(1))
+	})
+
+	t.Run("fails events after permanent errors", func(t *testing.T) {
+		srv := &streamingServer{}
+		srv.handle = func(n int, stream gp.BidiStreamingServer[pb.StreamEventsRequest, pb.StreamEventsResponse]) error {
+			if _, err := srv.recv(n, stream); err != nil {
+				return err
+			}
+			return status.Error(codes.PermissionDenied, "denied")
+		}
+		s := NewEventStream(newStreamingClient(t, srv), WithReconnectBackoff(time.Millisecond, time.Millisecond))
+
+		r := newResults()
+		publishN(t, s, r, "e", 1)
+		for _, err := range r.wait(t) {
+			assert.Equal(t, codes.PermissionDenied, status.Code(err))
+		}
+		require.NoError(t, s.Close(t.Context()))
+	})
+
+	t.Run("flow control", func(t *testing.T) {
+		release := make(chan struct{})
+		srv := &streamingServer{}
+		srv.handle = func(n int, stream gp.BidiStreamingServer[pb.StreamEventsRequest, pb.StreamEventsResponse]) error {
+			<-release
+			return srv.ackAll(n, stream, StreamAckSuccess)
+		}
+		s := NewEventStream(newStreamingClient(t, srv), WithMaxInFlight(2))
+
+		r := newResults()
+		publishN(t, s, r, "e", 2)
+		ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+		defer cancel()
+		err := s.Publish(ctx, &CloudEventPb{Id: "blocked"}, nil)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+
+		close(release)
+		for id, err := range r.wait(t) {
+			assert.NoError(t, err, id)
+		}
+		require.NoError(t, s.Publish(t.Context(), &CloudEventPb{Id: "unblocked"}, nil))
+		require.NoError(t, s.Close(t.Context()))
+	})
+
+	t.Run("close fails unacknowledged events", func(t *testing.T) {
+		srv := &streamingServer{}
+		srv.handle = func(n int, stream gp.BidiStreamingServer[pb.StreamEventsRequest, pb.StreamEventsResponse]) error {
+			<-stream.Context().Done()
+			return nil
+		}
+		s := NewEventStream(newStreamingClient(t, srv))
+
+		r := newResults()
+		publishN(t, s, r, "e", 2)
+		ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, s.Close(ctx), context.DeadlineExceeded)
+		for _, err := range r.wait(t) {
+			assert.ErrorIs(t, err, ErrStreamClosed)
+		}
+		assert.ErrorIs(t, s.Publish(t.Context(), &CloudEventPb{Id: "late"}, nil), ErrStreamClosed)
+		require.NoError(t, s.Close(t.Context()))
+	})
+
+	t.Run("rejects events without id", func(t *testing.T) {
+		s := NewEventStream(newStreamingClient(t, &streamingServer{}))
+		defer func() { require.NoError(t, s.Close(t.Context())) }()
+		require.Error(t, s.Publish(t.Context(), &CloudEventPb{}, nil))
+		require.Error(t, s.Publish(t.Context(), nil, nil))
+	})
+
+	t.Run("duplicate ids in flight", func(t *testing.T) {
+		srv := &streamingServer{}
+		srv.handle = func(n int, stream gp.BidiStreamingServer[pb.StreamEventsRequest, pb.StreamEventsResponse]) error {
+			<-stream.Context().Done()
+			return nil
+		}
+		s := NewEventStream(newStreamingClient(t, srv))
+
+		r := newResults()
+		require.NoError(t, s.Publish(t.Context(), &CloudEventPb{Id: "a"}, r.callback("first")))
+		require.NoError(t, s.Publish(t.Context(), &CloudEventPb{Id: "a"}, r.callback("second")))
+		ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+		defer cancel()
+		_ = s.Close(ctx)
+		errs := r.wait(t)
+		assert.ErrorIs(t, errs["first"], ErrStreamClosed)
+		require.Error(t, errs["second"])
+		assert.False(t, errors.Is(errs["second"], ErrStreamClosed))
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io"
 	"maps"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -98,6 +99,10 @@ type Config struct {
 	// DeleteBatchWorkers is the number of concurrent batch-delete goroutines.
 	// Zero defaults to 2.
 	DeleteBatchWorkers int
+	// Priorities enables priority classes, with per-class quotas of the durable
+	// queue, shedding of lower classes when it is full, and retransmission of
+	// higher classes first. Nil treats all events alike.
+	Priorities *PriorityConfig
 }
 
 // Hooks records delivery latency to locate pipeline bottlenecks.
@@ -146,6 +151,10 @@ func DefaultConfig() Config {
 // A separate expiry loop garbage-collects events older than EventTTL to bound
 // table growth.
 
+// errEmitterClosed is returned by coalesced inserts after Close. It is not
+// counted as a failed emit.
+var errEmitterClosed = errors.New("durable emitter closed")
+
 // insertRequest is a single Emit() caller waiting for a coalesced batch INSERT.
 type insertRequest struct {
 	payload []byte
@@ -171,6 +180,9 @@ type DurableEmitter struct {
 
 	metrics *durableEmitterMetrics
 
+	// priorities is non-nil when Config.Priorities is set.
+	priorities *priorityTracker
+
 	// batchInserter is non-nil when the store supports multi-row INSERTs
 	// and InsertBatchSize > 0.
 	batchInserter BatchInserter
@@ -250,6 +262,14 @@ func NewDurableEmitter(
 		}
 		store = newMetricsInstrumentedStore(store, m)
 	}
+	var pt *priorityTracker
+	if cfg.Priorities != nil {
+		var err error
+		pt, err = newPriorityTracker(cfg.Priorities)
+		if err != nil {
+			return nil, fmt.Errorf("durable emitter priorities: %w", err)
+		}
+	}
 	d := &DurableEmitter{
 		store:             store,
 		isStoreQueue:      isQueue,
@@ -257,6 +277,7 @@ func NewDurableEmitter(
 		retransmitEnabled: retransmitEnabled,
 		cfg:               cfg,
 		metrics:           m,
+		priorities:        pt,
 		stopCh:            make(chan struct{}),
 	}
 	d.Service, d.eng = services.Config{
@@ -315,6 +336,9 @@ func (d *DurableEmitter) start(ctx context.Context) error {
 	}
 
 	if d.retransmitEnabled {
+		if d.priorities != nil {
+			d.loadPriorityBacklog(ctx)
+		}
 		d.wg.Go(d.retransmitLoop)
 		if !d.cfg.DisablePruning {
 			d.wg.Go(d.expiryLoop)
@@ -350,16 +374,19 @@ func (d *DurableEmitter) Emit(ctx context.Context, body []byte, attrKVs ...any)
 				d.metrics.emitTotalDuration.Record(ctx, time.Since(tEmitTotal).Seconds())
 			}
 		}()
+		// class is nil without priority classes, or until the event is classified.
+		var class *priorityClassState
 		emitFail := func() {
-			if d.metrics != nil {
-				d.metrics.emitFail.Add(ctx, 1)
-			}
+			d.metrics.recordEmitResult(ctx, class, false)
 		}
 		sourceDomain, entityType, err := beholder.ExtractSourceAndType(attrKVs...)
 		if err != nil {
 			emitFail()
 			return err
 		}
+		if d.priorities != nil {
+			class = d.priorities.classify(sourceDomain, entityType)
+		}
 
 		attrs := parseAttrs(attrKVs...)
 		ensureIdempotencyKey(attrs, sourceDomain, entityType, body)
@@ -384,75 +411,14 @@ func (d *DurableEmitter) Emit(ctx context.Context, body []byte, attrKVs ...any)
 			return fmt.Errorf("failed to marshal event proto: %w", err)
 		}
 
-		var id int64
-		var insElapsed time.Duration
-
-		if d.insertCh != nil {
-			// Write coalescing: send payload to the batch insert loop and block
-			// until the multi-row INSERT completes.
-			req := &insertRequest{
-				payload: payload,
-				result:  make(chan insertResult, 1),
-			}
-			var res insertResult
-			var cerr error
-			func() {
-				d.insertInFlight.Add(1)
-				defer d.insertInFlight.Add(-1)
-				if d.insertShutdown.Load() {
-					cerr = errors.New("durable emitter closed")
-					return
-				}
-				tIns := time.Now()
-				select {
-				case d.insertCh <- req:
-				case <-ctx.Done():
-					cerr = ctx.Err()
-					return
-				}
-				res = <-req.result
-				insElapsed = time.Since(tIns)
-			}()
-			if cerr != nil {
-				if errors.Is(cerr, context.Canceled) || errors.Is(cerr, context.DeadlineExceeded) {
-					emitFail()
-				}
-				return cerr
-			}
-			if h := d.cfg.Hooks; h != nil && h.OnEmitInsert != nil {
-				h.OnEmitInsert(insElapsed, res.err)
-			}
-			if d.metrics != nil {
-				d.metrics.recordEmitDuration(ctx, insElapsed, res.err)
-				if res.err != nil {
-					d.metrics.emitFail.Add(ctx, 1)
-				} else {
-					d.metrics.emitSuccess.Add(ctx, 1)
-				}
-			}
-			if res.err != nil {
-				return fmt.Errorf("failed to persist event: %w", res.err)
-			}
-			id = res.id
-		} else {
-			tIns := time.Now()
-			id, err = d.store.Insert(ctx, payload)
-			insElapsed = time.Since(tIns)
-			if h := d.cfg.Hooks; h != nil && h.OnEmitInsert != nil {
-				h.OnEmitInsert(insElapsed, err)
-			}
-			if d.metrics != nil {
-				d.metrics.recordEmitDuration(ctx, insElapsed, err)
-				if err != nil {
-					d.metrics.emitFail.Add(ctx, 1)
-				} else {
-					d.metrics.emitSuccess.Add(ctx, 1)
-				}
-			}
-			if err != nil {
-				return fmt.Errorf("failed to persist event: %w", err)
+		id, err := d.persist(ctx, class, payload)
+		if err != nil {
+			if !errors.Is(err, errEmitterClosed) {
+				emitFail()
 			}
+			return err
 		}
+		d.metrics.recordEmitResult(ctx, class, true)
 
 		// Hand off to the batch emitter. The callback fires once the batch
 		// containing this event is sent (success or failure).
@@ -468,6 +434,141 @@ func (d *DurableEmitter) Emit(ctx context.Context, body []byte, attrKVs ...any)
 	})
 }
 
+// persist inserts a payload accounted to class, which is nil without priority
+// classes. Pending events of lower classes are shed first when the insert would
+// exceed PriorityConfig.MaxPayloadBytes, or when the store rejects it with
+// ErrStoreFull, in which case the insert is retried once.
+func (d *DurableEmitter) persist(ctx context.Context, class *priorityClassState, payload []byte) (int64, error) {
+	if class == nil {
+		return d.insert(ctx, nil, payload)
+	}
+	size := int64(len(payload))
+	victims, err := d.priorities.reserve(class, size, time.Now().Add(-d.cfg.EventTTL))
+	if err != nil {
+		d.priorityRejected(ctx, class, err)
+		return 0, err
+	}
+	d.shed(ctx, class, victims)
+	id, err := d.insert(ctx, class, payload)
+	if errors.Is(err, ErrStoreFull) {
+		if victims = d.priorities.shed(class, size); victims != nil {
+			d.shed(ctx, class, victims)
+			id, err = d.insert(ctx, class, payload)
+		}
+	}
+	if err != nil {
+		d.priorities.cancel(class, size)
+		d.priorityRejected(ctx, class, err)
+		return 0, err
+	}
+	d.priorities.commit(class, size, id)
+	return id, nil
+}
+
+// shed deletes pending events of lower classes to make room for an event of
+// class.
+func (d *DurableEmitter) shed(ctx context.Context, class *priorityClassState, victims []*trackedEvent) {
+	if len(victims) == 0 {
+		return
+	}
+	ids := make([]int64, len(victims))
+	shed := make(map[string]int64)
+	for i, e := range victims {
+		ids[i] = e.id
+		shed[e.class.Name]++
+	}
+	if _, err := d.store.BatchDelete(ctx, ids); err != nil {
+		// The events are still pending, so they are accounted for again, and
+		// the insert may exceed the limit, or be rejected by the store as full.
+		d.priorities.restore(victims)
+		d.eng.Errorw("DurableEmitter: failed to delete shed events", "count", len(ids), "class", class.Name, "error", err)
+		return
+	}
+	d.priorities.settle()
+	d.eng.Warnw("DurableEmitter: durable queue full, shed events of lower priority", "class", class.Name, "shed", shed)
+	if d.metrics != nil {
+		for name, n := range shed {
+			d.metrics.recordPriorityShed(ctx, name, n)
+		}
+	}
+}
+
+func (d *DurableEmitter) priorityRejected(ctx context.Context, class *priorityClassState, err error) {
+	var reason string
+	switch {
+	case errors.Is(err, ErrQuotaExceeded):
+		reason = "quota"
+	case errors.Is(err, ErrStoreFull):
+		reason = "full"
+	default:
+		return
+	}
+	d.eng.Warnw("DurableEmitter: event rejected", "class", class.Name, "reason", reason, "error", err)
+	if d.metrics != nil {
+		d.metrics.recordPriorityRejected(ctx, class.Name, reason)
+	}
+}
+
+// insert persists a payload through the write coalescer when enabled, or
+// directly otherwise, recording the insert hooks and duration. The outcome of
+// the emit is recorded by the caller, since an insert may be retried.
+func (d *DurableEmitter) insert(ctx context.Context, class *priorityClassState, payload []byte) (int64, error) {
+	var id int64
+	var insElapsed time.Duration
+	var err error
+
+	if d.insertCh != nil {
+		// Write coalescing: send payload to the batch insert loop and block
+		// until the multi-row INSERT completes.
+		req := &insertRequest{
+			payload: payload,
+			result:  make(chan insertResult, 1),
+		}
+		var res insertResult
+		var cerr error
+		func() {
+			d.insertInFlight.Add(1)
+			defer d.insertInFlight.Add(-1)
+			if d.insertShutdown.Load() {
+				cerr = errEmitterClosed
+				return
+			}
+			tIns := time.Now()
+			select {
+			case d.insertCh <- req:
+			case <-ctx.Done():
+				cerr = ctx.Err()
+				return
+			}
+			res = <-req.result
+			insElapsed = time.Since(tIns)
+		}()
+		if cerr != nil {
+			return 0, cerr
+		}
+		if h := d.cfg.Hooks; h != nil && h.OnEmitInsert != nil {
+			h.OnEmitInsert(insElapsed, res.err)
+		}
+		d.metrics.recordEmitDuration(ctx, class, insElapsed, res.err)
+		if res.err != nil {
+			return 0, fmt.Errorf("failed to persist event: %w", res.err)
+		}
+		id = res.id
+	} else {
+		tIns := time.Now()
+		id, err = d.store.Insert(ctx, payload)
+		insElapsed = time.Since(tIns)
+		if h := d.cfg.Hooks; h != nil && h.OnEmitInsert != nil {
+			h.OnEmitInsert(insElapsed, err)
+		}
+		d.metrics.recordEmitDuration(ctx, class, insElapsed, err)
+		if err != nil {
+			return 0, fmt.Errorf("failed to persist event: %w", err)
+		}
+	}
+	return id, nil
+}
+
 // deliveryCallback returns the function passed to BatchEmitter.QueueMessage.
 // On success, it deletes the delivered event. On failure, it leaves the event
 // in the DB for the retransmit loop.
@@ -491,6 +592,7 @@ func (d *DurableEmitter) deliveryCallback(id int64, eventPb *chipingress.CloudEv
 			d.eng.Warnw("DurableEmitter: failed to deliver event. Relying on retransmit.", "eventID", eventPb.Id, "err", sendErr)
 			return
 		}
+		d.priorities.remove(id)
 
 		// When delete coalescing is enabled the id is handed to the batch-delete
 		// workers (one DELETE for many ids); otherwise delete it inline.
@@ -728,25 +830,42 @@ func (d *DurableEmitter) retransmitPending() {
 }
 
 // retransmit re-enqueues pending DB rows through the batch emitter. Each row
-// gets its own delivery callback that deletes it on success.
+// gets its own delivery callback that deletes it on success. With priority
+// classes, the rows of higher classes are enqueued first, so they get the
+// buffer space when the batch emitter is backed up.
 func (d *DurableEmitter) retransmit(ctx context.Context, pending []DurableEvent) {
 	var enqueued, skipped int
 
+	type retransmitEvent struct {
+		id       int64
+		eventPb  *chipingress.CloudEventPb
+		priority int
+	}
+	events := make([]retransmitEvent, 0, len(pending))
 	for _, pe := range pending {
-		select {
-		case <-d.stopCh:
-			return
-		default:
-		}
-
 		eventPb := new(chipingress.CloudEventPb)
 		if err := proto.Unmarshal(pe.Payload, eventPb); err != nil {
 			d.eng.Errorw("DurableEmitter: failed to unmarshal event for retransmit", "id", pe.ID, "error", err)
 			continue
 		}
+		ev := retransmitEvent{id: pe.ID, eventPb: eventPb}
+		if d.priorities != nil {
+			ev.priority = d.priorities.classify(eventPb.Source, eventPb.Type).Priority
+		}
+		events = append(events, ev)
+	}
+	if d.priorities != nil {
+		slices.SortStableFunc(events, func(a, b retransmitEvent) int { return b.priority - a.priority })
+	}
+
+	for _, ev := range events {
+		select {
+		case <-d.stopCh:
+			return
+		default:
+		}
 
-		id := pe.ID
-		if err := d.batchEmitter.QueueMessage(eventPb, d.deliveryCallback(id, eventPb, time.Now(), publishPhaseRetransmit)); err != nil {
+		if err := d.batchEmitter.QueueMessage(ev.eventPb, d.deliveryCallback(ev.id, ev.eventPb, time.Now(), publishPhaseRetransmit)); err != nil {
 			skipped++
 			if d.metrics != nil {
 				d.metrics.batchEnqueueBufferFull.Add(ctx, 1,
@@ -764,6 +883,37 @@ func (d *DurableEmitter) retransmit(ctx context.Context, pending []DurableEvent)
 	)
 }
 
+// loadPriorityBacklog accounts the pending events found in the store to their
+// priority classes, so that the quotas hold across restarts.
+func (d *DurableEmitter) loadPriorityBacklog(ctx context.Context) {
+	const pageSize = 1000
+	var cursorTs time.Time
+	var cursorID int64
+	var loaded int
+	before := time.Now()
+	for {
+		pending, err := d.store.ListPending(ctx, before, cursorTs, cursorID, pageSize)
+		if err != nil {
+			d.eng.Errorw("DurableEmitter: failed to load pending events for priority accounting", "loaded", loaded, "error", err)
+			return
+		}
+		for _, pe := range pending {
+			eventPb := new(chipingress.CloudEventPb)
+			if err := proto.Unmarshal(pe.Payload, eventPb); err != nil {
+				continue
+			}
+			d.priorities.add(d.priorities.classify(eventPb.Source, eventPb.Type), pe)
+		}
+		loaded += len(pending)
+		if len(pending) < pageSize {
+			break
+		}
+		last := pending[len(pending)-1]
+		cursorTs, cursorID = last.CreatedAt, last.ID
+	}
+	d.eng.Infow("DurableEmitter: loaded pending events for priority accounting", "count", loaded)
+}
+
 func (d *DurableEmitter) expiryLoop() {
 	ticker := time.NewTicker(d.cfg.ExpiryInterval)
 	defer ticker.Stop()
@@ -775,6 +925,7 @@ func (d *DurableEmitter) expiryLoop() {
 		case <-d.stopCh:
 			return
 		case <-ticker.C:
+			d.priorities.expire(time.Now().Add(-d.cfg.EventTTL))
 			deleted, err := d.store.DeleteExpired(ctx, d.cfg.EventTTL)
 			if err != nil {
 				d.eng.Errorw("failed to delete expired events", "error", err)
@@ -830,6 +981,9 @@ func (d *DurableEmitter) metricsLoop() {
 			} else {
 				d.metrics.deleteCoalescerFill.Record(ctx, 0)
 			}
+			if d.priorities != nil {
+				d.metrics.recordPriorityStats(ctx, d.priorities.stats())
+			}
 			d.metrics.pollProcessGauges(ctx)
 		}
 	}
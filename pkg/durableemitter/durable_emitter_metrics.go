@@ -79,6 +79,13 @@ type durableEmitterMetrics struct {
 	// deleteCoalescerFill reports the delete-coalescer channel fill ratio
 	// (len/cap). Only meaningful when DeleteBatchSize > 0; otherwise 0.
 	deleteCoalescerFill metric.Float64Gauge
+	// priority* instruments are labelled by priority_class, and only recorded
+	// when Config.Priorities is set.
+	priorityPendingBytes  metric.Int64Gauge
+	priorityPendingEvents metric.Int64Gauge
+	priorityQuotaRatio    metric.Float64Gauge
+	priorityShed          metric.Int64Counter
+	priorityRejected      metric.Int64Counter
 }
 
 // durationBuckets provides histogram boundaries (in seconds) tuned for
@@ -294,6 +301,41 @@ func newDurableEmitterMetrics(meter metric.Meter, clientName string) (*durableEm
 	); err != nil {
 		return nil, err
 	}
+	if m.priorityPendingBytes, err = meter.Int64Gauge(
+		"durable_emitter.priority.pending_payload_bytes",
+		metric.WithUnit("By"),
+		metric.WithDescription("Pending payload bytes accounted to a priority class; labels: priority_class"),
+	); err != nil {
+		return nil, err
+	}
+	if m.priorityPendingEvents, err = meter.Int64Gauge(
+		"durable_emitter.priority.pending_events",
+		metric.WithUnit("{event}"),
+		metric.WithDescription("Pending events accounted to a priority class; labels: priority_class"),
+	); err != nil {
+		return nil, err
+	}
+	if m.priorityQuotaRatio, err = meter.Float64Gauge(
+		"durable_emitter.priority.quota_usage_ratio",
+		metric.WithUnit("1"),
+		metric.WithDescription("pending_payload_bytes / MaxPayloadBytes of a priority class with a quota; labels: priority_class"),
+	); err != nil {
+		return nil, err
+	}
+	if m.priorityShed, err = meter.Int64Counter(
+		"durable_emitter.priority.shed",
+		metric.WithUnit("{event}"),
+		metric.WithDescription("Pending events deleted to make room for events of higher priority; labels: priority_class"),
+	); err != nil {
+		return nil, err
+	}
+	if m.priorityRejected, err = meter.Int64Counter(
+		"durable_emitter.priority.rejected",
+		metric.WithUnit("{event}"),
+		metric.WithDescription("Emit calls rejected for lack of room; labels: priority_class, reason={quota,full}"),
+	); err != nil {
+		return nil, err
+	}
 	return m, nil
 }
 
@@ -329,15 +371,36 @@ func (m *durableEmitterMetrics) recordQueueStats(ctx context.Context, st Durable
 	}
 }
 
-func (m *durableEmitterMetrics) recordEmitDuration(ctx context.Context, elapsed time.Duration, err error) {
+// classAttrs returns the priority_class attribute of class, which is nil without priority classes.
+func classAttrs(class *priorityClassState) []attribute.KeyValue {
+	if class == nil {
+		return nil
+	}
+	return []attribute.KeyValue{attribute.String("priority_class", class.Name)}
+}
+
+func (m *durableEmitterMetrics) recordEmitDuration(ctx context.Context, class *priorityClassState, elapsed time.Duration, err error) {
 	if m == nil {
 		return
 	}
 	m.emitDuration.Record(ctx, elapsed.Seconds(),
-		metric.WithAttributes(attribute.Bool("error", err != nil)),
+		metric.WithAttributes(append(classAttrs(class), attribute.Bool("error", err != nil))...),
 	)
 }
 
+// recordEmitResult counts the outcome of an Emit, once.
+func (m *durableEmitterMetrics) recordEmitResult(ctx context.Context, class *priorityClassState, ok bool) {
+	if m == nil {
+		return
+	}
+	attrs := metric.WithAttributes(classAttrs(class)...)
+	if ok {
+		m.emitSuccess.Add(ctx, 1, attrs)
+	} else {
+		m.emitFail.Add(ctx, 1, attrs)
+	}
+}
+
 func (m *durableEmitterMetrics) recordPublish(ctx context.Context, elapsed time.Duration, phase publishPhase, err error) {
 	if m == nil {
 		return
@@ -365,3 +428,34 @@ func (m *durableEmitterMetrics) recordPublishBatchEvent(ctx context.Context, pha
 		m.publishBatchEvOK.Add(ctx, 1, attrs)
 	}
 }
+
+func (m *durableEmitterMetrics) recordPriorityStats(ctx context.Context, stats []priorityClassStats) {
+	if m == nil {
+		return
+	}
+	for _, st := range stats {
+		attrs := metric.WithAttributes(attribute.String("priority_class", st.name))
+		m.priorityPendingBytes.Record(ctx, st.pendingBytes, attrs)
+		m.priorityPendingEvents.Record(ctx, st.pendingEvents, attrs)
+		if st.maxPayloadBytes > 0 {
+			m.priorityQuotaRatio.Record(ctx, float64(st.pendingBytes)/float64(st.maxPayloadBytes), attrs)
+		}
+	}
+}
+
+func (m *durableEmitterMetrics) recordPriorityShed(ctx context.Context, class string, n int64) {
+	if m == nil {
+		return
+	}
+	m.priorityShed.Add(ctx, n, metric.WithAttributes(attribute.String("priority_class", class)))
+}
+
+func (m *durableEmitterMetrics) recordPriorityRejected(ctx context.Context, class, reason string) {
+	if m == nil {
+		return
+	}
+	m.priorityRejected.Add(ctx, 1, metric.WithAttributes(
+		attribute.String("priority_class", class),
+		attribute.String("reason", reason),
+	))
+}
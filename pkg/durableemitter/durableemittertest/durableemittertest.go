@@ -0,0 +1,252 @@
+// Package durableemittertest provides a conformance suite for durableemitter.DurableEventStore implementations.
+package durableemittertest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/durableemitter"
+)
+
+// future is a createdBefore which includes every event.
+func future() time.Time {
+	return time.Now().Add(time.Hour)
+}
+
+func insertN(t *testing.T, store durableemitter.DurableEventStore, n int) []int64 {
+	t.Helper()
+	ids := make([]int64, n)
+	for i := range n {
+		id, err := store.Insert(t.Context(), []byte(fmt.Sprintf("payload-%d", i)))
+		require.NoError(t, err)
+		ids[i] = id
+	}
+	return ids
+}
+
+func listIDs(t *testing.T, store durableemitter.DurableEventStore) []int64 {
+	t.Helper()
+	events, err := store.ListPending(t.Context(), future(), time.Time{}, 0, 1_000_000)
+	require.NoError(t, err)
+	ids := make([]int64, len(events))
+	for i, e := range events {
+		ids[i] = e.ID
+	}
+	return ids
+}
+
+// RunDurableEventStoreTests checks the semantics which DurableEmitter relies on. newStore must return a new, empty
+// store, and is called once per subtest. The optional BatchInserter and DurableQueueObserver interfaces are checked
+// when implemented.
+func RunDurableEventStoreTests(t *testing.T, newStore func(t *testing.T) durableemitter.DurableEventStore) {
+	t.Run("Insert", func(t *testing.T) {
+		store := newStore(t)
+		payload := []byte("payload")
+		before := time.Now().Add(-time.Second)
+		id, err := store.Insert(t.Context(), payload)
+		require.NoError(t, err)
+		payload[0] = 'X' // the store must not retain the caller's slice
+
+		events, err := store.ListPending(t.Context(), future(), time.Time{}, 0, 10)
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, id, events[0].ID)
+		assert.Equal(t, []byte("payload"), events[0].Payload)
+		assert.True(t, events[0].CreatedAt.After(before), "created at %s", events[0].CreatedAt)
+	})
+
+	t.Run("ListPending", func(t *testing.T) {
+		store := newStore(t)
+		ids := insertN(t, store, 5)
+		assert.Equal(t, ids, listIDs(t, store), "ordered by (created_at, id)")
+
+		events, err := store.ListPending(t.Context(), future(), time.Time{}, 0, 2)
+		require.NoError(t, err)
+		assert.Len(t, events, 2, "limit")
+
+		events, err = store.ListPending(t.Context(), time.Now().Add(-time.Hour), time.Time{}, 0, 10)
+		require.NoError(t, err)
+		assert.Empty(t, events, "createdBefore")
+	})
+
+	t.Run("ListPending cursor", func(t *testing.T) {
+		store := newStore(t)
+		ids := insertN(t, store, 7)
+		var paged []int64
+		var cursorTs time.Time
+		var cursorID int64
+		for {
+			events, err := store.ListPending(t.Context(), future(), cursorTs, cursorID, 3)
+			require.NoError(t, err)
+			if len(events) == 0 {
+				break
+			}
+			for _, e := range events {
+				paged = append(paged, e.ID)
+			}
+			last := events[len(events)-1]
+			cursorTs, cursorID = last.CreatedAt, last.ID
+		}
+		assert.Equal(t, ids, paged)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		store := newStore(t)
+		ids := insertN(t, store, 3)
+		require.NoError(t, store.Delete(t.Context(), ids[1]))
+		require.NoError(t, store.Delete(t.Context(), ids[1]), "deleting a missing event is not an error")
+		assert.Equal(t, []int64{ids[0], ids[2]}, listIDs(t, store))
+	})
+
+	t.Run("BatchDelete", func(t *testing.T) {
+		store := newStore(t)
+		ids := insertN(t, store, 4)
+		n, err := store.BatchDelete(t.Context(), nil)
+		require.NoError(t, err)
+		assert.Zero(t, n)
+
+		n, err = store.BatchDelete(t.Context(), []int64{ids[0], ids[2], ids[3] + 1000})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), n, "only existing events are counted")
+		assert.Equal(t, []int64{ids[1], ids[3]}, listIDs(t, store))
+
+		n, err = store.BatchDelete(t.Context(), []int64{ids[0], ids[1]})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+	})
+
+	t.Run("DeleteExpired", func(t *testing.T) {
+		store := newStore(t)
+		old := insertN(t, store, 2)
+		time.Sleep(100 * time.Millisecond)
+		recent := insertN(t, store, 1)
+
+		n, err := store.DeleteExpired(t.Context(), time.Hour)
+		require.NoError(t, err)
+		assert.Zero(t, n)
+
+		n, err = store.DeleteExpired(t.Context(), 50*time.Millisecond)
+		require.NoError(t, err)
+		assert.Equal(t, int64(len(old)), n)
+		assert.Equal(t, recent, listIDs(t, store))
+	})
+
+	t.Run("concurrent inserts", func(t *testing.T) {
+		store := newStore(t)
+		const workers, perWorker = 8, 25
+		var wg sync.WaitGroup
+		idsCh := make(chan int64, workers*perWorker)
+		for range workers {
+			wg.Go(func() {
+				for range perWorker {
+					id, err := store.Insert(t.Context(), []byte("x"))
+					assert.NoError(t, err)
+					idsCh <- id
+				}
+			})
+		}
+		wg.Wait()
+		close(idsCh)
+		seen := make(map[int64]bool)
+		for id := range idsCh {
+			assert.False(t, seen[id], "duplicate id %d", id)
+			seen[id] = true
+		}
+		assert.Len(t, listIDs(t, store), workers*perWorker)
+	})
+
+	t.Run("BatchInserter", func(t *testing.T) {
+		store := newStore(t)
+		bi, ok := store.(durableemitter.BatchInserter)
+		if !ok {
+			t.Skip("not a BatchInserter")
+		}
+		ids, err := bi.InsertBatch(t.Context(), nil)
+		require.NoError(t, err)
+		assert.Empty(t, ids)
+
+		ids, err = bi.InsertBatch(t.Context(), [][]byte{[]byte("a"), []byte("b"), []byte("c")})
+		require.NoError(t, err)
+		require.Len(t, ids, 3)
+		assert.Equal(t, ids, listIDs(t, store))
+		events, err := store.ListPending(t.Context(), future(), time.Time{}, 0, 10)
+		require.NoError(t, err)
+		for i, payload := range []string{"a", "b", "c"} {
+			assert.Equal(t, payload, string(events[i].Payload))
+		}
+	})
+
+	t.Run("DurableQueueObserver", func(t *testing.T) {
+		store := newStore(t)
+		obs, ok := store.(durableemitter.DurableQueueObserver)
+		if !ok {
+			t.Skip("not a DurableQueueObserver")
+		}
+		const ttl = time.Hour
+		st, err := obs.ObserveDurableQueue(t.Context(), ttl)
+		require.NoError(t, err)
+		assert.Zero(t, st.Depth)
+		assert.Zero(t, st.PayloadBytes)
+		assert.Zero(t, st.OldestPendingAge)
+		assert.Equal(t, ttl, st.TTLBudget)
+
+		ids := insertN(t, store, 3) // 9 bytes each
+		require.NoError(t, store.Delete(t.Context(), ids[0]))
+		st, err = obs.ObserveDurableQueue(t.Context(), ttl)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), st.Depth)
+		assert.GreaterOrEqual(t, st.TotalRows, st.Depth)
+		assert.Equal(t, int64(18), st.PayloadBytes)
+		assert.GreaterOrEqual(t, st.OldestPendingAge, time.Duration(0))
+		assert.Equal(t, ttl-st.OldestPendingAge, st.TTLBudget)
+	})
+}
+
+// RunMaxPayloadBytesTests checks that a store limited to maxBytes of pending payloads rejects inserts which would
+// exceed it with durableemitter.ErrStoreFull, and accepts them again once events are deleted or expire.
+func RunMaxPayloadBytesTests(t *testing.T, newStore func(t *testing.T, maxBytes int64) durableemitter.DurableEventStore) {
+	t.Run("Insert", func(t *testing.T) {
+		store := newStore(t, 10)
+		id, err := store.Insert(t.Context(), make([]byte, 6))
+		require.NoError(t, err)
+		_, err = store.Insert(t.Context(), make([]byte, 4))
+		require.NoError(t, err, "inserts up to the limit succeed")
+		_, err = store.Insert(t.Context(), make([]byte, 1))
+		require.ErrorIs(t, err, durableemitter.ErrStoreFull)
+
+		require.NoError(t, store.Delete(t.Context(), id))
+		_, err = store.Insert(t.Context(), make([]byte, 6))
+		require.NoError(t, err)
+	})
+
+	t.Run("InsertBatch", func(t *testing.T) {
+		store := newStore(t, 10)
+		bi, ok := store.(durableemitter.BatchInserter)
+		if !ok {
+			t.Skip("not a BatchInserter")
+		}
+		_, err := bi.InsertBatch(t.Context(), [][]byte{make([]byte, 6), make([]byte, 6)})
+		require.ErrorIs(t, err, durableemitter.ErrStoreFull)
+		assert.Empty(t, listIDs(t, store), "batches are inserted entirely or not at all")
+
+		_, err = bi.InsertBatch(t.Context(), [][]byte{make([]byte, 5), make([]byte, 5)})
+		require.NoError(t, err)
+	})
+
+	t.Run("DeleteExpired", func(t *testing.T) {
+		store := newStore(t, 10)
+		_, err := store.Insert(t.Context(), make([]byte, 10))
+		require.NoError(t, err)
+		time.Sleep(20 * time.Millisecond)
+		n, err := store.DeleteExpired(t.Context(), 10*time.Millisecond)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), n)
+		_, err = store.Insert(t.Context(), make([]byte, 10))
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,530 @@
+package durableemitter
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrStoreFull is returned by Insert and InsertBatch of stores with a size limit, when the pending payloads would
+// exceed it.
+var ErrStoreFull = errors.New("durable event store is full")
+
+const (
+	fileStoreWAL = "events.wal"
+
+	walRecordInsert byte = 1
+	walRecordDelete byte = 2
+
+	// walHeaderSize is the crc32 and length of each record.
+	walHeaderSize = 8
+	// walInsertSize is the kind, id and created_at of an insert record, which precede the payload.
+	walInsertSize = 1 + 8 + 8
+	// walMaxRecordSize bounds the length of records read back, so that a corrupt length is detected as such.
+	walMaxRecordSize = 64 << 20
+
+	defaultCompactionThreshold = 64 << 20
+)
+
+var walCRCTable = crc32.MakeTable(crc32.Castagnoli)
+
+// FileStoreOpt configures a FileDurableEventStore.
+type FileStoreOpt func(*fileStoreConfig)
+
+type fileStoreConfig struct {
+	maxPayloadBytes     int64
+	noSync              bool
+	compactionThreshold int64
+}
+
+// WithMaxPayloadBytes limits the total size of pending payloads. Inserts which would exceed it fail with
+// ErrStoreFull. The log on disk may be up to twice as large between compactions.
+func WithMaxPayloadBytes(n int64) FileStoreOpt {
+	return func(c *fileStoreConfig) { c.maxPayloadBytes = n }
+}
+
+// WithoutSync disables fsync after inserts, trading durability on power loss for throughput. Inserts still survive a
+// process crash.
+func WithoutSync() FileStoreOpt {
+	return func(c *fileStoreConfig) { c.noSync = true }
+}
+
+// WithCompactionThreshold sets the log size above which it is rewritten, once less than half of it is live.
+// Defaults to 64 MiB.
+func WithCompactionThreshold(n int64) FileStoreOpt {
+	return func(c *fileStoreConfig) { c.compactionThreshold = n }
+}
+
+type fileStoreEntry struct {
+	DurableEvent
+	deleted bool
+}
+
+// FileDurableEventStore is an embedded DurableEventStore backed by a write-ahead log in a directory, for processes
+// without a database, like LOOP plugins and standalone tools.
+//
+// Every insert is appended to the log and synced before it returns, while deletes are appended without a sync: as with
+// PgDurableEventStore, a lost delete just leaves the event to be re-delivered or expired. Each record carries a
+// checksum, so a record torn by a crash is detected, and the log is truncated to the last whole record when opened.
+// The log is compacted by rewriting the live events once most of it is deleted.
+//
+// Created times are kept non-decreasing, even if the wall clock steps backwards, so that (created_at, id) order is
+// insertion order. Only one FileDurableEventStore may use a directory at a time.
+type FileDurableEventStore struct {
+	dir string
+	cfg fileStoreConfig
+
+	mu           sync.Mutex
+	f            *os.File
+	size         int64 // of the log
+	liveSize     int64 // of the records of live events
+	order        []*fileStoreEntry
+	byID         map[int64]*fileStoreEntry
+	deleted      int // entries of order which are deleted
+	payloadBytes int64
+	nextID       int64
+	lastCreated  time.Time
+	closed       bool
+}
+
+var (
+	_ DurableEventStore    = (*FileDurableEventStore)(nil)
+	_ DurableQueueObserver = (*FileDurableEventStore)(nil)
+	_ BatchInserter        = (*FileDurableEventStore)(nil)
+)
+
+// NewFileDurableEventStore opens the store in dir, creating it if necessary, and recovers any events persisted by a
+// previous process. It must be closed with Close.
+func NewFileDurableEventStore(dir string, opts ...FileStoreOpt) (*FileDurableEventStore, error) {
+	cfg := fileStoreConfig{compactionThreshold: defaultCompactionThreshold}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create durable event store directory: %w", err)
+	}
+	// an interrupted compaction leaves its temporary file behind, while the log is intact
+	if err := os.Remove(filepath.Join(dir, fileStoreWAL+".tmp")); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to remove temporary log: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, fileStoreWAL), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open durable event log: %w", err)
+	}
+	s := &FileDurableEventStore{
+		dir:    dir,
+		cfg:    cfg,
+		f:      f,
+		byID:   make(map[int64]*fileStoreEntry),
+		nextID: 1,
+	}
+	if err := s.recover(); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// recover replays the log, and truncates it after the last whole record.
+func (s *FileDurableEventStore) recover() error {
+	r := bufio.NewReader(s.f)
+	var offset int64
+	for {
+		body, err := readWALRecord(r)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, errTornRecord) {
+				return fmt.Errorf("failed to read durable event log: %w", err)
+			}
+			break
+		}
+		if err := s.apply(body); err != nil {
+			// a checksummed record can only be invalid if it was written by an incompatible version
+			return fmt.Errorf("invalid record at offset %d of durable event log: %w", offset, err)
+		}
+		offset += int64(walHeaderSize + len(body))
+	}
+	if err := s.f.Truncate(offset); err != nil {
+		return fmt.Errorf("failed to truncate durable event log: %w", err)
+	}
+	if _, err := s.f.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek durable event log: %w", err)
+	}
+	s.size = offset
+	s.compactOrder()
+	return nil
+}
+
+var errTornRecord = errors.New("torn record")
+
+func readWALRecord(r io.Reader) ([]byte, error) {
+	var header [walHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errTornRecord
+		}
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(header[4:])
+	if n == 0 || n > walMaxRecordSize {
+		return nil, errTornRecord
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errTornRecord
+		}
+		return nil, err
+	}
+	if crc32.Checksum(body, walCRCTable) != binary.BigEndian.Uint32(header[:4]) {
+		return nil, errTornRecord
+	}
+	return body, nil
+}
+
+// apply replays a record read from the log.
+func (s *FileDurableEventStore) apply(body []byte) error {
+	switch body[0] {
+	case walRecordInsert:
+		if len(body) < walInsertSize {
+			return errors.New("short insert record")
+		}
+		e := &fileStoreEntry{DurableEvent: DurableEvent{
+			ID:        int64(binary.BigEndian.Uint64(body[1:])),
+			CreatedAt: time.Unix(0, int64(binary.BigEndian.Uint64(body[9:]))),
+			Payload:   body[walInsertSize:],
+		}}
+		s.add(e)
+		s.nextID = max(s.nextID, e.ID+1)
+	case walRecordDelete:
+		if (len(body)-1)%8 != 0 {
+			return errors.New("malformed delete record")
+		}
+		for ids := body[1:]; len(ids) > 0; ids = ids[8:] {
+			s.remove(int64(binary.BigEndian.Uint64(ids)))
+		}
+	default:
+		return fmt.Errorf("unknown record kind %d", body[0])
+	}
+	return nil
+}
+
+func (s *FileDurableEventStore) add(e *fileStoreEntry) {
+	s.order = append(s.order, e)
+	s.byID[e.ID] = e
+	s.payloadBytes += int64(len(e.Payload))
+	s.liveSize += int64(walHeaderSize + walInsertSize + len(e.Payload))
+	if e.CreatedAt.After(s.lastCreated) {
+		s.lastCreated = e.CreatedAt
+	}
+}
+
+func (s *FileDurableEventStore) remove(id int64) bool {
+	e, ok := s.byID[id]
+	if !ok {
+		return false
+	}
+	delete(s.byID, id)
+	e.deleted = true
+	s.deleted++
+	s.payloadBytes -= int64(len(e.Payload))
+	s.liveSize -= int64(walHeaderSize + walInsertSize + len(e.Payload))
+	return true
+}
+
+// compactOrder drops deleted entries from order, once they are the majority.
+func (s *FileDurableEventStore) compactOrder() {
+	if s.deleted <= len(s.order)/2 {
+		return
+	}
+	s.order = slices.DeleteFunc(s.order, func(e *fileStoreEntry) bool { return e.deleted })
+	s.deleted = 0
+}
+
+func appendWALRecord(buf, body []byte) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, crc32.Checksum(body, walCRCTable))
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(body)))
+	return append(buf, body...)
+}
+
+func insertRecord(e *DurableEvent) []byte {
+	body := make([]byte, 0, walInsertSize+len(e.Payload))
+	body = append(body, walRecordInsert)
+	body = binary.BigEndian.AppendUint64(body, uint64(e.ID))
+	body = binary.BigEndian.AppendUint64(body, uint64(e.CreatedAt.UnixNano()))
+	return append(body, e.Payload...)
+}
+
+// write appends records to the log, and syncs it if requested. Must be called with s.mu held.
+func (s *FileDurableEventStore) write(buf []byte, sync bool) error {
+	if s.closed {
+		return errors.New("durable event store is closed")
+	}
+	if _, err := s.f.Write(buf); err != nil {
+		// drop any partial write, so that later records are not appended after a torn one
+		_ = s.f.Truncate(s.size)
+		_, _ = s.f.Seek(s.size, io.SeekStart)
+		return fmt.Errorf("failed to write durable event log: %w", err)
+	}
+	s.size += int64(len(buf))
+	if sync && !s.cfg.noSync {
+		if err := s.f.Sync(); err != nil {
+			return fmt.Errorf("failed to sync durable event log: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *FileDurableEventStore) Insert(ctx context.Context, payload []byte) (int64, error) {
+	ids, err := s.InsertBatch(ctx, [][]byte{payload})
+	if err != nil {
+		return 0, err
+	}
+	return ids[0], nil
+}
+
+// InsertBatch persists all payloads with a single write and sync. Either all or none are inserted.
+func (s *FileDurableEventStore) InsertBatch(ctx context.Context, payloads [][]byte) ([]int64, error) {
+	if len(payloads) == 0 {
+		return nil, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var total int64
+	for _, p := range payloads {
+		total += int64(len(p))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cfg.maxPayloadBytes > 0 && s.payloadBytes+total > s.cfg.maxPayloadBytes {
+		return nil, fmt.Errorf("%w: %d pending payload bytes, limit %d", ErrStoreFull, s.payloadBytes, s.cfg.maxPayloadBytes)
+	}
+	now := time.Now()
+	if now.Before(s.lastCreated) {
+		now = s.lastCreated
+	}
+	// strip the monotonic reading, so that times compare the same before and after recovery
+	now = now.Round(0)
+	entries := make([]*fileStoreEntry, len(payloads))
+	var buf []byte
+	for i, p := range payloads {
+		entries[i] = &fileStoreEntry{DurableEvent: DurableEvent{
+			ID:        s.nextID + int64(i),
+			Payload:   append([]byte(nil), p...),
+			CreatedAt: now,
+		}}
+		buf = appendWALRecord(buf, insertRecord(&entries[i].DurableEvent))
+	}
+	if err := s.write(buf, true); err != nil {
+		return nil, err
+	}
+	ids := make([]int64, len(entries))
+	for i, e := range entries {
+		s.add(e)
+		ids[i] = e.ID
+	}
+	s.nextID += int64(len(entries))
+	return ids, nil
+}
+
+func (s *FileDurableEventStore) Delete(ctx context.Context, id int64) error {
+	_, err := s.BatchDelete(ctx, []int64{id})
+	return err
+}
+
+func (s *FileDurableEventStore) BatchDelete(ctx context.Context, ids []int64) (int64, error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deleteLocked(ids)
+}
+
+// deleteLocked removes the events which exist, and logs their deletion. Must be called with s.mu held.
+func (s *FileDurableEventStore) deleteLocked(ids []int64) (int64, error) {
+	body := []byte{walRecordDelete}
+	for _, id := range ids {
+		if _, ok := s.byID[id]; ok {
+			body = binary.BigEndian.AppendUint64(body, uint64(id))
+		}
+	}
+	if len(body) == 1 {
+		return 0, nil
+	}
+	if err := s.write(appendWALRecord(nil, body), false); err != nil {
+		return 0, err
+	}
+	var n int64
+	for ids := body[1:]; len(ids) > 0; ids = ids[8:] {
+		if s.remove(int64(binary.BigEndian.Uint64(ids))) {
+			n++
+		}
+	}
+	s.compactOrder()
+	if err := s.maybeCompact(); err != nil {
+		return n, err
+	}
+	return n, nil
+}
+
+func (s *FileDurableEventStore) ListPending(ctx context.Context, createdBefore, afterCreatedAt time.Time, afterID int64, limit int) ([]DurableEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// order is sorted by (created_at, id), so skip to the cursor
+	start, _ := slices.BinarySearchFunc(s.order, struct{}{}, func(e *fileStoreEntry, _ struct{}) int {
+		if e.CreatedAt.Before(afterCreatedAt) || (e.CreatedAt.Equal(afterCreatedAt) && e.ID <= afterID) {
+			return -1
+		}
+		return 1
+	})
+	var out []DurableEvent
+	for _, e := range s.order[start:] {
+		if len(out) >= limit || !e.CreatedAt.Before(createdBefore) {
+			break
+		}
+		if e.deleted {
+			continue
+		}
+		ev := e.DurableEvent
+		ev.Payload = append([]byte(nil), e.Payload...)
+		out = append(out, ev)
+	}
+	return out, nil
+}
+
+// DeleteExpired removes events created at or before now - ttl.
+func (s *FileDurableEventStore) DeleteExpired(ctx context.Context, ttl time.Duration) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	cutoff := time.Now().Add(-ttl)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []int64
+	for _, e := range s.order {
+		if e.CreatedAt.After(cutoff) {
+			break
+		}
+		if !e.deleted {
+			ids = append(ids, e.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return s.deleteLocked(ids)
+}
+
+// ObserveDurableQueue implements DurableQueueObserver. Since deletes are physical, TotalRows equals Depth.
+func (s *FileDurableEventStore) ObserveDurableQueue(ctx context.Context, eventTTL time.Duration) (DurableQueueStats, error) {
+	if err := ctx.Err(); err != nil {
+		return DurableQueueStats{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := DurableQueueStats{
+		Depth:        int64(len(s.byID)),
+		TotalRows:    int64(len(s.byID)),
+		PayloadBytes: s.payloadBytes,
+		TTLBudget:    eventTTL,
+	}
+	for _, e := range s.order {
+		if !e.deleted {
+			st.OldestPendingAge = time.Since(e.CreatedAt)
+			st.TTLBudget = eventTTL - st.OldestPendingAge
+			break
+		}
+	}
+	return st, nil
+}
+
+// maybeCompact rewrites the log with only the live events, once it is over the threshold and mostly deleted. The
+// new log is synced before it replaces the old one, so a crash leaves one or the other intact. Must be called with
+// s.mu held.
+func (s *FileDurableEventStore) maybeCompact() error {
+	if s.size < s.cfg.compactionThreshold || s.liveSize > s.size/2 {
+		return nil
+	}
+	tmpPath := filepath.Join(s.dir, fileStoreWAL+".tmp")
+	tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create compacted log: %w", err)
+	}
+	cleanup := func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+	}
+	w := bufio.NewWriter(tmp)
+	var size int64
+	for _, e := range s.order {
+		if e.deleted {
+			continue
+		}
+		rec := appendWALRecord(nil, insertRecord(&e.DurableEvent))
+		if _, err := w.Write(rec); err != nil {
+			cleanup()
+			return fmt.Errorf("failed to write compacted log: %w", err)
+		}
+		size += int64(len(rec))
+	}
+	if err := w.Flush(); err != nil {
+		cleanup()
+		return fmt.Errorf("failed to write compacted log: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		cleanup()
+		return fmt.Errorf("failed to sync compacted log: %w", err)
+	}
+	if err := os.Rename(tmpPath, filepath.Join(s.dir, fileStoreWAL)); err != nil {
+		cleanup()
+		return fmt.Errorf("failed to replace log: %w", err)
+	}
+	if !s.cfg.noSync {
+		// if the rename is not yet durable, a crash leaves the old log, which is equally valid
+		_ = syncDir(s.dir)
+	}
+	_ = s.f.Close()
+	s.f = tmp // positioned at the end by the writes
+	s.size = size
+	s.liveSize = size
+	s.order = slices.DeleteFunc(s.order, func(e *fileStoreEntry) bool { return e.deleted })
+	s.deleted = 0
+	return nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// Close closes the log. Events are persisted as they are inserted, so nothing is flushed.
+func (s *FileDurableEventStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.f.Close()
+}
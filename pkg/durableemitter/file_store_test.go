@@ -0,0 +1,126 @@
+package durableemitter_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/durableemitter"
+	"github.com/smartcontractkit/chainlink-common/pkg/durableemitter/durableemittertest"
+)
+
+func newFileStore(t *testing.T, dir string, opts ...durableemitter.FileStoreOpt) *durableemitter.FileDurableEventStore {
+	t.Helper()
+	store, err := durableemitter.NewFileDurableEventStore(dir, opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, store.Close()) })
+	return store
+}
+
+func pendingPayloads(t *testing.T, store durableemitter.DurableEventStore) map[int64]string {
+	t.Helper()
+	events, err := store.ListPending(t.Context(), time.Now().Add(time.Hour), time.Time{}, 0, 1000)
+	require.NoError(t, err)
+	payloads := make(map[int64]string)
+	for _, e := range events {
+		payloads[e.ID] = string(e.Payload)
+	}
+	return payloads
+}
+
+func TestFileDurableEventStore(t *testing.T) {
+	durableemittertest.RunDurableEventStoreTests(t, func(t *testing.T) durableemitter.DurableEventStore {
+		return newFileStore(t, t.TempDir())
+	})
+}
+
+func TestFileDurableEventStore_MaxPayloadBytes(t *testing.T) {
+	durableemittertest.RunMaxPayloadBytesTests(t, func(t *testing.T, maxBytes int64) durableemitter.DurableEventStore {
+		return newFileStore(t, t.TempDir(), durableemitter.WithMaxPayloadBytes(maxBytes))
+	})
+}
+
+func TestFileDurableEventStore_Recovery(t *testing.T) {
+	dir := t.TempDir()
+	store, err := durableemitter.NewFileDurableEventStore(dir)
+	require.NoError(t, err)
+	ids, err := store.InsertBatch(t.Context(), [][]byte{[]byte("a"), []byte("b"), []byte("c")})
+	require.NoError(t, err)
+	_, err = store.BatchDelete(t.Context(), ids[:1])
+	require.NoError(t, err)
+	want := pendingPayloads(t, store)
+	require.NoError(t, store.Close())
+
+	reopened := newFileStore(t, dir)
+	assert.Equal(t, want, pendingPayloads(t, reopened))
+	id, err := reopened.Insert(t.Context(), []byte("d"))
+	require.NoError(t, err)
+	assert.Greater(t, id, ids[2], "ids are not reused")
+}
+
+func TestFileDurableEventStore_TornWrite(t *testing.T) {
+	dir := t.TempDir()
+	store, err := durableemitter.NewFileDurableEventStore(dir)
+	require.NoError(t, err)
+	_, err = store.InsertBatch(t.Context(), [][]byte{[]byte("a"), []byte("b")})
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+
+	// simulate a crash part way through appending the last record
+	path := filepath.Join(dir, "events.wal")
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(path, info.Size()-3))
+
+	reopened, err := durableemitter.NewFileDurableEventStore(dir)
+	require.NoError(t, err)
+	payloads := pendingPayloads(t, reopened)
+	assert.ElementsMatch(t, []string{"a"}, mapValues(payloads))
+
+	// the torn record is dropped, so new records are readable after it
+	_, err = reopened.Insert(t.Context(), []byte("c"))
+	require.NoError(t, err)
+	require.NoError(t, reopened.Close())
+	assert.ElementsMatch(t, []string{"a", "c"}, mapValues(pendingPayloads(t, newFileStore(t, dir))))
+}
+
+func TestFileDurableEventStore_Compaction(t *testing.T) {
+	dir := t.TempDir()
+	store := newFileStore(t, dir, durableemitter.WithCompactionThreshold(1024))
+	payloads := make([][]byte, 100)
+	for i := range payloads {
+		payloads[i] = make([]byte, 100)
+	}
+	ids, err := store.InsertBatch(t.Context(), payloads)
+	require.NoError(t, err)
+	path := filepath.Join(dir, "events.wal")
+	before, err := os.Stat(path)
+	require.NoError(t, err)
+
+	_, err = store.BatchDelete(t.Context(), ids[:90])
+	require.NoError(t, err)
+	after, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Less(t, after.Size(), before.Size()/5)
+
+	// the compacted log is appended to, and recovered
+	id, err := store.Insert(t.Context(), []byte("new"))
+	require.NoError(t, err)
+	require.NoError(t, store.Close())
+	reopened := newFileStore(t, dir)
+	pending := pendingPayloads(t, reopened)
+	assert.Len(t, pending, 11)
+	assert.Equal(t, "new", pending[id])
+}
+
+func mapValues(m map[int64]string) []string {
+	var values []string
+	for _, v := range m {
+		values = append(values, v)
+	}
+	return values
+}
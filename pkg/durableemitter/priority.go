@@ -0,0 +1,388 @@
+package durableemitter
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrQuotaExceeded is returned by Emit when the pending payloads of the event's priority class would exceed the
+// class quota.
+var ErrQuotaExceeded = errors.New("priority class quota exceeded")
+
+// DefaultPriorityClassName is the name of PriorityConfig.Default when it is unnamed.
+const DefaultPriorityClassName = "default"
+
+// PriorityClass is a group of events, selected by source domain and entity type, which shares a quota of the durable
+// queue and a retransmission priority.
+type PriorityClass struct {
+	// Name labels the class in metrics and logs.
+	Name string
+	// Priority orders classes: events of higher classes are retransmitted first, and shed last.
+	Priority int
+	// Domains and EntityTypes select the events of the class, by exact match or, for values ending in "*", by
+	// prefix. An empty list matches any value.
+	Domains     []string
+	EntityTypes []string
+	// MaxPayloadBytes is the quota of pending payload bytes of the class. Emit fails with ErrQuotaExceeded beyond
+	// it. Zero is unlimited.
+	MaxPayloadBytes int64
+}
+
+func (c *PriorityClass) matches(domain, entityType string) bool {
+	return matchesAny(c.Domains, domain) && matchesAny(c.EntityTypes, entityType)
+}
+
+func matchesAny(patterns []string, s string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if prefix, ok := strings.CutSuffix(p, "*"); (ok && strings.HasPrefix(s, prefix)) || p == s {
+			return true
+		}
+	}
+	return false
+}
+
+// PriorityConfig enables priority classes. Set on Config.Priorities; nil treats all events alike.
+//
+// For example, to protect metering records from a burst of telemetry:
+//
+//	&PriorityConfig{
+//		Classes: []PriorityClass{
+//			{Name: "billing", Priority: 10, EntityTypes: []string{"metering.*"}},
+//			{Name: "telemetry", Priority: -10, Domains: []string{"platform"}, MaxPayloadBytes: 64 << 20},
+//		},
+//		MaxPayloadBytes: 512 << 20,
+//	}
+//
+// Pending payloads are accounted in memory, from the events this process inserts and, when retransmit is enabled,
+// the backlog found in the store on start. Events delivered or expired by other processes sharing the store are only
+// forgotten once they are older than EventTTL.
+type PriorityConfig struct {
+	// Classes are matched in order, and the first match classifies an event.
+	Classes []PriorityClass
+	// Default is the class of events which match none of Classes. Its Domains and EntityTypes are ignored, and its
+	// Name defaults to DefaultPriorityClassName.
+	Default PriorityClass
+	// MaxPayloadBytes bounds the pending payload bytes of all classes together. When an insert would exceed it, or the
+	// store fails it with ErrStoreFull, pending events of lower priority than the new one are shed, lowest priority
+	// and oldest first. If that is not enough, Emit fails with ErrStoreFull. Zero is unlimited.
+	MaxPayloadBytes int64
+}
+
+// trackedEvent is a pending event accounted to a priority class.
+type trackedEvent struct {
+	id        int64
+	class     *priorityClassState
+	size      int64
+	createdAt time.Time
+	removed   bool
+}
+
+type priorityClassState struct {
+	PriorityClass
+	// events are in insertion order, including removed ones until compacted.
+	events  []*trackedEvent
+	removed int
+	// pendingBytes includes the reservations of inserts in progress.
+	pendingBytes  int64
+	pendingEvents int64
+}
+
+// compact drops removed events, once they are the majority.
+func (c *priorityClassState) compact() {
+	if c.removed <= len(c.events)/2 {
+		return
+	}
+	c.events = slices.DeleteFunc(c.events, func(e *trackedEvent) bool { return e.removed })
+	c.removed = 0
+}
+
+// priorityClassStats is a snapshot of a class for metrics.
+type priorityClassStats struct {
+	name            string
+	pendingBytes    int64
+	pendingEvents   int64
+	maxPayloadBytes int64
+}
+
+// priorityTracker classifies events and accounts their pending payloads against the quotas of PriorityConfig.
+type priorityTracker struct {
+	maxBytes int64
+	// classes are in matching order, with the default last.
+	classes []*priorityClassState
+	// byPriority is in ascending priority, the order in which classes are shed.
+	byPriority []*priorityClassState
+
+	mu         sync.Mutex
+	byID       map[int64]*trackedEvent
+	totalBytes int64
+	// unsettled counts the reservations and sheds in progress. While there are any, the ids removed without being
+	// tracked are remembered in removedIDs, since they may be of events which are being inserted, or failed to shed.
+	unsettled  int
+	removedIDs map[int64]bool
+}
+
+func newPriorityTracker(cfg *PriorityConfig) (*priorityTracker, error) {
+	if cfg.MaxPayloadBytes < 0 {
+		return nil, errors.New("priority MaxPayloadBytes must not be negative")
+	}
+	def := cfg.Default
+	def.Domains, def.EntityTypes = nil, nil
+	if def.Name == "" {
+		def.Name = DefaultPriorityClassName
+	}
+	t := &priorityTracker{
+		maxBytes:   cfg.MaxPayloadBytes,
+		byID:       make(map[int64]*trackedEvent),
+		removedIDs: make(map[int64]bool),
+	}
+	names := make(map[string]bool)
+	for _, c := range append(slices.Clone(cfg.Classes), def) {
+		if c.Name == "" {
+			return nil, errors.New("priority class name is required")
+		}
+		if names[c.Name] {
+			return nil, fmt.Errorf("duplicate priority class %q", c.Name)
+		}
+		if c.MaxPayloadBytes < 0 {
+			return nil, fmt.Errorf("priority class %q: MaxPayloadBytes must not be negative", c.Name)
+		}
+		names[c.Name] = true
+		t.classes = append(t.classes, &priorityClassState{PriorityClass: c})
+	}
+	t.byPriority = slices.Clone(t.classes)
+	slices.SortStableFunc(t.byPriority, func(a, b *priorityClassState) int { return a.Priority - b.Priority })
+	return t, nil
+}
+
+// classify returns the class of an event.
+func (t *priorityTracker) classify(domain, entityType string) *priorityClassState {
+	for _, c := range t.classes[:len(t.classes)-1] {
+		if c.matches(domain, entityType) {
+			return c
+		}
+	}
+	return t.classes[len(t.classes)-1]
+}
+
+// reserve accounts size bytes to class ahead of an insert, which must be followed by commit or cancel. If the total
+// limit would be exceeded, it selects pending events of lower classes to shed, which it stops accounting for and the
+// caller must delete, followed by settle or restore. Events created at or before expiredBefore are forgotten first.
+func (t *priorityTracker) reserve(class *priorityClassState, size int64, expiredBefore time.Time) ([]*trackedEvent, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.expireLocked(expiredBefore)
+	if class.MaxPayloadBytes > 0 && class.pendingBytes+size > class.MaxPayloadBytes {
+		return nil, fmt.Errorf("%w: class %q has %d pending payload bytes, quota %d", ErrQuotaExceeded, class.Name, class.pendingBytes, class.MaxPayloadBytes)
+	}
+	var victims []*trackedEvent
+	if t.maxBytes > 0 && t.totalBytes+size > t.maxBytes {
+		victims = t.shedLocked(class, t.totalBytes+size-t.maxBytes)
+		if victims == nil {
+			return nil, fmt.Errorf("%w: %d pending payload bytes, limit %d, and no events of lower priority than class %q to shed", ErrStoreFull, t.totalBytes, t.maxBytes, class.Name)
+		}
+	}
+	class.pendingBytes += size
+	t.totalBytes += size
+	t.unsettled++
+	return victims, nil
+}
+
+// commit accounts an inserted event, previously reserved. An event which was already delivered and removed, before
+// its insert returned, is not accounted.
+func (t *priorityTracker) commit(class *priorityClassState, size int64, id int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.removedIDs[id] {
+		class.pendingBytes -= size
+		t.totalBytes -= size
+	} else {
+		e := &trackedEvent{id: id, class: class, size: size, createdAt: time.Now()}
+		class.events = append(class.events, e)
+		class.pendingEvents++
+		t.byID[id] = e
+	}
+	t.settleLocked()
+}
+
+// cancel releases a reservation whose insert failed.
+func (t *priorityTracker) cancel(class *priorityClassState, size int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	class.pendingBytes -= size
+	t.totalBytes -= size
+	t.settleLocked()
+}
+
+// shed selects pending events of lower classes than class with at least size bytes, after the store rejected an
+// insert as full. It returns nil if there are not enough. Otherwise, the caller must delete the events, followed by
+// settle or restore.
+func (t *priorityTracker) shed(class *priorityClassState, size int64) []*trackedEvent {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.shedLocked(class, size)
+}
+
+func (t *priorityTracker) shedLocked(class *priorityClassState, need int64) []*trackedEvent {
+	var victims []*trackedEvent
+	var freed int64
+collecting:
+	for _, c := range t.byPriority {
+		if c.Priority >= class.Priority {
+			break
+		}
+		for _, e := range c.events {
+			if freed >= need {
+				break collecting
+			}
+			if !e.removed {
+				victims = append(victims, e)
+				freed += e.size
+			}
+		}
+	}
+	if freed < need {
+		return nil
+	}
+	for _, e := range victims {
+		t.removeLocked(e)
+	}
+	t.compactLocked()
+	t.unsettled++
+	return victims
+}
+
+// settle completes a shed whose events were deleted.
+func (t *priorityTracker) settle() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.settleLocked()
+}
+
+// restore accounts for shed events again, after deleting them failed. Those removed in the meantime, because they
+// were delivered, are not.
+func (t *priorityTracker) restore(victims []*trackedEvent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, v := range victims {
+		if t.removedIDs[v.id] {
+			continue
+		}
+		e := &trackedEvent{id: v.id, class: v.class, size: v.size, createdAt: v.createdAt}
+		c := e.class
+		// keep events in creation order, for expireLocked
+		i := len(c.events)
+		for i > 0 && c.events[i-1].createdAt.After(e.createdAt) {
+			i--
+		}
+		c.events = slices.Insert(c.events, i, e)
+		c.pendingBytes += e.size
+		c.pendingEvents++
+		t.totalBytes += e.size
+		t.byID[e.id] = e
+	}
+	t.settleLocked()
+}
+
+// settleLocked ends a reservation or shed, and forgets the removed ids once none are left in progress.
+func (t *priorityTracker) settleLocked() {
+	t.unsettled--
+	if t.unsettled == 0 {
+		clear(t.removedIDs)
+	}
+}
+
+// add accounts an event found in the store.
+func (t *priorityTracker) add(class *priorityClassState, ev DurableEvent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.byID[ev.ID]; ok {
+		return
+	}
+	e := &trackedEvent{id: ev.ID, class: class, size: int64(len(ev.Payload)), createdAt: ev.CreatedAt}
+	class.events = append(class.events, e)
+	class.pendingBytes += e.size
+	class.pendingEvents++
+	t.totalBytes += e.size
+	t.byID[ev.ID] = e
+}
+
+// remove stops accounting for deleted events.
+func (t *priorityTracker) remove(ids ...int64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, id := range ids {
+		if e, ok := t.byID[id]; ok {
+			t.removeLocked(e)
+		} else if t.unsettled > 0 {
+			t.removedIDs[id] = true
+		}
+	}
+	t.compactLocked()
+}
+
+func (t *priorityTracker) removeLocked(e *trackedEvent) {
+	delete(t.byID, e.id)
+	e.removed = true
+	c := e.class
+	c.removed++
+	c.pendingBytes -= e.size
+	c.pendingEvents--
+	t.totalBytes -= e.size
+}
+
+// compactLocked compacts the classes after removals. It must not be called while iterating their events, which it
+// moves in place.
+func (t *priorityTracker) compactLocked() {
+	for _, c := range t.classes {
+		c.compact()
+	}
+}
+
+// expire stops accounting for events created at or before cutoff, which the expiry loop deletes.
+func (t *priorityTracker) expire(cutoff time.Time) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.expireLocked(cutoff)
+}
+
+func (t *priorityTracker) expireLocked(cutoff time.Time) {
+	for _, c := range t.classes {
+		for _, e := range c.events {
+			if e.createdAt.After(cutoff) {
+				break
+			}
+			if !e.removed {
+				t.removeLocked(e)
+			}
+		}
+	}
+	t.compactLocked()
+}
+
+func (t *priorityTracker) stats() []priorityClassStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	stats := make([]priorityClassStats, len(t.classes))
+	for i, c := range t.classes {
+		stats[i] = priorityClassStats{
+			name:            c.Name,
+			pendingBytes:    c.pendingBytes,
+			pendingEvents:   c.pendingEvents,
+			maxPayloadBytes: c.MaxPayloadBytes,
+		}
+	}
+	return stats
+}
@@ -0,0 +1,363 @@
+package durableemitter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/chipingress"
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+	"github.com/smartcontractkit/chainlink-common/pkg/services/servicetest"
+)
+
+func testPriorityConfig() *PriorityConfig {
+	return &PriorityConfig{
+		Classes: []PriorityClass{
+			{Name: "billing", Priority: 10, EntityTypes: []string{"metering.*"}},
+			{Name: "telemetry", Priority: -10, Domains: []string{"platform"}},
+		},
+	}
+}
+
+func TestPriorityTracker_Classify(t *testing.T) {
+	pt, err := newPriorityTracker(testPriorityConfig())
+	require.NoError(t, err)
+
+	for _, tt := range []struct {
+		domain, entityType, want string
+	}{
+		{"resourcemanager", "metering.Record", "billing"},
+		{"platform", "metering.Record", "billing"},
+		{"platform", "workflow.Started", "telemetry"},
+		{"resourcemanager", "metering", DefaultPriorityClassName},
+		{"other", "other", DefaultPriorityClassName},
+	} {
+		assert.Equal(t, tt.want, pt.classify(tt.domain, tt.entityType).Name, "%s/%s", tt.domain, tt.entityType)
+	}
+}
+
+func TestNewPriorityTracker_ValidationErrors(t *testing.T) {
+	for name, cfg := range map[string]PriorityConfig{
+		"unnamed":        {Classes: []PriorityClass{{}}},
+		"duplicate":      {Classes: []PriorityClass{{Name: "a"}, {Name: "a"}}},
+		"shadow default": {Classes: []PriorityClass{{Name: DefaultPriorityClassName}}},
+		"negative quota": {Classes: []PriorityClass{{Name: "a", MaxPayloadBytes: -1}}},
+		"negative total": {MaxPayloadBytes: -1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := newPriorityTracker(&cfg)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func newPriorityTestEmitter(t *testing.T, store DurableEventStore, be BatchEmitter, priorities *PriorityConfig) *DurableEmitter {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.RetransmitInterval = time.Hour
+	cfg.InsertBatchSize = 0
+	cfg.DeleteBatchSize = 0
+	cfg.Priorities = priorities
+	em := newTestDurableEmitter(t, store, be, &cfg)
+	servicetest.Run(t, em)
+	return em
+}
+
+func priorityStats(em *DurableEmitter) map[string]priorityClassStats {
+	stats := make(map[string]priorityClassStats)
+	for _, st := range em.priorities.stats() {
+		stats[st.name] = st
+	}
+	return stats
+}
+
+func TestDurableEmitter_PriorityClassQuota(t *testing.T) {
+	store := NewMemDurableEventStore()
+	be := newTestBatchEmitter()
+	be.setPublishErr(errors.New("connection refused"))
+	cfg := testPriorityConfig()
+	cfg.Classes[1].MaxPayloadBytes = 3000
+	em := newPriorityTestEmitter(t, store, be, cfg)
+	ctx := t.Context()
+
+	body := bytes.Repeat([]byte("x"), 1000)
+	require.NoError(t, em.Emit(ctx, body, "source", "platform", "type", "workflow.Started"))
+	require.NoError(t, em.Emit(ctx, body, "source", "platform", "type", "workflow.Finished"))
+	err := em.Emit(ctx, body, "source", "platform", "type", "workflow.Failed")
+	require.ErrorIs(t, err, ErrQuotaExceeded)
+
+	// other classes are not limited by the quota
+	require.NoError(t, em.Emit(ctx, body, "source", "resourcemanager", "type", "metering.Record"))
+	require.NoError(t, em.Emit(ctx, body, "source", "other", "type", "other"))
+	assert.Equal(t, 4, store.Len())
+
+	stats := priorityStats(em)
+	assert.Equal(t, int64(2), stats["telemetry"].pendingEvents)
+	assert.Equal(t, int64(1), stats["billing"].pendingEvents)
+	assert.Equal(t, int64(1), stats[DefaultPriorityClassName].pendingEvents)
+
+	// delivery releases the quota
+	be.setPublishErr(nil)
+	em.cfg.RetransmitAfter = 0
+	em.retransmitPending()
+	require.Eventually(t, func() bool { return store.Len() == 0 }, 2*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool { return priorityStats(em)["telemetry"].pendingBytes == 0 }, 2*time.Second, 10*time.Millisecond)
+	require.NoError(t, em.Emit(ctx, body, "source", "platform", "type", "workflow.Failed"))
+}
+
+// pendingTypes returns the entity types of the pending events of store, oldest first.
+func pendingTypes(t *testing.T, store DurableEventStore) []string {
+	t.Helper()
+	events, err := store.ListPending(t.Context(), time.Now().Add(time.Hour), time.Time{}, 0, 1000)
+	require.NoError(t, err)
+	var types []string
+	for _, e := range events {
+		var pb chipingress.CloudEventPb
+		require.NoError(t, proto.Unmarshal(e.Payload, &pb))
+		types = append(types, pb.Type)
+	}
+	return types
+}
+
+func TestDurableEmitter_PriorityShedsLowerClasses(t *testing.T) {
+	store := NewMemDurableEventStore()
+	be := newTestBatchEmitter()
+	be.setPublishErr(errors.New("connection refused"))
+	cfg := testPriorityConfig()
+	cfg.MaxPayloadBytes = 4000
+	em := newPriorityTestEmitter(t, store, be, cfg)
+	ctx := t.Context()
+
+	body := bytes.Repeat([]byte("x"), 1000)
+	require.NoError(t, em.Emit(ctx, body, "source", "platform", "type", "telemetry.1"))
+	require.NoError(t, em.Emit(ctx, body, "source", "other", "type", "other.1"))
+	require.NoError(t, em.Emit(ctx, body, "source", "platform", "type", "telemetry.2"))
+
+	// there is nothing of lower priority to shed for telemetry
+	err := em.Emit(ctx, body, "source", "platform", "type", "telemetry.3")
+	require.ErrorIs(t, err, ErrStoreFull)
+
+	// the oldest event of the lowest class goes first
+	require.NoError(t, em.Emit(ctx, body, "source", "resourcemanager", "type", "metering.1"))
+	assert.Equal(t, []string{"other.1", "telemetry.2", "metering.1"}, pendingTypes(t, store))
+	require.NoError(t, em.Emit(ctx, body, "source", "other", "type", "other.2"))
+	assert.Equal(t, []string{"other.1", "metering.1", "other.2"}, pendingTypes(t, store))
+	require.NoError(t, em.Emit(ctx, body, "source", "resourcemanager", "type", "metering.2"))
+	assert.Equal(t, []string{"metering.1", "other.2", "metering.2"}, pendingTypes(t, store))
+
+	stats := priorityStats(em)
+	assert.Equal(t, int64(0), stats["telemetry"].pendingEvents)
+	assert.Equal(t, int64(1), stats[DefaultPriorityClassName].pendingEvents)
+	assert.Equal(t, int64(2), stats["billing"].pendingEvents)
+}
+
+func TestDurableEmitter_PriorityShedsWhenStoreFull(t *testing.T) {
+	store, err := NewFileDurableEventStore(t.TempDir(), WithMaxPayloadBytes(3000))
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, store.Close()) })
+	be := newTestBatchEmitter()
+	be.setPublishErr(errors.New("connection refused"))
+	em := newPriorityTestEmitter(t, store, be, testPriorityConfig())
+	ctx := t.Context()
+
+	body := bytes.Repeat([]byte("x"), 1000)
+	require.NoError(t, em.Emit(ctx, body, "source", "platform", "type", "telemetry.1"))
+	require.NoError(t, em.Emit(ctx, body, "source", "platform", "type", "telemetry.2"))
+	require.ErrorIs(t, em.Emit(ctx, body, "source", "platform", "type", "telemetry.3"), ErrStoreFull)
+
+	// the store does not report how much room is left, so events as large as the new one are shed
+	require.NoError(t, em.Emit(ctx, body[:900], "source", "resourcemanager", "type", "metering.1"))
+	assert.Equal(t, []string{"telemetry.2", "metering.1"}, pendingTypes(t, store))
+}
+
+func TestDurableEmitter_PriorityLoadsBacklog(t *testing.T) {
+	store := NewMemDurableEventStore()
+	for _, typ := range []string{"telemetry.1", "telemetry.2"} {
+		payload, err := proto.Marshal(&chipingress.CloudEventPb{Id: typ, Source: "platform", Type: typ, SpecVersion: "1.0"})
+		require.NoError(t, err)
+		_, err = store.Insert(t.Context(), payload)
+		require.NoError(t, err)
+	}
+	be := newTestBatchEmitter()
+	be.setPublishErr(errors.New("connection refused"))
+	cfg := testPriorityConfig()
+	cfg.Classes[1].MaxPayloadBytes = 1
+
+	em := newPriorityTestEmitter(t, store, be, cfg)
+	assert.Equal(t, int64(2), priorityStats(em)["telemetry"].pendingEvents)
+	require.ErrorIs(t, em.Emit(t.Context(), []byte("x"), "source", "platform", "type", "telemetry.3"), ErrQuotaExceeded)
+}
+
+// orderRecordingBatchEmitter records the types of the queued events and fails their delivery.
+type orderRecordingBatchEmitter struct {
+	mu    sync.Mutex
+	types []string
+}
+
+func (b *orderRecordingBatchEmitter) QueueMessage(event *chipingress.CloudEventPb, _ func(error)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.types = append(b.types, event.Type)
+	return nil
+}
+
+func (b *orderRecordingBatchEmitter) Start(context.Context) {}
+
+func (b *orderRecordingBatchEmitter) Stop() {}
+
+func TestDurableEmitter_PriorityRetransmitOrder(t *testing.T) {
+	store := NewMemDurableEventStore()
+	be := &orderRecordingBatchEmitter{}
+	cfg := DefaultConfig()
+	cfg.Priorities = testPriorityConfig()
+	em, err := NewDurableEmitter(store, be, true, cfg, logger.Test(t), nil)
+	require.NoError(t, err)
+
+	var pending []DurableEvent
+	for i, ev := range [][2]string{
+		{"platform", "telemetry.1"},
+		{"other", "other.1"},
+		{"resourcemanager", "metering.1"},
+		{"platform", "telemetry.2"},
+		{"resourcemanager", "metering.2"},
+	} {
+		payload, err := proto.Marshal(&chipingress.CloudEventPb{Id: ev[1], Source: ev[0], Type: ev[1], SpecVersion: "1.0"})
+		require.NoError(t, err)
+		pending = append(pending, DurableEvent{ID: int64(i + 1), Payload: payload, CreatedAt: time.Now()})
+	}
+	em.retransmit(t.Context(), pending)
+	assert.Equal(t, []string{"metering.1", "metering.2", "other.1", "telemetry.1", "telemetry.2"}, be.types)
+}
+
+func TestPriorityTracker_RemovedBeforeCommit(t *testing.T) {
+	pt, err := newPriorityTracker(testPriorityConfig())
+	require.NoError(t, err)
+	class := pt.classify("platform", "telemetry")
+
+	// the event is delivered and removed before its insert returns
+	_, err = pt.reserve(class, 100, time.Time{})
+	require.NoError(t, err)
+	pt.remove(1)
+	pt.commit(class, 100, 1)
+	assert.Equal(t, int64(0), class.pendingBytes)
+	assert.Equal(t, int64(0), class.pendingEvents)
+	assert.Empty(t, pt.byID)
+	assert.Empty(t, pt.removedIDs, "forgotten once settled")
+
+	// untracked ids are not remembered otherwise
+	pt.remove(2)
+	assert.Empty(t, pt.removedIDs)
+}
+
+func TestPriorityTracker_Restore(t *testing.T) {
+	cfg := testPriorityConfig()
+	cfg.MaxPayloadBytes = 300
+	pt, err := newPriorityTracker(cfg)
+	require.NoError(t, err)
+	telemetry := pt.classify("platform", "telemetry")
+	billing := pt.classify("resourcemanager", "metering.Record")
+
+	for id := range int64(3) {
+		_, err = pt.reserve(telemetry, 100, time.Time{})
+		require.NoError(t, err)
+		pt.commit(telemetry, 100, id)
+	}
+	victims, err := pt.reserve(billing, 200, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, victims, 2)
+	assert.Equal(t, int64(100), telemetry.pendingBytes)
+
+	// deleting the victims failed, but one was delivered meanwhile
+	pt.remove(victims[0].id)
+	pt.restore(victims)
+	pt.commit(billing, 200, 3)
+	assert.Equal(t, int64(200), telemetry.pendingBytes)
+	assert.Equal(t, int64(2), telemetry.pendingEvents)
+	assert.Equal(t, []int64{1, 2}, []int64{telemetry.events[0].id, telemetry.events[1].id}, "in creation order")
+	assert.Equal(t, int64(400), pt.totalBytes)
+	assert.Empty(t, pt.removedIDs)
+}
+
+// failingDeleteStore fails BatchDelete.
+type failingDeleteStore struct {
+	*MemDurableEventStore
+}
+
+func (s failingDeleteStore) BatchDelete(context.Context, []int64) (int64, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestDurableEmitter_PriorityShedDeleteFails(t *testing.T) {
+	store := failingDeleteStore{NewMemDurableEventStore()}
+	be := newTestBatchEmitter()
+	be.setPublishErr(errors.New("connection refused"))
+	cfg := testPriorityConfig()
+	cfg.MaxPayloadBytes = 2000
+	em := newPriorityTestEmitter(t, store, be, cfg)
+	ctx := t.Context()
+
+	body := bytes.Repeat([]byte("x"), 1000)
+	require.NoError(t, em.Emit(ctx, body, "source", "platform", "type", "telemetry.1"))
+	require.NoError(t, em.Emit(ctx, body, "source", "resourcemanager", "type", "metering.1"))
+
+	// the event which could not be shed is still accounted for
+	assert.Equal(t, []string{"telemetry.1", "metering.1"}, pendingTypes(t, store))
+	stats := priorityStats(em)
+	assert.Equal(t, int64(1), stats["telemetry"].pendingEvents)
+	assert.Equal(t, int64(1), stats["billing"].pendingEvents)
+}
+
+func TestDurableEmitter_PriorityMetrics(t *testing.T) {
+	meter, reader := newTestMeter(t)
+	store, err := NewFileDurableEventStore(t.TempDir(), WithMaxPayloadBytes(3000))
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, store.Close()) })
+	be := newTestBatchEmitter()
+	be.setPublishErr(errors.New("connection refused"))
+	cfg := DefaultConfig()
+	cfg.RetransmitInterval = time.Hour
+	cfg.InsertBatchSize = 0
+	cfg.DeleteBatchSize = 0
+	cfg.Priorities = testPriorityConfig()
+	cfg.Metrics = &DurableEmitterMetricsConfig{PollInterval: time.Hour}
+	em, err := NewDurableEmitter(store, be, true, cfg, logger.Test(t), meter)
+	require.NoError(t, err)
+	servicetest.Run(t, em)
+	ctx := t.Context()
+
+	body := bytes.Repeat([]byte("x"), 1000)
+	require.NoError(t, em.Emit(ctx, body, "source", "platform", "type", "telemetry.1"))
+	require.NoError(t, em.Emit(ctx, body, "source", "platform", "type", "telemetry.2"))
+	// the store is full, so the insert is retried after shedding
+	require.NoError(t, em.Emit(ctx, body[:900], "source", "resourcemanager", "type", "metering.1"))
+	require.ErrorIs(t, em.Emit(ctx, body, "source", "platform", "type", "telemetry.3"), ErrStoreFull)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(ctx, &rm))
+	counts := make(map[string]int64)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			sum, ok := m.Data.(metricdata.Sum[int64])
+			if !ok || (m.Name != "durable_emitter.emit.success" && m.Name != "durable_emitter.emit.failure") {
+				continue
+			}
+			for _, dp := range sum.DataPoints {
+				class, _ := dp.Attributes.Value("priority_class")
+				counts[m.Name+"/"+class.AsString()] += dp.Value
+			}
+		}
+	}
+	assert.Equal(t, map[string]int64{
+		"durable_emitter.emit.success/telemetry": 2,
+		"durable_emitter.emit.success/billing":   1,
+		"durable_emitter.emit.failure/telemetry": 1,
+	}, counts)
+}
@@ -2,10 +2,12 @@ package durableemitter
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/lib/pq"
 
 	"github.com/smartcontractkit/chainlink-common/pkg/sqlutil"
@@ -14,6 +16,9 @@ import (
 const chipDurableEventsTable = "cre.chip_durable_events"
 
 // PgDurableEventStore is a Postgres-backed implementation of DurableEventStore.
+// Inserts fail with ErrStoreFull when the database is out of disk space, which
+// is usually too late: bound the queue with PriorityConfig.MaxPayloadBytes
+// instead, so that events are shed or rejected before other tables are affected.
 // Tests live in chainlink/core/services/durableemitter as they require DB migrations.
 type PgDurableEventStore struct {
 	ds sqlutil.DataSource
@@ -33,7 +38,7 @@ func (s *PgDurableEventStore) Insert(ctx context.Context, payload []byte) (int64
 	const q = `INSERT INTO ` + chipDurableEventsTable + ` (payload) VALUES ($1) RETURNING id`
 	var id int64
 	if err := s.ds.GetContext(ctx, &id, q, payload); err != nil {
-		return 0, fmt.Errorf("failed to insert chip durable event: %w", err)
+		return 0, fmt.Errorf("failed to insert chip durable event: %w", storeFullError(err))
 	}
 	return id, nil
 }
@@ -56,11 +61,25 @@ func (s *PgDurableEventStore) InsertBatch(ctx context.Context, payloads [][]byte
 
 	var ids []int64
 	if err := s.ds.SelectContext(ctx, &ids, q, args...); err != nil {
-		return nil, fmt.Errorf("failed to batch insert chip durable events: %w", err)
+		return nil, fmt.Errorf("failed to batch insert chip durable events: %w", storeFullError(err))
 	}
 	return ids, nil
 }
 
+// pgDiskFull is the SQLSTATE of statements which failed for lack of disk space.
+const pgDiskFull = "53100"
+
+// storeFullError wraps err with ErrStoreFull if Postgres is out of disk space.
+func storeFullError(err error) error {
+	if pgErr, ok := errors.AsType[*pgconn.PgError](err); ok && pgErr.Code == pgDiskFull {
+		return fmt.Errorf("%w: %w", ErrStoreFull, err)
+	}
+	if pqErr, ok := errors.AsType[*pq.Error](err); ok && string(pqErr.Code) == pgDiskFull {
+		return fmt.Errorf("%w: %w", ErrStoreFull, err)
+	}
+	return err
+}
+
 func (s *PgDurableEventStore) Delete(ctx context.Context, id int64) error {
 	const q = `DELETE FROM ` + chipDurableEventsTable + ` WHERE id = $1`
 	if _, err := s.ds.ExecContext(ctx, q, id); err != nil {
@@ -0,0 +1,31 @@
+package durableemitter
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreFullError(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  error
+		full bool
+	}{
+		{"pgx", &pgconn.PgError{Code: pgDiskFull}, true},
+		{"pq", &pq.Error{Code: pgDiskFull}, true},
+		{"wrapped", fmt.Errorf("insert: %w", &pgconn.PgError{Code: pgDiskFull}), true},
+		{"other", &pgconn.PgError{Code: "23505"}, false},
+		{"plain", errors.New("connection refused"), false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := storeFullError(tc.err)
+			assert.Equal(t, tc.full, errors.Is(err, ErrStoreFull))
+			assert.ErrorIs(t, err, tc.err)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package hashutil
+
+import (
+	"bytes"
+	"crypto/sha256"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// NewSHA256 returns a SHA-256 Hasher.
+func NewSHA256(opts ...Option) Hasher[[32]byte] {
+	return newDigestHasher(sha256.Sum256, newConfig(opts))
+}
+
+// NewBlake2b returns a Blake2b-256 Hasher.
+func NewBlake2b(opts ...Option) Hasher[[32]byte] {
+	return newDigestHasher(blake2b.Sum256, newConfig(opts))
+}
+
+// digestHasher implements Hasher for any 32 byte digest, by prepending the domain separators to the input.
+type digestHasher struct {
+	sum      func([]byte) [32]byte
+	leaf     []byte
+	internal []byte
+}
+
+func newDigestHasher(sum func([]byte) [32]byte, c config) digestHasher {
+	return digestHasher{
+		sum:      sum,
+		leaf:     bytes.Clone(c.domains.Leaf),
+		internal: bytes.Clone(c.domains.Internal),
+	}
+}
+
+// Hash hashes a byte array, prefixed by the leaf domain separator.
+func (d digestHasher) Hash(l []byte) [32]byte {
+	if len(d.leaf) == 0 {
+		return d.sum(l)
+	}
+	return d.sum(append(bytes.Clone(d.leaf), l...))
+}
+
+// HashInternal orders two [32]byte values and prepends them with
+// a separator before hashing them.
+func (d digestHasher) HashInternal(a, b [32]byte) [32]byte {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		a, b = b, a
+	}
+	in := make([]byte, 0, len(d.internal)+64)
+	in = append(in, d.internal...)
+	in = append(in, a[:]...)
+	return d.sum(append(in, b[:]...))
+}
+
+// ZeroHash returns the zero hash: 0xFF..FF
+// We use bytes32 0xFF..FF for zeroHash in the CCIP research spec, this needs to match.
+// This value is chosen since it is unlikely to be the result of a hash, and cannot match any internal node preimage.
+func (d digestHasher) ZeroHash() [32]byte {
+	var zeroes [32]byte
+	for i := range 32 {
+		zeroes[i] = 0xFF
+	}
+	return zeroes
+}
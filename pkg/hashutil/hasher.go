@@ -11,3 +11,42 @@ type Hasher[H Hash] interface {
 	HashInternal(a, b H) H
 	ZeroHash() H
 }
+
+// DomainSeparators are prefixes which separate the hashes of leaves from those of internal nodes, so that one cannot
+// be passed off as the other.
+type DomainSeparators struct {
+	// Leaf is prepended to data passed to Hash.
+	Leaf []byte
+	// Internal is prepended to the sorted pair passed to HashInternal.
+	Internal []byte
+}
+
+// DefaultDomainSeparators returns the separators used by the CCIP research spec: no leaf prefix, since leaves are
+// prefixed by the caller, and a 32 byte internal prefix of 0x00..01.
+func DefaultDomainSeparators() DomainSeparators {
+	internal := make([]byte, 32)
+	internal[31] = 1
+	return DomainSeparators{Internal: internal}
+}
+
+type config struct {
+	domains DomainSeparators
+}
+
+func newConfig(opts []Option) config {
+	c := config{domains: DefaultDomainSeparators()}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// Option configures a Hasher.
+type Option func(*config)
+
+// WithDomainSeparators overrides DefaultDomainSeparators, e.g. to match a non-EVM verifier.
+func WithDomainSeparators(ds DomainSeparators) Option {
+	return func(c *config) {
+		c.domains = ds
+	}
+}
@@ -0,0 +1,76 @@
+package hashutil_test
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/hashutil"
+	"github.com/smartcontractkit/chainlink-common/pkg/hashutil/hashertest"
+)
+
+func TestHashers(t *testing.T) {
+	custom := hashutil.WithDomainSeparators(hashutil.DomainSeparators{Leaf: []byte{0x00}, Internal: []byte{0x01}})
+	poseidon, err := hashutil.NewPoseidon()
+	require.NoError(t, err)
+	customPoseidon, err := hashutil.NewPoseidon(custom)
+	require.NoError(t, err)
+
+	for name, hasher := range map[string]hashutil.Hasher[[32]byte]{
+		"keccak":          hashutil.NewKeccak(),
+		"keccak/custom":   hashutil.NewKeccak(custom),
+		"sha256":          hashutil.NewSHA256(),
+		"sha256/custom":   hashutil.NewSHA256(custom),
+		"blake2b":         hashutil.NewBlake2b(),
+		"blake2b/custom":  hashutil.NewBlake2b(custom),
+		"poseidon":        poseidon,
+		"poseidon/custom": customPoseidon,
+	} {
+		t.Run(name, func(t *testing.T) {
+			hashertest.RunHasherTests(t, hasher)
+		})
+	}
+}
+
+func TestKeccak(t *testing.T) {
+	// keccak256(0x00..01 || a || b) with a < b
+	h := hashutil.NewKeccak()
+	var a, b [32]byte
+	b[31] = 1
+	sep := make([]byte, 32)
+	sep[31] = 1
+	assert.Equal(t, h.Hash(append(append(sep, a[:]...), b[:]...)), h.HashInternal(b, a))
+	assert.Equal(t, "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470", hex.EncodeToString(func() []byte { x := h.Hash(nil); return x[:] }()))
+}
+
+func TestDomainSeparators(t *testing.T) {
+	a := [32]byte{1}
+	def := hashutil.NewSHA256()
+	custom := hashutil.NewSHA256(hashutil.WithDomainSeparators(hashutil.DomainSeparators{Leaf: []byte("leaf"), Internal: []byte("node")}))
+	assert.NotEqual(t, def.Hash([]byte("x")), custom.Hash([]byte("x")))
+	assert.Equal(t, def.Hash([]byte("leafx")), custom.Hash([]byte("x")))
+	assert.NotEqual(t, def.HashInternal(a, a), custom.HashInternal(a, a))
+}
+
+func TestPoseidon(t *testing.T) {
+	// circomlib poseidon([1, 2])
+	h, err := hashutil.NewPoseidon(hashutil.WithDomainSeparators(hashutil.DomainSeparators{}))
+	require.NoError(t, err)
+	var one, two [32]byte
+	one[31], two[31] = 1, 2
+	expected, _ := new(big.Int).SetString("7853200120776062878684798364095072458815029376092732009249414926327459813530", 10)
+	got := h.HashInternal(two, one)
+	assert.Equal(t, expected, new(big.Int).SetBytes(got[:]))
+
+	var notField [32]byte
+	for i := range notField {
+		notField[i] = 0xff
+	}
+	assert.Panics(t, func() { h.HashInternal(one, notField) })
+
+	_, err = hashutil.NewPoseidon(hashutil.WithDomainSeparators(hashutil.DomainSeparators{Internal: []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}}))
+	require.Error(t, err)
+}
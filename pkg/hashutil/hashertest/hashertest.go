@@ -0,0 +1,71 @@
+// Package hashertest provides a conformance suite for hashutil.Hasher implementations.
+package hashertest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/hashutil"
+	"github.com/smartcontractkit/chainlink-common/pkg/merklemulti"
+)
+
+// RunHasherTests checks the properties that merklemulti relies on, and that trees built with hasher round-trip
+// proofs of every size.
+func RunHasherTests[H hashutil.Hash](t *testing.T, hasher hashutil.Hasher[H]) {
+	a, b, c := hasher.Hash([]byte{0xa}), hasher.Hash([]byte{0xb}), hasher.Hash([]byte{0xc})
+
+	t.Run("Hash", func(t *testing.T) {
+		assert.Equal(t, a, hasher.Hash([]byte{0xa}), "deterministic")
+		assert.NotEqual(t, a, b)
+		assert.NotEqual(t, hasher.Hash(nil), hasher.Hash([]byte{0}), "length sensitive")
+		assert.NotEqual(t, hasher.Hash([]byte{0}), hasher.Hash([]byte{0, 0}), "length sensitive")
+		assert.NotEqual(t, hasher.ZeroHash(), hasher.Hash(nil))
+	})
+
+	t.Run("HashInternal", func(t *testing.T) {
+		assert.Equal(t, hasher.HashInternal(a, b), hasher.HashInternal(b, a), "commutative")
+		assert.Equal(t, hasher.HashInternal(a, b), hasher.HashInternal(a, b), "deterministic")
+		assert.NotEqual(t, hasher.HashInternal(a, b), hasher.HashInternal(a, c))
+		z := hasher.ZeroHash()
+		assert.NotEqual(t, z, hasher.HashInternal(z, z))
+		assert.Equal(t, z, hasher.ZeroHash(), "constant")
+	})
+
+	t.Run("domain separation", func(t *testing.T) {
+		ab, ba := [32]byte(a), [32]byte(b)
+		if string(ab[:]) > string(ba[:]) {
+			ab, ba = ba, ab
+		}
+		internal := hasher.HashInternal(a, b)
+		assert.NotEqual(t, internal, hasher.Hash(append(ab[:], ba[:]...)), "leaf must not equal internal node")
+	})
+
+	t.Run("merklemulti", func(t *testing.T) {
+		var leaves []H
+		for i := range 9 {
+			leaves = append(leaves, hasher.Hash([]byte{byte(i)}))
+		}
+		for n := 1; n <= len(leaves); n++ {
+			tree, err := merklemulti.NewTree(hasher, leaves[:n])
+			require.NoError(t, err)
+			for i := range n {
+				proof, err := tree.Prove([]int{i})
+				require.NoError(t, err)
+				root, err := merklemulti.VerifyComputeRoot(hasher, []H{leaves[i]}, proof)
+				require.NoError(t, err)
+				require.Equal(t, tree.Root(), root, "tree of %d, leaf %d", n, i)
+			}
+			all := make([]int, n)
+			for i := range all {
+				all[i] = i
+			}
+			proof, err := tree.Prove(all)
+			require.NoError(t, err)
+			root, err := merklemulti.VerifyComputeRoot(hasher, leaves[:n], proof)
+			require.NoError(t, err)
+			require.Equal(t, tree.Root(), root, "tree of %d, all leaves", n)
+		}
+	})
+}
@@ -0,0 +1,211 @@
+package hashutil
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sync"
+)
+
+// bn254 is the scalar field modulus of the BN254 curve, which Poseidon operates over.
+var bn254, _ = new(big.Int).SetString("21888242871839275222246405745257275088548364400416034343698204186575808495617", 10)
+
+const (
+	poseidonWidth         = 3 // capacity of 1 and rate of 2
+	poseidonFullRounds    = 8
+	poseidonPartialRounds = 57
+	// poseidonChunkSize is the number of bytes absorbed per field element, so that every chunk is less than the modulus.
+	poseidonChunkSize = 31
+)
+
+// NewPoseidon returns a Poseidon Hasher over the BN254 scalar field, with width 3 and x^5 S-box. It is compatible
+// with circomlib, i.e. HashInternal(a, b) with a zero internal domain separator is Poseidon([a, b]) on the sorted
+// pair, which is cheap to verify in zero-knowledge circuits and on chains with native Poseidon support.
+//
+// The domain separators are interpreted as big-endian field elements, and used as the initial capacity element.
+// Hash absorbs the data in 31 byte chunks, with 0x01 then zero padding.
+//
+// Hashes are big-endian field elements, so ZeroHash is zero rather than 0xFF..FF, which is not a field element.
+func NewPoseidon(opts ...Option) (Hasher[[32]byte], error) {
+	c := newConfig(opts)
+	leaf, err := poseidonDomain(c.domains.Leaf)
+	if err != nil {
+		return nil, fmt.Errorf("invalid leaf domain separator: %w", err)
+	}
+	internal, err := poseidonDomain(c.domains.Internal)
+	if err != nil {
+		return nil, fmt.Errorf("invalid internal domain separator: %w", err)
+	}
+	return poseidon{leaf: leaf, internal: internal}, nil
+}
+
+func poseidonDomain(b []byte) (*big.Int, error) {
+	d := new(big.Int).SetBytes(b)
+	if d.Cmp(bn254) >= 0 {
+		return nil, fmt.Errorf("%x is not a field element", b)
+	}
+	return d, nil
+}
+
+type poseidon struct {
+	leaf     *big.Int
+	internal *big.Int
+}
+
+// Hash hashes a byte array as a sponge, with the leaf domain separator as the initial capacity element.
+func (p poseidon) Hash(l []byte) [32]byte {
+	// pad with 0x01 then zeros to a whole number of blocks
+	const blockSize = (poseidonWidth - 1) * poseidonChunkSize
+	padded := append(bytes.Clone(l), 0x01)
+	if r := len(padded) % blockSize; r != 0 {
+		padded = append(padded, make([]byte, blockSize-r)...)
+	}
+
+	state := []*big.Int{new(big.Int).Set(p.leaf), new(big.Int), new(big.Int)}
+	for len(padded) > 0 {
+		for i := 1; i < poseidonWidth; i++ {
+			chunk := new(big.Int).SetBytes(padded[:poseidonChunkSize])
+			state[i].Add(state[i], chunk).Mod(state[i], bn254)
+			padded = padded[poseidonChunkSize:]
+		}
+		poseidonPermute(state)
+	}
+	return fieldToHash(state[0])
+}
+
+// HashInternal orders two [32]byte values and hashes them with the internal domain separator as the capacity element.
+// It panics if either value is not a field element, since reducing it would collide with another value, and every
+// hash of this Hasher is one.
+func (p poseidon) HashInternal(a, b [32]byte) [32]byte {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		a, b = b, a
+	}
+	state := []*big.Int{new(big.Int).Set(p.internal), hashToField(a), hashToField(b)}
+	poseidonPermute(state)
+	return fieldToHash(state[0])
+}
+
+// ZeroHash returns the zero field element.
+func (p poseidon) ZeroHash() [32]byte {
+	return [32]byte{}
+}
+
+func hashToField(h [32]byte) *big.Int {
+	x := new(big.Int).SetBytes(h[:])
+	if x.Cmp(bn254) >= 0 {
+		panic(fmt.Sprintf("poseidon: %x is not a field element", h))
+	}
+	return x
+}
+
+func fieldToHash(x *big.Int) (h [32]byte) {
+	x.FillBytes(h[:])
+	return
+}
+
+// poseidonPermute applies the Poseidon permutation to state in place.
+func poseidonPermute(state []*big.Int) {
+	constants, mds := poseidonParams()
+	tmp := new(big.Int)
+	next := make([]*big.Int, poseidonWidth)
+	for r := range poseidonFullRounds + poseidonPartialRounds {
+		for i, s := range state {
+			s.Add(s, constants[r*poseidonWidth+i])
+		}
+		full := r < poseidonFullRounds/2 || r >= poseidonFullRounds/2+poseidonPartialRounds
+		for i, s := range state {
+			if i == 0 || full {
+				tmp.Exp(s, big.NewInt(5), bn254)
+				s.Set(tmp)
+			}
+		}
+		for i := range next {
+			next[i] = new(big.Int)
+			for j, s := range state {
+				next[i].Add(next[i], tmp.Mul(mds[i][j], s))
+			}
+			next[i].Mod(next[i], bn254)
+		}
+		copy(state, next)
+	}
+}
+
+var poseidonParams = sync.OnceValues(func() ([]*big.Int, [][]*big.Int) {
+	g := newGrain(poseidonWidth, poseidonFullRounds, poseidonPartialRounds)
+	constants := make([]*big.Int, (poseidonFullRounds+poseidonPartialRounds)*poseidonWidth)
+	for i := range constants {
+		for {
+			constants[i] = g.field()
+			if constants[i].Cmp(bn254) < 0 {
+				break
+			}
+		}
+	}
+	// Cauchy matrix 1/(x_i + y_j)
+	xy := make([]*big.Int, 2*poseidonWidth)
+	for i := range xy {
+		xy[i] = g.field()
+		xy[i].Mod(xy[i], bn254)
+	}
+	mds := make([][]*big.Int, poseidonWidth)
+	for i := range mds {
+		mds[i] = make([]*big.Int, poseidonWidth)
+		for j := range mds[i] {
+			sum := new(big.Int).Add(xy[i], xy[poseidonWidth+j])
+			mds[i][j] = sum.ModInverse(sum.Mod(sum, bn254), bn254)
+		}
+	}
+	return constants, mds
+})
+
+// grain is the Grain LFSR used by the Poseidon reference implementation to derive round constants and the MDS matrix.
+type grain struct {
+	bits []byte
+}
+
+func newGrain(width, fullRounds, partialRounds int) *grain {
+	g := &grain{}
+	appendBits := func(v, n int) {
+		for i := n - 1; i >= 0; i-- {
+			g.bits = append(g.bits, byte(v>>i)&1)
+		}
+	}
+	appendBits(1, 2)               // prime field
+	appendBits(0, 4)               // x^alpha S-box
+	appendBits(bn254.BitLen(), 12) // field size
+	appendBits(width, 12)
+	appendBits(fullRounds, 10)
+	appendBits(partialRounds, 10)
+	appendBits(1<<30-1, 30)
+	for range 160 {
+		g.step()
+	}
+	return g
+}
+
+func (g *grain) step() byte {
+	b := g.bits[62] ^ g.bits[51] ^ g.bits[38] ^ g.bits[23] ^ g.bits[13] ^ g.bits[0]
+	g.bits = append(g.bits[1:], b)
+	return b
+}
+
+// bit returns the next output bit, using the self-shrinking rule: of each pair, the second is output only if the
+// first is set.
+func (g *grain) bit() byte {
+	for {
+		first, second := g.step(), g.step()
+		if first == 1 {
+			return second
+		}
+	}
+}
+
+// field returns the next field-sized integer, which may exceed the modulus.
+func (g *grain) field() *big.Int {
+	x := new(big.Int)
+	for range bn254.BitLen() {
+		x.Lsh(x, 1)
+		x.SetBit(x, 0, uint(g.bit()))
+	}
+	return x
+}
@@ -0,0 +1,331 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// JWTGenerator creates a JWT for a request. It is implemented by nodeauth/jwt.NodeJWTGenerator.
+// The request passed is the Request.Digest, as expected by a Router's JWTAuthenticator.
+type JWTGenerator interface {
+	CreateJWTForRequest(req any) (string, error)
+}
+
+// transport sends an encoded request, and returns the encoded response, or nil if none was expected.
+type transport interface {
+	roundTrip(ctx context.Context, body []byte, expectResponse bool) ([]byte, error)
+	close() error
+}
+
+// Client is a JSON RPC client for a Router, or any other JSON RPC 2.0 server, over HTTP or WebSocket.
+type Client struct {
+	t            transport
+	jwtGen       JWTGenerator // optional
+	verifyDigest bool
+	nextID       atomic.Uint64
+}
+
+// ClientOpt configures optional features of a Client.
+type ClientOpt func(*Client)
+
+// WithJWTGenerator attaches a JWT to every request.
+func WithJWTGenerator(gen JWTGenerator) ClientOpt {
+	return func(c *Client) {
+		c.jwtGen = gen
+	}
+}
+
+// WithResponseDigestVerification rejects responses which do not match their Response.ResponseDigest, as set by a
+// Router. Standard JSON RPC servers do not set a digest, so this must only be used with a Router.
+func WithResponseDigestVerification() ClientOpt {
+	return func(c *Client) {
+		c.verifyDigest = true
+	}
+}
+
+// NewHTTPClient returns a Client which POSTs requests to url. If httpClient is nil, http.DefaultClient is used.
+func NewHTTPClient(url string, httpClient *http.Client, opts ...ClientOpt) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return newClient(&httpTransport{url: url, client: httpClient}, opts)
+}
+
+// DialWebSocket returns a Client connected to the WebSocket at url, e.g. "ws://host/path".
+// Requests on the connection are sent one at a time.
+func DialWebSocket(ctx context.Context, url string, opts ...ClientOpt) (*Client, error) {
+	cfg, err := websocket.NewConfig(url, url)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := cfg.DialContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial: %w", err)
+	}
+	return newClient(&wsTransport{conn: conn}, opts), nil
+}
+
+func newClient(t transport, opts []ClientOpt) *Client {
+	c := &Client{t: t}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Close closes the underlying connection, if any.
+func (c *Client) Close() error {
+	return c.t.close()
+}
+
+func (c *Client) newRequest(method string, params any, notification bool) (*Request[json.RawMessage], error) {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode params: %w", err)
+	}
+	msg := json.RawMessage(raw)
+	req := &Request[json.RawMessage]{
+		Version: JsonRpcVersion,
+		Method:  method,
+		Params:  &msg,
+	}
+	if !notification {
+		req.ID = strconv.FormatUint(c.nextID.Add(1), 10)
+	}
+	if c.jwtGen != nil {
+		digest, err := req.Digest()
+		if err != nil {
+			return nil, err
+		}
+		req.Auth, err = c.jwtGen.CreateJWTForRequest(digest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create JWT: %w", err)
+		}
+	}
+	return req, nil
+}
+
+// Call invokes method with params, and decodes the result. A *WireError is returned if the server responded with an
+// error.
+func Call[Params, Result any](ctx context.Context, c *Client, method string, params Params) (Result, error) {
+	var result Result
+	req, err := c.newRequest(method, params, false)
+	if err != nil {
+		return result, err
+	}
+	body, err := c.t.roundTrip(ctx, mustEncode(req), true)
+	if err != nil {
+		return result, err
+	}
+	resp, err := c.decodeResponse(body)
+	if err != nil {
+		return result, err
+	}
+	if err := checkResponse(resp, req.ID, &result); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// Notify invokes method with params, without waiting for a response.
+func Notify[Params any](ctx context.Context, c *Client, method string, params Params) error {
+	req, err := c.newRequest(method, params, true)
+	if err != nil {
+		return err
+	}
+	_, err = c.t.roundTrip(ctx, mustEncode(req), false)
+	return err
+}
+
+// BatchElem is a single call in a batch.
+type BatchElem struct {
+	Method string
+	Params any
+	// Result must be a pointer to decode the result into. If nil, the call is sent as a notification.
+	Result any
+	// Error is set after the batch completes if this call failed, e.g. to a *WireError.
+	Error error
+}
+
+// BatchCall sends all elems as a single batch request. An error is returned only if the batch as a whole failed, and
+// the outcome of each call is set in its BatchElem.Error.
+func (c *Client) BatchCall(ctx context.Context, elems []BatchElem) error {
+	if len(elems) == 0 {
+		return errors.New("empty batch")
+	}
+	reqs := make([]*Request[json.RawMessage], len(elems))
+	byID := make(map[string]int)
+	for i, e := range elems {
+		req, err := c.newRequest(e.Method, e.Params, e.Result == nil)
+		if err != nil {
+			return err
+		}
+		reqs[i] = req
+		if req.ID != "" {
+			byID[req.ID] = i
+		}
+	}
+	body, err := c.t.roundTrip(ctx, mustEncode(reqs), len(byID) > 0)
+	if err != nil || len(byID) == 0 {
+		return err
+	}
+	var raws []json.RawMessage
+	if err := json.Unmarshal(body, &raws); err != nil {
+		// the batch itself was rejected
+		resp, rerr := c.decodeResponse(body)
+		if rerr != nil {
+			return fmt.Errorf("failed to decode batch response: %w", err)
+		}
+		if resp.Error != nil {
+			return resp.Error
+		}
+		return errors.New("unexpected batch response")
+	}
+	for _, raw := range raws {
+		resp, err := c.decodeResponse(raw)
+		if err != nil {
+			return err
+		}
+		i, ok := byID[resp.ID]
+		if !ok {
+			return fmt.Errorf("unexpected response id: %q", resp.ID)
+		}
+		delete(byID, resp.ID)
+		elems[i].Error = checkResponse(resp, resp.ID, elems[i].Result)
+	}
+	for id, i := range byID {
+		elems[i].Error = fmt.Errorf("missing response for id: %s", id)
+	}
+	return nil
+}
+
+// decodeResponse decodes a response, and checks its version, and its digest if verification is enabled.
+func (c *Client) decodeResponse(body []byte) (Response[json.RawMessage], error) {
+	resp, err := DecodeResponse[json.RawMessage](body)
+	if err != nil {
+		return resp, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if resp.Version != JsonRpcVersion {
+		return resp, errors.New("incorrect jsonrpc version")
+	}
+	if !c.verifyDigest {
+		return resp, nil
+	}
+	if resp.ResponseDigest == "" {
+		return resp, errors.New("missing response digest")
+	}
+	digest, err := resp.Digest()
+	if err != nil {
+		return resp, err
+	}
+	if digest != resp.ResponseDigest {
+		return resp, fmt.Errorf("response digest mismatch: got %s, want %s", resp.ResponseDigest, digest)
+	}
+	return resp, nil
+}
+
+func checkResponse(resp Response[json.RawMessage], id string, result any) error {
+	if resp.ID != id {
+		return fmt.Errorf("response id mismatch: got %q, want %q", resp.ID, id)
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if resp.Result == nil {
+		return errors.New("missing result")
+	}
+	if err := json.Unmarshal(*resp.Result, result); err != nil {
+		return fmt.Errorf("failed to decode result: %w", err)
+	}
+	return nil
+}
+
+type httpTransport struct {
+	url    string
+	client *http.Client
+}
+
+func (t *httpTransport) roundTrip(ctx context.Context, body []byte, expectResponse bool) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, DefaultMaxRequestBytes))
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusNoContent:
+		if expectResponse {
+			return nil, errors.New("no response")
+		}
+		return nil, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, respBody)
+	}
+	if !expectResponse {
+		return nil, nil
+	}
+	return respBody, nil
+}
+
+func (t *httpTransport) close() error { return nil }
+
+type wsTransport struct {
+	mu   sync.Mutex
+	conn *websocket.Conn
+}
+
+func (t *wsTransport) roundTrip(ctx context.Context, body []byte, expectResponse bool) ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := t.conn.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+	// Cancellation interrupts a blocked send or receive by moving the deadline to the past.
+	cancelled := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		_ = t.conn.SetDeadline(time.Unix(1, 0))
+		close(cancelled)
+	})
+	defer func() {
+		if !stop() {
+			<-cancelled
+		}
+		_ = t.conn.SetDeadline(time.Time{})
+	}()
+	if err := websocket.Message.Send(t.conn, string(body)); err != nil {
+		return nil, fmt.Errorf("failed to send: %w", errors.Join(ctx.Err(), err))
+	}
+	if !expectResponse {
+		return nil, nil
+	}
+	var resp []byte
+	if err := websocket.Message.Receive(t.conn, &resp); err != nil {
+		return nil, fmt.Errorf("failed to receive: %w", errors.Join(ctx.Err(), err))
+	}
+	return resp, nil
+}
+
+func (t *wsTransport) close() error {
+	return t.conn.Close()
+}
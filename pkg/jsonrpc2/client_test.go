@@ -0,0 +1,125 @@
+package jsonrpc2
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+func TestClient(t *testing.T) {
+	r, notified := newTestRouter(t)
+	mux := http.NewServeMux()
+	mux.Handle("/rpc", r)
+	mux.Handle("/ws", r.WebSocketHandler())
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	ws, err := DialWebSocket(t.Context(), "ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", WithResponseDigestVerification())
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, ws.Close()) })
+
+	for name, c := range map[string]*Client{
+		"http": NewHTTPClient(srv.URL+"/rpc", srv.Client(), WithResponseDigestVerification()),
+		"ws":   ws,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx := t.Context()
+			sum, err := Call[addParams, int](ctx, c, "math.add", addParams{A: 2, B: 3})
+			require.NoError(t, err)
+			assert.Equal(t, 5, sum)
+
+			_, err = Call[addParams, int](ctx, c, "math.fail", addParams{A: -1})
+			var we *WireError
+			require.ErrorAs(t, err, &we)
+			assert.Equal(t, ErrLimitExceeded, we.Code)
+			assert.Equal(t, "too negative", we.Message)
+
+			before := notified.Load()
+			require.NoError(t, Notify(ctx, c, "events.notify", "x"))
+			// a subsequent call ensures the notification was handled
+			_, err = Call[addParams, int](ctx, c, "math.add", addParams{})
+			require.NoError(t, err)
+			assert.Equal(t, before+1, notified.Load())
+
+			var a, b int
+			batch := []BatchElem{
+				{Method: "math.add", Params: addParams{A: 1, B: 1}, Result: &a},
+				{Method: "events.notify", Params: "x"},
+				{Method: "math.fail", Params: addParams{A: 1}, Result: &b},
+				{Method: "math.add", Params: addParams{A: 2, B: 2}, Result: &b},
+			}
+			require.NoError(t, c.BatchCall(ctx, batch))
+			assert.Equal(t, 2, a)
+			assert.NoError(t, batch[0].Error)
+			assert.NoError(t, batch[1].Error)
+			require.ErrorAs(t, batch[2].Error, &we)
+			assert.Equal(t, ErrUnknown, we.Code)
+			assert.NoError(t, batch[3].Error)
+			assert.Equal(t, 4, b)
+		})
+	}
+}
+
+func TestClient_standardServer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":"1","result":4}`))
+	}))
+	t.Cleanup(srv.Close)
+	got, err := Call[addParams, int](context.Background(), NewHTTPClient(srv.URL, nil), "math.add", addParams{A: 2, B: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 4, got)
+}
+
+func TestClient_version(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"jsonrpc":"1.0","id":"1","result":4}`))
+	}))
+	t.Cleanup(srv.Close)
+	_, err := Call[addParams, int](context.Background(), NewHTTPClient(srv.URL, nil), "math.add", addParams{A: 2, B: 2})
+	require.ErrorContains(t, err, "incorrect jsonrpc version")
+}
+
+func TestClient_verifiesDigest(t *testing.T) {
+	for name, body := range map[string]string{
+		"mismatch": `{"jsonrpc":"2.0","id":"1","method":"math.add","result":4,"digest":"bad"}`,
+		"missing":  `{"jsonrpc":"2.0","id":"1","result":4}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(body))
+			}))
+			t.Cleanup(srv.Close)
+			c := NewHTTPClient(srv.URL, nil, WithResponseDigestVerification())
+			_, err := Call[addParams, int](context.Background(), c, "math.add", addParams{A: 2, B: 2})
+			require.ErrorContains(t, err, "response digest")
+		})
+	}
+}
+
+func TestClient_webSocketCancel(t *testing.T) {
+	done := make(chan struct{})
+	srv := httptest.NewServer(websocket.Handler(func(conn *websocket.Conn) {
+		// never responds
+		var msg string
+		_ = websocket.Message.Receive(conn, &msg)
+		<-done
+	}))
+	t.Cleanup(srv.Close)
+	t.Cleanup(func() { close(done) })
+
+	c, err := DialWebSocket(t.Context(), "ws"+strings.TrimPrefix(srv.URL, "http"))
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, c.Close()) })
+
+	ctx, cancel := context.WithCancel(t.Context())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	_, err = Call[addParams, int](ctx, c, "math.add", addParams{A: 2, B: 2})
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,272 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/websocket"
+
+	nodeauthtypes "github.com/smartcontractkit/chainlink-common/pkg/nodeauth/types"
+)
+
+// DefaultMaxRequestBytes is the default limit on the size of a request body or message.
+const DefaultMaxRequestBytes = 10 << 20
+
+// JWTAuthenticator validates the JWT of a request. It is implemented by nodeauth/jwt.NodeJWTAuthenticator.
+// The original request passed for digest verification is the Request.Digest.
+type JWTAuthenticator interface {
+	AuthenticateJWT(ctx context.Context, tokenString string, originalRequest any) (valid bool, claims *nodeauthtypes.NodeJWTClaims, err error)
+}
+
+// ClaimsFromContext returns the JWT claims of the request being handled, if it was authenticated.
+func ClaimsFromContext(ctx context.Context) (*nodeauthtypes.NodeJWTClaims, bool) {
+	return nodeauthtypes.ClaimsFromContext(ctx)
+}
+
+// handlerFunc is a type-erased method handler.
+type handlerFunc func(ctx context.Context, params json.RawMessage) (json.RawMessage, error)
+
+// Router dispatches JSON RPC requests, including batches and notifications, to registered methods.
+// It serves HTTP via ServeHTTP, and WebSocket via WebSocketHandler.
+type Router struct {
+	auth     JWTAuthenticator // optional
+	maxBytes int64
+
+	mu      sync.RWMutex
+	methods map[string]handlerFunc
+}
+
+// RouterOpt configures optional features of a Router.
+type RouterOpt func(*Router)
+
+// WithJWTAuthenticator requires every request to carry a JWT, either in the auth field or as a Bearer token, which is
+// validated by auth. The claims are available to handlers via ClaimsFromContext.
+func WithJWTAuthenticator(auth JWTAuthenticator) RouterOpt {
+	return func(r *Router) {
+		r.auth = auth
+	}
+}
+
+// WithMaxRequestBytes overrides DefaultMaxRequestBytes.
+func WithMaxRequestBytes(n int64) RouterOpt {
+	return func(r *Router) {
+		r.maxBytes = n
+	}
+}
+
+func NewRouter(opts ...RouterOpt) *Router {
+	r := &Router{
+		maxBytes: DefaultMaxRequestBytes,
+		methods:  make(map[string]handlerFunc),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Register adds a typed handler for method. Params which fail to decode are rejected with ErrInvalidParams.
+// Handlers may return a *WireError to control the error code, otherwise ErrUnknown is used.
+func Register[Params, Result any](r *Router, method string, fn func(ctx context.Context, params Params) (Result, error)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.methods[method] = func(ctx context.Context, raw json.RawMessage) (json.RawMessage, error) {
+		var params Params
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return nil, &WireError{Code: ErrInvalidParams, Message: fmt.Sprintf("invalid params: %v", err)}
+		}
+		result, err := fn(ctx, params)
+		if err != nil {
+			return nil, err
+		}
+		b, err := json.Marshal(result)
+		if err != nil {
+			return nil, &WireError{Code: ErrInternal, Message: fmt.Sprintf("failed to encode result: %v", err)}
+		}
+		return b, nil
+	}
+}
+
+func (r *Router) handler(method string) (handlerFunc, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	h, ok := r.methods[method]
+	return h, ok
+}
+
+// Handle processes a single or batch request body, and returns the encoded response, or nil if there is nothing to
+// respond with, i.e. only notifications. The jwtTokenFromHeader is used for requests without their own auth field.
+func (r *Router) Handle(ctx context.Context, body []byte, jwtTokenFromHeader string) []byte {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		resp := r.handleOne(ctx, trimmed, jwtTokenFromHeader)
+		if resp == nil {
+			return nil
+		}
+		return mustEncode(resp)
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(trimmed, &batch); err != nil {
+		return mustEncode(errorResponse("", "", &WireError{Code: ErrParse, Message: err.Error()}))
+	}
+	if len(batch) == 0 {
+		return mustEncode(errorResponse("", "", &WireError{Code: ErrInvalidRequest, Message: "empty batch"}))
+	}
+	var resps []*Response[json.RawMessage]
+	for _, raw := range batch {
+		if resp := r.handleOne(ctx, raw, jwtTokenFromHeader); resp != nil {
+			resps = append(resps, resp)
+		}
+	}
+	if len(resps) == 0 {
+		return nil
+	}
+	return mustEncode(resps)
+}
+
+// handleOne returns the response to a single request, or nil for a notification.
+func (r *Router) handleOne(ctx context.Context, raw []byte, jwtTokenFromHeader string) *Response[json.RawMessage] {
+	if !json.Valid(raw) {
+		return errorResponse("", "", &WireError{Code: ErrParse, Message: "invalid JSON"})
+	}
+	req, err := DecodeRequest[json.RawMessage](raw, jwtTokenFromHeader)
+	if err != nil {
+		var partial struct {
+			ID string `json:"id"`
+		}
+		_ = json.Unmarshal(raw, &partial)
+		return errorResponse(partial.ID, "", &WireError{Code: ErrInvalidRequest, Message: err.Error()})
+	}
+	notification := req.ID == ""
+
+	result, err := r.dispatch(ctx, req)
+	if notification {
+		return nil
+	}
+	if err != nil {
+		return errorResponse(req.ID, req.Method, toWireError(err))
+	}
+	return withDigest(&Response[json.RawMessage]{
+		Version: JsonRpcVersion,
+		ID:      req.ID,
+		Method:  req.Method,
+		Result:  &result,
+	})
+}
+
+func (r *Router) dispatch(ctx context.Context, req Request[json.RawMessage]) (json.RawMessage, error) {
+	if r.auth != nil {
+		if req.Auth == "" {
+			return nil, &WireError{Code: ErrUnauthorized, Message: "missing JWT"}
+		}
+		digest, err := req.Digest()
+		if err != nil {
+			return nil, &WireError{Code: ErrInvalidRequest, Message: err.Error()}
+		}
+		valid, claims, err := r.auth.AuthenticateJWT(ctx, req.Auth, digest)
+		if err != nil || !valid {
+			msg := "invalid JWT"
+			if err != nil {
+				msg = fmt.Sprintf("invalid JWT: %v", err)
+			}
+			return nil, &WireError{Code: ErrUnauthorized, Message: msg}
+		}
+		ctx = nodeauthtypes.WithClaims(ctx, claims)
+	}
+	h, ok := r.handler(req.Method)
+	if !ok {
+		return nil, &WireError{Code: ErrMethodNotFound, Message: fmt.Sprintf("method not found: %s", req.Method)}
+	}
+	return h(ctx, *req.Params)
+}
+
+func toWireError(err error) *WireError {
+	var we *WireError
+	if errors.As(err, &we) {
+		return we
+	}
+	return &WireError{Code: ErrUnknown, Message: err.Error()}
+}
+
+func errorResponse(id, method string, err *WireError) *Response[json.RawMessage] {
+	return withDigest(&Response[json.RawMessage]{
+		Version: JsonRpcVersion,
+		ID:      id,
+		Method:  method,
+		Error:   err,
+	})
+}
+
+func withDigest(resp *Response[json.RawMessage]) *Response[json.RawMessage] {
+	// Responses only contain valid JSON, so this cannot fail.
+	resp.ResponseDigest, _ = resp.Digest()
+	return resp
+}
+
+func mustEncode(v any) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("failed to encode response: %v", err))
+	}
+	return b
+}
+
+// ServeHTTP handles POST requests, with an optional "Authorization: Bearer <JWT>" header.
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, r.maxBytes))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+	resp := r.Handle(req.Context(), body, bearerToken(req))
+	if resp == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(resp)
+}
+
+// WebSocketHandler returns a handler which upgrades to a WebSocket, and handles each text message as a request body.
+// Messages on a connection are handled in order.
+func (r *Router) WebSocketHandler() http.Handler {
+	return websocket.Server{Handler: func(conn *websocket.Conn) {
+		defer conn.Close()
+		conn.MaxPayloadBytes = int(r.maxBytes)
+		ctx := conn.Request().Context()
+		token := bearerToken(conn.Request())
+		for {
+			var msg []byte
+			if err := websocket.Message.Receive(conn, &msg); err != nil {
+				return
+			}
+			resp := r.Handle(ctx, msg, token)
+			if resp == nil {
+				continue
+			}
+			if err := websocket.Message.Send(conn, string(resp)); err != nil {
+				return
+			}
+		}
+	}}
+}
+
+func bearerToken(req *http.Request) string {
+	token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return ""
+	}
+	return token
+}
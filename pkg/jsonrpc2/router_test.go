@@ -0,0 +1,160 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	nodeauthtypes "github.com/smartcontractkit/chainlink-common/pkg/nodeauth/types"
+	"github.com/smartcontractkit/chainlink-common/pkg/nodeauth/utils"
+)
+
+type addParams struct {
+	A, B int
+}
+
+func newTestRouter(t *testing.T, opts ...RouterOpt) (*Router, *atomic.Int64) {
+	r := NewRouter(opts...)
+	var notified atomic.Int64
+	Register(r, "math.add", func(ctx context.Context, p addParams) (int, error) {
+		return p.A + p.B, nil
+	})
+	Register(r, "math.fail", func(ctx context.Context, p addParams) (int, error) {
+		if p.A < 0 {
+			return 0, &WireError{Code: ErrLimitExceeded, Message: "too negative"}
+		}
+		return 0, errors.New("boom")
+	})
+	Register(r, "events.notify", func(ctx context.Context, p string) (struct{}, error) {
+		notified.Add(1)
+		return struct{}{}, nil
+	})
+	return r, &notified
+}
+
+func decodeResponses(t *testing.T, b []byte) []Response[json.RawMessage] {
+	t.Helper()
+	var resps []Response[json.RawMessage]
+	if strings.HasPrefix(string(b), "[") {
+		require.NoError(t, json.Unmarshal(b, &resps))
+	} else {
+		var resp Response[json.RawMessage]
+		require.NoError(t, json.Unmarshal(b, &resp))
+		resps = append(resps, resp)
+	}
+	for _, resp := range resps {
+		digest, err := resp.Digest()
+		require.NoError(t, err)
+		require.Equal(t, digest, resp.ResponseDigest)
+	}
+	return resps
+}
+
+func TestRouter_Handle(t *testing.T) {
+	r, notified := newTestRouter(t)
+	ctx := t.Context()
+
+	t.Run("call", func(t *testing.T) {
+		resps := decodeResponses(t, r.Handle(ctx, []byte(`{"jsonrpc":"2.0","id":"1","method":"math.add","params":{"A":1,"B":2}}`), ""))
+		require.Len(t, resps, 1)
+		assert.Equal(t, "1", resps[0].ID)
+		assert.Equal(t, "math.add", resps[0].Method)
+		assert.JSONEq(t, "3", string(*resps[0].Result))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, tt := range []struct {
+			name string
+			body string
+			code int64
+		}{
+			{"parse", `{"jsonrpc":`, ErrParse},
+			{"version", `{"jsonrpc":"1.0","id":"1","method":"math.add","params":{}}`, ErrInvalidRequest},
+			{"method", `{"jsonrpc":"2.0","id":"1","method":"math.sub","params":{}}`, ErrMethodNotFound},
+			{"params", `{"jsonrpc":"2.0","id":"1","method":"math.add","params":"nope"}`, ErrInvalidParams},
+			{"uncoded", `{"jsonrpc":"2.0","id":"1","method":"math.fail","params":{"A":1}}`, ErrUnknown},
+			{"coded", `{"jsonrpc":"2.0","id":"1","method":"math.fail","params":{"A":-1}}`, ErrLimitExceeded},
+			{"empty batch", `[]`, ErrInvalidRequest},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				resps := decodeResponses(t, r.Handle(ctx, []byte(tt.body), ""))
+				require.Len(t, resps, 1)
+				require.NotNil(t, resps[0].Error)
+				assert.Equal(t, tt.code, resps[0].Error.Code)
+			})
+		}
+	})
+
+	t.Run("notification", func(t *testing.T) {
+		before := notified.Load()
+		assert.Nil(t, r.Handle(ctx, []byte(`{"jsonrpc":"2.0","method":"events.notify","params":"x"}`), ""))
+		assert.Equal(t, before+1, notified.Load())
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		before := notified.Load()
+		resps := decodeResponses(t, r.Handle(ctx, []byte(`[
+			{"jsonrpc":"2.0","id":"1","method":"math.add","params":{"A":1,"B":2}},
+			{"jsonrpc":"2.0","method":"events.notify","params":"x"},
+			{"jsonrpc":"2.0","id":"2","method":"math.sub","params":{}},
+			42
+		]`), ""))
+		require.Len(t, resps, 3)
+		assert.JSONEq(t, "3", string(*resps[0].Result))
+		assert.Equal(t, ErrMethodNotFound, resps[1].Error.Code)
+		assert.Equal(t, ErrInvalidRequest, resps[2].Error.Code)
+		assert.Equal(t, before+1, notified.Load())
+
+		assert.Nil(t, r.Handle(ctx, []byte(`[{"jsonrpc":"2.0","method":"events.notify","params":"x"}]`), ""))
+	})
+}
+
+type testAuthenticator struct{}
+
+// AuthenticateJWT accepts tokens of the form "valid:<digest>".
+func (testAuthenticator) AuthenticateJWT(ctx context.Context, token string, req any) (bool, *nodeauthtypes.NodeJWTClaims, error) {
+	digest := utils.CalculateRequestDigest(req)
+	if token != "valid:"+digest {
+		return false, nil, errors.New("bad token")
+	}
+	return true, &nodeauthtypes.NodeJWTClaims{PublicKey: "node", Digest: digest}, nil
+}
+
+type testGenerator struct{}
+
+func (testGenerator) CreateJWTForRequest(req any) (string, error) {
+	return "valid:" + utils.CalculateRequestDigest(req), nil
+}
+
+func TestRouter_auth(t *testing.T) {
+	r := NewRouter(WithJWTAuthenticator(testAuthenticator{}))
+	Register(r, "whoami", func(ctx context.Context, _ struct{}) (string, error) {
+		claims, ok := ClaimsFromContext(ctx)
+		if !ok {
+			return "", errors.New("missing claims")
+		}
+		return claims.PublicKey, nil
+	})
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+
+	c := NewHTTPClient(srv.URL, srv.Client(), WithJWTGenerator(testGenerator{}))
+	who, err := Call[struct{}, string](t.Context(), c, "whoami", struct{}{})
+	require.NoError(t, err)
+	assert.Equal(t, "node", who)
+
+	_, err = Call[struct{}, string](t.Context(), NewHTTPClient(srv.URL, srv.Client()), "whoami", struct{}{})
+	var we *WireError
+	require.ErrorAs(t, err, &we)
+	assert.Equal(t, ErrUnauthorized, we.Code)
+
+	resps := decodeResponses(t, r.Handle(t.Context(), []byte(`{"jsonrpc":"2.0","id":"1","method":"whoami","params":{},"auth":"valid:wrong"}`), ""))
+	assert.Equal(t, ErrUnauthorized, resps[0].Error.Code)
+}
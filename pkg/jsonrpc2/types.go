@@ -44,6 +44,9 @@ const (
 
 	// ErrConflict is returned when a request conflicts with an existing request.
 	ErrConflict int64 = -32003
+
+	// ErrUnauthorized is returned when a request's JWT is missing or invalid.
+	ErrUnauthorized int64 = -32004
 )
 
 // Wrapping/unwrapping Message objects into JSON RPC ones folllowing https://www.jsonrpc.org/specification
@@ -93,12 +96,22 @@ type Response[Result any] struct {
 	Method  string     `json:"method"`
 	Result  *Result    `json:"result,omitempty"`
 	Error   *WireError `json:"error,omitempty"`
+	// ResponseDigest is set by servers to the Digest of the response, so that clients can verify it was received and
+	// decoded intact. It is not part of the JSON RPC specification.
+	ResponseDigest string `json:"digest,omitempty"`
 }
 
 // Digest returns a digest of the response. This is used for signature verification.
-// The digest is a SHA256 hash of the canonical JSON string of the response.
+// The digest is a SHA256 hash of the canonical JSON string of the response excluding the digest field.
 func (r *Response[Result]) Digest() (string, error) {
-	JSONBytes, err := jsonv2.Marshal(r, jsonv2.Deterministic(true))
+	JSONBytes, err := jsonv2.Marshal(Response[Result]{
+		Version: r.Version,
+		ID:      r.ID,
+		Method:  r.Method,
+		Result:  r.Result,
+		Error:   r.Error,
+		// ResponseDigest is intentionally excluded from the digest
+	}, jsonv2.Deterministic(true))
 	if err != nil {
 		return "", fmt.Errorf("error marshaling JSON: %w", err)
 	}
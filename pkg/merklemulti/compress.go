@@ -0,0 +1,87 @@
+package merklemulti
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/hashutil"
+)
+
+// maxEmptyHeight bounds the height of empty subtrees recognized by CompressProof. Taller empty subtrees only occur in
+// trees of more than 2^maxEmptyHeight leaves, far beyond the MaxNumberTreeLeaves that VerifyComputeRoot accepts,
+// and their hashes are simply left in Hashes.
+const maxEmptyHeight = 16
+
+// CompressedProof is a Proof with the hashes of empty subtrees omitted. These are common in proofs of padded and
+// sparse trees, and can be recomputed by the verifier.
+type CompressedProof[H hashutil.Hash] struct {
+	// Hashes are the remaining proof hashes.
+	Hashes []H `json:"hashes"`
+	// Empty has one entry per original proof hash: 0 if it is the next of Hashes, otherwise the height+1 of the
+	// empty subtree it was the root of.
+	Empty       []uint8 `json:"empty"`
+	SourceFlags []bool  `json:"source_flags"`
+}
+
+// emptySubtrees returns the roots of empty subtrees by height, starting with hasher.ZeroHash().
+func emptySubtrees[H hashutil.Hash](hasher hashutil.Hasher[H]) []H {
+	empty := []H{hasher.ZeroHash()}
+	for i := 1; i < maxEmptyHeight; i++ {
+		empty = append(empty, hasher.HashInternal(empty[i-1], empty[i-1]))
+	}
+	return empty
+}
+
+// CompressProof omits the hashes of empty subtrees from the proof. If none are found, then Empty is nil and Hashes
+// are unchanged.
+func CompressProof[H hashutil.Hash](hasher hashutil.Hasher[H], proof Proof[H]) CompressedProof[H] {
+	empty := emptySubtrees(hasher)
+	c := CompressedProof[H]{SourceFlags: proof.SourceFlags}
+	var found bool
+	marks := make([]uint8, len(proof.Hashes))
+	for i, h := range proof.Hashes {
+		for height, e := range empty {
+			if h == e {
+				marks[i] = uint8(height + 1)
+				found = true
+				break
+			}
+		}
+		if marks[i] == 0 {
+			c.Hashes = append(c.Hashes, h)
+		}
+	}
+	if found {
+		c.Empty = marks
+	}
+	return c
+}
+
+// Decompress restores the original proof.
+func (c CompressedProof[H]) Decompress(hasher hashutil.Hasher[H]) (Proof[H], error) {
+	proof := Proof[H]{SourceFlags: c.SourceFlags}
+	if c.Empty == nil {
+		proof.Hashes = c.Hashes
+		return proof, nil
+	}
+	empty := emptySubtrees(hasher)
+	var next int
+	for _, mark := range c.Empty {
+		if mark == 0 {
+			if next >= len(c.Hashes) {
+				return Proof[H]{}, errors.New("not enough hashes")
+			}
+			proof.Hashes = append(proof.Hashes, c.Hashes[next])
+			next++
+			continue
+		}
+		if int(mark) > len(empty) {
+			return Proof[H]{}, fmt.Errorf("invalid empty subtree height %d", mark-1)
+		}
+		proof.Hashes = append(proof.Hashes, empty[mark-1])
+	}
+	if next != len(c.Hashes) {
+		return Proof[H]{}, fmt.Errorf("%d unused hashes", len(c.Hashes)-next)
+	}
+	return proof, nil
+}
@@ -0,0 +1,110 @@
+package merklemulti
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/hashutil"
+)
+
+const abiWordSize = 32
+
+// ProofFlagsToBits packs source flags into the uint256 bitmap expected by the on-chain verifier, where bit i is set
+// if flag i is SourceFromHashes.
+func ProofFlagsToBits(flags []bool) (*big.Int, error) {
+	if len(flags) > 256 {
+		return nil, fmt.Errorf("%d source flags do not fit in a uint256", len(flags))
+	}
+	bits := new(big.Int)
+	for i, flag := range flags {
+		if flag == SourceFromHashes {
+			bits.SetBit(bits, i, 1)
+		}
+	}
+	return bits, nil
+}
+
+// BitsToProofFlags unpacks n source flags from a bitmap created by ProofFlagsToBits.
+func BitsToProofFlags(bits *big.Int, n int) ([]bool, error) {
+	if n < 0 || n > 256 {
+		return nil, fmt.Errorf("invalid number of source flags: %d", n)
+	}
+	if bits.Sign() < 0 || bits.BitLen() > n {
+		return nil, fmt.Errorf("bitmap has bits set beyond %d source flags", n)
+	}
+	flags := make([]bool, n)
+	for i := range flags {
+		flags[i] = bits.Bit(i) == 1
+	}
+	return flags, nil
+}
+
+// EncodeProof returns the canonical ABI encoding of the proof, i.e. abi.encode(bytes32[] proofs, uint256 proofFlagBits),
+// as consumed by the on-chain verifier.
+func EncodeProof[H hashutil.Hash](proof Proof[H]) ([]byte, error) {
+	bits, err := ProofFlagsToBits(proof.SourceFlags)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 3*abiWordSize, (3+len(proof.Hashes))*abiWordSize)
+	out[abiWordSize-1] = 2 * abiWordSize // offset of the dynamic array, after the two head words
+	bits.FillBytes(out[abiWordSize : 2*abiWordSize])
+	binary.BigEndian.PutUint64(out[3*abiWordSize-8:], uint64(len(proof.Hashes)))
+	for _, h := range proof.Hashes {
+		a := [32]byte(h)
+		out = append(out, a[:]...)
+	}
+	return out, nil
+}
+
+// DecodeProof decodes a proof encoded by EncodeProof. The number of leaves being proven is required, since the
+// encoding does not include the number of source flags.
+func DecodeProof[H hashutil.Hash](data []byte, numLeaves int) (Proof[H], error) {
+	if len(data) < 3*abiWordSize {
+		return Proof[H]{}, errors.New("proof encoding is too short")
+	}
+	offset, err := abiUint(data[:abiWordSize])
+	if err != nil {
+		return Proof[H]{}, fmt.Errorf("invalid offset: %w", err)
+	}
+	if offset != 2*abiWordSize {
+		return Proof[H]{}, fmt.Errorf("non-canonical offset %d", offset)
+	}
+	n, err := abiUint(data[2*abiWordSize : 3*abiWordSize])
+	if err != nil {
+		return Proof[H]{}, fmt.Errorf("invalid length: %w", err)
+	}
+	if n > MaxNumberTreeLeaves+1 {
+		return Proof[H]{}, fmt.Errorf("proof length %d is beyond the limit %d", n, MaxNumberTreeLeaves)
+	}
+	rest := data[3*abiWordSize:]
+	if uint64(len(rest)) != n*abiWordSize {
+		return Proof[H]{}, fmt.Errorf("expected %d bytes of hashes but got %d", n*abiWordSize, len(rest))
+	}
+	var proof Proof[H]
+	for i := range int(n) {
+		proof.Hashes = append(proof.Hashes, H([32]byte(rest[i*abiWordSize:(i+1)*abiWordSize])))
+	}
+	numFlags := numLeaves + int(n) - 1
+	if numLeaves <= 0 || numFlags < 0 {
+		return Proof[H]{}, fmt.Errorf("invalid number of leaves: %d", numLeaves)
+	}
+	bits := new(big.Int).SetBytes(data[abiWordSize : 2*abiWordSize])
+	proof.SourceFlags, err = BitsToProofFlags(bits, numFlags)
+	if err != nil {
+		return Proof[H]{}, err
+	}
+	return proof, nil
+}
+
+// abiUint decodes a uint256 word which must fit in a uint64.
+func abiUint(word []byte) (uint64, error) {
+	for _, b := range word[:abiWordSize-8] {
+		if b != 0 {
+			return 0, errors.New("value overflows uint64")
+		}
+	}
+	return binary.BigEndian.Uint64(word[abiWordSize-8:]), nil
+}
@@ -0,0 +1,100 @@
+package merklemulti
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProofFlagsToBits(t *testing.T) {
+	bits, err := ProofFlagsToBits([]bool{true, false, true, true})
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(0b1101), bits)
+
+	flags, err := BitsToProofFlags(bits, 4)
+	require.NoError(t, err)
+	assert.Equal(t, []bool{true, false, true, true}, flags)
+
+	_, err = BitsToProofFlags(bits, 3)
+	require.Error(t, err)
+	_, err = ProofFlagsToBits(make([]bool, 257))
+	require.Error(t, err)
+}
+
+func TestEncodeProof(t *testing.T) {
+	tr, err := NewTree(hasher, [][32]byte{a, b, c, d, e})
+	require.NoError(t, err)
+	proof, err := tr.Prove([]int{0, 3})
+	require.NoError(t, err)
+
+	enc, err := EncodeProof(proof)
+	require.NoError(t, err)
+	require.Len(t, enc, (3+len(proof.Hashes))*32)
+	word := func(i int) string { return hex.EncodeToString(enc[i*32 : (i+1)*32]) }
+	assert.Equal(t, "0000000000000000000000000000000000000000000000000000000000000040", word(0))
+	bits, err := ProofFlagsToBits(proof.SourceFlags)
+	require.NoError(t, err)
+	assert.Equal(t, bits, new(big.Int).SetBytes(enc[32:64]))
+	assert.Equal(t, big.NewInt(int64(len(proof.Hashes))), new(big.Int).SetBytes(enc[64:96]))
+	assert.Equal(t, hex.EncodeToString(proof.Hashes[0][:]), word(3))
+
+	decoded, err := DecodeProof[[32]byte](enc, 2)
+	require.NoError(t, err)
+	assert.Equal(t, proof, decoded)
+	root, err := VerifyComputeRoot(hasher, [][32]byte{a, d}, decoded)
+	require.NoError(t, err)
+	assert.Equal(t, tr.Root(), root)
+
+	t.Run("invalid", func(t *testing.T) {
+		_, err := DecodeProof[[32]byte](enc[:len(enc)-1], 2)
+		require.Error(t, err)
+		_, err = DecodeProof[[32]byte](enc[:64], 2)
+		require.Error(t, err)
+		_, err = DecodeProof[[32]byte](enc, 0)
+		require.Error(t, err)
+		bad := append([]byte{}, enc...)
+		bad[32] = 0x80 // flag bits beyond the count
+		_, err = DecodeProof[[32]byte](bad, 2)
+		require.Error(t, err)
+		bad = append([]byte{}, enc...)
+		bad[31] = 0x20
+		_, err = DecodeProof[[32]byte](bad, 2)
+		require.Error(t, err)
+	})
+}
+
+func TestCompressProof(t *testing.T) {
+	tr, err := NewSparseTree(hasher, 16, map[int][32]byte{0: a, 9: b})
+	require.NoError(t, err)
+	proof, err := tr.Prove([]int{0})
+	require.NoError(t, err)
+
+	cp := CompressProof(hasher, proof)
+	assert.Len(t, cp.Hashes, 1, "only the subtree containing b is not empty")
+	assert.Len(t, cp.Empty, len(proof.Hashes))
+	decompressed, err := cp.Decompress(hasher)
+	require.NoError(t, err)
+	assert.Equal(t, proof, decompressed)
+
+	// nothing to compress
+	full, err := NewTree(hasher, [][32]byte{a, b, c, d})
+	require.NoError(t, err)
+	proof, err = full.Prove([]int{1})
+	require.NoError(t, err)
+	cp = CompressProof(hasher, proof)
+	assert.Nil(t, cp.Empty)
+	assert.Equal(t, proof.Hashes, cp.Hashes)
+	decompressed, err = cp.Decompress(hasher)
+	require.NoError(t, err)
+	assert.Equal(t, proof, decompressed)
+
+	cp.Empty = []uint8{0, 0, 0}
+	_, err = cp.Decompress(hasher)
+	require.Error(t, err)
+	cp.Empty = []uint8{0, maxEmptyHeight + 1}
+	_, err = cp.Decompress(hasher)
+	require.Error(t, err)
+}
@@ -77,6 +77,8 @@ func proveSingleLayer[H hashutil.Hash](layer []H, indices []int) (singleLayerPro
 }
 
 type Tree[H hashutil.Hash] struct {
+	hasher hashutil.Hasher[H]
+	leaves int
 	layers [][]H
 }
 
@@ -96,10 +98,31 @@ func NewTree[H hashutil.Hash](hasher hashutil.Hasher[H], leafHashes []H) (*Tree[
 		layer = nextLayer
 	}
 	return &Tree[H]{
+		hasher: hasher,
+		leaves: len(leafHashes),
 		layers: layers,
 	}, nil
 }
 
+// NewSparseTree constructs a tree of numLeaves leaves, where only the given leafHashes are set, by index.
+// All other leaves are hasher.ZeroHash(), so proofs include many empty subtree hashes; see CompressProof.
+func NewSparseTree[H hashutil.Hash](hasher hashutil.Hasher[H], numLeaves int, leafHashes map[int]H) (*Tree[H], error) {
+	if numLeaves <= 0 {
+		return nil, errors.New("Cannot construct a tree without leaves")
+	}
+	leaves := make([]H, numLeaves)
+	for i := range leaves {
+		leaves[i] = hasher.ZeroHash()
+	}
+	for i, h := range leafHashes {
+		if i < 0 || i >= numLeaves {
+			return nil, fmt.Errorf("leaf index %d is out of bounds", i)
+		}
+		leaves[i] = h
+	}
+	return NewTree(hasher, leaves)
+}
+
 // Revive appears confused with the generics "receiver name t should be consistent with previous receiver name p for invalid-type"
 //
 //revive:disable:receiver-naming
@@ -115,6 +138,41 @@ func (t *Tree[H]) Root() H {
 	return t.layers[len(t.layers)-1][0]
 }
 
+// Len returns the number of leaves, excluding padding.
+func (t *Tree[H]) Len() int {
+	return t.leaves
+}
+
+// Append adds a leaf to the end of the tree, and updates the root in O(log n) by only recomputing the new leaf's
+// ancestors. The resulting tree is identical to one constructed by NewTree with all leaves. Trees are limited to
+// MaxNumberTreeLeaves, since larger ones can't be verified.
+func (t *Tree[H]) Append(leafHash H) error {
+	if t.leaves >= MaxNumberTreeLeaves {
+		return fmt.Errorf("tree has the maximum number of leaves %d", MaxNumberTreeLeaves)
+	}
+	t.leaves++
+	// The new node is always the last non-padding node of its layer.
+	node, size := leafHash, t.leaves
+	for k := 0; ; k++ {
+		if k == len(t.layers) {
+			t.layers = append(t.layers, nil)
+		}
+		layer := append(t.layers[k][:size-1], node)
+		if size == 1 {
+			t.layers[k] = layer
+			t.layers = t.layers[:k+1]
+			return nil
+		}
+		if size%2 != 0 {
+			layer = append(layer, t.hasher.ZeroHash())
+		}
+		t.layers[k] = layer
+		idx := size - 1
+		node = t.hasher.HashInternal(layer[idx&^1], layer[idx|1])
+		size = (size + 1) / 2
+	}
+}
+
 func (t *Tree[H]) Prove(indices []int) (Proof[H], error) {
 	var proof Proof[H]
 	for _, layer := range t.layers[:len(t.layers)-1] {
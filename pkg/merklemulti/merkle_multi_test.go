@@ -172,3 +172,50 @@ func TestMerkleMultiProof(t *testing.T) {
 		require.Error(t, err)
 	})
 }
+
+func TestTree_Append(t *testing.T) {
+	var leaves [][32]byte
+	for i := range 40 {
+		leaves = append(leaves, hasher.Hash([]byte{byte(i)}))
+	}
+	tr, err := NewTree(hasher, leaves[:1])
+	require.NoError(t, err)
+	for n := 2; n <= len(leaves); n++ {
+		require.NoError(t, tr.Append(leaves[n-1]))
+		expected, err := NewTree(hasher, leaves[:n])
+		require.NoError(t, err)
+		require.Equal(t, expected.layers, tr.layers, "size %d", n)
+		require.Equal(t, n, tr.Len())
+
+		proof, err := tr.Prove([]int{0, n - 1})
+		require.NoError(t, err)
+		root, err := VerifyComputeRoot(hasher, [][32]byte{leaves[0], leaves[n-1]}, proof)
+		require.NoError(t, err)
+		require.Equal(t, expected.Root(), root)
+	}
+
+	t.Run("limits the number of leaves", func(t *testing.T) {
+		tr, err := NewTree(hasher, make([][32]byte, MaxNumberTreeLeaves-1))
+		require.NoError(t, err)
+		require.NoError(t, tr.Append(leaves[0]))
+		require.Equal(t, MaxNumberTreeLeaves, tr.Len())
+		root := tr.Root()
+		require.Error(t, tr.Append(leaves[1]))
+		require.Equal(t, MaxNumberTreeLeaves, tr.Len())
+		require.Equal(t, root, tr.Root())
+	})
+}
+
+func TestNewSparseTree(t *testing.T) {
+	tr, err := NewSparseTree(hasher, 5, map[int][32]byte{1: b, 4: e})
+	require.NoError(t, err)
+	z := hasher.ZeroHash()
+	expected, err := NewTree(hasher, [][32]byte{z, b, z, z, e})
+	require.NoError(t, err)
+	assert.Equal(t, expected.Root(), tr.Root())
+
+	_, err = NewSparseTree(hasher, 5, map[int][32]byte{5: a})
+	require.Error(t, err)
+	_, err = NewSparseTree(hasher, 0, nil)
+	require.Error(t, err)
+}
@@ -0,0 +1,162 @@
+package grpc
+
+import (
+	"context"
+	"slices"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/nodeauth/types"
+)
+
+// Authenticator validates a node JWT. It is implemented by nodeauth/jwt.NodeJWTAuthenticator.
+type Authenticator interface {
+	AuthenticateJWT(ctx context.Context, tokenString string, originalRequest any) (valid bool, claims *types.NodeJWTClaims, err error)
+}
+
+// Generator creates a node JWT. It is implemented by nodeauth/jwt.NodeJWTGenerator.
+type Generator interface {
+	CreateJWTForRequest(req any) (string, error)
+}
+
+// methodGenerator is implemented by generators which support binding tokens to a method, like
+// nodeauth/jwt.NodeJWTGenerator.
+type methodGenerator interface {
+	CreateJWTForMethod(method string, req any) (string, error)
+}
+
+// ClaimsFromContext returns the verified claims of the node calling the RPC being handled.
+func ClaimsFromContext(ctx context.Context) (*types.NodeJWTClaims, bool) {
+	return types.ClaimsFromContext(ctx)
+}
+
+type serverConfig struct {
+	skip []string
+}
+
+// ServerOpt configures the server interceptors.
+type ServerOpt func(*serverConfig)
+
+// WithUnauthenticatedMethods exempts full method names, e.g. "/grpc.health.v1.Health/Check", from authentication.
+func WithUnauthenticatedMethods(methods ...string) ServerOpt {
+	return func(c *serverConfig) {
+		c.skip = append(c.skip, methods...)
+	}
+}
+
+func newServerConfig(opts []ServerOpt) serverConfig {
+	var c serverConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return c
+}
+
+// authenticate validates the bearer token against req, and returns a context with the claims.
+func authenticate(ctx context.Context, auth Authenticator, method string, req any) (context.Context, error) {
+	token, err := ExtractBearerToken(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	valid, claims, err := auth.AuthenticateJWT(types.WithMethod(ctx, method), token, req)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
+	}
+	if !valid {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+	return types.WithClaims(ctx, claims), nil
+}
+
+// UnaryServerInterceptor authenticates the bearer token of each RPC against its decoded request. The claims are
+// available to handlers via ClaimsFromContext, and failures are returned as codes.Unauthenticated.
+func UnaryServerInterceptor(auth Authenticator, opts ...ServerOpt) grpc.UnaryServerInterceptor {
+	cfg := newServerConfig(opts)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if slices.Contains(cfg.skip, info.FullMethod) {
+			return handler(ctx, req)
+		}
+		ctx, err := authenticate(ctx, auth, info.FullMethod, req)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor authenticates the bearer token of each stream. Since the token is sent before any messages,
+// the digest of stream tokens is of the full method name rather than a request. See StreamClientInterceptor.
+func StreamServerInterceptor(auth Authenticator, opts ...ServerOpt) grpc.StreamServerInterceptor {
+	cfg := newServerConfig(opts)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if slices.Contains(cfg.skip, info.FullMethod) {
+			return handler(srv, ss)
+		}
+		ctx, err := authenticate(ss.Context(), auth, info.FullMethod, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context { return s.ctx }
+
+// createToken creates a token bound to method, if supported by gen. Unbound tokens are rejected by
+// nodeauth/jwt.NodeJWTAuthenticator, but may be accepted by other Authenticators.
+func createToken(gen Generator, method string, req any) (string, error) {
+	if mg, ok := gen.(methodGenerator); ok {
+		return mg.CreateJWTForMethod(method, req)
+	}
+	return gen.CreateJWTForRequest(req)
+}
+
+// tokenCredentials mints a fresh token for each attempt of an RPC. Tokens are single use, so retries must not resend
+// the token of a previous attempt.
+type tokenCredentials struct {
+	gen    Generator
+	method string
+	req    any
+}
+
+var _ credentials.PerRPCCredentials = tokenCredentials{}
+
+func (c tokenCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	token, err := createToken(c.gen, c.method, c.req)
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "failed to create token: %v", err)
+	}
+	return map[string]string{AuthorizationHeader: BearerPrefix + token}, nil
+}
+
+// RequireTransportSecurity is false, since the transport is configured separately from the interceptors.
+func (tokenCredentials) RequireTransportSecurity() bool { return false }
+
+// withTokens appends a call option which attaches a fresh token for req to each attempt.
+func withTokens(opts []grpc.CallOption, gen Generator, method string, req any) []grpc.CallOption {
+	return append(slices.Clip(opts), grpc.PerRPCCredentials(tokenCredentials{gen: gen, method: method, req: req}))
+}
+
+// UnaryClientInterceptor attaches a fresh token for each attempt of an RPC's request, including retries, as expected by
+// UnaryServerInterceptor.
+func UnaryClientInterceptor(gen Generator) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(ctx, method, req, reply, cc, withTokens(opts, gen, method, req)...)
+	}
+}
+
+// StreamClientInterceptor attaches a fresh token for each attempt of a stream's method, including retries, as expected
+// by StreamServerInterceptor.
+func StreamClientInterceptor(gen Generator) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(ctx, desc, cc, method, withTokens(opts, gen, method, method)...)
+	}
+}
@@ -0,0 +1,208 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/nodeauth/types"
+	"github.com/smartcontractkit/chainlink-common/pkg/nodeauth/utils"
+)
+
+// testGenerator creates unique tokens of the form "<method>|<digest>|<id>".
+type testGenerator struct{}
+
+var testTokenID atomic.Int64
+
+func (g testGenerator) CreateJWTForRequest(req any) (string, error) {
+	return g.CreateJWTForMethod("", req)
+}
+
+func (testGenerator) CreateJWTForMethod(method string, req any) (string, error) {
+	return fmt.Sprintf("%s|%s|%d", method, utils.CalculateRequestDigest(req), testTokenID.Add(1)), nil
+}
+
+// testAuthenticator accepts tokens from testGenerator, and rejects replays.
+type testAuthenticator struct {
+	seen sync.Map
+}
+
+func (a *testAuthenticator) AuthenticateJWT(ctx context.Context, token string, req any) (bool, *types.NodeJWTClaims, error) {
+	parts := strings.Split(token, "|")
+	if len(parts) != 3 {
+		return false, nil, errors.New("invalid token")
+	}
+	method, digest := parts[0], parts[1]
+	if digest != utils.CalculateRequestDigest(req) {
+		return false, nil, errors.New("digest mismatch")
+	}
+	if method != types.MethodFromContext(ctx) {
+		return false, nil, errors.New("method mismatch")
+	}
+	if _, replayed := a.seen.LoadOrStore(token, struct{}{}); replayed {
+		return false, nil, errors.New("token replayed")
+	}
+	return true, &types.NodeJWTClaims{PublicKey: "node", Digest: digest, Method: method}, nil
+}
+
+// claimsHealthServer reports SERVING only if the claims are in the context.
+type claimsHealthServer struct {
+	healthpb.UnimplementedHealthServer
+}
+
+func (claimsHealthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if _, ok := ClaimsFromContext(ctx); !ok {
+		return nil, errors.New("missing claims")
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+func (claimsHealthServer) Watch(req *healthpb.HealthCheckRequest, stream grpc.ServerStreamingServer[healthpb.HealthCheckResponse]) error {
+	claims, ok := ClaimsFromContext(stream.Context())
+	if !ok {
+		return errors.New("missing claims")
+	}
+	if claims.Method != healthpb.Health_Watch_FullMethodName {
+		return errors.New("wrong method")
+	}
+	return stream.Send(&healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING})
+}
+
+func startServer(t *testing.T, opts ...ServerOpt) *bufconn.Listener {
+	return startServerWith(t, nil, opts...)
+}
+
+// startServerWith starts a server, which calls next after authenticating unary RPCs, if not nil.
+func startServerWith(t *testing.T, next grpc.UnaryServerInterceptor, opts ...ServerOpt) *bufconn.Listener {
+	lis := bufconn.Listen(1 << 20)
+	auth := &testAuthenticator{}
+	unary := []grpc.UnaryServerInterceptor{UnaryServerInterceptor(auth, opts...)}
+	if next != nil {
+		unary = append(unary, next)
+	}
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.StreamInterceptor(StreamServerInterceptor(auth, opts...)),
+	)
+	healthpb.RegisterHealthServer(srv, claimsHealthServer{})
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	return lis
+}
+
+func dial(t *testing.T, lis *bufconn.Listener, opts ...grpc.DialOption) healthpb.HealthClient {
+	opts = append(opts,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+	)
+	conn, err := grpc.NewClient("passthrough:///bufnet", opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestInterceptors(t *testing.T) {
+	lis := startServer(t)
+	client := dial(t, lis,
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(testGenerator{})),
+		grpc.WithStreamInterceptor(StreamClientInterceptor(testGenerator{})),
+	)
+
+	resp, err := client.Check(t.Context(), &healthpb.HealthCheckRequest{Service: "svc"})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	stream, err := client.Watch(t.Context(), &healthpb.HealthCheckRequest{Service: "svc"})
+	require.NoError(t, err)
+	resp, err = stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+}
+
+func TestInterceptors_unauthenticated(t *testing.T) {
+	lis := startServer(t)
+
+	t.Run("missing token", func(t *testing.T) {
+		client := dial(t, lis)
+		_, err := client.Check(t.Context(), &healthpb.HealthCheckRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+		stream, err := client.Watch(t.Context(), &healthpb.HealthCheckRequest{})
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("wrong request", func(t *testing.T) {
+		// a token for a different request
+		token, err := testGenerator{}.CreateJWTForMethod(healthpb.Health_Check_FullMethodName, &healthpb.HealthCheckRequest{Service: "other"})
+		require.NoError(t, err)
+		client := dial(t, lis, grpc.WithUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			ctx = metadata.AppendToOutgoingContext(ctx, AuthorizationHeader, BearerPrefix+token)
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}))
+		_, err = client.Check(t.Context(), &healthpb.HealthCheckRequest{Service: "svc"})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		assert.ErrorContains(t, err, "digest mismatch")
+	})
+
+	t.Run("generator error", func(t *testing.T) {
+		client := dial(t, lis, grpc.WithUnaryInterceptor(UnaryClientInterceptor(failingGenerator{})))
+		_, err := client.Check(t.Context(), &healthpb.HealthCheckRequest{})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+}
+
+func TestInterceptors_retry(t *testing.T) {
+	var attempts atomic.Int32
+	lis := startServerWith(t, func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if attempts.Add(1) == 1 {
+			return nil, status.Error(codes.Unavailable, "try again")
+		}
+		return handler(ctx, req)
+	})
+	client := dial(t, lis,
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor(testGenerator{})),
+		grpc.WithDefaultServiceConfig(`{"methodConfig":[{"name":[{"service":"grpc.health.v1.Health"}],"retryPolicy":{
+			"maxAttempts":2,"initialBackoff":"0.01s","maxBackoff":"0.01s","backoffMultiplier":1,
+			"retryableStatusCodes":["UNAVAILABLE"]}}]}`),
+	)
+
+	resp, err := client.Check(t.Context(), &healthpb.HealthCheckRequest{Service: "svc"})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+	assert.Equal(t, int32(2), attempts.Load())
+}
+
+type failingGenerator struct{}
+
+func (failingGenerator) CreateJWTForRequest(any) (string, error) { return "", errors.New("no signer") }
+
+func TestWithUnauthenticatedMethods(t *testing.T) {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(grpc.UnaryInterceptor(UnaryServerInterceptor(&testAuthenticator{},
+		WithUnauthenticatedMethods(healthpb.Health_Check_FullMethodName))))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	resp, err := dial(t, lis).Check(t.Context(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+}
@@ -3,6 +3,7 @@ package jwt
 import (
 	"context"
 	"crypto/ed25519"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -23,6 +24,13 @@ type NodeJWTAuthenticatorConfig struct {
 	Logger  logger.Logger
 	// Leeway is the time leeway for JWT validation to address clock skew between systems.
 	Leeway time.Duration
+	// ReplayCache, if set, records used tokens, so that they are rejected if replayed. Otherwise, tokens may be reused
+	// until they expire. See NewMemoryReplayCache and pgreplay.
+	ReplayCache ReplayCache // optional
+	// RequireTokenID rejects tokens without an ID (jti) claim. Otherwise, such tokens are identified by their hash.
+	RequireTokenID bool
+	// Audience, if set, rejects tokens which do not include it in their audience claim. See WithAudience.
+	Audience string
 }
 
 type nodeJWTLogger interface {
@@ -35,6 +43,8 @@ type nodeJWTLogger interface {
 type NodeJWTAuthenticator struct {
 	nodeAuthProvider NodeAuthProvider // Source of truth to validate public key in the JWT claim.
 	parser           *jwt.Parser      // JWT parser to parse the JWT token.
+	replayCache      ReplayCache      // Tokens which have already been used. Optional.
+	requireTokenID   bool
 	logger           nodeJWTLogger
 }
 
@@ -57,12 +67,17 @@ func (config NodeJWTAuthenticatorConfig) New(nodeAuthProvider NodeAuthProvider)
 	if config.Leeway > 0 {
 		parserOpts = append(parserOpts, jwt.WithLeeway(config.Leeway))
 	}
+	if config.Audience != "" {
+		parserOpts = append(parserOpts, jwt.WithAudience(config.Audience))
+	}
 
 	parser := jwt.NewParser(parserOpts...)
 
 	return &NodeJWTAuthenticator{
 		nodeAuthProvider: nodeAuthProvider,
 		parser:           parser,
+		replayCache:      config.ReplayCache,
+		requireTokenID:   config.RequireTokenID,
 		logger:           config.buildLogger(),
 	}
 }
@@ -82,6 +97,15 @@ func resolveNodeJWTConfig(configs ...*NodeJWTAuthenticatorConfig) NodeJWTAuthent
 		if cfg.Leeway > 0 {
 			resolved.Leeway = cfg.Leeway
 		}
+		if cfg.ReplayCache != nil {
+			resolved.ReplayCache = cfg.ReplayCache
+		}
+		if cfg.RequireTokenID {
+			resolved.RequireTokenID = true
+		}
+		if cfg.Audience != "" {
+			resolved.Audience = cfg.Audience
+		}
 	}
 	return resolved
 }
@@ -97,7 +121,9 @@ func (config NodeJWTAuthenticatorConfig) buildLogger() nodeJWTLogger {
 }
 
 // 1. Standard JWT Validation: validate the JWT claims and signature against public key.
-// 2. Public Key Whitelist Validation: validate the node's public key is trusted.
+// 2. Binding Validation: validate the request digest, and the method if set via WithMethod.
+// 3. Public Key Whitelist Validation: validate the node's public key is trusted.
+// 4. Replay Validation: validate the token has not been used before, if a ReplayCache is configured.
 func (v *NodeJWTAuthenticator) AuthenticateJWT(ctx context.Context, tokenString string, originalRequest any) (bool, *types.NodeJWTClaims, error) {
 	// Parse JWT claims
 	claims, err := v.parseJWTClaims(tokenString)
@@ -121,6 +147,11 @@ func (v *NodeJWTAuthenticator) AuthenticateJWT(ctx context.Context, tokenString
 		return false, claims, fmt.Errorf("request integrity check failed: %w", err)
 	}
 
+	// Verify method binding
+	if err := v.verifyMethod(ctx, claims); err != nil {
+		return false, claims, err
+	}
+
 	// Public Key Validation: Verify node's CSA pubkey against the whitelisted registry via NodeAuthProvider.
 	isValid, err := v.nodeAuthProvider.IsNodePubKeyTrusted(ctx, publicKey)
 	if err != nil {
@@ -138,6 +169,15 @@ func (v *NodeJWTAuthenticator) AuthenticateJWT(ctx context.Context, tokenString
 		return false, claims, fmt.Errorf("unauthorized node: %s", hex.EncodeToString(publicKey))
 	}
 
+	// Replay Validation: only after all other checks, so that invalid tokens are not recorded.
+	if err := v.verifyNotReplayed(ctx, tokenString, claims); err != nil {
+		v.logger.Warnw("Rejected JWT",
+			"csaPubKey", hex.EncodeToString(publicKey),
+			"error", err,
+		)
+		return false, claims, err
+	}
+
 	v.logger.Debugw("JWT validation successful",
 		"csaPubKey", hex.EncodeToString(publicKey),
 	)
@@ -191,3 +231,51 @@ func (v *NodeJWTAuthenticator) verifyRequestDigest(claims *types.NodeJWTClaims,
 
 	return nil
 }
+
+// WithMethod returns a context for authenticating a request to method. Tokens must be bound to the same method via
+// NodeJWTGenerator.CreateJWTForMethod.
+func WithMethod(ctx context.Context, method string) context.Context {
+	return types.WithMethod(ctx, method)
+}
+
+// verifyMethod ensures the token is bound to the method set via WithMethod, if any.
+func (v *NodeJWTAuthenticator) verifyMethod(ctx context.Context, claims *types.NodeJWTClaims) error {
+	method := types.MethodFromContext(ctx)
+	if method == "" {
+		return nil
+	}
+	if claims.Method == "" {
+		return fmt.Errorf("missing method: token must be bound to %s", method)
+	}
+	if claims.Method != method {
+		return fmt.Errorf("method mismatch: token is for %s, not %s", claims.Method, method)
+	}
+	return nil
+}
+
+// verifyNotReplayed records the token in the replay cache, if any, and fails if it was already recorded.
+func (v *NodeJWTAuthenticator) verifyNotReplayed(ctx context.Context, tokenString string, claims *types.NodeJWTClaims) error {
+	id := claims.ID
+	if id == "" && v.requireTokenID {
+		return errors.New("missing token ID")
+	}
+	if v.replayCache == nil {
+		return nil
+	}
+	if id == "" {
+		hash := sha256.Sum256([]byte(tokenString))
+		id = "sha256:" + hex.EncodeToString(hash[:])
+	}
+	// Token IDs are only unique per node.
+	key := claims.PublicKey + "/" + id
+
+	// Expiration is required by the parser.
+	replayed, err := v.replayCache.Seen(ctx, key, claims.ExpiresAt.Time)
+	if err != nil {
+		return fmt.Errorf("replay check failed: %w", err)
+	}
+	if replayed {
+		return fmt.Errorf("token replayed: %s", id)
+	}
+	return nil
+}
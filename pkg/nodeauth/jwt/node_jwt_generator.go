@@ -2,8 +2,10 @@ package jwt
 
 import (
 	"crypto/ed25519"
+	"crypto/rand"
 	"encoding/hex"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -17,22 +19,50 @@ import (
 type NodeJWTGenerator struct {
 	signer    *core.Ed25519Signer // The Ed25519Signer to sign the JWT (no private key exposure)
 	csaPubKey ed25519.PublicKey   // the ed25519 public key (signature key's counterpart) of the node to verify the JWT's signature.
+	audience  []string            // optional intended recipients of the JWT.
+}
+
+// NodeJWTGeneratorOpt configures optional features of a NodeJWTGenerator.
+type NodeJWTGeneratorOpt func(*NodeJWTGenerator)
+
+// WithAudience sets the audience claim, so that tokens are only accepted by the intended services.
+// See NodeJWTAuthenticatorConfig.Audience.
+func WithAudience(audience ...string) NodeJWTGeneratorOpt {
+	return func(m *NodeJWTGenerator) {
+		m.audience = audience
+	}
 }
 
 // NewNodeJWTGenerator creates a new node JWT generator
-func NewNodeJWTGenerator(signer *core.Ed25519Signer, csaPubKey ed25519.PublicKey) *NodeJWTGenerator {
-	return &NodeJWTGenerator{
+func NewNodeJWTGenerator(signer *core.Ed25519Signer, csaPubKey ed25519.PublicKey, opts ...NodeJWTGeneratorOpt) *NodeJWTGenerator {
+	m := &NodeJWTGenerator{
 		signer:    signer,
 		csaPubKey: csaPubKey,
 	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
 }
 
 // CreateJWTForRequest creates a JWT token for the given request
 func (m *NodeJWTGenerator) CreateJWTForRequest(req any) (string, error) {
+	return m.CreateJWTForMethod("", req)
+}
+
+// CreateJWTForMethod creates a JWT token for the given request, which is only valid for method.
+// See WithMethod.
+func (m *NodeJWTGenerator) CreateJWTForMethod(method string, req any) (string, error) {
 	if m.signer == nil {
 		return "", errors.New("no signer configured")
 	}
 
+	// Unique token ID for replay protection
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", fmt.Errorf("failed to generate token ID: %w", err)
+	}
+
 	// Create request digest for integrity
 	digest := utils.CalculateRequestDigest(req)
 
@@ -41,7 +71,10 @@ func (m *NodeJWTGenerator) CreateJWTForRequest(req any) (string, error) {
 	claims := types.NodeJWTClaims{
 		PublicKey: hex.EncodeToString(m.csaPubKey), // PublicKey: Node's public key to proof JWT's signature.
 		Digest:    digest,                          // Digest: Request integrity hash
+		Method:    method,                          // Method: Optional method binding
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        hex.EncodeToString(jti),         // ID: Unique token ID for replay protection
+			Audience:  m.audience,                      // Audience: Optional intended recipients
 			Issuer:    hex.EncodeToString(m.csaPubKey), // Issuer: Node's CSA Public Key  // TODO: change to DON ID if node is aware of its DON ID
 			Subject:   hex.EncodeToString(m.csaPubKey), // Subject: Node's CSA Public Key for on-chain verification of node-DON relationship.
 			ExpiresAt: jwt.NewNumericDate(now.Add(workflowJWTExpiration)),
@@ -0,0 +1,96 @@
+// Package pgreplay provides a Postgres backed [jwt.ReplayCache], so that replayed tokens are detected by every server
+// using the same database.
+package pgreplay
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/nodeauth/jwt"
+	"github.com/smartcontractkit/chainlink-common/pkg/sqlutil"
+)
+
+// DefaultSchema is the Postgres schema of the table used by a Cache, unless configured otherwise.
+const DefaultSchema = "cre"
+
+// Config holds optional configuration for a Cache.
+type Config struct {
+	// Schema is the Postgres schema containing the table. Defaults to DefaultSchema.
+	Schema string
+}
+
+func (c Config) schema() string {
+	if c.Schema == "" {
+		return pq.QuoteIdentifier(DefaultSchema)
+	}
+	return pq.QuoteIdentifier(c.Schema)
+}
+
+// TablesSQL returns the statements which create the table used by a Cache with this Config. Production databases
+// should apply them via migrations.
+func (c Config) TablesSQL() string {
+	schema := c.schema()
+	tokens := schema + ".nodeauth_jwt_replay"
+	return `
+CREATE SCHEMA IF NOT EXISTS ` + schema + `;
+CREATE TABLE IF NOT EXISTS ` + tokens + ` (
+	id TEXT PRIMARY KEY,
+	expires_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_nodeauth_jwt_replay_expires_at ON ` + tokens + ` (expires_at);`
+}
+
+// Schema creates the table used by a Cache in DefaultSchema. Production databases should apply it via migrations.
+var Schema = Config{}.TablesSQL()
+
+var _ jwt.ReplayCache = (*Cache)(nil)
+
+// Cache is a Postgres backed jwt.ReplayCache. Expired tokens are overwritten when seen again, and should otherwise be
+// removed periodically via DeleteExpired.
+type Cache struct {
+	ds     sqlutil.DataSource
+	tokens string
+	now    func() time.Time
+}
+
+// New returns a new Cache backed by ds, with its table in DefaultSchema. The table from Schema must already exist.
+func New(ds sqlutil.DataSource) *Cache {
+	return Config{}.New(ds)
+}
+
+// New returns a new Cache backed by ds. The table from TablesSQL must already exist.
+func (c Config) New(ds sqlutil.DataSource) *Cache {
+	return &Cache{ds: ds, tokens: c.schema() + ".nodeauth_jwt_replay", now: time.Now}
+}
+
+func (c *Cache) Seen(ctx context.Context, id string, expiresAt time.Time) (bool, error) {
+	// Removes the token if expired, then inserts it. No rows are inserted if the token is still live.
+	var (
+		qDelete = `DELETE FROM ` + c.tokens + ` WHERE id = $1 AND expires_at <= $2`
+		qInsert = `INSERT INTO ` + c.tokens + ` (id, expires_at) VALUES ($1, $2) ON CONFLICT (id) DO NOTHING`
+	)
+	if _, err := c.ds.ExecContext(ctx, qDelete, id, c.now()); err != nil {
+		return false, fmt.Errorf("failed to delete expired token: %w", err)
+	}
+	res, err := c.ds.ExecContext(ctx, qInsert, id, expiresAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to record token: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to record token: %w", err)
+	}
+	return n == 0, nil
+}
+
+// DeleteExpired removes expired tokens, and returns how many were removed.
+func (c *Cache) DeleteExpired(ctx context.Context) (int64, error) {
+	res, err := c.ds.ExecContext(ctx, `DELETE FROM `+c.tokens+` WHERE expires_at <= $1`, c.now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired tokens: %w", err)
+	}
+	return res.RowsAffected()
+}
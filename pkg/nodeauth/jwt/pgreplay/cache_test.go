@@ -0,0 +1,56 @@
+package pgreplay
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/sqlutil/sqltest"
+)
+
+func TestCache_Seen(t *testing.T) {
+	db := sqltest.NewDB(t, sqltest.TestURL(t))
+	_, err := db.ExecContext(t.Context(), Schema)
+	require.NoError(t, err)
+	c := New(db)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	ctx := t.Context()
+
+	seen, err := c.Seen(ctx, "node/a", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, seen)
+	seen, err = c.Seen(ctx, "node/a", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, seen)
+	_, err = c.Seen(ctx, "node/b", now.Add(time.Hour))
+	require.NoError(t, err)
+
+	now = now.Add(2 * time.Minute)
+	n, err := c.DeleteExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	seen, err = c.Seen(ctx, "node/a", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, seen, "expired")
+	seen, err = c.Seen(ctx, "node/b", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, seen)
+}
+
+func TestConfig_Schema(t *testing.T) {
+	db := sqltest.NewDB(t, sqltest.TestURL(t))
+	cfg := Config{Schema: "replay_test"}
+	_, err := db.ExecContext(t.Context(), cfg.TablesSQL())
+	require.NoError(t, err)
+	c := cfg.New(db)
+
+	seen, err := c.Seen(t.Context(), "node/a", time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, seen)
+	var n int
+	require.NoError(t, db.GetContext(t.Context(), &n, `SELECT COUNT(*) FROM replay_test.nodeauth_jwt_replay`))
+	assert.Equal(t, 1, n)
+}
@@ -0,0 +1,79 @@
+package jwt
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultReplayCacheSize is the default capacity of the in-memory replay cache.
+const DefaultReplayCacheSize = 100_000
+
+// ReplayCache records the IDs of tokens which have been used, so that they can not be replayed before they expire.
+// Implementations must be safe for concurrent use.
+type ReplayCache interface {
+	// Seen records that the token id was used, until expiresAt. It returns true if the id was already recorded and
+	// has not yet expired.
+	Seen(ctx context.Context, id string, expiresAt time.Time) (bool, error)
+}
+
+var _ ReplayCache = (*MemoryReplayCache)(nil)
+
+// MemoryReplayCache is an in-memory ReplayCache which holds at most a fixed number of tokens, evicting the least
+// recently used once full. Evicted tokens could be replayed until they expire, so the size should comfortably exceed
+// the number of tokens expected within their lifetime.
+// Only tokens seen by the same process are detected. Use a shared store, like pgreplay, for replicated servers.
+type MemoryReplayCache struct {
+	size int
+	now  func() time.Time
+
+	mu  sync.Mutex
+	ids map[string]*list.Element // of *replayEntry
+	lru list.List                // most recently used first
+}
+
+type replayEntry struct {
+	id        string
+	expiresAt time.Time
+}
+
+// NewMemoryReplayCache returns a MemoryReplayCache holding up to size tokens, or DefaultReplayCacheSize if size is not
+// positive.
+func NewMemoryReplayCache(size int) *MemoryReplayCache {
+	if size <= 0 {
+		size = DefaultReplayCacheSize
+	}
+	return &MemoryReplayCache{size: size, now: time.Now, ids: make(map[string]*list.Element)}
+}
+
+func (c *MemoryReplayCache) Seen(_ context.Context, id string, expiresAt time.Time) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := c.now()
+	if e, ok := c.ids[id]; ok {
+		entry := e.Value.(*replayEntry)
+		if now.Before(entry.expiresAt) {
+			c.lru.MoveToFront(e)
+			return true, nil
+		}
+		c.remove(e)
+	}
+	for c.lru.Len() >= c.size {
+		c.remove(c.lru.Back())
+	}
+	c.ids[id] = c.lru.PushFront(&replayEntry{id: id, expiresAt: expiresAt})
+	return false, nil
+}
+
+// Len returns the number of tokens currently held.
+func (c *MemoryReplayCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+func (c *MemoryReplayCache) remove(e *list.Element) {
+	entry := c.lru.Remove(e).(*replayEntry)
+	delete(c.ids, entry.id)
+}
@@ -0,0 +1,170 @@
+package jwt
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/nodeauth/jwt/mocks"
+)
+
+func TestMemoryReplayCache(t *testing.T) {
+	ctx := t.Context()
+	now := time.Now()
+	c := NewMemoryReplayCache(2)
+	c.now = func() time.Time { return now }
+
+	seen, err := c.Seen(ctx, "a", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, seen)
+	seen, err = c.Seen(ctx, "a", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, seen)
+
+	_, err = c.Seen(ctx, "b", now.Add(time.Minute))
+	require.NoError(t, err)
+	_, err = c.Seen(ctx, "c", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 2, c.Len())
+	seen, err = c.Seen(ctx, "a", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, seen, "least recently used was evicted")
+
+	now = now.Add(2 * time.Minute)
+	seen, err = c.Seen(ctx, "c", now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, seen, "expired")
+}
+
+func TestNodeJWTAuthenticator_replay(t *testing.T) {
+	signer, csaPubKey := createTestSigner()
+	mockProvider := &mocks.NodeAuthProvider{}
+	mockProvider.On("IsNodePubKeyTrusted", mock.Anything, csaPubKey).Return(true, nil)
+	req := mockRequest{Field: "test request"}
+
+	t.Run("reused without a replay cache", func(t *testing.T) {
+		authenticator := NodeJWTAuthenticatorConfig{Logger: createTestLogger(t)}.New(mockProvider)
+		token, err := NewNodeJWTGenerator(signer, csaPubKey).CreateJWTForRequest(req)
+		require.NoError(t, err)
+		for range 2 {
+			valid, _, err := authenticator.AuthenticateJWT(t.Context(), token, req)
+			require.NoError(t, err)
+			assert.True(t, valid)
+		}
+	})
+
+	t.Run("replayed", func(t *testing.T) {
+		authenticator := NodeJWTAuthenticatorConfig{Logger: createTestLogger(t), ReplayCache: NewMemoryReplayCache(0)}.New(mockProvider)
+		generator := NewNodeJWTGenerator(signer, csaPubKey)
+		token, err := generator.CreateJWTForRequest(req)
+		require.NoError(t, err)
+
+		valid, claims, err := authenticator.AuthenticateJWT(t.Context(), token, req)
+		require.NoError(t, err)
+		assert.True(t, valid)
+		assert.NotEmpty(t, claims.ID)
+
+		valid, _, err = authenticator.AuthenticateJWT(t.Context(), token, req)
+		require.ErrorContains(t, err, "token replayed")
+		assert.False(t, valid)
+
+		// a fresh token for the same request is fine
+		token, err = generator.CreateJWTForRequest(req)
+		require.NoError(t, err)
+		valid, _, err = authenticator.AuthenticateJWT(t.Context(), token, req)
+		require.NoError(t, err)
+		assert.True(t, valid)
+	})
+
+	t.Run("without token ID", func(t *testing.T) {
+		privateKey, csaPubKey := createValidatorTestKeys()
+		mockProvider := &mocks.NodeAuthProvider{}
+		mockProvider.On("IsNodePubKeyTrusted", mock.Anything, csaPubKey).Return(true, nil)
+		token := createValidJWT(privateKey, csaPubKey)
+		testReq := testRequest{Field: "test-request"}
+
+		authenticator := NodeJWTAuthenticatorConfig{Logger: createTestLogger(t), ReplayCache: NewMemoryReplayCache(0)}.New(mockProvider)
+		_, _, err := authenticator.AuthenticateJWT(t.Context(), token, testReq)
+		require.NoError(t, err)
+		_, _, err = authenticator.AuthenticateJWT(t.Context(), token, testReq)
+		require.ErrorContains(t, err, "token replayed", "identified by hash")
+
+		strict := NodeJWTAuthenticatorConfig{Logger: createTestLogger(t), RequireTokenID: true}.New(mockProvider)
+		_, _, err = strict.AuthenticateJWT(t.Context(), createValidJWT(privateKey, csaPubKey), testReq)
+		require.ErrorContains(t, err, "missing token ID")
+	})
+
+	t.Run("replay cache error", func(t *testing.T) {
+		authenticator := NodeJWTAuthenticatorConfig{ReplayCache: failingReplayCache{}}.New(mockProvider)
+		token, err := NewNodeJWTGenerator(signer, csaPubKey).CreateJWTForRequest(req)
+		require.NoError(t, err)
+		valid, _, err := authenticator.AuthenticateJWT(t.Context(), token, req)
+		require.ErrorContains(t, err, "replay check failed")
+		assert.False(t, valid)
+	})
+}
+
+type failingReplayCache struct{}
+
+func (failingReplayCache) Seen(context.Context, string, time.Time) (bool, error) {
+	return false, assert.AnError
+}
+
+func TestNodeJWTAuthenticator_binding(t *testing.T) {
+	signer, csaPubKey := createTestSigner()
+	mockProvider := &mocks.NodeAuthProvider{}
+	mockProvider.On("IsNodePubKeyTrusted", mock.Anything, csaPubKey).Return(true, nil)
+	req := mockRequest{Field: "test request"}
+
+	t.Run("audience", func(t *testing.T) {
+		authenticator := NodeJWTAuthenticatorConfig{Audience: "storage"}.New(mockProvider)
+
+		token, err := NewNodeJWTGenerator(signer, csaPubKey, WithAudience("storage", "gateway")).CreateJWTForRequest(req)
+		require.NoError(t, err)
+		_, claims, err := authenticator.AuthenticateJWT(t.Context(), token, req)
+		require.NoError(t, err)
+		assert.Equal(t, jwt.ClaimStrings{"storage", "gateway"}, claims.Audience)
+
+		token, err = NewNodeJWTGenerator(signer, csaPubKey, WithAudience("gateway")).CreateJWTForRequest(req)
+		require.NoError(t, err)
+		_, _, err = authenticator.AuthenticateJWT(t.Context(), token, req)
+		require.ErrorIs(t, err, jwt.ErrTokenInvalidAudience)
+
+		token, err = NewNodeJWTGenerator(signer, csaPubKey).CreateJWTForRequest(req)
+		require.NoError(t, err)
+		_, _, err = authenticator.AuthenticateJWT(t.Context(), token, req)
+		require.Error(t, err, "audience required")
+	})
+
+	t.Run("method", func(t *testing.T) {
+		authenticator := NodeJWTAuthenticatorConfig{}.New(mockProvider)
+		generator := NewNodeJWTGenerator(signer, csaPubKey)
+
+		token, err := generator.CreateJWTForMethod("/svc/Get", req)
+		require.NoError(t, err)
+		_, claims, err := authenticator.AuthenticateJWT(WithMethod(t.Context(), "/svc/Get"), token, req)
+		require.NoError(t, err)
+		assert.Equal(t, "/svc/Get", claims.Method)
+
+		token, err = generator.CreateJWTForMethod("/svc/Get", req)
+		require.NoError(t, err)
+		_, _, err = authenticator.AuthenticateJWT(WithMethod(t.Context(), "/svc/Delete"), token, req)
+		require.ErrorContains(t, err, "method mismatch")
+
+		// unbound tokens are rejected when a method is required
+		token, err = generator.CreateJWTForRequest(req)
+		require.NoError(t, err)
+		_, _, err = authenticator.AuthenticateJWT(WithMethod(t.Context(), "/svc/Delete"), token, req)
+		require.ErrorContains(t, err, "missing method")
+
+		// but accepted otherwise
+		_, claims, err = authenticator.AuthenticateJWT(t.Context(), token, req)
+		require.NoError(t, err)
+		assert.Empty(t, claims.Method)
+	})
+}
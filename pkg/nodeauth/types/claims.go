@@ -8,5 +8,7 @@ import (
 type NodeJWTClaims struct {
 	PublicKey string `json:"public_key" validate:"required"`
 	Digest    string `json:"digest" validate:"required"`
+	// Method optionally binds the token to a single method, e.g. a gRPC full method name.
+	Method string `json:"method,omitempty"`
 	jwt.RegisteredClaims
 }
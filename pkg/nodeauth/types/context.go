@@ -0,0 +1,30 @@
+package types
+
+import "context"
+
+type claimsCtxKey struct{}
+
+// WithClaims returns a context carrying the verified claims of the request being handled.
+func WithClaims(ctx context.Context, claims *NodeJWTClaims) context.Context {
+	return context.WithValue(ctx, claimsCtxKey{}, claims)
+}
+
+// ClaimsFromContext returns the verified claims of the request being handled, if it was authenticated.
+func ClaimsFromContext(ctx context.Context) (*NodeJWTClaims, bool) {
+	claims, ok := ctx.Value(claimsCtxKey{}).(*NodeJWTClaims)
+	return claims, ok && claims != nil
+}
+
+type methodCtxKey struct{}
+
+// WithMethod returns a context for authenticating a request to method. Tokens bound to a different method are
+// rejected.
+func WithMethod(ctx context.Context, method string) context.Context {
+	return context.WithValue(ctx, methodCtxKey{}, method)
+}
+
+// MethodFromContext returns the method set by WithMethod, or "" if none.
+func MethodFromContext(ctx context.Context) string {
+	method, _ := ctx.Value(methodCtxKey{}).(string)
+	return method
+}
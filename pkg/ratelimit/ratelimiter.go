@@ -1,18 +1,57 @@
 package ratelimit
 
 import (
+	"container/list"
+	"context"
 	"errors"
+	"math"
 	"sync"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
 	"golang.org/x/time/rate"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/config"
+)
+
+const (
+	// DefaultMaxSenders is the default RateLimiterConfig.MaxSenders.
+	DefaultMaxSenders = 10_000
+	// DefaultSenderIdleTimeout is the default RateLimiterConfig.SenderIdleTimeout.
+	DefaultSenderIdleTimeout = 10 * time.Minute
 )
 
+var promRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "ratelimit_requests_total",
+	Help: "Requests checked by a rate limiter, by limit (sender or global) and result (allowed or denied)",
+}, []string{"name", "limit", "result"})
+
 // Wrapper around Go's rate.Limiter that supports both global and a per-sender rate limiting.
+// Per-sender state is evicted after SenderIdleTimeout, or once the sender's burst has refilled if that takes longer, and
+// at most MaxSenders are tracked, evicting the least recently used. Since an evicted sender can't be told apart from a
+// new one, senders added while MaxSenders are tracked start with an empty burst, so eviction never grants a sender
+// more requests.
 type RateLimiter struct {
-	global    *rate.Limiter
-	perSender map[string]*rate.Limiter
-	config    RateLimiterConfig
+	name   string
+	global *rate.Limiter
+	config RateLimiterConfig
+	now    func() time.Time
+
 	mu        sync.Mutex
+	perSender map[string]*list.Element // of *senderLimiter
+	lru       list.List                // most recently used first
+
+	allowedCounter metric.Int64Counter // optional
+	deniedCounter  metric.Int64Counter // optional
+}
+
+type senderLimiter struct {
+	sender   string
+	limiter  *rate.Limiter
+	lastSeen time.Time
 }
 
 type RateLimiterConfig struct {
@@ -20,9 +59,67 @@ type RateLimiterConfig struct {
 	GlobalBurst    int     `json:"globalBurst"`
 	PerSenderRPS   float64 `json:"perSenderRPS"`
 	PerSenderBurst int     `json:"perSenderBurst"`
+
+	// MaxSenders is the maximum number of senders tracked. Defaults to DefaultMaxSenders.
+	MaxSenders int `json:"maxSenders,omitempty"`
+	// SenderIdleTimeout is how long a sender is tracked after its last request, e.g. "10m". Defaults to
+	// DefaultSenderIdleTimeout.
+	SenderIdleTimeout config.Duration `json:"senderIdleTimeout,omitzero"`
+}
+
+func (c RateLimiterConfig) maxSenders() int {
+	if c.MaxSenders <= 0 {
+		return DefaultMaxSenders
+	}
+	return c.MaxSenders
+}
+
+func (c RateLimiterConfig) senderIdleTimeout() time.Duration {
+	if d := c.SenderIdleTimeout.Duration(); d > 0 {
+		return d
+	}
+	return DefaultSenderIdleTimeout
+}
+
+// senderEvictAfter returns how long a sender is tracked after its last request: the idle timeout, or the time its
+// burst takes to refill if longer, so that evicting an idle sender doesn't reset its limit.
+func (c RateLimiterConfig) senderEvictAfter() time.Duration {
+	timeout := c.senderIdleTimeout()
+	refill := float64(c.PerSenderBurst) / c.PerSenderRPS * float64(time.Second)
+	if refill >= float64(math.MaxInt64) {
+		return time.Duration(math.MaxInt64)
+	}
+	return max(timeout, time.Duration(refill))
 }
 
-func NewRateLimiter(config RateLimiterConfig) (*RateLimiter, error) {
+// Option configures optional features of a RateLimiter.
+type Option func(*RateLimiter) error
+
+// WithName sets the name used to label metrics.
+func WithName(name string) Option {
+	return func(rl *RateLimiter) error {
+		rl.name = name
+		return nil
+	}
+}
+
+// WithMeter emits Open Telemetry counters in addition to Prometheus:
+//   - ratelimit.allowed - int counter
+//   - ratelimit.denied - int counter
+//
+// Both have a "limit" attribute of either "sender" or "global", and a "name" attribute if set via WithName.
+func WithMeter(meter metric.Meter) Option {
+	return func(rl *RateLimiter) (err error) {
+		rl.allowedCounter, err = meter.Int64Counter("ratelimit.allowed")
+		if err != nil {
+			return err
+		}
+		rl.deniedCounter, err = meter.Int64Counter("ratelimit.denied")
+		return err
+	}
+}
+
+func NewRateLimiter(config RateLimiterConfig, opts ...Option) (*RateLimiter, error) {
 	if config.GlobalRPS <= 0.0 || config.PerSenderRPS <= 0.0 {
 		return nil, errors.New("RPS values must be positive")
 	}
@@ -30,42 +127,161 @@ func NewRateLimiter(config RateLimiterConfig) (*RateLimiter, error) {
 		return nil, errors.New("burst values must be positive")
 	}
 
-	return &RateLimiter{
+	rl := &RateLimiter{
 		global:    rate.NewLimiter(rate.Limit(config.GlobalRPS), config.GlobalBurst),
-		perSender: make(map[string]*rate.Limiter),
+		perSender: make(map[string]*list.Element),
 		config:    config,
-	}, nil
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		if err := opt(rl); err != nil {
+			return nil, err
+		}
+	}
+	return rl, nil
 }
 
 // Allow checks that the sender is not rate limited,
 // and that there is not a global rate limit.
+// A denied request takes a token from neither limit, so a sender denied by the global limit keeps its burst.
 func (rl *RateLimiter) Allow(sender string) bool {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	senderLimiter, ok := rl.perSender[sender]
-	if !ok {
-		senderLimiter = rate.NewLimiter(rate.Limit(rl.config.PerSenderRPS), rl.config.PerSenderBurst)
-		rl.perSender[sender] = senderLimiter
-	}
-
-	return senderLimiter.Allow() && rl.global.Allow()
+	senderAllow, globalAllow, _ := rl.allow(sender)
+	return senderAllow && globalAllow
 }
 
 // Allow checks that the sender is not rate limited,
 // and that there is not a global rate limit.
 // Returns if allowed as separate outputs.
+// Unlike Allow, each limit is checked independently, so a token is taken from the sender limit even if the global limit
+// denies the request, and vice versa.
 func (rl *RateLimiter) AllowVerbose(sender string) (senderAllow bool, globalAllow bool) {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
-	senderLimiter, ok := rl.perSender[sender]
-	if !ok {
-		senderLimiter = rate.NewLimiter(rate.Limit(rl.config.PerSenderRPS), rl.config.PerSenderBurst)
-		rl.perSender[sender] = senderLimiter
+	now := rl.now()
+	senderAllow = rl.getSender(sender, now).AllowN(now, 1)
+	globalAllow = rl.global.AllowN(now, 1)
+
+	rl.record(senderAllow, "sender")
+	rl.record(globalAllow, "global")
+	return
+}
+
+// AllowRetryAfter is like Allow, but if the request is denied, it also returns how long the sender should wait before
+// retrying, e.g. for a Retry-After header.
+func (rl *RateLimiter) AllowRetryAfter(sender string) (allowed bool, retryAfter time.Duration) {
+	senderAllow, globalAllow, retryAfter := rl.allow(sender)
+	return senderAllow && globalAllow, retryAfter
+}
+
+// allow takes a token from both the sender and global limiters, or neither. If denied, retryAfter is the time until
+// both would allow the request.
+func (rl *RateLimiter) allow(sender string) (senderAllow, globalAllow bool, retryAfter time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	senderLimiter := rl.getSender(sender, now)
+
+	senderRes := senderLimiter.ReserveN(now, 1)
+	globalRes := rl.global.ReserveN(now, 1)
+	senderDelay, globalDelay := delayFrom(senderRes, now), delayFrom(globalRes, now)
+	senderAllow, globalAllow = senderDelay == 0, globalDelay == 0
+	allowed := senderAllow && globalAllow
+	if !allowed {
+		senderRes.CancelAt(now)
+		globalRes.CancelAt(now)
+		retryAfter = max(senderDelay, globalDelay)
+	}
+
+	// a limit is only recorded as allowed if the request was, since otherwise its token was returned
+	if allowed || !senderAllow {
+		rl.record(senderAllow, "sender")
+	}
+	if allowed || !globalAllow {
+		rl.record(globalAllow, "global")
+	}
+	return
+}
+
+// delayFrom returns the delay of r, which is infinite if r is not OK.
+func delayFrom(r *rate.Reservation, now time.Time) time.Duration {
+	if !r.OK() {
+		return rate.InfDuration
+	}
+	return r.DelayFrom(now)
+}
+
+func (rl *RateLimiter) record(allowed bool, limit string) {
+	result := "allowed"
+	if !allowed {
+		result = "denied"
+	}
+	promRequests.WithLabelValues(rl.name, limit, result).Inc()
+
+	counter := rl.allowedCounter
+	if !allowed {
+		counter = rl.deniedCounter
+	}
+	if counter != nil {
+		attrs := []attribute.KeyValue{attribute.String("limit", limit)}
+		if rl.name != "" {
+			attrs = append(attrs, attribute.String("name", rl.name))
+		}
+		counter.Add(context.Background(), 1, metric.WithAttributes(attrs...))
+	}
+}
+
+// getSender returns the limiter for sender, creating it if necessary, and evicts idle or excess senders.
+// The caller must hold mu.
+func (rl *RateLimiter) getSender(sender string, now time.Time) *rate.Limiter {
+	rl.evict(now)
+
+	if e, ok := rl.perSender[sender]; ok {
+		s := e.Value.(*senderLimiter)
+		s.lastSeen = now
+		rl.lru.MoveToFront(e)
+		return s.limiter
 	}
 
-	return senderLimiter.Allow(), rl.global.Allow()
+	limiter := rate.NewLimiter(rate.Limit(rl.config.PerSenderRPS), rl.config.PerSenderBurst)
+	if rl.lru.Len() >= rl.config.maxSenders() {
+		for rl.lru.Len() >= rl.config.maxSenders() {
+			rl.remove(rl.lru.Back())
+		}
+		// sender may have been evicted before, so it doesn't get a full burst
+		limiter.AllowN(now, rl.config.PerSenderBurst)
+	}
+	s := &senderLimiter{
+		sender:   sender,
+		limiter:  limiter,
+		lastSeen: now,
+	}
+	rl.perSender[sender] = rl.lru.PushFront(s)
+	return s.limiter
+}
+
+// evict removes senders which have been idle for longer than senderEvictAfter. The caller must hold mu.
+func (rl *RateLimiter) evict(now time.Time) {
+	timeout := rl.config.senderEvictAfter()
+	for e := rl.lru.Back(); e != nil; e = rl.lru.Back() {
+		if now.Sub(e.Value.(*senderLimiter).lastSeen) <= timeout {
+			return
+		}
+		rl.remove(e)
+	}
+}
+
+func (rl *RateLimiter) remove(e *list.Element) {
+	s := rl.lru.Remove(e).(*senderLimiter)
+	delete(rl.perSender, s.sender)
+}
+
+// Senders returns the number of senders currently tracked.
+func (rl *RateLimiter) Senders() int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return rl.lru.Len()
 }
 
 func (rl *RateLimiter) SetConfig(config RateLimiterConfig) {
@@ -75,8 +291,12 @@ func (rl *RateLimiter) SetConfig(config RateLimiterConfig) {
 	rl.global.SetLimit(rate.Limit(config.GlobalRPS))
 	rl.global.SetBurst(config.GlobalBurst)
 
-	for _, limiter := range rl.perSender {
+	for _, e := range rl.perSender {
+		limiter := e.Value.(*senderLimiter).limiter
 		limiter.SetLimit(rate.Limit(config.PerSenderRPS))
 		limiter.SetBurst(config.PerSenderBurst)
 	}
+	for rl.lru.Len() > config.maxSenders() {
+		rl.remove(rl.lru.Back())
+	}
 }
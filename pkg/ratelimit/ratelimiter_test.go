@@ -1,9 +1,16 @@
 package ratelimit
 
 import (
+	"encoding/json"
 	"testing"
+	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/require"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/config"
 )
 
 func TestRateLimiter_PerSender(t *testing.T) {
@@ -23,3 +30,199 @@ func TestRateLimiter_PerSender(t *testing.T) {
 	require.False(t, rl.Allow("user1"))
 	require.False(t, rl.Allow("user3"))
 }
+
+func TestRateLimiter_GlobalDenialDoesNotConsumeSender(t *testing.T) {
+	t.Parallel()
+
+	rl, err := NewRateLimiter(RateLimiterConfig{
+		GlobalRPS:      1.0,
+		GlobalBurst:    1,
+		PerSenderRPS:   0.1,
+		PerSenderBurst: 2,
+	})
+	require.NoError(t, err)
+	now := time.Now()
+	rl.now = func() time.Time { return now }
+
+	require.True(t, rl.Allow("user1"))
+	require.False(t, rl.Allow("user1"), "global limit")
+
+	now = now.Add(time.Second)
+	require.True(t, rl.Allow("user1"), "sender token was returned")
+}
+
+func TestRateLimiter_AllowVerbose(t *testing.T) {
+	t.Parallel()
+
+	rl, err := NewRateLimiter(RateLimiterConfig{
+		GlobalRPS:      1.0,
+		GlobalBurst:    1,
+		PerSenderRPS:   0.1,
+		PerSenderBurst: 2,
+	})
+	require.NoError(t, err)
+	now := time.Now()
+	rl.now = func() time.Time { return now }
+
+	senderAllow, globalAllow := rl.AllowVerbose("user1")
+	require.True(t, senderAllow)
+	require.True(t, globalAllow)
+	senderAllow, globalAllow = rl.AllowVerbose("user1")
+	require.True(t, senderAllow)
+	require.False(t, globalAllow)
+
+	// limits are checked independently, so the sender token was taken
+	now = now.Add(time.Second)
+	senderAllow, globalAllow = rl.AllowVerbose("user1")
+	require.False(t, senderAllow)
+	require.True(t, globalAllow)
+}
+
+func TestRateLimiterConfig_JSON(t *testing.T) {
+	t.Parallel()
+
+	var cfg RateLimiterConfig
+	require.NoError(t, json.Unmarshal([]byte(`{"globalRPS": 1, "globalBurst": 1, "perSenderRPS": 1, "perSenderBurst": 1, "senderIdleTimeout": "1m30s"}`), &cfg))
+	require.Equal(t, 90*time.Second, cfg.senderIdleTimeout())
+
+	b, err := json.Marshal(cfg)
+	require.NoError(t, err)
+	require.Contains(t, string(b), `"senderIdleTimeout":"1m30s"`)
+
+	b, err = json.Marshal(RateLimiterConfig{})
+	require.NoError(t, err)
+	require.NotContains(t, string(b), "senderIdleTimeout")
+}
+
+func TestRateLimiter_AllowRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	rl, err := NewRateLimiter(RateLimiterConfig{
+		GlobalRPS:      10.0,
+		GlobalBurst:    10,
+		PerSenderRPS:   0.5,
+		PerSenderBurst: 1,
+	})
+	require.NoError(t, err)
+	now := time.Now()
+	rl.now = func() time.Time { return now }
+
+	ok, retryAfter := rl.AllowRetryAfter("user1")
+	require.True(t, ok)
+	require.Zero(t, retryAfter)
+
+	ok, retryAfter = rl.AllowRetryAfter("user1")
+	require.False(t, ok)
+	require.Equal(t, 2*time.Second, retryAfter)
+
+	now = now.Add(time.Second)
+	ok, retryAfter = rl.AllowRetryAfter("user1")
+	require.False(t, ok)
+	require.Equal(t, time.Second, retryAfter, "denied requests do not extend the wait")
+}
+
+func TestRateLimiter_Eviction(t *testing.T) {
+	t.Parallel()
+
+	rl, err := NewRateLimiter(RateLimiterConfig{
+		GlobalRPS:         100.0,
+		GlobalBurst:       100,
+		PerSenderRPS:      1.0,
+		PerSenderBurst:    1,
+		MaxSenders:        2,
+		SenderIdleTimeout: *config.MustNewDuration(time.Minute),
+	})
+	require.NoError(t, err)
+	now := time.Now()
+	rl.now = func() time.Time { return now }
+
+	require.True(t, rl.Allow("user1"))
+	require.True(t, rl.Allow("user2"))
+	require.False(t, rl.Allow("user1"))
+	require.Equal(t, 2, rl.Senders())
+
+	// user2 is least recently used
+	require.False(t, rl.Allow("user3"), "senders added at capacity start with an empty burst")
+	require.Equal(t, 2, rl.Senders())
+	require.False(t, rl.Allow("user2"), "evicted sender doesn't get a full burst")
+	now = now.Add(time.Second)
+	require.True(t, rl.Allow("user2"))
+	require.True(t, rl.Allow("user3"))
+
+	now = now.Add(2 * time.Minute)
+	require.True(t, rl.Allow("user4"))
+	require.Equal(t, 1, rl.Senders(), "idle senders evicted")
+
+	rl.SetConfig(RateLimiterConfig{GlobalRPS: 100.0, GlobalBurst: 100, PerSenderRPS: 1.0, PerSenderBurst: 1, MaxSenders: 1})
+	require.Equal(t, 1, rl.Senders())
+}
+
+func TestRateLimiter_EvictionWaitsForRefill(t *testing.T) {
+	t.Parallel()
+
+	rl, err := NewRateLimiter(RateLimiterConfig{
+		GlobalRPS:         100.0,
+		GlobalBurst:       100,
+		PerSenderRPS:      0.01,
+		PerSenderBurst:    2,
+		SenderIdleTimeout: *config.MustNewDuration(time.Minute),
+	})
+	require.NoError(t, err)
+	now := time.Now()
+	rl.now = func() time.Time { return now }
+
+	require.True(t, rl.Allow("user1"))
+	require.True(t, rl.Allow("user1"))
+
+	// idle for longer than the timeout, but the burst takes 200s to refill
+	now = now.Add(2 * time.Minute)
+	require.True(t, rl.Allow("user2"))
+	require.Equal(t, 2, rl.Senders())
+	require.True(t, rl.Allow("user1"))
+	require.False(t, rl.Allow("user1"), "idle sender kept its state")
+
+	now = now.Add(201 * time.Second)
+	require.True(t, rl.Allow("user2"))
+	require.Equal(t, 1, rl.Senders(), "refilled sender evicted")
+}
+
+func TestRateLimiter_Metrics(t *testing.T) {
+	t.Parallel()
+
+	reader := sdkmetric.NewManualReader()
+	meter := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader)).Meter(t.Name())
+	rl, err := NewRateLimiter(RateLimiterConfig{
+		GlobalRPS:      1.0,
+		GlobalBurst:    1,
+		PerSenderRPS:   1.0,
+		PerSenderBurst: 1,
+	}, WithName(t.Name()), WithMeter(meter))
+	require.NoError(t, err)
+
+	require.True(t, rl.Allow("user1"))
+	require.False(t, rl.Allow("user2"))
+	require.False(t, rl.Allow("user1"))
+
+	// user2 was denied by the global limit, so its sender limit is not recorded as allowed
+	require.InDelta(t, 1, testutil.ToFloat64(promRequests.WithLabelValues(t.Name(), "sender", "allowed")), 0)
+	require.InDelta(t, 1, testutil.ToFloat64(promRequests.WithLabelValues(t.Name(), "sender", "denied")), 0)
+	require.InDelta(t, 1, testutil.ToFloat64(promRequests.WithLabelValues(t.Name(), "global", "allowed")), 0)
+	require.InDelta(t, 2, testutil.ToFloat64(promRequests.WithLabelValues(t.Name(), "global", "denied")), 0)
+
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(t.Context(), &rm))
+	require.Len(t, rm.ScopeMetrics, 1)
+	sums := map[string]int64{}
+	for _, m := range rm.ScopeMetrics[0].Metrics {
+		for _, dp := range m.Data.(metricdata.Sum[int64]).DataPoints {
+			limit, _ := dp.Attributes.Value("limit")
+			sums[m.Name+"."+limit.AsString()] = dp.Value
+		}
+	}
+	require.Equal(t, map[string]int64{
+		"ratelimit.allowed.sender": 1,
+		"ratelimit.denied.sender":  1,
+		"ratelimit.allowed.global": 1,
+		"ratelimit.denied.global":  2,
+	}, sums)
+}
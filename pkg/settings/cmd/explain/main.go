@@ -0,0 +1,123 @@
+// Command explain prints the effective value of settings for a CRE tenant, along with the scope and source file which
+// supplied each value.
+//
+//	go run ./pkg/settings/cmd/explain -dir ./config -owner 0x1234 -workflow abcd PerWorkflow.ExecutionTimeout
+//
+// If no keys are given, then every setting from cresettings.Default is explained.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/contexts"
+	"github.com/smartcontractkit/chainlink-common/pkg/settings"
+	"github.com/smartcontractkit/chainlink-common/pkg/settings/cresettings"
+)
+
+var (
+	dir      = flag.String("dir", "", "Directory containing global, org/, owner/, and workflow/ settings files")
+	format   = flag.String("format", "json", "Format of the settings files: json or toml")
+	org      = flag.String("org", "", "Org ID of the tenant")
+	owner    = flag.String("owner", "", "Owner address of the tenant")
+	workflow = flag.String("workflow", "", "Workflow ID of the tenant")
+	scope    = flag.String("scope", "workflow", "Scope for keys which are not defined in cresettings")
+
+	chainSelector = flag.Uint64("chain-selector", 0, "Chain selector for per-chain settings")
+)
+
+func main() {
+	flag.Parse()
+	if err := run(context.Background(), os.Stdout, os.Stderr, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// run writes the explanation of each key to w. Keys which fail are reported to errW, and result in an error after the
+// rest have been written.
+func run(ctx context.Context, w, errW io.Writer, keys []string) error {
+	if *dir == "" {
+		return fmt.Errorf("-dir is required")
+	}
+	var src settings.Source
+	switch *format {
+	case "json":
+		src = settings.JSONDirSource(*dir)
+	case "toml":
+		src = settings.TOMLDirSource(*dir)
+	default:
+		return fmt.Errorf("unsupported format: %s", *format)
+	}
+	defaultScope, err := settings.ParseScope(*scope)
+	if err != nil {
+		return err
+	}
+	r, err := settings.RegistryConfig{}.NewRegistry(ctx, src)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]reflect.Value)
+	collectSettings(reflect.ValueOf(&cresettings.Default).Elem(), known)
+	if len(keys) == 0 {
+		keys = slices.Sorted(maps.Keys(known))
+	}
+
+	ctx = contexts.WithCRE(ctx, contexts.CRE{Org: *org, Owner: *owner, Workflow: *workflow})
+	if *chainSelector != 0 {
+		ctx = contexts.WithChainSelector(ctx, *chainSelector)
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSCOPE\tTENANT\tSOURCE")
+	var failed int
+	for _, key := range keys {
+		var e settings.Explanation
+		if s, ok := known[key]; ok {
+			e, err = explain(ctx, s, r)
+		} else {
+			e, err = settings.ExplainScoped(ctx, r, defaultScope, key)
+		}
+		if err != nil {
+			failed++
+			fmt.Fprintf(errW, "failed to explain %s: %v\n", key, err)
+		} else if e.Default {
+			fmt.Fprintf(tw, "%s\t%s\tdefault\t\t\n", key, e.Value)
+		} else {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", key, e.Value, e.Scope, e.Tenant, e.Source)
+		}
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to explain %d of %d settings", failed, len(keys))
+	}
+	return nil
+}
+
+// explain calls the generic SettingSpec.Explain method of s, and returns the embedded Explanation.
+func explain(ctx context.Context, s reflect.Value, g settings.Getter) (settings.Explanation, error) {
+	out := s.MethodByName("Explain").Call([]reflect.Value{reflect.ValueOf(ctx), reflect.ValueOf(&g).Elem()})
+	err, _ := out[1].Interface().(error)
+	return out[0].FieldByName("Explanation").Interface().(settings.Explanation), err
+}
+
+// collectSettings walks a settings schema struct, like cresettings.Schema, and records a pointer to each setting by key.
+func collectSettings(v reflect.Value, m map[string]reflect.Value) {
+	for i := range v.NumField() {
+		f := v.Field(i).Addr()
+		if s, ok := f.Interface().(interface{ GetKey() string }); ok {
+			m[s.GetKey()] = f
+		} else if f.Elem().Kind() == reflect.Struct {
+			collectSettings(f.Elem(), m)
+		}
+	}
+}
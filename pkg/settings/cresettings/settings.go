@@ -226,6 +226,11 @@ var Default = Schema{
 		UserMetricNameLengthLimit:     Int(128),
 		UserMetricLabelsPerMetric:     Int(10),
 		UserMetricLabelValueLength:    Int(256),
+		Telemetry: telemetry{
+			LogRate:                Rate(rate.Limit(100), 1_000),
+			MetricCardinalityLimit: Int(1_000),
+			SpanSampleRatio:        Float64(1),
+		},
 		ChainAllowed: PerChainSelector(Bool(false), map[string]bool{
 			// geth-devnet2
 			"12922642891491394802": true,
@@ -472,6 +477,8 @@ type Workflows struct {
 	UserMetricLabelsPerMetric  Setting[int] `unit:"{label}"`
 	UserMetricLabelValueLength Setting[int] `unit:"{char}"`
 
+	Telemetry telemetry
+
 	ChainAllowed SettingMap[bool]
 
 	CRONTrigger cronTrigger
@@ -628,3 +635,10 @@ type consensus struct {
 type donTime struct {
 	RequestTimeout Setting[time.Duration]
 }
+
+// telemetry holds the per-workflow budgets of the beholder exporter pipeline.
+type telemetry struct {
+	LogRate                Setting[config.Rate]
+	MetricCardinalityLimit Setting[int] `unit:"{attribute_set}"`
+	SpanSampleRatio        Setting[float64]
+}
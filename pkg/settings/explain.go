@@ -0,0 +1,118 @@
+package settings
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Explainer extends Getter with provenance for each value.
+type Explainer interface {
+	Getter
+	// ExplainScoped is like GetScoped, but includes an Explanation of which layer supplied the value.
+	ExplainScoped(ctx context.Context, scope Scope, key string) (Explanation, error)
+}
+
+// SettingExplainer is implemented by SettingSpecs which can explain where their value came from, like Setting and
+// SettingMap.
+type SettingExplainer[T any] interface {
+	SettingSpec[T]
+	// Explain is like GetOrDefault, but includes an Explanation of where the value came from.
+	Explain(context.Context, Getter) (Explained[T], error)
+}
+
+var (
+	_ SettingExplainer[int] = &Setting[int]{}
+	_ SettingExplainer[int] = &SettingMap[int]{}
+)
+
+// BuiltInSource is the Explanation.Source of values which are compiled in, rather than read from a Getter, like
+// SettingMap.Values.
+const BuiltInSource = "built-in"
+
+// Explanation describes where an effective setting value came from.
+type Explanation struct {
+	// Key is the setting key, e.g. PerWorkflow.ExecutionTimeout.
+	Key string
+	// Value is the raw, unparsed value.
+	Value string
+	// Default is true if no override was found, and Value is the default.
+	Default bool
+	// Scope is the layer that supplied the value. Always ScopeGlobal for defaults.
+	Scope Scope
+	// Tenant is the tenant of Scope, or empty for ScopeGlobal.
+	Tenant string
+	// RawKey is the fully qualified key that was found, e.g. owner.0x1234.PerWorkflow.ExecutionTimeout.
+	RawKey string
+	// Source is the file which supplied the value, if known, e.g. owner/0x1234.json, or BuiltInSource.
+	Source string
+}
+
+func (e Explanation) String() string {
+	if e.Default {
+		return fmt.Sprintf("%s=%s (default)", e.Key, e.Value)
+	}
+	s := fmt.Sprintf("%s=%s (%s", e.Key, e.Value, e.Scope)
+	if e.Tenant != "" {
+		s += " " + e.Tenant
+	}
+	if e.Source != "" {
+		s += " from " + e.Source
+	}
+	return s + ")"
+}
+
+// Explained holds an effective value along with its Explanation.
+type Explained[T any] struct {
+	Value T
+	Explanation
+}
+
+// ExplainScoped returns an Explanation for key from g. If g is not an Explainer, then only the Value is known, and
+// RawKey and Source will be empty.
+func ExplainScoped(ctx context.Context, g Getter, scope Scope, key string) (Explanation, error) {
+	if e, ok := g.(Explainer); ok {
+		return e.ExplainScoped(ctx, scope, key)
+	}
+	v, err := g.GetScoped(ctx, scope, key)
+	return Explanation{Key: key, Value: v, Default: v == "", Scope: scope}, err
+}
+
+// explainRawKey returns an Explanation for value found at rawKey, which is one of the keys from Scope.rawKeys.
+// The Source is derived from the CombineJSONFiles/CombineTOMLFiles layout with file extension ext.
+func explainRawKey(key, rawKey, value, ext string) Explanation {
+	e := Explanation{Key: key, Value: value, RawKey: rawKey}
+	prefix, rest, _ := strings.Cut(rawKey, ".")
+	scope, err := ParseScope(prefix)
+	if err != nil {
+		return e // unreachable with rawKeys
+	}
+	e.Scope = scope
+	if scope == ScopeGlobal {
+		e.Source = "global" + ext
+		return e
+	}
+	e.Tenant, _, _ = strings.Cut(rest, ".")
+	e.Source = scope.String() + "/" + e.Tenant + ext
+	return e
+}
+
+// Explain is like GetOrDefault, but includes an Explanation of where the value came from.
+func (s *Setting[T]) Explain(ctx context.Context, g Getter) (Explained[T], error) {
+	def := Explained[T]{
+		Value:       s.DefaultValue,
+		Explanation: Explanation{Key: s.Key, Value: fmt.Sprint(s.DefaultValue), Default: true},
+	}
+	if g == nil {
+		return def, nil
+	}
+	e, err := ExplainScoped(ctx, g, s.Scope, s.Key)
+	if err != nil || e.Default || e.Value == "" {
+		return def, err
+	}
+	value, err := s.Parse(e.Value)
+	if err != nil {
+		return def, err
+	}
+	return Explained[T]{Value: value, Explanation: e}, nil
+}
@@ -0,0 +1,141 @@
+package settings
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/contexts"
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+)
+
+func TestExplainScoped(t *testing.T) {
+	ctx := contexts.WithCRE(t.Context(), contexts.CRE{
+		Org:      "123",
+		Owner:    "8bd112d3f8f92e41c861939545ad387307af9703",
+		Workflow: "15c631d295ef5e32deb99a10ee6804bc4af1385568f9b3363f6552ac6dbb2cef",
+	})
+	for _, tt := range []struct {
+		name   string
+		getter func(t *testing.T) Getter
+		ext    string
+	}{
+		{"json", func(t *testing.T) Getter {
+			g, err := GetterConfig{Logger: logger.Test(t)}.NewJSONGetter([]byte(configJSON))
+			require.NoError(t, err)
+			return g
+		}, ".json"},
+		{"toml", func(t *testing.T) Getter {
+			g, err := GetterConfig{Logger: logger.Test(t)}.NewTOMLGetter([]byte(configTOML))
+			require.NoError(t, err)
+			return g
+		}, ".toml"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			g := tt.getter(t)
+
+			e, err := ExplainScoped(ctx, g, ScopeGlobal, "Foo")
+			require.NoError(t, err)
+			assert.Equal(t, Explanation{Key: "Foo", Value: "5", Scope: ScopeGlobal, RawKey: "global.Foo", Source: "global" + tt.ext}, e)
+
+			e, err = ExplainScoped(ctx, g, ScopeOrg, "Bar.Baz")
+			require.NoError(t, err)
+			assert.Equal(t, Explanation{Key: "Bar.Baz", Value: "99", Scope: ScopeOrg, Tenant: "123",
+				RawKey: "org.123.Bar.Baz", Source: "org/123" + tt.ext}, e)
+
+			e, err = ExplainScoped(ctx, g, ScopeWorkflow, "Missing")
+			require.NoError(t, err)
+			assert.True(t, e.Default)
+		})
+	}
+
+	t.Run("owner", func(t *testing.T) {
+		g, err := NewJSONGetter([]byte(configJSON))
+		require.NoError(t, err)
+		e, err := ExplainScoped(ctx, g, ScopeOwner, "Foo")
+		require.NoError(t, err)
+		assert.Equal(t, ScopeOwner, e.Scope)
+		assert.Equal(t, "8bd112d3f8f92e41c861939545ad387307af9703", e.Tenant)
+		assert.Equal(t, "owner/8bd112d3f8f92e41c861939545ad387307af9703.json", e.Source)
+		assert.Equal(t, "Foo=13 (owner 8bd112d3f8f92e41c861939545ad387307af9703 from owner/8bd112d3f8f92e41c861939545ad387307af9703.json)", e.String())
+	})
+}
+
+func TestSetting_Explain(t *testing.T) {
+	g, err := NewJSONGetter([]byte(configJSON))
+	require.NoError(t, err)
+	ctx := contexts.WithCRE(t.Context(), contexts.CRE{Org: "123"})
+
+	s := Int(1)
+	s.Key = "Foo"
+	s.Scope = ScopeOrg
+	got, err := s.Explain(ctx, g)
+	require.NoError(t, err)
+	assert.Equal(t, 42, got.Value)
+	assert.Equal(t, ScopeOrg, got.Scope)
+	assert.Equal(t, "org/123.json", got.Source)
+
+	s.Key = "Missing"
+	got, err = s.Explain(ctx, g)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.Value)
+	assert.True(t, got.Default)
+	assert.Equal(t, "Missing=1 (default)", got.String())
+
+	t.Run("registry", func(t *testing.T) {
+		dir := t.TempDir()
+		writeFile(t, filepath.Join(dir, "global.json"), `{"Foo": "5"}`)
+		r, err := RegistryConfig{}.NewRegistry(t.Context(), JSONDirSource(dir))
+		require.NoError(t, err)
+
+		s.Key = "Foo"
+		s.Scope = ScopeGlobal
+		got, err := s.Explain(ctx, r)
+		require.NoError(t, err)
+		assert.Equal(t, 5, got.Value)
+		assert.Equal(t, filepath.Join(dir, "global.json"), got.Source)
+	})
+}
+
+func TestSettingMap_Explain(t *testing.T) {
+	g, err := NewJSONGetter([]byte(`{"global": {"Gas": {"Default": "10", "Values": {"2": "20"}}}}`))
+	require.NoError(t, err)
+
+	s := PerChainSelector(Int(1), map[string]int{"3": 30})
+	s.Default.Key = "Gas"
+
+	got, err := s.Explain(contexts.WithChainSelector(t.Context(), 2), g)
+	require.NoError(t, err)
+	assert.Equal(t, 20, got.Value)
+	assert.Equal(t, "global.Gas.Values.2", got.RawKey)
+
+	got, err = s.Explain(contexts.WithChainSelector(t.Context(), 3), g)
+	require.NoError(t, err)
+	assert.Equal(t, 10, got.Value)
+	assert.Equal(t, "global.Gas.Default", got.RawKey)
+
+	got, err = s.Explain(contexts.WithChainSelector(t.Context(), 3), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 30, got.Value)
+	assert.False(t, got.Default)
+	assert.Equal(t, BuiltInSource, got.Source)
+
+	got, err = s.Explain(contexts.WithChainSelector(t.Context(), 4), nil)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got.Value)
+	assert.True(t, got.Default)
+
+	t.Run("errors match GetOrDefault", func(t *testing.T) {
+		g, err := NewJSONGetter([]byte(`{"global": {"Gas": {"Default": "invalid", "Values": {"2": "invalid"}}}}`))
+		require.NoError(t, err)
+		for _, selector := range []uint64{2, 3, 4} {
+			ctx := contexts.WithChainSelector(t.Context(), selector)
+			exp, expErr := s.GetOrDefault(ctx, g)
+			got, err := s.Explain(ctx, g)
+			assert.Equal(t, expErr, err)
+			assert.Equal(t, exp, got.Value)
+		}
+	})
+}
@@ -31,6 +31,9 @@ func CombineJSONFiles(files fs.FS) ([]byte, error) {
 		Workflow jsonMap `json:"workflow"`
 	}{}
 	global, err := readJSONMap(files, "global.json")
+	if errors.Is(err, fs.ErrNotExist) {
+		global, err = make(jsonMap), nil // no global settings
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -96,6 +99,9 @@ func readJSONMaps(files fs.FS, dir string) (jsonMap, error) {
 	ms := make(jsonMap)
 	if err := fs.WalkDir(files, dir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
+			if path == dir && errors.Is(err, fs.ErrNotExist) {
+				return nil // no overrides
+			}
 			return err
 		}
 		if d.IsDir() {
@@ -128,17 +134,18 @@ func newJSONSettings(b []byte) (*jsonSettings, error) {
 	return &s, nil
 }
 
-func (s *jsonSettings) getFirst(keys ...string) (string, error) {
-	for _, k := range keys {
+// getFirst returns the first value found, and the index of its key, or -1 if none was found.
+func (s *jsonSettings) getFirst(keys ...string) (string, int, error) {
+	for i, k := range keys {
 		v, err := s.get(k)
 		if err != nil {
-			return "", err
+			return "", -1, err
 		}
 		if v != "" {
-			return v, nil
+			return v, i, nil
 		}
 	}
-	return "", nil // no values
+	return "", -1, nil // no values
 }
 
 func (s *jsonSettings) get(key string) (string, error) {
@@ -172,8 +179,8 @@ func (s *jsonSettings) get(key string) (string, error) {
 	return "", nil // no value
 }
 
-// TODO https://smartcontract-it.atlassian.net/browse/CAPPL-775
-// NewJSONRegistry with polling & subscriptions
+var _ Explainer = &jsonGetter{}
+
 type jsonGetter struct {
 	settings *jsonSettings
 	lggr     logger.Logger
@@ -198,14 +205,23 @@ func (c GetterConfig) NewJSONGetter(b []byte) (Getter, error) {
 }
 
 func (j *jsonGetter) GetScoped(ctx context.Context, scope Scope, key string) (value string, err error) {
+	e, err := j.ExplainScoped(ctx, scope, key)
+	return e.Value, err
+}
+
+func (j *jsonGetter) ExplainScoped(ctx context.Context, scope Scope, key string) (Explanation, error) {
 	keys, err := scope.rawKeys(ctx, key)
 	if err != nil {
 		tme, ok := errors.AsType[tenantMissingError](err)
 		if ok && tme.Scope.IsTenantRequired() {
-			return "", fmt.Errorf("failed to get raw keys: %w", err)
+			return Explanation{Key: key, Default: true}, fmt.Errorf("failed to get raw keys: %w", err)
 		} else {
 			j.lggr.Errorf("Settings lookup for "+key+" limited by missing tenant at scope "+tme.Scope.String(), "key", key, "err", err)
 		}
 	}
-	return j.settings.getFirst(keys...)
+	v, i, err := j.settings.getFirst(keys...)
+	if err != nil || i < 0 {
+		return Explanation{Key: key, Default: true}, err
+	}
+	return explainRawKey(key, keys[i], v, ".json"), nil
 }
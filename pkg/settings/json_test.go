@@ -6,6 +6,7 @@ import (
 	"io/fs"
 	"os"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -32,6 +33,44 @@ func TestCombineJSONFiles(t *testing.T) {
 	}
 	require.JSONEq(t, configJSON, string(b))
 }
+
+// removedFS reports removed files as not existing, as if they were deleted after being listed.
+type removedFS struct {
+	fstest.MapFS
+	removed string
+}
+
+func (r removedFS) Open(name string) (fs.File, error) {
+	if name == r.removed {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return r.MapFS.Open(name)
+}
+
+func TestCombineJSONFiles_NotExist(t *testing.T) {
+	files := fstest.MapFS{
+		"global.json":    {Data: []byte(`{"Foo": "5"}`)},
+		"owner/abc.json": {Data: []byte(`{"Foo": "13"}`)},
+	}
+
+	t.Run("missing dir", func(t *testing.T) {
+		b, err := CombineJSONFiles(files)
+		require.NoError(t, err)
+		require.JSONEq(t, `{"global": {"Foo": "5"}, "org": {}, "owner": {"abc": {"Foo": "13"}}, "workflow": {}}`, string(b))
+	})
+
+	t.Run("missing global", func(t *testing.T) {
+		b, err := CombineJSONFiles(removedFS{MapFS: files, removed: "global.json"})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"global": {}, "org": {}, "owner": {"abc": {"Foo": "13"}}, "workflow": {}}`, string(b))
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := CombineJSONFiles(removedFS{MapFS: files, removed: "owner/abc.json"})
+		require.ErrorIs(t, err, fs.ErrNotExist)
+	})
+}
+
 func Test_jsonSettings_GetScoped(t *testing.T) {
 	s, err := newJSONSettings([]byte(configJSON))
 	require.NoError(t, err)
@@ -0,0 +1,396 @@
+package limits
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+	"github.com/smartcontractkit/chainlink-common/pkg/settings"
+)
+
+// AdaptiveLimiter is a concurrency limiter which adjusts the number of calls allowed in flight, based on the latency and
+// outcome of previous calls, in the style of Netflix's concurrency-limits. Limit returns the current adaptive limit.
+type AdaptiveLimiter interface {
+	Limiter[int]
+	// Acquire admits a call if the limit has not been reached, or returns ErrorResourceLimited.
+	// The outcome of the call must be reported via the AdaptiveToken.
+	Acquire(context.Context) (AdaptiveToken, error)
+	// Wait is like Acquire, but blocks until the call is admitted, or the context has expired.
+	Wait(context.Context) (AdaptiveToken, error)
+	// InFlight returns the number of calls currently admitted.
+	InFlight(context.Context) (int, error)
+}
+
+// AdaptiveToken reports the outcome of a call admitted by an AdaptiveLimiter. Exactly one method must be called.
+type AdaptiveToken interface {
+	// Success records the latency of a successful call.
+	Success()
+	// Dropped records that the call failed due to overload, e.g. a timeout or rejection, which reduces the limit.
+	Dropped()
+	// Ignore releases the call without affecting the limit, e.g. after a failure unrelated to load.
+	Ignore()
+}
+
+// AdaptiveAlgorithm creates the AdaptiveLimit for each tenant of an AdaptiveLimiter.
+type AdaptiveAlgorithm interface {
+	// NewLimit returns a new AdaptiveLimit, which starts at no more than maxLimit.
+	NewLimit(maxLimit int) AdaptiveLimit
+}
+
+// AdaptiveLimit calculates the limit of a single tenant from samples. Calls are serialized by the AdaptiveLimiter.
+type AdaptiveLimit interface {
+	// Limit returns the current limit.
+	Limit() int
+	// Update adjusts the limit after a call which took rtt, while inFlight calls (including this one) were admitted.
+	// dropped is true if the call failed due to overload. The limit must not exceed maxLimit, so that it never grows
+	// beyond what can be used, and responds immediately once the limit is reached.
+	Update(rtt time.Duration, inFlight, maxLimit int, dropped bool)
+}
+
+// clampLimit returns limit, bounded by minLimit and maxLimit, and at least one. maxLimit takes precedence over minLimit.
+func clampLimit(limit, minLimit, maxLimit int) int {
+	return min(max(limit, minLimit, 1), max(maxLimit, 1))
+}
+
+// AIMD is an AdaptiveAlgorithm which increases the limit by one after each successful call made while at least half of
+// the limit was in use, and decreases it by BackoffRatio when a call is dropped or exceeds Timeout.
+type AIMD struct {
+	Initial      int           // default 20
+	Min          int           // default 1
+	BackoffRatio float64       // default 0.9
+	Timeout      time.Duration // default 5s
+}
+
+func (a AIMD) NewLimit(maxLimit int) AdaptiveLimit {
+	l := &aimdLimit{AIMD: a, limit: a.Initial}
+	if l.limit <= 0 {
+		l.limit = 20
+	}
+	l.limit = clampLimit(l.limit, l.Min, maxLimit)
+	if l.BackoffRatio <= 0 || l.BackoffRatio >= 1 {
+		l.BackoffRatio = 0.9
+	}
+	if l.Timeout <= 0 {
+		l.Timeout = 5 * time.Second
+	}
+	return l
+}
+
+type aimdLimit struct {
+	AIMD
+	limit int
+}
+
+func (a *aimdLimit) Limit() int { return a.limit }
+
+func (a *aimdLimit) Update(rtt time.Duration, inFlight, maxLimit int, dropped bool) {
+	if dropped || rtt > a.Timeout {
+		a.limit = int(float64(a.limit) * a.BackoffRatio)
+	} else if inFlight*2 >= a.limit {
+		a.limit++
+	}
+	a.limit = clampLimit(a.limit, a.Min, maxLimit)
+}
+
+// Gradient is an AdaptiveAlgorithm which compares the latency of each call with a long term average, and reduces the
+// limit in proportion as latency increases. While latency is stable, the limit grows by the square root of the limit, to
+// allow for some queueing.
+type Gradient struct {
+	Initial   int     // default 20
+	Min       int     // default 1
+	Smoothing float64 // weight of each new limit, default 0.2
+	Tolerance float64 // ratio of latency increase tolerated before reducing the limit, default 1.5
+	Window    int     // number of samples averaged for the long term latency, default 600
+}
+
+func (g Gradient) NewLimit(maxLimit int) AdaptiveLimit {
+	l := &gradientLimit{Gradient: g, limit: g.Initial}
+	if l.limit <= 0 {
+		l.limit = 20
+	}
+	l.limit = clampLimit(l.limit, l.Min, maxLimit)
+	if l.Smoothing <= 0 || l.Smoothing > 1 {
+		l.Smoothing = 0.2
+	}
+	if l.Tolerance < 1 {
+		l.Tolerance = 1.5
+	}
+	if l.Window <= 0 {
+		l.Window = 600
+	}
+	return l
+}
+
+type gradientLimit struct {
+	Gradient
+	limit   int
+	longRTT float64 // nanoseconds
+}
+
+func (g *gradientLimit) Limit() int { return g.limit }
+
+func (g *gradientLimit) Update(rtt time.Duration, inFlight, maxLimit int, dropped bool) {
+	short := float64(rtt)
+	if g.longRTT == 0 {
+		g.longRTT = short
+	} else {
+		g.longRTT += (short - g.longRTT) / float64(g.Window)
+	}
+
+	// don't grow while most of the limit is unused
+	if !dropped && inFlight*2 < g.limit {
+		g.limit = clampLimit(g.limit, g.Min, maxLimit)
+		return
+	}
+
+	limit := float64(g.limit)
+	next := limit / 2 // overloaded, so without any allowance for queueing
+	if !dropped && short > 0 {
+		gradient := max(0.5, min(1.0, g.Tolerance*g.longRTT/short))
+		next = limit*gradient + math.Sqrt(limit)
+	}
+	next = limit*(1-g.Smoothing) + next*g.Smoothing
+	g.limit = clampLimit(int(next), g.Min, maxLimit)
+}
+
+func newAdaptiveLimiter(f Factory, limit settings.Setting[int], alg AdaptiveAlgorithm) (AdaptiveLimiter, error) {
+	if alg == nil {
+		return nil, errors.New("missing algorithm")
+	}
+	l := &adaptiveLimiter{
+		lggr:  logger.Sugared(logger.Nop()),
+		key:   limit.Key,
+		scope: limit.Scope,
+		alg:   alg,
+		maxFn: func(ctx context.Context) (int, error) {
+			return limit.GetOrDefault(ctx, f.Settings)
+		},
+	}
+	if f.Logger != nil {
+		l.lggr = logger.Sugared(f.Logger).Named("AdaptiveLimiter").With("key", limit.Key, "scope", limit.Scope)
+	}
+	if f.Meter != nil {
+		var err error
+		l.limitGauge, err = f.Meter.Int64Gauge("concurrency."+limit.Key+".limit", metric.WithUnit(limit.Unit))
+		if err != nil {
+			return nil, err
+		}
+		l.usageGauge, err = f.Meter.Int64Gauge("concurrency."+limit.Key+".usage", metric.WithUnit(limit.Unit))
+		if err != nil {
+			return nil, err
+		}
+		l.deniedHist, err = f.Meter.Int64Histogram("concurrency."+limit.Key+".denied", metric.WithUnit(limit.Unit))
+		if err != nil {
+			return nil, err
+		}
+		l.latencyHist, err = f.Meter.Float64Histogram("concurrency."+limit.Key+".latency", metric.WithUnit("s"))
+		if err != nil {
+			return nil, err
+		}
+		l.droppedCounter, err = f.Meter.Int64Counter("concurrency."+limit.Key+".dropped", metric.WithUnit(limit.Unit))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+type adaptiveLimiter struct {
+	lggr  logger.SugaredLogger
+	key   string
+	scope settings.Scope
+	alg   AdaptiveAlgorithm
+	maxFn func(context.Context) (int, error)
+
+	limitGauge     metric.Int64Gauge       // optional
+	usageGauge     metric.Int64Gauge       // optional
+	deniedHist     metric.Int64Histogram   // optional
+	latencyHist    metric.Float64Histogram // optional
+	droppedCounter metric.Int64Counter     // optional
+
+	// opt: reap after period of non-use
+	states   sync.Map // map[string]*adaptiveState
+	isClosed atomic.Bool
+}
+
+// adaptiveState is the state of a single tenant.
+type adaptiveState struct {
+	mu       sync.Mutex
+	cond     sync.Cond
+	limit    AdaptiveLimit
+	inFlight int
+}
+
+// current returns the effective limit. The caller must hold mu.
+func (s *adaptiveState) current(maxLimit int) int {
+	return min(s.limit.Limit(), maxLimit)
+}
+
+func (l *adaptiveLimiter) Close() error {
+	if l.isClosed.Swap(true) {
+		return nil
+	}
+	l.states.Range(func(_, v any) bool {
+		s := v.(*adaptiveState)
+		s.mu.Lock()
+		s.cond.Broadcast()
+		s.mu.Unlock()
+		return true
+	})
+	return nil
+}
+
+func (l *adaptiveLimiter) cleanup(ctx context.Context) {
+	tenant := l.scope.Value(ctx)
+	if tenant == "" && l.scope != settings.ScopeGlobal {
+		l.lggr.Warnw("Unable to cleanup adaptive limiter due to missing tenant", "scope", l.scope)
+		return
+	}
+	l.states.Delete(tenant)
+}
+
+// getState returns the state for the tenant in ctx, and the current maximum limit.
+func (l *adaptiveLimiter) getState(ctx context.Context) (string, *adaptiveState, int, error) {
+	if l.isClosed.Load() {
+		return "", nil, 0, errors.New("closed")
+	}
+	var tenant string
+	if l.scope != settings.ScopeGlobal {
+		tenant = l.scope.Value(ctx)
+		if tenant == "" {
+			return "", nil, 0, fmt.Errorf("failed to get adaptive limiter: missing tenant for scope: %s", l.scope)
+		}
+	}
+	maxLimit, err := l.maxFn(ctx)
+	if err != nil {
+		l.lggr.Errorw("Failed to get limit. Using default value", "default", maxLimit, "err", err)
+	}
+	v, ok := l.states.Load(tenant)
+	if !ok {
+		s := &adaptiveState{limit: l.alg.NewLimit(maxLimit)}
+		s.cond.L = &s.mu
+		v, _ = l.states.LoadOrStore(tenant, s)
+	}
+	return tenant, v.(*adaptiveState), maxLimit, nil
+}
+
+func (l *adaptiveLimiter) Limit(ctx context.Context) (int, error) {
+	_, s, maxLimit, err := l.getState(ctx)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.current(maxLimit), nil
+}
+
+func (l *adaptiveLimiter) InFlight(ctx context.Context) (int, error) {
+	_, s, _, err := l.getState(ctx)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.inFlight, nil
+}
+
+func (l *adaptiveLimiter) Acquire(ctx context.Context) (AdaptiveToken, error) {
+	tenant, s, maxLimit, err := l.getState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if limit := s.current(maxLimit); s.inFlight >= limit {
+		if l.deniedHist != nil {
+			l.deniedHist.Record(ctx, 1, withScope(ctx, l.scope))
+		}
+		return nil, ErrorResourceLimited[int]{Key: l.key, Scope: l.scope, Tenant: tenant, Used: s.inFlight, Limit: limit, Amount: 1}
+	}
+	return l.admit(ctx, s, maxLimit), nil
+}
+
+func (l *adaptiveLimiter) Wait(ctx context.Context) (AdaptiveToken, error) {
+	_, s, maxLimit, err := l.getState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.inFlight >= s.current(maxLimit) {
+		// Ensure cond.Wait() yields to context expiration
+		stop := context.AfterFunc(ctx, func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.cond.Broadcast()
+		})
+		defer stop()
+		for s.inFlight >= s.current(maxLimit) {
+			if l.isClosed.Load() {
+				return nil, errors.New("closed")
+			}
+			s.cond.Wait()
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return l.admit(ctx, s, maxLimit), nil
+}
+
+// admit starts a call. The caller must hold s.mu.
+func (l *adaptiveLimiter) admit(ctx context.Context, s *adaptiveState, maxLimit int) *adaptiveToken {
+	s.inFlight++
+	if l.usageGauge != nil {
+		l.usageGauge.Record(ctx, int64(s.inFlight), withScope(ctx, l.scope))
+	}
+	return &adaptiveToken{ctx: context.WithoutCancel(ctx), limiter: l, state: s, maxLimit: maxLimit, start: time.Now()}
+}
+
+type adaptiveToken struct {
+	ctx      context.Context
+	limiter  *adaptiveLimiter
+	state    *adaptiveState
+	maxLimit int
+	start    time.Time
+	once     sync.Once
+}
+
+func (t *adaptiveToken) Success() { t.release(true, false) }
+
+func (t *adaptiveToken) Dropped() { t.release(true, true) }
+
+func (t *adaptiveToken) Ignore() { t.release(false, false) }
+
+func (t *adaptiveToken) release(sample, dropped bool) {
+	t.once.Do(func() {
+		l, s := t.limiter, t.state
+		rtt := time.Since(t.start)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if sample {
+			s.limit.Update(rtt, s.inFlight, t.maxLimit, dropped)
+			if l.limitGauge != nil {
+				l.limitGauge.Record(t.ctx, int64(s.current(t.maxLimit)), withScope(t.ctx, l.scope))
+			}
+			if l.latencyHist != nil && !dropped {
+				l.latencyHist.Record(t.ctx, rtt.Seconds(), withScope(t.ctx, l.scope))
+			}
+			if l.droppedCounter != nil && dropped {
+				l.droppedCounter.Add(t.ctx, 1, withScope(t.ctx, l.scope))
+			}
+		}
+		s.inFlight--
+		if l.usageGauge != nil {
+			l.usageGauge.Record(t.ctx, int64(s.inFlight), withScope(t.ctx, l.scope))
+		}
+		s.cond.Broadcast()
+	})
+}
@@ -0,0 +1,150 @@
+package limits
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/contexts"
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+	"github.com/smartcontractkit/chainlink-common/pkg/settings"
+)
+
+func TestAIMD(t *testing.T) {
+	l := AIMD{Initial: 10, Min: 2, BackoffRatio: 0.5, Timeout: time.Second}.NewLimit(100)
+	assert.Equal(t, 10, l.Limit())
+
+	l.Update(time.Millisecond, 1, 100, false)
+	assert.Equal(t, 10, l.Limit(), "mostly unused")
+	l.Update(time.Millisecond, 5, 100, false)
+	assert.Equal(t, 11, l.Limit())
+
+	l.Update(time.Millisecond, 5, 100, true)
+	assert.Equal(t, 5, l.Limit())
+	l.Update(2*time.Second, 5, 100, false)
+	assert.Equal(t, 2, l.Limit(), "timeout")
+	l.Update(time.Millisecond, 5, 100, true)
+	assert.Equal(t, 2, l.Limit(), "min")
+
+	t.Run("max", func(t *testing.T) {
+		l := AIMD{Initial: 10, BackoffRatio: 0.5}.NewLimit(4)
+		assert.Equal(t, 4, l.Limit(), "initial")
+		for range 10 {
+			l.Update(time.Millisecond, 4, 4, false)
+		}
+		assert.Equal(t, 4, l.Limit())
+		l.Update(time.Millisecond, 4, 4, true)
+		assert.Equal(t, 2, l.Limit(), "drops from the maximum")
+	})
+}
+
+func TestGradient(t *testing.T) {
+	l := Gradient{Initial: 16, Smoothing: 1, Tolerance: 1}.NewLimit(100)
+	l.Update(10*time.Millisecond, 16, 100, false)
+	assert.Equal(t, 20, l.Limit(), "steady latency grows by sqrt")
+
+	l.Update(10*time.Millisecond, 1, 100, false)
+	assert.Equal(t, 20, l.Limit(), "mostly unused")
+
+	for range 10 {
+		l.Update(time.Second, 20, 100, false)
+	}
+	assert.Less(t, l.Limit(), 20, "increased latency")
+
+	before := l.Limit()
+	l.Update(time.Millisecond, before, 100, true)
+	assert.Less(t, l.Limit(), before, "dropped")
+
+	t.Run("max", func(t *testing.T) {
+		l := Gradient{Initial: 16, Smoothing: 1, Tolerance: 1}.NewLimit(8)
+		assert.Equal(t, 8, l.Limit(), "initial")
+		for range 10 {
+			l.Update(10*time.Millisecond, 8, 8, false)
+		}
+		assert.Equal(t, 8, l.Limit())
+		l.Update(10*time.Millisecond, 1, 4, false)
+		assert.Equal(t, 4, l.Limit(), "lowered maximum")
+	})
+}
+
+func TestMakeAdaptiveLimiter(t *testing.T) {
+	t.Parallel()
+	mc := newMetricsChecker(t)
+	limit := settings.Int(3)
+	limit.Key = "Calls"
+	limit.Scope = settings.ScopeWorkflow
+	al, err := MakeAdaptiveLimiter(Factory{Logger: logger.Test(t), Meter: mc.Meter(t.Name())}, limit,
+		AIMD{Initial: 2, BackoffRatio: 0.5})
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, al.Close()) })
+
+	ctx := contexts.WithCRE(t.Context(), contexts.CRE{Workflow: "wf"})
+	l, err := al.Limit(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, l)
+
+	a, err := al.Acquire(ctx)
+	require.NoError(t, err)
+	b, err := al.Acquire(ctx)
+	require.NoError(t, err)
+	_, err = al.Acquire(ctx)
+	require.ErrorIs(t, err, ErrorResourceLimited[int]{})
+	assert.ErrorContains(t, err, "Calls resource limited for workflow[wf]: cannot use 1, already using 2/2")
+
+	// other tenants are independent
+	other, err := al.Acquire(contexts.WithCRE(t.Context(), contexts.CRE{Workflow: "other"}))
+	require.NoError(t, err)
+	other.Ignore()
+
+	// success while busy raises the limit, up to the maximum
+	a.Success()
+	a.Success() // no-op
+	l, err = al.Limit(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, l)
+	n, err := al.InFlight(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	// overload lowers the limit
+	b.Dropped()
+	l, err = al.Limit(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, l)
+
+	c, err := al.Wait(ctx)
+	require.NoError(t, err)
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = al.Wait(waitCtx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		d, err := al.Wait(ctx)
+		if assert.NoError(t, err) {
+			d.Ignore()
+		}
+	}()
+	c.Ignore()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting")
+	}
+
+	_, err = al.Acquire(t.Context())
+	require.Error(t, err, "missing tenant")
+
+	ms := mc.lastResourceFirstScopeMetric(t)
+	var names []string
+	for _, m := range ms {
+		names = append(names, m.Name)
+	}
+	assert.ElementsMatch(t, []string{"concurrency.Calls.limit", "concurrency.Calls.usage", "concurrency.Calls.latency",
+		"concurrency.Calls.dropped", "concurrency.Calls.denied"}, names)
+}
@@ -22,6 +22,17 @@ type Factory struct {
 
 	// Logger is used when parsing fails and a limit falls back to the default value.
 	Logger logger.Logger // optional
+
+	// Store is an optional source of shared state. If set, RateLimiters and ResourcePoolLimiters keep their token
+	// buckets and usage in the Store instead of process memory, so that each limit is enforced once across every
+	// process sharing the Store. Store errors are logged and fail open.
+	Store StateStore // optional
+	// StoreWaitPeriod is how often a blocked ResourcePoolLimiter.Wait retries the Store.
+	// Defaults to DefaultStoreWaitPeriod.
+	StoreWaitPeriod time.Duration // optional
+	// StoreLeaseTTL is how long resources acquired from the Store remain in use after the ResourcePoolLimiter stops
+	// renewing them, e.g. because its process crashed. Defaults to DefaultStoreLeaseTTL.
+	StoreLeaseTTL time.Duration // optional
 }
 
 // Deprecated: use MakeRateLimiter
@@ -38,6 +49,9 @@ func (f Factory) NewRateLimiter(rate settings.Setting[config.Rate]) (RateLimiter
 //   - rate.*.usage - int counter
 //   - rate.*.denied - int histogram
 func (f Factory) MakeRateLimiter(rate settings.Setting[config.Rate]) (RateLimiter, error) {
+	if f.Store != nil {
+		return f.newSharedRateLimiter(rate)
+	}
 	if rate.Scope == settings.ScopeGlobal {
 		return f.globalRateLimiter(rate)
 	}
@@ -77,6 +91,9 @@ func NewResourcePoolLimiter[N Number](f Factory, limit settings.Setting[N]) (Res
 //   - resource.*.amount - histogram
 //   - resource.*.denied - histogram
 func MakeResourcePoolLimiter[N Number](f Factory, limit settings.Setting[N]) (ResourcePoolLimiter[N], error) {
+	if f.Store != nil {
+		return newSharedResourcePoolLimiter(f, limit)
+	}
 	if limit.Scope == settings.ScopeGlobal {
 		return newGlobalResourcePoolLimiter(f, limit)
 	}
@@ -130,6 +147,38 @@ func MakeQueueLimiter[T any](f Factory, limit settings.Setting[int]) (QueueLimit
 	return newScopedQueue[T](f, limit)
 }
 
+// MakeFairQueueLimiter returns a QueueLimiter which keeps a separate queue for each tenant of depth.Scope, and serves
+// them by deficit round-robin, so that one busy tenant cannot starve the others.
+// Each tenant may queue up to depth values, and receives up to weight values per round. Both are read from Settings
+// for the tenant, at depth.Scope. Weights less than one are treated as one. Regardless of tenant, at most totalDepth
+// values may be queued, read from Settings at settings.ScopeGlobal.
+//
+// Put, and Limit require a tenant in the context, while Get and Wait serve every tenant. Len returns the length of the
+// tenant's queue if the context has a tenant, otherwise the total length.
+//
+// If Meter is set, the following metrics will be emitted, with an attribute for the tenant
+//   - queue.*.limit - int gauge
+//   - queue.*.total_limit - int gauge, without a tenant attribute
+//   - queue.*.usage - int gauge
+//   - queue.*.weight - int gauge
+//   - queue.*.wait - float histogram of seconds spent queued
+//   - queue.*.denied - int histogram
+func MakeFairQueueLimiter[T any](f Factory, depth, totalDepth, weight settings.Setting[int]) (QueueLimiter[T], error) {
+	return newFairQueue[T](f, depth, totalDepth, weight)
+}
+
+// MakeAdaptiveLimiter returns an AdaptiveLimiter which adjusts the concurrency of each tenant of limit.Scope with alg,
+// up to a maximum of limit.
+// If Meter is set, the following metrics will be emitted
+//   - concurrency.*.limit - int gauge
+//   - concurrency.*.usage - int gauge
+//   - concurrency.*.latency - float histogram of seconds
+//   - concurrency.*.dropped - int counter
+//   - concurrency.*.denied - int histogram
+func MakeAdaptiveLimiter(f Factory, limit settings.Setting[int], alg AdaptiveAlgorithm) (AdaptiveLimiter, error) {
+	return newAdaptiveLimiter(f, limit, alg)
+}
+
 // MakeGateLimiter returns a GateLimiter for the given limit and configured by the factory.
 // If Meter is set, the following metrics will be emitted
 //   - gate.*.limit - int gauge
@@ -0,0 +1,343 @@
+package limits
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+	"github.com/smartcontractkit/chainlink-common/pkg/settings"
+)
+
+func newFairQueue[T any](f Factory, depth, totalDepth, weight settings.Setting[int]) (QueueLimiter[T], error) {
+	if depth.Scope == settings.ScopeGlobal {
+		return nil, errors.New("fair queue requires a tenant scope")
+	}
+	q := &fairQueue[T]{
+		lggr:     logger.Sugared(logger.Nop()),
+		key:      depth.Key,
+		totalKey: totalDepth.Key,
+		scope:    depth.Scope,
+		tenants:  make(map[string]*fairTenant),
+		depthFn: func(ctx context.Context) (int, error) {
+			return depth.GetOrDefault(ctx, f.Settings)
+		},
+	}
+	totalDepth.Scope = settings.ScopeGlobal
+	q.totalDepthFn = func(ctx context.Context) (int, error) {
+		return totalDepth.GetOrDefault(ctx, f.Settings)
+	}
+	weight.Scope = depth.Scope
+	q.weightFn = func(ctx context.Context) (int, error) {
+		return weight.GetOrDefault(ctx, f.Settings)
+	}
+	q.cond.L = &q.mu
+
+	if f.Logger != nil {
+		q.lggr = logger.Sugared(f.Logger).Named("FairQueueLimiter").With("key", depth.Key, "scope", depth.Scope)
+	}
+
+	if f.Meter != nil {
+		var err error
+		q.limitGauge, err = f.Meter.Int64Gauge("queue."+depth.Key+".limit", metric.WithUnit(depth.Unit))
+		if err != nil {
+			return nil, err
+		}
+		q.usageGauge, err = f.Meter.Int64Gauge("queue."+depth.Key+".usage", metric.WithUnit(depth.Unit))
+		if err != nil {
+			return nil, err
+		}
+		q.weightGauge, err = f.Meter.Int64Gauge("queue." + depth.Key + ".weight")
+		if err != nil {
+			return nil, err
+		}
+		q.totalLimitGauge, err = f.Meter.Int64Gauge("queue."+depth.Key+".total_limit", metric.WithUnit(depth.Unit))
+		if err != nil {
+			return nil, err
+		}
+		q.waitHist, err = f.Meter.Float64Histogram("queue."+depth.Key+".wait", metric.WithUnit("s"))
+		if err != nil {
+			return nil, err
+		}
+		q.deniedHist, err = f.Meter.Int64Histogram("queue."+depth.Key+".denied", metric.WithUnit(depth.Unit))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return q, nil
+}
+
+type fairQueue[T any] struct {
+	lggr     logger.SugaredLogger
+	key      string
+	totalKey string
+	scope    settings.Scope
+
+	depthFn      func(context.Context) (int, error)
+	totalDepthFn func(context.Context) (int, error)
+	weightFn     func(context.Context) (int, error)
+
+	limitGauge      metric.Int64Gauge       // optional
+	totalLimitGauge metric.Int64Gauge       // optional
+	usageGauge      metric.Int64Gauge       // optional
+	weightGauge     metric.Int64Gauge       // optional
+	waitHist        metric.Float64Histogram // optional
+	deniedHist      metric.Int64Histogram   // optional
+
+	mu      sync.Mutex
+	cond    sync.Cond
+	closed  bool
+	len     int
+	tenants map[string]*fairTenant
+	active  list.List // of *fairTenant with queued values, in round-robin order
+	next    *list.Element
+}
+
+// fairTenant is the queue of a single tenant.
+type fairTenant struct {
+	name    string
+	weight  int
+	deficit int
+	values  list.List // of fairValue
+	elem    *list.Element
+}
+
+type fairValue struct {
+	value  any
+	queued time.Time
+}
+
+func (q *fairQueue[T]) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.cond.Broadcast()
+	return nil
+}
+
+func (q *fairQueue[T]) cleanup(ctx context.Context) {
+	tenant := q.scope.Value(ctx)
+	if tenant == "" {
+		q.lggr.Warnw("Unable to cleanup fair queue limiter due to missing tenant", "scope", q.scope)
+		return
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	t, ok := q.tenants[tenant]
+	if !ok {
+		return
+	}
+	if t.elem != nil {
+		q.deactivate(t)
+	}
+	q.len -= t.values.Len()
+	delete(q.tenants, tenant)
+}
+
+func (q *fairQueue[T]) tenant(ctx context.Context) (string, error) {
+	tenant := q.scope.Value(ctx)
+	if tenant == "" {
+		return "", fmt.Errorf("failed to get queue: missing tenant for scope: %s", q.scope)
+	}
+	return tenant, nil
+}
+
+func (q *fairQueue[T]) withTenant(tenant string) metric.MeasurementOption {
+	return metric.WithAttributes(attribute.String(q.scope.String(), tenant))
+}
+
+func (q *fairQueue[T]) getDepth(ctx context.Context, tenant string) int {
+	d, err := q.depthFn(ctx)
+	if err != nil {
+		q.lggr.Errorw("Failed to get limit. Using default value", "tenant", tenant, "default", d, "err", err)
+	}
+	if q.limitGauge != nil {
+		q.limitGauge.Record(ctx, int64(d), q.withTenant(tenant))
+	}
+	return d
+}
+
+func (q *fairQueue[T]) getTotalDepth(ctx context.Context) int {
+	d, err := q.totalDepthFn(ctx)
+	if err != nil {
+		q.lggr.Errorw("Failed to get total limit. Using default value", "default", d, "err", err)
+	}
+	if q.totalLimitGauge != nil {
+		q.totalLimitGauge.Record(ctx, int64(d))
+	}
+	return d
+}
+
+func (q *fairQueue[T]) getWeight(ctx context.Context, tenant string) int {
+	w, err := q.weightFn(ctx)
+	if err != nil {
+		q.lggr.Errorw("Failed to get weight. Using default value", "tenant", tenant, "default", w, "err", err)
+	}
+	w = max(w, 1)
+	if q.weightGauge != nil {
+		q.weightGauge.Record(ctx, int64(w), q.withTenant(tenant))
+	}
+	return w
+}
+
+func (q *fairQueue[T]) recordUsage(ctx context.Context, t *fairTenant) {
+	if q.usageGauge != nil {
+		q.usageGauge.Record(ctx, int64(t.values.Len()), q.withTenant(t.name))
+	}
+}
+
+func (q *fairQueue[T]) Limit(ctx context.Context) (int, error) {
+	tenant, err := q.tenant(ctx)
+	if err != nil {
+		return -1, err
+	}
+	return q.getDepth(ctx, tenant), nil
+}
+
+func (q *fairQueue[T]) Len(ctx context.Context) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	tenant := q.scope.Value(ctx)
+	if tenant == "" {
+		return q.len, nil
+	}
+	if t, ok := q.tenants[tenant]; ok {
+		return t.values.Len(), nil
+	}
+	return 0, nil
+}
+
+func (q *fairQueue[T]) Put(ctx context.Context, v T) error {
+	tenant, err := q.tenant(ctx)
+	if err != nil {
+		return err
+	}
+	depth := q.getDepth(ctx, tenant)
+	totalDepth := q.getTotalDepth(ctx)
+	weight := q.getWeight(ctx, tenant)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return errors.New("closed")
+	}
+	// a tenant is only tracked once a value is admitted, so that denied tenants don't accumulate
+	t, ok := q.tenants[tenant]
+	var queued int
+	if ok {
+		queued = t.values.Len()
+	}
+	if queued >= depth {
+		if q.deniedHist != nil {
+			q.deniedHist.Record(ctx, 1, q.withTenant(tenant))
+		}
+		return ErrorQueueFull{Key: q.key, Scope: q.scope, Tenant: tenant, Limit: depth}
+	}
+	if q.len >= totalDepth {
+		if q.deniedHist != nil {
+			q.deniedHist.Record(ctx, 1, q.withTenant(tenant))
+		}
+		return ErrorQueueFull{Key: q.totalKey, Scope: settings.ScopeGlobal, Limit: totalDepth}
+	}
+	if !ok {
+		t = &fairTenant{name: tenant}
+		q.tenants[tenant] = t
+	}
+	t.weight = weight
+	t.values.PushBack(fairValue{value: v, queued: time.Now()})
+	q.len++
+	if t.elem == nil {
+		t.elem = q.active.PushBack(t)
+	}
+	q.recordUsage(ctx, t)
+	q.cond.Signal()
+	return nil
+}
+
+func (q *fairQueue[T]) Get(ctx context.Context) (T, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.len == 0 {
+		var zero T
+		return zero, ErrQueueEmpty
+	}
+	return q.pop(ctx), nil
+}
+
+func (q *fairQueue[T]) Wait(ctx context.Context) (T, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.len == 0 {
+		// Ensure cond.Wait() yields to context expiration
+		stop := context.AfterFunc(ctx, func() {
+			q.mu.Lock()
+			defer q.mu.Unlock()
+			q.cond.Broadcast()
+		})
+		defer stop()
+		for q.len == 0 {
+			if q.closed {
+				var zero T
+				return zero, errors.New("closed")
+			}
+			q.cond.Wait()
+			if ctx.Err() != nil {
+				var zero T
+				return zero, ctx.Err()
+			}
+		}
+	}
+	return q.pop(ctx), nil
+}
+
+// pop removes the next value by deficit round-robin. The caller must hold mu, and ensure the queue is not empty.
+func (q *fairQueue[T]) pop(ctx context.Context) T {
+	if q.next == nil {
+		q.next = q.active.Front()
+	}
+	t := q.next.Value.(*fairTenant)
+	if t.deficit == 0 {
+		t.deficit = t.weight // start a new round
+	}
+	t.deficit--
+	fv := t.values.Remove(t.values.Front()).(fairValue)
+	q.len--
+	if q.waitHist != nil {
+		q.waitHist.Record(ctx, time.Since(fv.queued).Seconds(), q.withTenant(t.name))
+	}
+	q.recordUsage(ctx, t)
+	if t.values.Len() == 0 {
+		q.deactivate(t)
+	} else if t.deficit == 0 {
+		q.advance()
+	}
+	return fv.value.(T)
+}
+
+// advance moves to the next active tenant. The caller must hold mu.
+func (q *fairQueue[T]) advance() {
+	if q.next = q.next.Next(); q.next == nil {
+		q.next = q.active.Front()
+	}
+}
+
+// deactivate removes t from the round-robin, and forfeits any remaining deficit. The caller must hold mu.
+func (q *fairQueue[T]) deactivate(t *fairTenant) {
+	if q.next == t.elem {
+		q.advance()
+		if q.next == t.elem {
+			q.next = nil // last one
+		}
+	}
+	q.active.Remove(t.elem)
+	t.elem = nil
+	t.deficit = 0
+}
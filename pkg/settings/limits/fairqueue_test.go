@@ -0,0 +1,127 @@
+package limits
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/contexts"
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+	"github.com/smartcontractkit/chainlink-common/pkg/settings"
+)
+
+func TestMakeFairQueueLimiter(t *testing.T) {
+	t.Parallel()
+	g, err := settings.NewJSONGetter([]byte(`{
+	"global": {"Tasks": {"Depth": "3", "Total": "7", "Weight": "1"}},
+	"workflow": {"wf-b": {"Tasks": {"Weight": "2"}}}
+}`))
+	require.NoError(t, err)
+	mc := newMetricsChecker(t)
+	f := Factory{Settings: g, Logger: logger.Test(t), Meter: mc.Meter(t.Name())}
+
+	depth := settings.Int(10)
+	depth.Key = "Tasks.Depth"
+	depth.Scope = settings.ScopeWorkflow
+	totalDepth := settings.Int(100)
+	totalDepth.Key = "Tasks.Total"
+	weight := settings.Int(1)
+	weight.Key = "Tasks.Weight"
+	q, err := MakeFairQueueLimiter[string](f, depth, totalDepth, weight)
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, q.Close()) })
+
+	ctxA := contexts.WithCRE(t.Context(), contexts.CRE{Org: "org", Owner: "owner", Workflow: "wf-a"})
+	ctxB := contexts.WithCRE(t.Context(), contexts.CRE{Org: "org", Owner: "owner", Workflow: "wf-b"})
+	ctxC := contexts.WithCRE(t.Context(), contexts.CRE{Org: "org", Owner: "owner", Workflow: "wf-c"})
+
+	limit, err := q.Limit(ctxA)
+	require.NoError(t, err)
+	assert.Equal(t, 3, limit)
+
+	// wf-a fills its own queue, but not the others
+	for _, v := range []string{"a1", "a2", "a3"} {
+		require.NoError(t, q.Put(ctxA, v))
+	}
+	err = q.Put(ctxA, "a4")
+	require.ErrorIs(t, err, ErrorQueueFull{})
+	assert.ErrorContains(t, err, "limited for workflow[wf-a]: queue of 3 is full")
+	for _, v := range []string{"b1", "b2", "b3"} {
+		require.NoError(t, q.Put(ctxB, v))
+	}
+	require.NoError(t, q.Put(ctxC, "c1"))
+	// wf-c has room of its own, but the queue is full
+	err = q.Put(ctxC, "c2")
+	require.ErrorIs(t, err, ErrorQueueFull{})
+	assert.ErrorContains(t, err, "Tasks.Total limited: queue of 7 is full")
+	// denied tenants are not tracked
+	ctxD := contexts.WithCRE(t.Context(), contexts.CRE{Org: "org", Owner: "owner", Workflow: "wf-d"})
+	require.ErrorIs(t, q.Put(ctxD, "d1"), ErrorQueueFull{})
+	assert.NotContains(t, q.(*fairQueue[string]).tenants, "wf-d")
+
+	n, err := q.Len(ctxA)
+	require.NoError(t, err)
+	assert.Equal(t, 3, n)
+	n, err = q.Len(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, 7, n)
+
+	var got []string
+	for range 7 {
+		v, err := q.Get(t.Context())
+		require.NoError(t, err)
+		got = append(got, v)
+	}
+	// wf-b has double weight
+	assert.Equal(t, []string{"a1", "b1", "b2", "c1", "a2", "b3", "a3"}, got)
+	_, err = q.Get(t.Context())
+	require.ErrorIs(t, err, ErrQueueEmpty)
+
+	_, err = q.Limit(t.Context())
+	require.Error(t, err)
+	require.Error(t, q.Put(t.Context(), "none"))
+
+	ms := mc.lastResourceFirstScopeMetric(t)
+	var names []string
+	for _, m := range ms {
+		names = append(names, m.Name)
+	}
+	assert.ElementsMatch(t, []string{"queue.Tasks.Depth.limit", "queue.Tasks.Depth.usage", "queue.Tasks.Depth.weight",
+		"queue.Tasks.Depth.total_limit", "queue.Tasks.Depth.wait", "queue.Tasks.Depth.denied"}, names)
+
+	t.Run("global", func(t *testing.T) {
+		depth := settings.Int(1)
+		_, err := MakeFairQueueLimiter[string](f, depth, totalDepth, weight)
+		require.Error(t, err)
+	})
+}
+
+func TestFairQueue_Wait(t *testing.T) {
+	t.Parallel()
+	depth := settings.Int(2)
+	depth.Scope = settings.ScopeOwner
+	q, err := MakeFairQueueLimiter[int](Factory{}, depth, settings.Int(100), settings.Int(1))
+	require.NoError(t, err)
+
+	done := make(chan int)
+	go func() {
+		defer close(done)
+		v, err := q.Wait(t.Context())
+		assert.NoError(t, err)
+		done <- v
+	}()
+	require.NoError(t, q.Put(contexts.WithCRE(t.Context(), contexts.CRE{Owner: "o"}), 42))
+	select {
+	case v := <-done:
+		assert.Equal(t, 42, v)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for value")
+	}
+
+	TryCleanup(contexts.WithCRE(t.Context(), contexts.CRE{Owner: "o"}), q)
+	require.NoError(t, q.Close())
+	_, err = q.Wait(t.Context())
+	require.Error(t, err)
+}
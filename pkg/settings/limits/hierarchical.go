@@ -0,0 +1,235 @@
+package limits
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/config"
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+	"github.com/smartcontractkit/chainlink-common/pkg/settings"
+)
+
+// MakeHierarchicalRateLimiter returns a RateLimiter which enforces limit at every scope from settings.ScopeGlobal down
+// to limit.Scope. The rate of each level is read from the same Key at that Scope, so a level without a setting of its
+// own inherits the rate of the enclosing scope. Tokens are taken from every level atomically, only when every level
+// allows the request, and the returned ErrorRateLimited identifies the level which denied it.
+// If Meter is set, the following metrics will be emitted for each level
+//   - rate.*.limit - float gauge
+//   - rate.*.burst - int gauge
+//   - rate.*.usage - int counter
+//   - rate.*.denied - int histogram
+func (f Factory) MakeHierarchicalRateLimiter(limit settings.Setting[config.Rate]) (RateLimiter, error) {
+	h := &hierarchicalRateLimiter{
+		lggr:  logger.Sugared(logger.Nop()),
+		key:   limit.Key,
+		store: f.Store,
+	}
+	if h.store == nil {
+		h.store = NewMemoryStore()
+	}
+	if f.Logger != nil {
+		h.lggr = logger.Sugared(f.Logger).Named("HierarchicalRateLimiter").With("key", limit.Key, "scope", limit.Scope)
+	}
+	levelFactory := f
+	levelFactory.Store = h.store
+	for scope := limit.Scope; scope >= settings.ScopeGlobal; scope-- {
+		level := limit
+		level.Scope = scope
+		l, err := levelFactory.newSharedRateLimiter(level)
+		if err != nil {
+			return nil, err
+		}
+		h.levels = append(h.levels, l.(*sharedRateLimiter))
+	}
+	return h, nil
+}
+
+// hierarchicalRateLimiter is a RateLimiter which takes tokens from a bucket at each level of scope, or none at all.
+type hierarchicalRateLimiter struct {
+	lggr   logger.SugaredLogger
+	key    string
+	store  StateStore
+	levels []*sharedRateLimiter // innermost first
+}
+
+func (h *hierarchicalRateLimiter) Close() error { return nil }
+
+// Limit returns the effective rate, which is the lowest limit and burst of any level.
+func (h *hierarchicalRateLimiter) Limit(ctx context.Context) (config.Rate, error) {
+	var r config.Rate
+	for i, l := range h.levels {
+		lr := l.getRate(ctx)
+		if i == 0 {
+			r = lr
+			continue
+		}
+		r.Limit = min(r.Limit, lr.Limit)
+		r.Burst = min(r.Burst, lr.Burst)
+	}
+	return r, nil
+}
+
+// take takes n tokens from every level if they will be available within maxWait, otherwise from none.
+func (h *hierarchicalRateLimiter) take(ctx context.Context, t time.Time, n int, maxWait time.Duration) (*hierarchicalReservation, error) {
+	res := &hierarchicalReservation{ctx: context.WithoutCancel(ctx), limiter: h, n: n, readyAt: t}
+	var levels []levelTokens
+	var buckets []RateBucket
+	for _, l := range h.levels {
+		tenant, failOpen, err := sharedTenant(ctx, h.lggr, l.scope)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rate limiter: %w", err)
+		} else if failOpen {
+			continue
+		}
+		lt := levelTokens{limiter: l, key: StoreKey("rate", h.key, l.scope, tenant), tenant: tenant, rate: l.getRate(ctx)}
+		levels = append(levels, lt)
+		buckets = append(buckets, RateBucket{Key: lt.key, Rate: lt.rate})
+	}
+	delays, denied, ok, err := h.store.TakeAllTokens(ctx, buckets, n, t, maxWait)
+	if err != nil {
+		h.lggr.Errorw("Failed to take tokens from store: failing open", "err", err)
+		return res, nil
+	}
+	if !ok {
+		lt := levels[denied]
+		lt.limiter.recordDenied(ctx, n)
+		return nil, ErrorRateLimited{Key: h.key, Scope: lt.limiter.scope, Tenant: lt.tenant, N: n}
+	}
+	res.taken = levels
+	for i, delay := range delays {
+		if ready := t.Add(delay); ready.After(res.readyAt) {
+			res.readyAt, res.delayedBy = ready, i
+		}
+	}
+	for _, lt := range res.taken {
+		lt.limiter.addUsage(ctx, n)
+	}
+	return res, nil
+}
+
+// returnTokens puts n tokens back into each of the taken levels.
+func (h *hierarchicalRateLimiter) returnTokens(ctx context.Context, taken []levelTokens, n int, t time.Time) {
+	for _, lt := range taken {
+		if err := h.store.ReturnTokens(ctx, lt.key, lt.rate, n, t); err != nil {
+			h.lggr.Errorw("Failed to return tokens to store", "scope", lt.limiter.scope, "tenant", lt.tenant, "err", err)
+		}
+	}
+}
+
+func (h *hierarchicalRateLimiter) Allow(ctx context.Context) bool {
+	return h.AllowN(ctx, time.Now(), 1)
+}
+
+func (h *hierarchicalRateLimiter) AllowN(ctx context.Context, t time.Time, n int) bool {
+	return h.AllowNErr(ctx, t, n) == nil
+}
+
+func (h *hierarchicalRateLimiter) AllowErr(ctx context.Context) error {
+	return h.AllowNErr(ctx, time.Now(), 1)
+}
+
+func (h *hierarchicalRateLimiter) AllowNErr(ctx context.Context, t time.Time, n int) error {
+	_, err := h.take(ctx, t, n, 0)
+	return err
+}
+
+func (h *hierarchicalRateLimiter) Reserve(ctx context.Context) (Reservation, error) {
+	return h.ReserveN(ctx, time.Now(), 1)
+}
+
+func (h *hierarchicalRateLimiter) ReserveN(ctx context.Context, t time.Time, n int) (Reservation, error) {
+	r, err := h.take(ctx, t, n, math.MaxInt64)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (h *hierarchicalRateLimiter) Wait(ctx context.Context) error {
+	return h.WaitN(ctx, 1)
+}
+
+func (h *hierarchicalRateLimiter) WaitN(ctx context.Context, n int) error {
+	now := time.Now()
+	maxWait := time.Duration(math.MaxInt64)
+	if deadline, ok := ctx.Deadline(); ok {
+		maxWait = deadline.Sub(now)
+	}
+	r, err := h.take(ctx, now, n, maxWait)
+	if err != nil {
+		return err
+	}
+	delay := r.DelayFrom(now)
+	if delay <= 0 {
+		return nil
+	}
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return r.limitedErr(ctx.Err())
+	}
+}
+
+// levelTokens records tokens taken from a single level.
+type levelTokens struct {
+	limiter *sharedRateLimiter
+	key     string
+	tenant  string
+	rate    config.Rate
+}
+
+var _ Reservation = &hierarchicalReservation{}
+
+// hierarchicalReservation is a Reservation of tokens from every level of a hierarchicalRateLimiter.
+type hierarchicalReservation struct {
+	ctx     context.Context
+	limiter *hierarchicalRateLimiter
+	taken   []levelTokens
+	n       int
+	readyAt time.Time
+
+	delayedBy int // index of the level in taken with the longest delay
+
+	cancelOnce sync.Once
+}
+
+func (r *hierarchicalReservation) OK() bool { return true }
+
+func (r *hierarchicalReservation) Delay() time.Duration { return r.DelayFrom(time.Now()) }
+
+func (r *hierarchicalReservation) DelayFrom(t time.Time) time.Duration {
+	return max(r.readyAt.Sub(t), 0)
+}
+
+func (r *hierarchicalReservation) Cancel() { r.CancelAt(time.Now()) }
+
+func (r *hierarchicalReservation) CancelAt(t time.Time) {
+	if t.After(r.readyAt) {
+		return // already acted
+	}
+	r.cancelOnce.Do(func() {
+		r.limiter.returnTokens(r.ctx, r.taken, r.n, t)
+	})
+}
+
+func (r *hierarchicalReservation) Allow() bool { return r.Delay() <= 0 }
+
+func (r *hierarchicalReservation) AllowErr() error {
+	if r.Delay() <= 0 {
+		return nil
+	}
+	return r.limitedErr(nil)
+}
+
+// limitedErr returns an ErrorRateLimited for the level which imposed the delay.
+func (r *hierarchicalReservation) limitedErr(cause error) error {
+	lt := r.taken[r.delayedBy]
+	return ErrorRateLimited{Key: r.limiter.key, Scope: lt.limiter.scope, Tenant: lt.tenant, N: r.n, Err: cause}
+}
@@ -0,0 +1,168 @@
+package limits
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/config"
+	"github.com/smartcontractkit/chainlink-common/pkg/contexts"
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+	"github.com/smartcontractkit/chainlink-common/pkg/settings"
+)
+
+func TestFactory_MakeHierarchicalRateLimiter(t *testing.T) {
+	g, err := settings.NewJSONGetter([]byte(`{
+	"global": {"Calls": "1rps:4"},
+	"org": {"org-a": {"Calls": "1rps:3"}},
+	"workflow": {"wf-1": {"Calls": "1rps:2"}}
+}`))
+	require.NoError(t, err)
+	limit := settings.Rate(rate.Every(time.Hour), 10)
+	limit.Key = "Calls"
+	limit.Scope = settings.ScopeWorkflow
+
+	for _, tt := range []struct {
+		name  string
+		store StateStore
+	}{
+		{"memory", nil},
+		{"store", NewMemoryStore()},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rl, err := Factory{Settings: g, Logger: logger.Test(t), Store: tt.store}.MakeHierarchicalRateLimiter(limit)
+			require.NoError(t, err)
+			t.Cleanup(func() { assert.NoError(t, rl.Close()) })
+
+			wf1 := contexts.WithCRE(t.Context(), contexts.CRE{Org: "org-a", Owner: "owner-a", Workflow: "wf-1"})
+			wf2 := contexts.WithCRE(t.Context(), contexts.CRE{Org: "org-a", Owner: "owner-b", Workflow: "wf-2"})
+			wf3 := contexts.WithCRE(t.Context(), contexts.CRE{Org: "org-b", Owner: "owner-c", Workflow: "wf-3"})
+			now := time.Now()
+
+			r, err := rl.Limit(wf1)
+			require.NoError(t, err)
+			assert.Equal(t, config.Rate{Limit: 1, Burst: 2}, r)
+
+			require.True(t, rl.AllowN(wf1, now, 2))
+			err = rl.AllowNErr(wf1, now, 1)
+			require.ErrorIs(t, err, ErrorRateLimited{})
+			assert.ErrorContains(t, err, "Calls rate limited for workflow[wf-1]")
+
+			// wf-2 inherits the org rate for its own workflow and owner levels, but shares the org with wf-1
+			require.True(t, rl.AllowN(wf2, now, 1))
+			err = rl.AllowNErr(wf2, now, 1)
+			require.ErrorIs(t, err, ErrorRateLimited{})
+			assert.ErrorContains(t, err, "Calls rate limited for org[org-a]")
+
+			// denial by an inner level does not consume from outer levels
+			for range 3 {
+				assert.False(t, rl.AllowN(wf1, now, 1))
+			}
+			require.True(t, rl.AllowN(wf3, now, 1))
+			err = rl.AllowNErr(wf3, now, 1)
+			require.ErrorIs(t, err, ErrorRateLimited{})
+			assert.ErrorContains(t, err, "Calls rate limited")
+			assert.NotContains(t, err.Error(), " for ")
+		})
+	}
+}
+
+// observedStore is a StateStore which records whether any bucket was updated individually, which other processes
+// could observe before the request was denied.
+type observedStore struct {
+	*MemoryStore
+	observed bool
+}
+
+func (o *observedStore) TakeTokens(ctx context.Context, key string, r config.Rate, n int, now time.Time, maxWait time.Duration) (time.Duration, bool, error) {
+	o.observed = true
+	return o.MemoryStore.TakeTokens(ctx, key, r, n, now, maxWait)
+}
+
+func (o *observedStore) ReturnTokens(ctx context.Context, key string, r config.Rate, n int, now time.Time) error {
+	o.observed = true
+	return o.MemoryStore.ReturnTokens(ctx, key, r, n, now)
+}
+
+func TestHierarchicalRateLimiter_Atomic(t *testing.T) {
+	limit := settings.Rate(1, 2)
+	limit.Key = "Calls"
+	limit.Scope = settings.ScopeOwner
+	store := &observedStore{MemoryStore: NewMemoryStore()}
+	rl, err := Factory{Store: store}.MakeHierarchicalRateLimiter(limit)
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, rl.Close()) })
+
+	// exhaust the global level via another owner
+	now := time.Now()
+	require.True(t, rl.AllowN(contexts.WithCRE(t.Context(), contexts.CRE{Owner: "owner-b"}), now, 2))
+
+	ownerA := contexts.WithCRE(t.Context(), contexts.CRE{Owner: "owner-a"})
+	for range 3 {
+		err := rl.AllowNErr(ownerA, now, 2)
+		require.ErrorIs(t, err, ErrorRateLimited{})
+		assert.ErrorContains(t, err, "Calls rate limited")
+	}
+	assert.False(t, store.observed, "tokens were never taken and returned")
+}
+
+func TestHierarchicalRateLimiter_Reserve(t *testing.T) {
+	limit := settings.Rate(1, 2)
+	limit.Key = "Calls"
+	limit.Scope = settings.ScopeOwner
+	rl, err := Factory{}.MakeHierarchicalRateLimiter(limit)
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, rl.Close()) })
+
+	ctx := contexts.WithCRE(t.Context(), contexts.CRE{Org: "org-a", Owner: "owner-a"})
+	now := time.Now()
+
+	r, err := rl.ReserveN(ctx, now, 2)
+	require.NoError(t, err)
+	require.True(t, r.Allow())
+	r, err = rl.ReserveN(ctx, now, 1)
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, r.DelayFrom(now))
+	assert.ErrorIs(t, r.AllowErr(), ErrorRateLimited{})
+
+	// cancelling returns the tokens to every level
+	r.CancelAt(now)
+	r, err = rl.ReserveN(ctx, now, 1)
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, r.DelayFrom(now))
+
+	_, err = rl.ReserveN(ctx, now, 3)
+	assert.ErrorIs(t, err, ErrorRateLimited{}, "exceeds burst")
+
+	t.Run("wait", func(t *testing.T) {
+		rl, err := Factory{}.MakeHierarchicalRateLimiter(limit)
+		require.NoError(t, err)
+		t.Cleanup(func() { assert.NoError(t, rl.Close()) })
+		ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+		require.NoError(t, rl.WaitN(ctx, 2))
+		assert.ErrorIs(t, rl.Wait(ctx), ErrorRateLimited{})
+	})
+
+	t.Run("missing tenant", func(t *testing.T) {
+		assert.Error(t, rl.AllowErr(t.Context()))
+	})
+}
+
+func TestMultiRateLimiter_noLeak(t *testing.T) {
+	outer := GlobalRateLimiter(rate.Every(time.Hour), 2)
+	inner := WorkflowRateLimiter(rate.Every(time.Hour), 1)
+	ml := MultiRateLimiter{outer, inner}
+	ctx := contexts.WithCRE(t.Context(), contexts.CRE{Workflow: "wf"})
+
+	require.NoError(t, ml.AllowErr(ctx))
+	for range 3 {
+		require.ErrorIs(t, ml.AllowErr(ctx), ErrorRateLimited{})
+	}
+	// the outer limiter has not leaked the denied attempts
+	assert.True(t, outer.Allow(ctx))
+}
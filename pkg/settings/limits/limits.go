@@ -3,7 +3,8 @@
 //   - [ResourceLimiter]/[ResourcePoolLimiter]: for allocating resources
 //   - [TimeLimiter]: for enforcing timeouts
 //   - [BoundLimiter]: for enforcing bounds
-//   - [QueueLimiter]: for limited capacity queues
+//   - [QueueLimiter]: for limited capacity queues, optionally shared fairly between tenants
+//   - [AdaptiveLimiter]: for concurrency which adapts to latency and overload
 //
 // Every limit requires a default value. Additional features like Otel metrics and dynamic updates are available by
 // using the [settings.Setting] variants.
@@ -0,0 +1,342 @@
+// Package pgstore provides a Postgres backed [limits.StateStore], so that limits can be shared by every process using
+// the same database.
+package pgstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"golang.org/x/time/rate"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/config"
+	"github.com/smartcontractkit/chainlink-common/pkg/settings/limits"
+	"github.com/smartcontractkit/chainlink-common/pkg/sqlutil"
+)
+
+// DefaultSchema is the Postgres schema of the tables used by a Store, unless configured otherwise.
+const DefaultSchema = "cre"
+
+// maxAttempts is the number of optimistic update attempts before TakeTokens gives up due to contention.
+const maxAttempts = 10
+
+// Config holds optional configuration for a Store.
+type Config struct {
+	// Schema is the Postgres schema containing the tables. Defaults to DefaultSchema.
+	Schema string
+}
+
+func (c Config) tables() (tokenBuckets, resourcePools, resourceLeases string) {
+	schema := c.Schema
+	if schema == "" {
+		schema = DefaultSchema
+	}
+	schema = pq.QuoteIdentifier(schema)
+	return schema + ".limits_token_buckets", schema + ".limits_resource_pools", schema + ".limits_resource_leases"
+}
+
+// TablesSQL returns the statements which create the tables used by a Store with this Config. Production databases
+// should apply them via migrations.
+func (c Config) TablesSQL() string {
+	tokenBuckets, resourcePools, resourceLeases := c.tables()
+	schema, _, _ := strings.Cut(tokenBuckets, ".")
+	return `
+CREATE SCHEMA IF NOT EXISTS ` + schema + `;
+CREATE TABLE IF NOT EXISTS ` + tokenBuckets + ` (
+	key TEXT PRIMARY KEY,
+	tokens DOUBLE PRECISION NOT NULL,
+	last_ns BIGINT NOT NULL,
+	version BIGINT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS ` + resourcePools + ` (
+	key TEXT PRIMARY KEY,
+	used DOUBLE PRECISION NOT NULL
+);
+CREATE TABLE IF NOT EXISTS ` + resourceLeases + ` (
+	key TEXT NOT NULL,
+	holder TEXT NOT NULL,
+	used DOUBLE PRECISION NOT NULL,
+	expires_at_ns BIGINT NOT NULL,
+	PRIMARY KEY (key, holder)
+);`
+}
+
+// Schema creates the tables used by a Store in DefaultSchema. Production databases should apply it via migrations.
+var Schema = Config{}.TablesSQL()
+
+var _ limits.StateStore = (*Store)(nil)
+
+// Store is a Postgres backed limits.StateStore. Token buckets are updated with optimistic concurrency control, and
+// resource pools with conditional updates, so that no explicit locking is required. The usage of each resource pool
+// is also tracked per holder, so that expired leases can be reclaimed.
+type Store struct {
+	ds sqlutil.DataSource
+
+	tokenBuckets, resourcePools, resourceLeases string
+}
+
+// New returns a new Store backed by ds, with tables in DefaultSchema. The tables from Schema must already exist.
+func New(ds sqlutil.DataSource) *Store {
+	return Config{}.New(ds)
+}
+
+// New returns a new Store backed by ds. The tables from TablesSQL must already exist.
+func (c Config) New(ds sqlutil.DataSource) *Store {
+	s := &Store{ds: ds}
+	s.tokenBuckets, s.resourcePools, s.resourceLeases = c.tables()
+	return s
+}
+
+type bucketRow struct {
+	Tokens  float64 `db:"tokens"`
+	LastNs  int64   `db:"last_ns"`
+	Version int64   `db:"version"`
+}
+
+func (r bucketRow) bucket() limits.TokenBucket {
+	b := limits.TokenBucket{Tokens: r.Tokens}
+	if r.LastNs != 0 {
+		b.Last = time.Unix(0, r.LastNs)
+	}
+	return b
+}
+
+func (s *Store) TakeTokens(ctx context.Context, key string, r config.Rate, n int, now time.Time, maxWait time.Duration) (time.Duration, bool, error) {
+	var (
+		qSelect = `SELECT tokens, last_ns, version FROM ` + s.tokenBuckets + ` WHERE key = $1`
+		qInsert = `INSERT INTO ` + s.tokenBuckets + ` (key, tokens, last_ns, version) VALUES ($1, $2, $3, 0) ON CONFLICT (key) DO NOTHING`
+		qUpdate = `UPDATE ` + s.tokenBuckets + ` SET tokens = $2, last_ns = $3, version = version + 1 WHERE key = $1 AND version = $4`
+	)
+	if r.Limit == rate.Inf {
+		return 0, true, nil
+	}
+	for range maxAttempts {
+		var row bucketRow
+		exists := true
+		if err := s.ds.GetContext(ctx, &row, qSelect, key); errors.Is(err, sql.ErrNoRows) {
+			exists = false
+		} else if err != nil {
+			return 0, false, fmt.Errorf("failed to get token bucket %s: %w", key, err)
+		}
+		b := row.bucket()
+		delay, ok := b.Take(r, n, now, maxWait)
+		if !ok {
+			return 0, false, nil
+		}
+		var res sql.Result
+		var err error
+		if exists {
+			res, err = s.ds.ExecContext(ctx, qUpdate, key, b.Tokens, b.Last.UnixNano(), row.Version)
+		} else {
+			res, err = s.ds.ExecContext(ctx, qInsert, key, b.Tokens, b.Last.UnixNano())
+		}
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to update token bucket %s: %w", key, err)
+		}
+		if updated, err := res.RowsAffected(); err != nil {
+			return 0, false, fmt.Errorf("failed to update token bucket %s: %w", key, err)
+		} else if updated == 1 {
+			return delay, true, nil
+		}
+		// lost a race with another process, so try again
+	}
+	return 0, false, fmt.Errorf("failed to update token bucket %s: too much contention after %d attempts", key, maxAttempts)
+}
+
+// errConflict aborts a transaction which lost a race with another process.
+var errConflict = errors.New("conflict")
+
+func (s *Store) TakeAllTokens(ctx context.Context, buckets []limits.RateBucket, n int, now time.Time, maxWait time.Duration) (delays []time.Duration, denied int, ok bool, err error) {
+	var (
+		qSelect = `SELECT tokens, last_ns, version FROM ` + s.tokenBuckets + ` WHERE key = $1`
+		qInsert = `INSERT INTO ` + s.tokenBuckets + ` (key, tokens, last_ns, version) VALUES ($1, $2, $3, 0) ON CONFLICT (key) DO NOTHING`
+		qUpdate = `UPDATE ` + s.tokenBuckets + ` SET tokens = $2, last_ns = $3, version = version + 1 WHERE key = $1 AND version = $4`
+	)
+	for range maxAttempts {
+		delays, denied, ok = make([]time.Duration, len(buckets)), 0, true
+		err = sqlutil.TransactDataSource(ctx, s.ds, nil, func(tx sqlutil.DataSource) error {
+			rows := make([]*bucketRow, len(buckets)) // nil if new
+			next := make([]limits.TokenBucket, len(buckets))
+			for i, rb := range buckets {
+				var row bucketRow
+				if err := tx.GetContext(ctx, &row, qSelect, rb.Key); err == nil {
+					rows[i] = &row
+				} else if !errors.Is(err, sql.ErrNoRows) {
+					return fmt.Errorf("failed to get token bucket %s: %w", rb.Key, err)
+				}
+				next[i] = row.bucket()
+				var allowed bool
+				if delays[i], allowed = next[i].Take(rb.Rate, n, now, maxWait); !allowed {
+					denied, ok = i, false
+					return nil // nothing written
+				}
+			}
+			for i, rb := range buckets {
+				if rb.Rate.Limit == rate.Inf {
+					continue
+				}
+				var res sql.Result
+				var err error
+				if rows[i] != nil {
+					res, err = tx.ExecContext(ctx, qUpdate, rb.Key, next[i].Tokens, next[i].Last.UnixNano(), rows[i].Version)
+				} else {
+					res, err = tx.ExecContext(ctx, qInsert, rb.Key, next[i].Tokens, next[i].Last.UnixNano())
+				}
+				if err != nil {
+					return fmt.Errorf("failed to update token bucket %s: %w", rb.Key, err)
+				}
+				if updated, err := res.RowsAffected(); err != nil {
+					return fmt.Errorf("failed to update token bucket %s: %w", rb.Key, err)
+				} else if updated != 1 {
+					return errConflict // roll back the buckets already updated
+				}
+			}
+			return nil
+		})
+		if err == nil && !ok {
+			return nil, denied, false, nil
+		}
+		if !errors.Is(err, errConflict) {
+			break
+		}
+		// lost a race with another process, so try again
+	}
+	if errors.Is(err, errConflict) {
+		return nil, 0, false, fmt.Errorf("failed to update token buckets: too much contention after %d attempts", maxAttempts)
+	} else if err != nil {
+		return nil, 0, false, err
+	}
+	return delays, 0, true, nil
+}
+
+func (s *Store) ReturnTokens(ctx context.Context, key string, r config.Rate, n int, now time.Time) error {
+	var (
+		qSelect = `SELECT tokens, last_ns, version FROM ` + s.tokenBuckets + ` WHERE key = $1`
+		qUpdate = `UPDATE ` + s.tokenBuckets + ` SET tokens = $2, last_ns = $3, version = version + 1 WHERE key = $1 AND version = $4`
+	)
+	for range maxAttempts {
+		var row bucketRow
+		if err := s.ds.GetContext(ctx, &row, qSelect, key); errors.Is(err, sql.ErrNoRows) {
+			return nil // nothing to return to
+		} else if err != nil {
+			return fmt.Errorf("failed to get token bucket %s: %w", key, err)
+		}
+		b := row.bucket()
+		b.Put(r, n, now)
+		res, err := s.ds.ExecContext(ctx, qUpdate, key, b.Tokens, b.Last.UnixNano(), row.Version)
+		if err != nil {
+			return fmt.Errorf("failed to update token bucket %s: %w", key, err)
+		}
+		if updated, err := res.RowsAffected(); err != nil {
+			return fmt.Errorf("failed to update token bucket %s: %w", key, err)
+		} else if updated == 1 {
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to update token bucket %s: too much contention after %d attempts", key, maxAttempts)
+}
+
+type leaseRow struct {
+	Holder string  `db:"holder"`
+	Used   float64 `db:"used"`
+}
+
+func (s *Store) AcquireResource(ctx context.Context, key, holder string, limit, amount float64, now time.Time, ttl time.Duration) (used float64, ok bool, err error) {
+	var (
+		qInsert      = `INSERT INTO ` + s.resourcePools + ` (key, used) VALUES ($1, 0) ON CONFLICT (key) DO NOTHING`
+		qExpired     = `SELECT holder, used FROM ` + s.resourceLeases + ` WHERE key = $1 AND expires_at_ns <= $2`
+		qReclaim     = `DELETE FROM ` + s.resourceLeases + ` WHERE key = $1 AND holder = $2 AND expires_at_ns <= $3`
+		qRelease     = `UPDATE ` + s.resourcePools + ` SET used = GREATEST(used - $2, 0) WHERE key = $1`
+		qUpdate      = `UPDATE ` + s.resourcePools + ` SET used = used + $2 WHERE key = $1 AND used + $2 <= $3 RETURNING used`
+		qSelect      = `SELECT used FROM ` + s.resourcePools + ` WHERE key = $1`
+		qUpsertLease = `INSERT INTO ` + s.resourceLeases + ` (key, holder, used, expires_at_ns) VALUES ($1, $2, $3, $4)
+ON CONFLICT (key, holder) DO UPDATE SET used = ` + s.resourceLeases + `.used + EXCLUDED.used, expires_at_ns = EXCLUDED.expires_at_ns`
+	)
+	err = sqlutil.TransactDataSource(ctx, s.ds, nil, func(tx sqlutil.DataSource) error {
+		if _, err := tx.ExecContext(ctx, qInsert, key); err != nil {
+			return fmt.Errorf("failed to create resource pool %s: %w", key, err)
+		}
+		var expired []leaseRow
+		if err := tx.SelectContext(ctx, &expired, qExpired, key, now.UnixNano()); err != nil {
+			return fmt.Errorf("failed to get expired leases of resource pool %s: %w", key, err)
+		}
+		for _, l := range expired {
+			// only the process which deletes a lease reclaims it
+			res, err := tx.ExecContext(ctx, qReclaim, key, l.Holder, now.UnixNano())
+			if err != nil {
+				return fmt.Errorf("failed to reclaim expired lease of resource pool %s: %w", key, err)
+			}
+			if deleted, err := res.RowsAffected(); err != nil {
+				return fmt.Errorf("failed to reclaim expired lease of resource pool %s: %w", key, err)
+			} else if deleted == 1 && l.Used > 0 {
+				if _, err := tx.ExecContext(ctx, qRelease, key, l.Used); err != nil {
+					return fmt.Errorf("failed to reclaim expired lease of resource pool %s: %w", key, err)
+				}
+			}
+		}
+
+		err := tx.GetContext(ctx, &used, qUpdate, key, amount, limit)
+		if errors.Is(err, sql.ErrNoRows) {
+			if err := tx.GetContext(ctx, &used, qSelect, key); err != nil {
+				return fmt.Errorf("failed to get resource pool %s: %w", key, err)
+			}
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to acquire from resource pool %s: %w", key, err)
+		}
+		ok = true
+		if amount == 0 {
+			return nil
+		}
+		if _, err := tx.ExecContext(ctx, qUpsertLease, key, holder, amount, now.Add(ttl).UnixNano()); err != nil {
+			return fmt.Errorf("failed to lease from resource pool %s: %w", key, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, false, err
+	}
+	return used, ok, nil
+}
+
+func (s *Store) ReleaseResource(ctx context.Context, key, holder string, amount float64) error {
+	var (
+		qSelectLease = `SELECT used FROM ` + s.resourceLeases + ` WHERE key = $1 AND holder = $2`
+		qUpdateLease = `UPDATE ` + s.resourceLeases + ` SET used = GREATEST(used - $3, 0) WHERE key = $1 AND holder = $2`
+		qRelease     = `UPDATE ` + s.resourcePools + ` SET used = GREATEST(used - $2, 0) WHERE key = $1`
+	)
+	return sqlutil.TransactDataSource(ctx, s.ds, nil, func(tx sqlutil.DataSource) error {
+		var leased float64
+		if err := tx.GetContext(ctx, &leased, qSelectLease, key, holder); errors.Is(err, sql.ErrNoRows) {
+			return nil // expired and reclaimed, or never acquired
+		} else if err != nil {
+			return fmt.Errorf("failed to get lease of resource pool %s: %w", key, err)
+		}
+		amount = min(amount, leased)
+		if _, err := tx.ExecContext(ctx, qUpdateLease, key, holder, amount); err != nil {
+			return fmt.Errorf("failed to release lease of resource pool %s: %w", key, err)
+		}
+		if _, err := tx.ExecContext(ctx, qRelease, key, amount); err != nil {
+			return fmt.Errorf("failed to release to resource pool %s: %w", key, err)
+		}
+		return nil
+	})
+}
+
+func (s *Store) RenewResources(ctx context.Context, holder string, now time.Time, ttl time.Duration) error {
+	var (
+		qDelete = `DELETE FROM ` + s.resourceLeases + ` WHERE holder = $1 AND used <= 0`
+		qRenew  = `UPDATE ` + s.resourceLeases + ` SET expires_at_ns = $2 WHERE holder = $1`
+	)
+	if _, err := s.ds.ExecContext(ctx, qDelete, holder); err != nil {
+		return fmt.Errorf("failed to delete released leases of %s: %w", holder, err)
+	}
+	if _, err := s.ds.ExecContext(ctx, qRenew, holder, now.Add(ttl).UnixNano()); err != nil {
+		return fmt.Errorf("failed to renew leases of %s: %w", holder, err)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package pgstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/config"
+	"github.com/smartcontractkit/chainlink-common/pkg/settings/limits"
+	"github.com/smartcontractkit/chainlink-common/pkg/sqlutil/sqltest"
+)
+
+func newStore(t *testing.T) *Store {
+	db := sqltest.NewDB(t, sqltest.TestURL(t))
+	_, err := db.ExecContext(t.Context(), Schema)
+	require.NoError(t, err)
+	return New(db)
+}
+
+func TestStore_TakeTokens(t *testing.T) {
+	s := newStore(t)
+	ctx := t.Context()
+	r := config.Rate{Limit: 2, Burst: 4}
+	now := time.Now()
+
+	_, ok, err := s.TakeTokens(ctx, "rate.foo", r, 5, now, time.Hour)
+	require.NoError(t, err)
+	assert.False(t, ok, "exceeds burst")
+
+	delay, ok, err := s.TakeTokens(ctx, "rate.foo", r, 4, now, 0)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Zero(t, delay)
+
+	_, ok, err = s.TakeTokens(ctx, "rate.foo", r, 1, now, 0)
+	require.NoError(t, err)
+	assert.False(t, ok, "empty")
+
+	delay, ok, err = s.TakeTokens(ctx, "rate.foo", r, 1, now, time.Second)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, 500*time.Millisecond, delay)
+
+	require.NoError(t, s.ReturnTokens(ctx, "rate.foo", r, 1, now))
+	delay, ok, err = s.TakeTokens(ctx, "rate.foo", r, 1, now.Add(time.Second), 0)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Zero(t, delay)
+
+	// independent keys
+	_, ok, err = s.TakeTokens(ctx, "rate.bar", r, 4, now, 0)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestStore_AcquireResource(t *testing.T) {
+	s := newStore(t)
+	ctx := t.Context()
+	now := time.Now()
+
+	used, ok, err := s.AcquireResource(ctx, "resource.foo", "a", 3, 2, now, time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.InDelta(t, 2, used, 0)
+
+	used, ok, err = s.AcquireResource(ctx, "resource.foo", "b", 3, 2, now, time.Minute)
+	require.NoError(t, err)
+	require.False(t, ok)
+	assert.InDelta(t, 2, used, 0)
+
+	require.NoError(t, s.ReleaseResource(ctx, "resource.foo", "a", 1))
+	used, ok, err = s.AcquireResource(ctx, "resource.foo", "b", 3, 2, now, time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.InDelta(t, 3, used, 0)
+
+	// only the amount leased to the holder is released
+	require.NoError(t, s.ReleaseResource(ctx, "resource.foo", "a", 10))
+	used, ok, err = s.AcquireResource(ctx, "resource.foo", "a", 3, 0, now, time.Minute)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.InDelta(t, 2, used, 0)
+
+	t.Run("expired leases are reclaimed", func(t *testing.T) {
+		require.NoError(t, s.RenewResources(ctx, "b", now.Add(time.Minute), time.Minute))
+		used, ok, err = s.AcquireResource(ctx, "resource.foo", "a", 3, 0, now.Add(90*time.Second), time.Minute)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.InDelta(t, 2, used, 0, "renewed")
+
+		used, ok, err = s.AcquireResource(ctx, "resource.foo", "a", 3, 3, now.Add(3*time.Minute), time.Minute)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.InDelta(t, 3, used, 0)
+
+		// b's lease is gone, so it has nothing to release
+		require.NoError(t, s.ReleaseResource(ctx, "resource.foo", "b", 2))
+		used, _, err = s.AcquireResource(ctx, "resource.foo", "a", 3, 0, now.Add(3*time.Minute), time.Minute)
+		require.NoError(t, err)
+		assert.InDelta(t, 3, used, 0)
+	})
+}
+
+func TestConfig_Schema(t *testing.T) {
+	db := sqltest.NewDB(t, sqltest.TestURL(t))
+	cfg := Config{Schema: "limits_test"}
+	_, err := db.ExecContext(t.Context(), cfg.TablesSQL())
+	require.NoError(t, err)
+	s := cfg.New(db)
+
+	_, ok, err := s.AcquireResource(t.Context(), "resource.foo", "a", 1, 1, time.Now(), time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	var used float64
+	require.NoError(t, db.GetContext(t.Context(), &used, `SELECT used FROM limits_test.limits_resource_pools WHERE key = 'resource.foo'`))
+	assert.InDelta(t, 1, used, 0)
+}
+
+func TestStore_TakeAllTokens(t *testing.T) {
+	s := newStore(t)
+	ctx := t.Context()
+	now := time.Now()
+	buckets := []limits.RateBucket{
+		{Key: "rate.foo.owner.a", Rate: config.Rate{Limit: 1, Burst: 4}},
+		{Key: "rate.foo", Rate: config.Rate{Limit: 1, Burst: 2}},
+	}
+
+	delays, _, ok, err := s.TakeAllTokens(ctx, buckets, 2, now, 0)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, []time.Duration{0, 0}, delays)
+
+	_, denied, ok, err := s.TakeAllTokens(ctx, buckets, 1, now, 0)
+	require.NoError(t, err)
+	require.False(t, ok)
+	assert.Equal(t, 1, denied)
+
+	// nothing was taken from the first bucket
+	delays, _, ok, err = s.TakeAllTokens(ctx, buckets[:1], 2, now, 0)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, []time.Duration{0}, delays)
+
+	delays, _, ok, err = s.TakeAllTokens(ctx, buckets, 1, now, time.Second)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, []time.Duration{time.Second, time.Second}, delays)
+}
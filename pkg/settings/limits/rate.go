@@ -574,7 +574,9 @@ func (s *scopedRateLimiter) WaitN(ctx context.Context, n int) (err error) {
 
 var _ RateLimiter = MultiRateLimiter{}
 
-// MultiRateLimiter is a RateLimiter composed of other RateLimiters which are applied in order.
+// MultiRateLimiter is a RateLimiter composed of other RateLimiters which are applied in order. Tokens reserved from
+// earlier RateLimiters are returned if a later one denies the request.
+// To enforce a single limit at every scope, see Factory.MakeHierarchicalRateLimiter.
 type MultiRateLimiter []RateLimiter
 
 func (m MultiRateLimiter) Close() (err error) {
@@ -606,7 +608,7 @@ func (m MultiRateLimiter) AllowN(ctx context.Context, t time.Time, n int) bool {
 	for _, l := range m {
 		r, err := l.ReserveN(ctx, t, n)
 		if err != nil || !r.Allow() {
-			mr.Cancel()
+			mr.CancelAt(t)
 			return false
 		}
 		mr = append(mr, r)
@@ -623,10 +625,11 @@ func (m MultiRateLimiter) AllowNErr(ctx context.Context, t time.Time, n int) err
 	for _, l := range m {
 		r, err := l.ReserveN(ctx, t, n)
 		if err != nil {
-			mr.Cancel()
+			mr.CancelAt(t)
 			return err
 		} else if err = r.AllowErr(); err != nil {
-			mr.Cancel()
+			r.CancelAt(t)
+			mr.CancelAt(t)
 			return err
 		}
 		mr = append(mr, r)
@@ -635,16 +638,7 @@ func (m MultiRateLimiter) AllowNErr(ctx context.Context, t time.Time, n int) err
 }
 
 func (m MultiRateLimiter) Reserve(ctx context.Context) (Reservation, error) {
-	var mr multiReservation
-	for _, l := range m {
-		r, err := l.Reserve(ctx)
-		if err != nil {
-			mr.Cancel()
-			return nil, err
-		}
-		mr = append(mr, r)
-	}
-	return &mr, nil
+	return m.ReserveN(ctx, time.Now(), 1)
 }
 
 func (m MultiRateLimiter) ReserveN(ctx context.Context, t time.Time, n int) (Reservation, error) {
@@ -652,7 +646,7 @@ func (m MultiRateLimiter) ReserveN(ctx context.Context, t time.Time, n int) (Res
 	for _, l := range m {
 		r, err := l.ReserveN(ctx, t, n)
 		if err != nil {
-			mr.Cancel()
+			mr.CancelAt(t)
 			return nil, err
 		}
 		mr = append(mr, r)
@@ -661,37 +655,19 @@ func (m MultiRateLimiter) ReserveN(ctx context.Context, t time.Time, n int) (Res
 }
 
 func (m MultiRateLimiter) Wait(ctx context.Context) error {
-	var mr multiReservation
-	for _, l := range m {
-		r, err := l.Reserve(ctx)
-		if err != nil {
-			mr.Cancel()
-			return err
-		}
-		mr = append(mr, r)
-	}
-	select {
-	case <-ctx.Done():
-		return ctx.Err()
-	case <-time.After(mr.Delay()):
-	}
-	return nil
+	return m.WaitN(ctx, 1)
 }
 
 func (m MultiRateLimiter) WaitN(ctx context.Context, n int) (err error) {
-	var mr multiReservation
-	for _, l := range m {
-		r, err := l.ReserveN(ctx, time.Now(), n)
-		if err != nil {
-			mr.Cancel()
-			return err
-		}
-		mr = append(mr, r)
+	r, err := m.ReserveN(ctx, time.Now(), n)
+	if err != nil {
+		return err
 	}
 	select {
 	case <-ctx.Done():
+		r.Cancel()
 		return ctx.Err()
-	case <-time.After(mr.Delay()):
+	case <-time.After(r.Delay()):
 	}
 	return nil
 }
@@ -0,0 +1,488 @@
+package limits
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/config"
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+	"github.com/smartcontractkit/chainlink-common/pkg/services"
+	"github.com/smartcontractkit/chainlink-common/pkg/settings"
+)
+
+const (
+	// DefaultStoreWaitPeriod is the default Factory.StoreWaitPeriod.
+	DefaultStoreWaitPeriod = 100 * time.Millisecond
+	// DefaultStoreLeaseTTL is the default Factory.StoreLeaseTTL.
+	DefaultStoreLeaseTTL = time.Minute
+)
+
+// sharedTenant returns the tenant of scope from ctx, or failOpen=true if the limit should not be applied.
+func sharedTenant(ctx context.Context, lggr logger.SugaredLogger, scope settings.Scope) (tenant string, failOpen bool, err error) {
+	if scope == settings.ScopeGlobal {
+		return "", false, nil
+	}
+	tenant = scope.Value(ctx)
+	if tenant == "" {
+		if !scope.IsTenantRequired() {
+			lggr.Errorw("Unable to apply scoped limit due to missing tenant: failing open", "scope", scope)
+			return "", true, nil
+		}
+		return "", false, fmt.Errorf("missing tenant for scope: %s", scope)
+	}
+	return tenant, false, nil
+}
+
+// sharedRateLimiter is a RateLimiter with token buckets kept in a StateStore.
+// StateStore errors are logged, and fail open.
+type sharedRateLimiter struct {
+	lggr  logger.SugaredLogger
+	store StateStore
+	key   string
+	scope settings.Scope
+
+	rateFn func(context.Context) (config.Rate, error)
+
+	limitGauge   metric.Float64Gauge   // optional
+	burstGauge   metric.Int64Gauge     // optional
+	usageCounter metric.Int64Counter   // optional
+	deniedHist   metric.Int64Histogram // optional
+}
+
+func (f Factory) newSharedRateLimiter(limit settings.Setting[config.Rate]) (RateLimiter, error) {
+	l := &sharedRateLimiter{
+		lggr:  logger.Sugared(logger.Nop()),
+		store: f.Store,
+		key:   limit.Key,
+		scope: limit.Scope,
+		rateFn: func(ctx context.Context) (config.Rate, error) {
+			return limit.GetOrDefault(ctx, f.Settings)
+		},
+	}
+	if f.Logger != nil {
+		l.lggr = logger.Sugared(f.Logger).Named("SharedRateLimiter").With("key", limit.Key, "scope", limit.Scope)
+	}
+	if f.Meter != nil {
+		var err error
+		l.limitGauge, err = f.Meter.Float64Gauge("rate."+limit.Key+".limit", metric.WithUnit("rps"))
+		if err != nil {
+			return nil, err
+		}
+		l.burstGauge, err = f.Meter.Int64Gauge("rate."+limit.Key+".burst", metric.WithUnit(limit.Unit))
+		if err != nil {
+			return nil, err
+		}
+		l.usageCounter, err = f.Meter.Int64Counter("rate."+limit.Key+".usage", metric.WithUnit(limit.Unit))
+		if err != nil {
+			return nil, err
+		}
+		l.deniedHist, err = f.Meter.Int64Histogram("rate."+limit.Key+".denied", metric.WithUnit(limit.Unit))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+func (s *sharedRateLimiter) Close() error { return nil }
+
+func (s *sharedRateLimiter) getRate(ctx context.Context) config.Rate {
+	r, err := s.rateFn(ctx)
+	if err != nil {
+		s.lggr.Errorw("Failed to get limit. Using default value", "default", r, "err", err)
+	}
+	if s.limitGauge != nil {
+		s.limitGauge.Record(ctx, float64(r.Limit), withScope(ctx, s.scope))
+	}
+	if s.burstGauge != nil {
+		s.burstGauge.Record(ctx, int64(r.Burst), withScope(ctx, s.scope))
+	}
+	return r
+}
+
+func (s *sharedRateLimiter) Limit(ctx context.Context) (config.Rate, error) {
+	return s.getRate(ctx), nil
+}
+
+// take takes n tokens from the StateStore if they will be available within maxWait.
+func (s *sharedRateLimiter) take(ctx context.Context, t time.Time, n int, maxWait time.Duration) (*sharedReservation, error) {
+	tenant, failOpen, err := sharedTenant(ctx, s.lggr, s.scope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rate limiter: %w", err)
+	} else if failOpen {
+		return &sharedReservation{readyAt: t}, nil
+	}
+	r := s.getRate(ctx)
+	res := &sharedReservation{
+		ctx:     context.WithoutCancel(ctx),
+		limiter: s,
+		key:     StoreKey("rate", s.key, s.scope, tenant),
+		tenant:  tenant,
+		rate:    r,
+		n:       n,
+	}
+	delay, ok, err := s.store.TakeTokens(ctx, res.key, r, n, t, maxWait)
+	if err != nil {
+		s.lggr.Errorw("Failed to take tokens from store: failing open", "tenant", tenant, "err", err)
+		return &sharedReservation{readyAt: t}, nil
+	}
+	if !ok {
+		s.recordDenied(ctx, n)
+		return nil, ErrorRateLimited{Key: s.key, Scope: s.scope, Tenant: tenant, N: n}
+	}
+	s.addUsage(ctx, n)
+	res.readyAt = t.Add(delay)
+	return res, nil
+}
+
+func (s *sharedRateLimiter) addUsage(ctx context.Context, n int) {
+	if s.usageCounter != nil {
+		s.usageCounter.Add(ctx, int64(n), withScope(ctx, s.scope))
+	}
+}
+
+func (s *sharedRateLimiter) recordDenied(ctx context.Context, n int) {
+	if s.deniedHist != nil {
+		s.deniedHist.Record(ctx, int64(n), withScope(ctx, s.scope))
+	}
+}
+
+func (s *sharedRateLimiter) Allow(ctx context.Context) bool {
+	return s.AllowN(ctx, time.Now(), 1)
+}
+
+func (s *sharedRateLimiter) AllowN(ctx context.Context, t time.Time, n int) bool {
+	return s.AllowNErr(ctx, t, n) == nil
+}
+
+func (s *sharedRateLimiter) AllowErr(ctx context.Context) error {
+	return s.AllowNErr(ctx, time.Now(), 1)
+}
+
+func (s *sharedRateLimiter) AllowNErr(ctx context.Context, t time.Time, n int) error {
+	_, err := s.take(ctx, t, n, 0)
+	return err
+}
+
+func (s *sharedRateLimiter) Reserve(ctx context.Context) (Reservation, error) {
+	return s.ReserveN(ctx, time.Now(), 1)
+}
+
+func (s *sharedRateLimiter) ReserveN(ctx context.Context, t time.Time, n int) (Reservation, error) {
+	r, err := s.take(ctx, t, n, math.MaxInt64)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (s *sharedRateLimiter) Wait(ctx context.Context) error {
+	return s.WaitN(ctx, 1)
+}
+
+func (s *sharedRateLimiter) WaitN(ctx context.Context, n int) error {
+	now := time.Now()
+	maxWait := time.Duration(math.MaxInt64)
+	if deadline, ok := ctx.Deadline(); ok {
+		maxWait = deadline.Sub(now)
+	}
+	r, err := s.take(ctx, now, n, maxWait)
+	if err != nil {
+		return err
+	}
+	delay := r.DelayFrom(now)
+	if delay <= 0 {
+		return nil
+	}
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		r.Cancel()
+		return ErrorRateLimited{Key: s.key, Scope: s.scope, Tenant: r.tenant, N: n, Err: ctx.Err()}
+	}
+}
+
+var _ Reservation = &sharedReservation{}
+
+// sharedReservation is a Reservation of tokens from a StateStore. The zero limiter is a fail-open reservation.
+type sharedReservation struct {
+	ctx     context.Context
+	limiter *sharedRateLimiter // optional
+	key     string
+	tenant  string
+	rate    config.Rate
+	n       int
+	readyAt time.Time
+
+	cancelOnce sync.Once
+}
+
+func (r *sharedReservation) OK() bool { return true }
+
+func (r *sharedReservation) Delay() time.Duration { return r.DelayFrom(time.Now()) }
+
+func (r *sharedReservation) DelayFrom(t time.Time) time.Duration {
+	return max(r.readyAt.Sub(t), 0)
+}
+
+func (r *sharedReservation) Cancel() { r.CancelAt(time.Now()) }
+
+// CancelAt returns the tokens unless they were already used before t. Like rate.Reservation.CancelAt, tokens are
+// still returned when t is exactly the time to act.
+func (r *sharedReservation) CancelAt(t time.Time) {
+	if r.limiter == nil || t.After(r.readyAt) {
+		return // nothing to restore
+	}
+	r.cancelOnce.Do(func() {
+		if err := r.limiter.store.ReturnTokens(r.ctx, r.key, r.rate, r.n, t); err != nil {
+			r.limiter.lggr.Errorw("Failed to return tokens to store", "tenant", r.tenant, "err", err)
+		}
+	})
+}
+
+func (r *sharedReservation) Allow() bool { return r.Delay() <= 0 }
+
+func (r *sharedReservation) AllowErr() error {
+	if r.Delay() > 0 {
+		var key string
+		var scope settings.Scope
+		if r.limiter != nil {
+			key, scope = r.limiter.key, r.limiter.scope
+		}
+		return ErrorRateLimited{Key: key, Scope: scope, Tenant: r.tenant, N: r.n}
+	}
+	return nil
+}
+
+// sharedResourcePoolLimiter is a ResourcePoolLimiter with usage kept in a StateStore.
+// StateStore errors are logged, and fail open. Blocked calls to Wait poll the StateStore every waitPeriod.
+// Acquired resources are leased to holder, and renewed in the background until Close, so that a crashed process does
+// not hold them forever.
+type sharedResourcePoolLimiter[N Number] struct {
+	resourcePoolLimiter[N] // only used for metrics
+	lggr                   logger.SugaredLogger
+	store                  StateStore
+	scope                  settings.Scope
+	holder                 string
+	waitPeriod             time.Duration
+	leaseTTL               time.Duration
+
+	limitFn func(context.Context) (N, error)
+
+	mu         sync.Mutex
+	unacquired map[string]N // store key -> amount used while failing open, which must not be released
+
+	stopCh    services.StopChan
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newSharedResourcePoolLimiter[N Number](f Factory, limit settings.Setting[N]) (ResourcePoolLimiter[N], error) {
+	l := &sharedResourcePoolLimiter[N]{
+		resourcePoolLimiter: resourcePoolLimiter[N]{key: limit.Key},
+		lggr:                logger.Sugared(logger.Nop()),
+		store:               f.Store,
+		scope:               limit.Scope,
+		holder:              uuid.NewString(),
+		waitPeriod:          f.StoreWaitPeriod,
+		leaseTTL:            f.StoreLeaseTTL,
+		limitFn: func(ctx context.Context) (N, error) {
+			return limit.GetOrDefault(ctx, f.Settings)
+		},
+		unacquired: make(map[string]N),
+		stopCh:     make(services.StopChan),
+		done:       make(chan struct{}),
+	}
+	if l.waitPeriod <= 0 {
+		l.waitPeriod = DefaultStoreWaitPeriod
+	}
+	if l.leaseTTL <= 0 {
+		l.leaseTTL = DefaultStoreLeaseTTL
+	}
+	if f.Logger != nil {
+		l.lggr = logger.Sugared(f.Logger).Named("SharedResourcePoolLimiter").With("key", limit.Key, "scope", limit.Scope)
+	}
+	if f.Meter != nil {
+		if err := l.createGauges(f.Meter, limit.Unit); err != nil {
+			return nil, err
+		}
+	}
+	go l.renewLeases()
+	return l, nil
+}
+
+// renewLeases renews the leases of holder well before they expire, until Close.
+func (s *sharedResourcePoolLimiter[N]) renewLeases() {
+	defer close(s.done)
+	ctx, cancel := s.stopCh.NewCtx()
+	defer cancel()
+	t := time.NewTicker(s.leaseTTL / 3)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := s.store.RenewResources(ctx, s.holder, time.Now(), s.leaseTTL); err != nil {
+				s.lggr.Errorw("Failed to renew resource leases", "err", err)
+			}
+		}
+	}
+}
+
+// Close stops renewing leases. Resources still in use are reclaimed by the store after they expire.
+func (s *sharedResourcePoolLimiter[N]) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.stopCh)
+		<-s.done
+	})
+	return nil
+}
+
+func (s *sharedResourcePoolLimiter[N]) Limit(ctx context.Context) (N, error) {
+	limit, err := s.limitFn(ctx)
+	if err != nil {
+		s.lggr.Errorw("Failed to get limit. Using default value", "default", limit, "err", err)
+	}
+	if s.recordLimit != nil {
+		s.recordLimit(ctx, limit, withScope(ctx, s.scope))
+	}
+	return limit, nil
+}
+
+func (s *sharedResourcePoolLimiter[N]) Available(ctx context.Context) (N, error) {
+	limit, _ := s.Limit(ctx)
+	tenant, failOpen, err := sharedTenant(ctx, s.lggr, s.scope)
+	if err != nil {
+		var zero N
+		return zero, fmt.Errorf("failed to get resource pool: %w", err)
+	} else if failOpen {
+		return maxVal[N]()
+	}
+	// acquiring zero reports the current usage
+	used, _, err := s.store.AcquireResource(ctx, StoreKey("resource", s.key, s.scope, tenant), s.holder, float64(limit), 0, time.Now(), s.leaseTTL)
+	if err != nil {
+		var zero N
+		return zero, fmt.Errorf("failed to get usage from store: %w", err)
+	}
+	return limit - N(used), nil
+}
+
+// use tries once to acquire amount, and returns ok=false without error if the limit was reached. If ok, then release
+// frees what was actually acquired from the store, which is nothing when failing open.
+func (s *sharedResourcePoolLimiter[N]) use(ctx context.Context, amount N) (release func(context.Context) error, ok bool, err error) {
+	limit, _ := s.Limit(ctx)
+	tenant, failOpen, err := sharedTenant(ctx, s.lggr, s.scope)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get resource pool: %w", err)
+	} else if failOpen {
+		return func(context.Context) error { return nil }, true, nil
+	}
+	key := StoreKey("resource", s.key, s.scope, tenant)
+	used, ok, err := s.store.AcquireResource(ctx, key, s.holder, float64(limit), float64(amount), time.Now(), s.leaseTTL)
+	if err != nil {
+		s.lggr.Errorw("Failed to acquire resource from store: failing open", "tenant", tenant, "err", err)
+		s.mu.Lock()
+		s.unacquired[key] += amount
+		s.mu.Unlock()
+		return func(context.Context) error {
+			s.takeUnacquired(key, amount)
+			return nil
+		}, true, nil
+	}
+	if !ok {
+		return nil, false, ErrorResourceLimited[N]{Key: s.key, Scope: s.scope, Tenant: tenant, Used: N(used), Limit: limit, Amount: amount}
+	}
+	if s.recordUsage != nil {
+		s.recordUsage(ctx, N(used), withScope(ctx, s.scope))
+	}
+	if s.recordAmount != nil {
+		s.recordAmount(ctx, amount, withScope(ctx, s.scope))
+	}
+	return func(ctx context.Context) error { return s.release(ctx, key, amount) }, true, nil
+}
+
+// takeUnacquired removes up to amount from the unacquired amount of key, and returns the remainder.
+func (s *sharedResourcePoolLimiter[N]) takeUnacquired(key string, amount N) N {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	taken := min(s.unacquired[key], amount)
+	if s.unacquired[key] -= taken; s.unacquired[key] <= 0 {
+		delete(s.unacquired, key)
+	}
+	return amount - taken
+}
+
+func (s *sharedResourcePoolLimiter[N]) release(ctx context.Context, key string, amount N) error {
+	if err := s.store.ReleaseResource(ctx, key, s.holder, float64(amount)); err != nil {
+		return fmt.Errorf("failed to release resource to store: %w", err)
+	}
+	return nil
+}
+
+func (s *sharedResourcePoolLimiter[N]) Use(ctx context.Context, amount N) error {
+	_, _, err := s.use(ctx, amount)
+	if _, limited := errors.AsType[ErrorResourceLimited[N]](err); limited && s.recordDenied != nil {
+		s.recordDenied(ctx, amount, withScope(ctx, s.scope))
+	}
+	return err
+}
+
+// Free releases amount to the store, except for any amount which was used while failing open.
+func (s *sharedResourcePoolLimiter[N]) Free(ctx context.Context, amount N) error {
+	tenant, failOpen, err := sharedTenant(ctx, s.lggr, s.scope)
+	if err != nil {
+		return fmt.Errorf("failed to get resource pool: %w", err)
+	} else if failOpen {
+		return nil
+	}
+	key := StoreKey("resource", s.key, s.scope, tenant)
+	if amount = s.takeUnacquired(key, amount); amount <= 0 {
+		return nil
+	}
+	return s.release(ctx, key, amount)
+}
+
+func (s *sharedResourcePoolLimiter[N]) Wait(ctx context.Context, amount N) (func(), error) {
+	start := time.Now()
+	var t *time.Ticker
+	for {
+		release, ok, err := s.use(ctx, amount)
+		if ok {
+			if s.recordBlockTime != nil {
+				s.recordBlockTime(ctx, time.Since(start).Seconds(), withScope(ctx, s.scope))
+			}
+			freeCtx := context.WithoutCancel(ctx)
+			return sync.OnceFunc(func() {
+				if err := release(freeCtx); err != nil {
+					s.lggr.Errorw("Failed to free resources", "amount", amount, "err", err)
+				}
+			}), nil
+		}
+		if _, limited := errors.AsType[ErrorResourceLimited[N]](err); !limited {
+			return nil, err
+		}
+		if t == nil {
+			t = time.NewTicker(s.waitPeriod)
+			defer t.Stop()
+		}
+		select {
+		case <-ctx.Done():
+			if s.recordDenied != nil {
+				s.recordDenied(ctx, amount, withScope(ctx, s.scope))
+			}
+			return nil, fmt.Errorf("context error (%w) after waiting %s for limit: %w", ctx.Err(), time.Since(start), err)
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package limits
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/config"
+	"github.com/smartcontractkit/chainlink-common/pkg/contexts"
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+	"github.com/smartcontractkit/chainlink-common/pkg/settings"
+)
+
+func TestTokenBucket(t *testing.T) {
+	r := config.Rate{Limit: 2, Burst: 4}
+	now := time.Now()
+	var b TokenBucket
+
+	delay, ok := b.Take(r, 5, now, time.Hour)
+	assert.False(t, ok, "exceeds burst")
+	assert.Zero(t, delay)
+
+	delay, ok = b.Take(r, 4, now, 0)
+	require.True(t, ok)
+	assert.Zero(t, delay)
+
+	_, ok = b.Take(r, 1, now, 0)
+	assert.False(t, ok, "empty")
+	delay, ok = b.Take(r, 1, now, time.Second)
+	require.True(t, ok)
+	assert.Equal(t, 500*time.Millisecond, delay)
+
+	b.Put(r, 1, now)
+	delay, ok = b.Take(r, 1, now.Add(time.Second), 0)
+	require.True(t, ok)
+	assert.Zero(t, delay)
+
+	delay, ok = b.Take(config.Rate{Limit: rate.Inf}, 100, now, 0)
+	assert.True(t, ok)
+	assert.Zero(t, delay)
+}
+
+func TestFactory_Store_RateLimiter(t *testing.T) {
+	store := NewMemoryStore()
+	limit := settings.Rate(rate.Every(time.Hour), 3)
+	limit.Key = "foo"
+	limit.Scope = settings.ScopeOwner
+
+	// two limiters, as if from different processes
+	a, err := Factory{Store: store, Logger: logger.Test(t)}.MakeRateLimiter(limit)
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, a.Close()) })
+	b, err := Factory{Store: store, Logger: logger.Test(t)}.MakeRateLimiter(limit)
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, b.Close()) })
+
+	ctx := contexts.WithCRE(t.Context(), contexts.CRE{Owner: "owner-a"})
+	require.NoError(t, a.AllowErr(ctx))
+	require.NoError(t, b.AllowErr(ctx))
+	require.NoError(t, a.AllowErr(ctx))
+	err = b.AllowErr(ctx)
+	require.ErrorIs(t, err, ErrorRateLimited{})
+	assert.ErrorContains(t, err, "foo rate limited for owner[owner-a]")
+	assert.False(t, a.Allow(ctx))
+
+	// other tenants are unaffected
+	ctxB := contexts.WithCRE(t.Context(), contexts.CRE{Owner: "owner-b"})
+	assert.True(t, b.Allow(ctxB))
+
+	t.Run("reserve", func(t *testing.T) {
+		r, err := a.ReserveN(ctxB, time.Now(), 2)
+		require.NoError(t, err)
+		assert.True(t, r.Allow())
+		r, err = a.ReserveN(ctxB, time.Now(), 1)
+		require.NoError(t, err)
+		assert.False(t, r.Allow())
+		assert.ErrorIs(t, r.AllowErr(), ErrorRateLimited{})
+		r.Cancel()
+		_, err = b.ReserveN(ctxB, time.Now(), 4)
+		assert.ErrorIs(t, err, ErrorRateLimited{}, "exceeds burst")
+	})
+
+	t.Run("wait", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(contexts.WithCRE(t.Context(), contexts.CRE{Owner: "owner-c"}), time.Second)
+		defer cancel()
+		require.NoError(t, a.WaitN(ctx, 3))
+		assert.ErrorIs(t, b.Wait(ctx), ErrorRateLimited{})
+	})
+
+	t.Run("missing tenant", func(t *testing.T) {
+		assert.Error(t, a.AllowErr(t.Context()))
+	})
+}
+
+func TestFactory_Store_ResourcePoolLimiter(t *testing.T) {
+	store := NewMemoryStore()
+	limit := settings.Int(2)
+	limit.Key = "bar"
+	limit.Scope = settings.ScopeWorkflow
+
+	f := Factory{Store: store, StoreWaitPeriod: 10 * time.Millisecond}
+	a, err := MakeResourcePoolLimiter(f, limit)
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, a.Close()) })
+	b, err := MakeResourcePoolLimiter(f, limit)
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, b.Close()) })
+
+	ctx := contexts.WithCRE(t.Context(), contexts.CRE{Owner: "o", Workflow: "wf"})
+	require.NoError(t, a.Use(ctx, 1))
+	require.NoError(t, b.Use(ctx, 1))
+	err = a.Use(ctx, 1)
+	require.ErrorIs(t, err, ErrorResourceLimited[int]{})
+	assert.ErrorContains(t, err, "bar resource limited for workflow[wf]: cannot use 1, already using 2/2")
+
+	avail, err := b.Available(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, avail)
+
+	waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = b.Wait(waitCtx, 1)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		free, err := b.Wait(ctx, 2)
+		assert.NoError(t, err)
+		free()
+	}()
+	require.NoError(t, a.Free(ctx, 1))
+	require.NoError(t, b.Free(ctx, 1))
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for resources")
+	}
+
+	avail, err = a.Available(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, avail)
+}
+
+func TestSharedReservation_CancelAt(t *testing.T) {
+	store := NewMemoryStore()
+	limit := settings.Rate(rate.Every(time.Hour), 1)
+	limit.Key = "foo"
+	l, err := Factory{Store: store}.MakeRateLimiter(limit)
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, l.Close()) })
+
+	now := time.Now()
+	r, err := l.ReserveN(t.Context(), now, 1)
+	require.NoError(t, err)
+	r.CancelAt(now.Add(time.Nanosecond))
+	assert.False(t, l.AllowN(t.Context(), now, 1), "already used")
+
+	now = now.Add(time.Hour)
+	r, err = l.ReserveN(t.Context(), now, 1)
+	require.NoError(t, err)
+	r.CancelAt(now)
+	assert.True(t, l.AllowN(t.Context(), now, 1), "returned at the time to act, like rate.Reservation")
+}
+
+// failingStore is a StateStore which fails to acquire resources.
+type failingStore struct {
+	*MemoryStore
+}
+
+func (failingStore) AcquireResource(context.Context, string, string, float64, float64, time.Time, time.Duration) (float64, bool, error) {
+	return 0, false, errors.New("unavailable")
+}
+
+func TestFactory_Store_ResourcePoolLimiter_FailOpen(t *testing.T) {
+	store := NewMemoryStore()
+	limit := settings.Int(2)
+	limit.Key = "bar"
+
+	a, err := MakeResourcePoolLimiter(Factory{Store: store}, limit)
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, a.Close()) })
+	failing, err := MakeResourcePoolLimiter(Factory{Store: failingStore{store}}, limit)
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, failing.Close()) })
+
+	ctx := t.Context()
+	require.NoError(t, a.Use(ctx, 2))
+	require.NoError(t, failing.Use(ctx, 1), "fails open")
+	free, err := failing.Wait(ctx, 1)
+	require.NoError(t, err, "fails open")
+
+	// nothing was acquired, so nothing is released
+	require.NoError(t, failing.Free(ctx, 1))
+	free()
+	avail, err := a.Available(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, avail)
+}
+
+func TestFactory_Store_ResourcePoolLimiter_Lease(t *testing.T) {
+	store := NewMemoryStore()
+	limit := settings.Int(2)
+	limit.Key = "bar"
+	f := Factory{Store: store, StoreLeaseTTL: 50 * time.Millisecond}
+
+	a, err := MakeResourcePoolLimiter(f, limit)
+	require.NoError(t, err)
+	b, err := MakeResourcePoolLimiter(f, limit)
+	require.NoError(t, err)
+	t.Cleanup(func() { assert.NoError(t, b.Close()) })
+
+	ctx := t.Context()
+	require.NoError(t, a.Use(ctx, 1))
+	require.NoError(t, b.Use(ctx, 1))
+
+	// b keeps renewing its lease while a has stopped, as if it crashed
+	require.NoError(t, a.Close())
+	require.Eventually(t, func() bool {
+		avail, err := b.Available(ctx)
+		return err == nil && avail == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	time.Sleep(100 * time.Millisecond)
+	avail, err := b.Available(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, avail, "renewed")
+}
@@ -0,0 +1,228 @@
+package limits
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/config"
+	"github.com/smartcontractkit/chainlink-common/pkg/settings"
+)
+
+// StateStore holds the state of token buckets and resource pools, so that limits can be enforced across every process
+// sharing the same store. Keys are opaque, and are derived from the limit key, scope, and tenant.
+// See NewMemoryStore, and pgstore.New for a database backed implementation.
+type StateStore interface {
+	// TakeTokens refills the bucket for key at rate r, then takes n tokens if they will be available within maxWait.
+	// It returns ok=false without taking any tokens if n exceeds the burst, or the wait would exceed maxWait.
+	// Otherwise, delay is how long the caller must wait from now before acting.
+	TakeTokens(ctx context.Context, key string, r config.Rate, n int, now time.Time, maxWait time.Duration) (delay time.Duration, ok bool, err error)
+	// TakeAllTokens is like TakeTokens, but takes n tokens from every bucket, or from none. If not ok, then denied is
+	// the index of the first bucket which did not allow it. Otherwise, delays holds the delay of each bucket.
+	TakeAllTokens(ctx context.Context, buckets []RateBucket, n int, now time.Time, maxWait time.Duration) (delays []time.Duration, denied int, ok bool, err error)
+	// ReturnTokens puts n tokens back in the bucket for key, up to the burst. For example, to cancel a reservation.
+	ReturnTokens(ctx context.Context, key string, r config.Rate, n int, now time.Time) error
+
+	// AcquireResource increases the usage of the pool for key by amount, if the result would not exceed limit. The
+	// amount is leased to holder until now+ttl, and the usage of expired leases is reclaimed first. It returns the
+	// resulting usage if ok, or the current usage if not.
+	AcquireResource(ctx context.Context, key, holder string, limit, amount float64, now time.Time, ttl time.Duration) (used float64, ok bool, err error)
+	// ReleaseResource decreases the usage of the pool for key by amount, up to the amount leased to holder.
+	ReleaseResource(ctx context.Context, key, holder string, amount float64) error
+	// RenewResources extends every lease of holder until now+ttl. A holder which stops renewing, e.g. because its
+	// process crashed, loses its leases once they expire.
+	RenewResources(ctx context.Context, holder string, now time.Time, ttl time.Duration) error
+}
+
+// StoreKey returns the StateStore key for a limit of kind (e.g. "rate" or "resource") identified by key, scope, and
+// tenant.
+func StoreKey(kind, key string, scope settings.Scope, tenant string) string {
+	if scope == settings.ScopeGlobal {
+		return kind + "." + key
+	}
+	return kind + "." + key + "." + scope.String() + "." + tenant
+}
+
+// RateBucket identifies a token bucket in a StateStore, and the rate at which it refills.
+type RateBucket struct {
+	Key  string
+	Rate config.Rate
+}
+
+// TokenBucket is the state of a single token bucket, for use by StateStore implementations.
+// The zero value is a full bucket.
+type TokenBucket struct {
+	Tokens float64
+	Last   time.Time
+}
+
+// refill advances the bucket to now, adding tokens at rate r, up to the burst.
+func (b *TokenBucket) refill(r config.Rate, now time.Time) {
+	burst := float64(r.Burst)
+	if b.Last.IsZero() {
+		b.Tokens, b.Last = burst, now
+		return
+	}
+	if now.After(b.Last) {
+		b.Tokens += now.Sub(b.Last).Seconds() * float64(r.Limit)
+		b.Last = now
+	}
+	b.Tokens = math.Min(b.Tokens, burst)
+}
+
+// Take implements the bucket logic of StateStore.TakeTokens. The bucket is only modified if ok is true.
+func (b *TokenBucket) Take(r config.Rate, n int, now time.Time, maxWait time.Duration) (delay time.Duration, ok bool) {
+	if r.Limit == rate.Inf {
+		return 0, true
+	}
+	if n > r.Burst {
+		return 0, false
+	}
+	next := *b
+	next.refill(r, now)
+	next.Tokens -= float64(n)
+	if next.Tokens < 0 {
+		if r.Limit <= 0 {
+			return 0, false
+		}
+		delay = time.Duration(-next.Tokens / float64(r.Limit) * float64(time.Second))
+	}
+	if delay > maxWait {
+		return 0, false
+	}
+	*b = next
+	return delay, true
+}
+
+// Put implements the bucket logic of StateStore.ReturnTokens.
+func (b *TokenBucket) Put(r config.Rate, n int, now time.Time) {
+	if r.Limit == rate.Inf {
+		return
+	}
+	b.refill(r, now)
+	b.Tokens = math.Min(b.Tokens+float64(n), float64(r.Burst))
+}
+
+var _ StateStore = &MemoryStore{}
+
+// MemoryStore is a StateStore which keeps state in process memory.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*TokenBucket
+	resources map[string]map[string]*resourceLease // key -> holder -> lease
+}
+
+type resourceLease struct {
+	used      float64
+	expiresAt time.Time
+}
+
+// NewMemoryStore returns a new, empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets:   make(map[string]*TokenBucket),
+		resources: make(map[string]map[string]*resourceLease),
+	}
+}
+
+func (m *MemoryStore) TakeTokens(ctx context.Context, key string, r config.Rate, n int, now time.Time, maxWait time.Duration) (time.Duration, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &TokenBucket{}
+		m.buckets[key] = b
+	}
+	delay, ok := b.Take(r, n, now, maxWait)
+	return delay, ok, nil
+}
+
+func (m *MemoryStore) TakeAllTokens(ctx context.Context, buckets []RateBucket, n int, now time.Time, maxWait time.Duration) ([]time.Duration, int, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	next := make([]TokenBucket, len(buckets))
+	delays := make([]time.Duration, len(buckets))
+	for i, rb := range buckets {
+		if b, ok := m.buckets[rb.Key]; ok {
+			next[i] = *b
+		}
+		delay, ok := next[i].Take(rb.Rate, n, now, maxWait)
+		if !ok {
+			return nil, i, false, nil
+		}
+		delays[i] = delay
+	}
+	for i, rb := range buckets {
+		b := next[i]
+		m.buckets[rb.Key] = &b
+	}
+	return delays, 0, true, nil
+}
+
+func (m *MemoryStore) ReturnTokens(ctx context.Context, key string, r config.Rate, n int, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if b, ok := m.buckets[key]; ok {
+		b.Put(r, n, now)
+	}
+	return nil
+}
+
+func (m *MemoryStore) AcquireResource(ctx context.Context, key, holder string, limit, amount float64, now time.Time, ttl time.Duration) (float64, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	leases, ok := m.resources[key]
+	if !ok {
+		leases = make(map[string]*resourceLease)
+		m.resources[key] = leases
+	}
+	var used float64
+	for h, l := range leases {
+		if !l.expiresAt.After(now) {
+			delete(leases, h) // reclaim
+			continue
+		}
+		used += l.used
+	}
+	if used+amount > limit {
+		return used, false, nil
+	}
+	if amount == 0 {
+		return used, true, nil
+	}
+	l, ok := leases[holder]
+	if !ok {
+		l = &resourceLease{}
+		leases[holder] = l
+	}
+	l.used += amount
+	l.expiresAt = now.Add(ttl)
+	return used + amount, true, nil
+}
+
+func (m *MemoryStore) ReleaseResource(ctx context.Context, key, holder string, amount float64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if l, ok := m.resources[key][holder]; ok {
+		l.used = math.Max(l.used-amount, 0)
+	}
+	return nil
+}
+
+func (m *MemoryStore) RenewResources(ctx context.Context, holder string, now time.Time, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, leases := range m.resources {
+		if l, ok := leases[holder]; ok {
+			if l.used <= 0 {
+				delete(leases, holder)
+				continue
+			}
+			l.expiresAt = now.Add(ttl)
+		}
+	}
+	return nil
+}
@@ -98,6 +98,66 @@ func (s *SettingMap[T]) GetOrDefault(ctx context.Context, g Getter) (value T, er
 	return
 }
 
+// Explain is like GetOrDefault, but includes an Explanation of where the value came from. Errors are returned, or
+// ignored in favor of a fallback value, exactly as by GetOrDefault. A value from Values is not a Default, and has
+// Source BuiltInSource.
+func (s *SettingMap[T]) Explain(ctx context.Context, g Getter) (Explained[T], error) {
+	def := Explained[T]{
+		Value:       s.Default.DefaultValue,
+		Explanation: Explanation{Key: s.Default.Key, Value: fmt.Sprint(s.Default.DefaultValue), Default: true},
+	}
+	if s.KeyFromCtx == nil {
+		return def, errors.New("missing KeyFromCtx func")
+	}
+	k, err := s.KeyFromCtx(ctx)
+	if err != nil {
+		return def, fmt.Errorf("failed to get value from context: %w", err)
+	}
+	valueKey := s.Default.Key + ".Values." + strconv.FormatUint(k, 10)
+	valueOrDefault := func() (Explained[T], error) {
+		if str, ok := s.Values[strconv.FormatUint(k, 10)]; ok {
+			value, err := s.Default.Parse(str)
+			if err != nil {
+				return def, err
+			}
+			return Explained[T]{Value: value, Explanation: Explanation{
+				Key:    s.Default.Key,
+				Value:  str,
+				Scope:  ScopeGlobal,
+				RawKey: valueKey,
+				Source: BuiltInSource,
+			}}, nil
+		}
+		return def, nil
+	}
+	if g == nil {
+		return valueOrDefault()
+	}
+
+	// Values override
+	e, err := ExplainScoped(ctx, g, s.Default.Scope, valueKey)
+	if err != nil {
+		return def, err
+	} else if !e.Default && e.Value != "" {
+		value, err := s.Default.Parse(e.Value)
+		if err != nil {
+			return valueOrDefault()
+		}
+		return Explained[T]{Value: value, Explanation: e}, nil
+	}
+
+	// Default override
+	e, err = ExplainScoped(ctx, g, s.Default.Scope, s.Default.Key+".Default")
+	if err != nil || e.Default || e.Value == "" {
+		return valueOrDefault()
+	}
+	value, err := s.Default.Parse(e.Value)
+	if err != nil {
+		return valueOrDefault()
+	}
+	return Explained[T]{Value: value, Explanation: e}, nil
+}
+
 func (s *SettingMap[T]) Subscribe(ctx context.Context, registry Registry) (<-chan Update[T], func()) {
 	//TODO subscribe to Values & Default
 
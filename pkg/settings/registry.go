@@ -0,0 +1,336 @@
+package settings
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/contexts"
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+	"github.com/smartcontractkit/chainlink-common/pkg/services"
+)
+
+const (
+	// DefaultPollPeriod is the default period for checking a Source that can't be watched for changes.
+	DefaultPollPeriod = 5 * time.Second
+	// DefaultFallbackPollPeriod is the default period for checking a watched Source, in case the watcher misses a
+	// change, e.g. on a network file system.
+	DefaultFallbackPollPeriod = time.Minute
+
+	// watchDebounce coalesces the events of a change that writes several files, or writes a file in several steps.
+	watchDebounce = 50 * time.Millisecond
+)
+
+// Source provides raw settings to a Registry.
+type Source interface {
+	// Read returns the current raw settings.
+	Read(ctx context.Context) ([]byte, error)
+	// NewGetter parses raw settings from Read.
+	NewGetter(cfg GetterConfig, b []byte) (Getter, error)
+}
+
+// JSONDirSource returns a Source which combines the JSON files in dir via CombineJSONFiles.
+func JSONDirSource(dir string) Source { return &dirSource{dir: dir, json: true} }
+
+// TOMLDirSource returns a Source which combines the TOML files in dir via CombineTOMLFiles.
+func TOMLDirSource(dir string) Source { return &dirSource{dir: dir} }
+
+type dirSource struct {
+	dir  string
+	json bool
+}
+
+// watchedSource is implemented by Sources whose changes can be watched in the file system.
+type watchedSource interface {
+	// watchDirs returns the existing directories the Source reads from.
+	watchDirs() []string
+}
+
+var _ watchedSource = &dirSource{}
+
+func (d *dirSource) watchDirs() []string {
+	dirs := []string{d.dir}
+	_ = filepath.WalkDir(d.dir, func(path string, e fs.DirEntry, err error) error {
+		if err == nil && e.IsDir() && path != d.dir {
+			dirs = append(dirs, path)
+		}
+		return nil
+	})
+	return dirs
+}
+
+func (d *dirSource) Read(ctx context.Context) ([]byte, error) {
+	if d.json {
+		return CombineJSONFiles(os.DirFS(d.dir))
+	}
+	return CombineTOMLFiles(os.DirFS(d.dir))
+}
+
+func (d *dirSource) NewGetter(cfg GetterConfig, b []byte) (Getter, error) {
+	if d.json {
+		return cfg.NewJSONGetter(b)
+	}
+	return cfg.NewTOMLGetter(b)
+}
+
+// RegistryConfig configures a PollingRegistry.
+type RegistryConfig struct {
+	GetterConfig
+	// PollPeriod is how often a Source that can't be watched is checked for changes. Defaults to DefaultPollPeriod.
+	PollPeriod time.Duration
+	// FallbackPollPeriod is how often a watched Source, such as JSONDirSource and TOMLDirSource, is checked for
+	// changes the watcher missed. Defaults to DefaultFallbackPollPeriod.
+	FallbackPollPeriod time.Duration
+}
+
+var (
+	_ Registry  = &PollingRegistry{}
+	_ Explainer = &PollingRegistry{}
+)
+
+// PollingRegistry is a Registry which reads from a Source when it changes, and pushes changed values to subscribers.
+// Directory Sources are watched for changes, and only polled as a fallback. Other Sources are polled.
+// Subscriptions are grouped by key and tenant, so that each distinct value is only resolved once per change.
+type PollingRegistry struct {
+	services.Service
+	eng *services.Engine
+
+	cfg RegistryConfig
+	src Source
+
+	pollMu sync.Mutex // serializes polls
+
+	mu     sync.RWMutex
+	raw    []byte
+	getter Getter
+	groups map[subKey]*subGroup
+}
+
+// NewRegistry returns a new PollingRegistry for src. The Source is read once immediately, so that the Registry can
+// serve GetScoped before Start is called. Start must be called to begin watching for changes.
+func (c RegistryConfig) NewRegistry(ctx context.Context, src Source) (*PollingRegistry, error) {
+	if c.Logger == nil {
+		c.Logger = logger.Nop()
+	}
+	if c.PollPeriod <= 0 {
+		c.PollPeriod = DefaultPollPeriod
+	}
+	if c.FallbackPollPeriod <= 0 {
+		c.FallbackPollPeriod = DefaultFallbackPollPeriod
+	}
+	r := &PollingRegistry{cfg: c, src: src, groups: make(map[subKey]*subGroup)}
+	raw, err := src.Read(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read settings: %w", err)
+	}
+	getter, err := src.NewGetter(c.GetterConfig, raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse settings: %w", err)
+	}
+	r.raw, r.getter = raw, getter
+	r.Service, r.eng = services.Config{
+		Name:  "SettingsRegistry",
+		Start: r.start,
+	}.NewServiceEngine(c.Logger)
+	return r, nil
+}
+
+func (r *PollingRegistry) start(context.Context) error {
+	period := r.cfg.PollPeriod
+	if src, ok := r.src.(watchedSource); ok {
+		w, err := newDirWatcher(src.watchDirs())
+		if err != nil {
+			r.eng.Warnw("Failed to watch settings for changes. Polling instead", "err", err)
+		} else {
+			period = r.cfg.FallbackPollPeriod
+			r.eng.Go(func(ctx context.Context) { r.watch(ctx, w) })
+		}
+	}
+	r.eng.GoTick(services.TickerConfig{Initial: period}.NewTicker(period), r.poll)
+	return nil
+}
+
+func newDirWatcher(dirs []string) (*fsnotify.Watcher, error) {
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range dirs {
+		if err := w.Add(dir); err != nil {
+			_ = w.Close()
+			return nil, fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+	return w, nil
+}
+
+// watch polls the Source shortly after each change to the watched directories, until ctx is done. Directories
+// created later, such as a first owner override, are watched too.
+func (r *PollingRegistry) watch(ctx context.Context, w *fsnotify.Watcher) {
+	defer func() {
+		if err := w.Close(); err != nil {
+			r.eng.Errorw("Failed to close settings watcher", "err", err)
+		}
+	}()
+	var debounce <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.Events:
+			if !ok {
+				return
+			}
+			if event.Has(fsnotify.Create) {
+				if fi, err := os.Stat(event.Name); err == nil && fi.IsDir() {
+					if err := w.Add(event.Name); err != nil {
+						r.eng.Errorw("Failed to watch settings directory", "dir", event.Name, "err", err)
+					}
+				}
+			}
+			if debounce == nil {
+				debounce = time.After(watchDebounce)
+			}
+		case err, ok := <-w.Errors:
+			if !ok {
+				return
+			}
+			r.eng.Errorw("Settings watcher failed", "err", err)
+		case <-debounce:
+			debounce = nil
+			r.poll(ctx)
+		}
+	}
+}
+
+func (r *PollingRegistry) GetScoped(ctx context.Context, scope Scope, key string) (string, error) {
+	r.mu.RLock()
+	g := r.getter
+	r.mu.RUnlock()
+	return g.GetScoped(ctx, scope, key)
+}
+
+// ExplainScoped implements Explainer. Sources are qualified by the directory for JSONDirSource and TOMLDirSource.
+func (r *PollingRegistry) ExplainScoped(ctx context.Context, scope Scope, key string) (Explanation, error) {
+	r.mu.RLock()
+	g := r.getter
+	r.mu.RUnlock()
+	e, err := ExplainScoped(ctx, g, scope, key)
+	if d, ok := r.src.(*dirSource); ok && e.Source != "" {
+		e.Source = filepath.Join(d.dir, e.Source)
+	}
+	return e, err
+}
+
+// SubscribeScoped implements Registry. The current value is sent immediately, followed by each change.
+// Only the latest update is buffered, so slow consumers skip intermediate values.
+// The subscription is also stopped when ctx is done.
+func (r *PollingRegistry) SubscribeScoped(ctx context.Context, scope Scope, key string) (<-chan Update[string], func()) {
+	k := subKey{scope: scope, key: key, cre: scope.RoundCRE(contexts.CREValue(ctx))}
+	s := &subscription{ch: make(chan Update[string], 1)}
+
+	r.mu.Lock()
+	g, ok := r.groups[k]
+	if !ok {
+		g = &subGroup{subs: make(map[*subscription]struct{})}
+		g.value, g.err = r.getter.GetScoped(k.ctx(), scope, key)
+		r.groups[k] = g
+	}
+	g.subs[s] = struct{}{}
+	s.send(Update[string]{Value: g.value, Err: g.err})
+	r.mu.Unlock()
+
+	stop := sync.OnceFunc(func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		delete(g.subs, s)
+		if len(g.subs) == 0 {
+			delete(r.groups, k)
+		}
+	})
+	context.AfterFunc(ctx, stop)
+	return s.ch, stop
+}
+
+// poll reads the Source and notifies subscribers of any changed values.
+func (r *PollingRegistry) poll(ctx context.Context) {
+	r.pollMu.Lock()
+	defer r.pollMu.Unlock()
+	raw, err := r.src.Read(ctx)
+	if err != nil {
+		r.eng.Errorw("Failed to read settings", "err", err)
+		return
+	}
+	r.mu.RLock()
+	unchanged := bytes.Equal(raw, r.raw)
+	r.mu.RUnlock()
+	if unchanged {
+		return
+	}
+	getter, err := r.src.NewGetter(r.cfg.GetterConfig, raw)
+	if err != nil {
+		r.eng.Errorw("Failed to parse settings. Keeping previous values", "err", err)
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.raw, r.getter = raw, getter
+	var changed int
+	for k, g := range r.groups {
+		value, err := getter.GetScoped(k.ctx(), k.scope, k.key)
+		if value == g.value && errString(err) == errString(g.err) {
+			continue
+		}
+		changed++
+		g.value, g.err = value, err
+		for s := range g.subs {
+			s.send(Update[string]{Value: value, Err: err})
+		}
+	}
+	r.eng.Debugw("Settings updated", "changed", changed, "subscriptions", len(r.groups))
+}
+
+// subKey identifies a distinct value: a key resolved for a particular tenant at a particular scope.
+type subKey struct {
+	scope Scope
+	key   string
+	cre   contexts.CRE
+}
+
+func (k subKey) ctx() context.Context {
+	return contexts.WithCRE(context.Background(), k.cre)
+}
+
+type subGroup struct {
+	value string
+	err   error
+	subs  map[*subscription]struct{}
+}
+
+type subscription struct {
+	ch chan Update[string]
+}
+
+// send replaces any pending update with u. Must only be called with the Registry lock held.
+func (s *subscription) send(u Update[string]) {
+	select {
+	case <-s.ch: // drop stale
+	default:
+	}
+	s.ch <- u
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
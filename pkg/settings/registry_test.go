@@ -0,0 +1,167 @@
+package settings
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/contexts"
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+}
+
+func TestPollingRegistry(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "global.json"), `{"Foo": "5", "Bar": "1"}`)
+	writeFile(t, filepath.Join(dir, "owner", "abc.json"), `{"Foo": "13"}`)
+
+	r, err := RegistryConfig{
+		GetterConfig: GetterConfig{Logger: logger.Test(t)},
+		PollPeriod:   10 * time.Millisecond,
+	}.NewRegistry(t.Context(), JSONDirSource(dir))
+	require.NoError(t, err)
+
+	ctx := contexts.WithCRE(t.Context(), contexts.CRE{Owner: "abc", Workflow: "wf"})
+	got, err := r.GetScoped(ctx, ScopeOwner, "Foo")
+	require.NoError(t, err)
+	assert.Equal(t, "13", got)
+
+	runRegistry(t, r)
+
+	owner, stopOwner := r.SubscribeScoped(ctx, ScopeOwner, "Foo")
+	t.Cleanup(stopOwner)
+	global, stopGlobal := r.SubscribeScoped(ctx, ScopeGlobal, "Foo")
+	t.Cleanup(stopGlobal)
+	bar, stopBar := r.SubscribeScoped(ctx, ScopeGlobal, "Bar")
+	t.Cleanup(stopBar)
+
+	requireUpdate(t, owner, "13")
+	requireUpdate(t, global, "5")
+	requireUpdate(t, bar, "1")
+
+	writeFile(t, filepath.Join(dir, "owner", "abc.json"), `{"Foo": "42"}`)
+	requireUpdate(t, owner, "42")
+
+	writeFile(t, filepath.Join(dir, "global.json"), `{"Foo": "6", "Bar": "1"}`)
+	requireUpdate(t, global, "6")
+
+	select {
+	case u := <-bar:
+		t.Fatalf("unexpected update for unchanged key: %v", u)
+	case <-owner:
+		t.Fatal("unexpected update for overridden key")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	t.Run("stop", func(t *testing.T) {
+		updates, stop := r.SubscribeScoped(ctx, ScopeGlobal, "Bar")
+		requireUpdate(t, updates, "1")
+		stop()
+		stop() // idempotent
+		writeFile(t, filepath.Join(dir, "global.json"), `{"Foo": "6", "Bar": "2"}`)
+		requireUpdate(t, bar, "2")
+		select {
+		case u := <-updates:
+			t.Fatalf("unexpected update after stop: %v", u)
+		default:
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		writeFile(t, filepath.Join(dir, "global.json"), `{"Foo": `)
+		time.Sleep(50 * time.Millisecond)
+		got, err := r.GetScoped(ctx, ScopeGlobal, "Foo")
+		require.NoError(t, err)
+		assert.Equal(t, "6", got)
+	})
+}
+
+const waitTimeout = 5 * time.Second
+
+func runRegistry(t *testing.T, r *PollingRegistry) {
+	t.Helper()
+	require.NoError(t, r.Start(t.Context()))
+	t.Cleanup(func() { assert.NoError(t, r.Close()) })
+}
+
+func requireUpdate(t *testing.T, ch <-chan Update[string], exp string) {
+	t.Helper()
+	select {
+	case u := <-ch:
+		require.NoError(t, u.Err)
+		require.Equal(t, exp, u.Value)
+	case <-time.After(waitTimeout):
+		t.Fatalf("timed out waiting for update: %s", exp)
+	}
+}
+
+func TestPollingRegistry_Setting(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "global.toml"), "Foo = \"5\"\n")
+
+	r, err := RegistryConfig{PollPeriod: 10 * time.Millisecond}.NewRegistry(t.Context(), TOMLDirSource(dir))
+	require.NoError(t, err)
+	runRegistry(t, r)
+
+	s := Int(1)
+	s.Key = "Foo"
+	updates, stop := s.Subscribe(t.Context(), r)
+	t.Cleanup(stop)
+
+	select {
+	case u := <-updates:
+		require.NoError(t, u.Err)
+		assert.Equal(t, 5, u.Value)
+	case <-time.After(waitTimeout):
+		t.Fatal("timed out waiting for initial value")
+	}
+
+	writeFile(t, filepath.Join(dir, "global.toml"), "Foo = \"7\"\n")
+	select {
+	case u := <-updates:
+		require.NoError(t, u.Err)
+		assert.Equal(t, 7, u.Value)
+	case <-time.After(waitTimeout):
+		t.Fatal("timed out waiting for update")
+	}
+}
+
+func TestPollingRegistry_Watch(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "global.json"), `{"Foo": "5"}`)
+
+	r, err := RegistryConfig{
+		GetterConfig:       GetterConfig{Logger: logger.Test(t)},
+		PollPeriod:         time.Hour,
+		FallbackPollPeriod: time.Hour,
+	}.NewRegistry(t.Context(), JSONDirSource(dir))
+	require.NoError(t, err)
+	runRegistry(t, r)
+
+	ctx := contexts.WithCRE(t.Context(), contexts.CRE{Owner: "abc", Workflow: "wf"})
+	global, stopGlobal := r.SubscribeScoped(ctx, ScopeGlobal, "Foo")
+	t.Cleanup(stopGlobal)
+	owner, stopOwner := r.SubscribeScoped(ctx, ScopeOwner, "Foo")
+	t.Cleanup(stopOwner)
+	requireUpdate(t, global, "5")
+	requireUpdate(t, owner, "5")
+
+	writeFile(t, filepath.Join(dir, "global.json"), `{"Foo": "6"}`)
+	requireUpdate(t, global, "6")
+	requireUpdate(t, owner, "6")
+
+	// A directory created after Start is watched too.
+	writeFile(t, filepath.Join(dir, "owner", "abc.json"), `{"Foo": "13"}`)
+	requireUpdate(t, owner, "13")
+	writeFile(t, filepath.Join(dir, "owner", "abc.json"), `{"Foo": "42"}`)
+	requireUpdate(t, owner, "42")
+}
@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
-	"os"
 	"strings"
 
 	"github.com/pelletier/go-toml"
@@ -30,6 +29,9 @@ func CombineTOMLFiles(files fs.FS) ([]byte, error) {
 		return nil, fmt.Errorf("failed to ecode TOML: %w", err)
 	}
 	global, err := readTOMLTree(files, "global.toml")
+	if errors.Is(err, fs.ErrNotExist) {
+		global, err = toml.TreeFromMap(map[string]any{}) // no global settings
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -69,9 +71,6 @@ func CombineTOMLFiles(files fs.FS) ([]byte, error) {
 func readTOMLTree(files fs.FS, name string) (*toml.Tree, error) {
 	f, err := files.Open(name)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return toml.TreeFromMap(make(map[string]any))
-		}
 		return nil, fmt.Errorf("failed to open %s: %w", name, err)
 	}
 	defer f.Close()
@@ -90,6 +89,9 @@ func readTOMLTrees(files fs.FS, dir string) (*toml.Tree, error) {
 	}
 	if err := fs.WalkDir(files, dir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
+			if path == dir && errors.Is(err, fs.ErrNotExist) {
+				return nil // no overrides
+			}
 			return err
 		}
 		if d.IsDir() {
@@ -102,7 +104,7 @@ func readTOMLTrees(files fs.FS, dir string) (*toml.Tree, error) {
 		name := strings.TrimSuffix(d.Name(), ".toml")
 		trees.Set(name, t)
 		return nil
-	}); err != nil && !os.IsNotExist(err) {
+	}); err != nil {
 		return nil, fmt.Errorf("failed to walk %s: %w", dir, err)
 	}
 	return trees, nil
@@ -120,25 +122,24 @@ func newTOMLSettings(b []byte) (*tomlSettings, error) {
 	return &tomlSettings{tree: tree}, nil
 }
 
-func (t *tomlSettings) getFirst(keys ...string) (string, error) {
-	for _, k := range keys {
+// getFirst returns the first value found, and the index of its key, or -1 if none was found.
+func (t *tomlSettings) getFirst(keys ...string) (string, int, error) {
+	for i, k := range keys {
 		v := t.tree.Get(k)
 		if v == nil {
 			continue // next key
 		}
 		s, ok := v.(string)
 		if !ok {
-			return "", fmt.Errorf("non-string value: %s: %t(%v)", k, v, v)
+			return "", -1, fmt.Errorf("non-string value: %s: %t(%v)", k, v, v)
 		}
-		return s, nil
+		return s, i, nil
 	}
-	return "", nil // no values
+	return "", -1, nil // no values
 }
 
-var _ Getter = &tomlGetter{}
+var _ Explainer = &tomlGetter{}
 
-// TODO https://smartcontract-it.atlassian.net/browse/CAPPL-775
-// NewTOMLRegistry with polling & subscriptions
 type tomlGetter struct {
 	settings *tomlSettings
 	lggr     logger.Logger
@@ -163,14 +164,23 @@ func (c GetterConfig) NewTOMLGetter(b []byte) (Getter, error) {
 }
 
 func (t *tomlGetter) GetScoped(ctx context.Context, scope Scope, key string) (value string, err error) {
+	e, err := t.ExplainScoped(ctx, scope, key)
+	return e.Value, err
+}
+
+func (t *tomlGetter) ExplainScoped(ctx context.Context, scope Scope, key string) (Explanation, error) {
 	keys, err := scope.rawKeys(ctx, key)
 	if err != nil {
 		tme, ok := errors.AsType[tenantMissingError](err)
 		if ok && tme.Scope.IsTenantRequired() {
-			return "", fmt.Errorf("failed to get raw keys: %w", err)
+			return Explanation{Key: key, Default: true}, fmt.Errorf("failed to get raw keys: %w", err)
 		} else {
 			t.lggr.Errorf("Settings lookup for "+key+" limited by missing tenant at scope "+tme.Scope.String(), "key", key, "err", err)
 		}
 	}
-	return t.settings.getFirst(keys...)
+	v, i, err := t.settings.getFirst(keys...)
+	if err != nil || i < 0 {
+		return Explanation{Key: key, Default: true}, err
+	}
+	return explainRawKey(key, keys[i], v, ".toml"), nil
 }
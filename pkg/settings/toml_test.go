@@ -6,6 +6,7 @@ import (
 	"io/fs"
 	"os"
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -51,6 +52,23 @@ func TestCombineTOMLFiles(t *testing.T) {
 	})
 }
 
+func TestCombineTOMLFiles_NotExist(t *testing.T) {
+	files := fstest.MapFS{
+		"owner/abc.toml": {Data: []byte(`Foo = "13"`)},
+	}
+
+	t.Run("missing global and dir", func(t *testing.T) {
+		b, err := CombineTOMLFiles(files)
+		require.NoError(t, err)
+		require.Equal(t, generatedHeader+"\n[owner]\n\n[owner.abc]\nFoo = \"13\"\n", string(b))
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := CombineTOMLFiles(removedFS{MapFS: files, removed: "owner/abc.toml"})
+		require.ErrorIs(t, err, fs.ErrNotExist)
+	})
+}
+
 func Test_tomlSettings_GetScoped(t *testing.T) {
 	s, err := newTOMLSettings([]byte(configTOML))
 	require.NoError(t, err)
@@ -3,37 +3,17 @@ package nitro
 
 import (
 	"bytes"
-	"crypto/x509"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
-)
-
-// HexBytes is a custom type that unmarshals hex strings into a byte slice
-// and marshals byte slices back to hex strings. This allows parsing AWS Nitro
-// measurements, which use hex byte strings in JSON.
-type HexBytes []byte
-
-func (h *HexBytes) UnmarshalJSON(b []byte) error {
-	var s string
-	if err := json.Unmarshal(b, &s); err != nil {
-		return fmt.Errorf("HexBytes: cannot unmarshal JSON into string: %w", err)
-	}
 
-	decoded, err := hex.DecodeString(s)
-	if err != nil {
-		return fmt.Errorf("HexBytes: failed to decode hex string '%s': %w", s, err)
-	}
-	*h = decoded
-	return nil
-}
+	"github.com/smartcontractkit/chainlink-common/pkg/teeattestation"
+)
 
-func (h HexBytes) MarshalJSON() ([]byte, error) {
-	s := hex.EncodeToString(h)
-	return json.Marshal(s)
-}
+// HexBytes unmarshals hex strings into a byte slice and marshals byte slices
+// back to hex strings, for the hex encoded PCRs of AWS Nitro measurements.
+type HexBytes = teeattestation.HexBytes
 
 // PCRs holds Platform Configuration Register values for attestation validation.
 type PCRs struct {
@@ -112,10 +92,9 @@ func ValidateAndParseWithRoots(attestation, expectedUserData, trustedMeasurement
 		return nil, errors.New("attestation is nil")
 	}
 
-	pool := x509.NewCertPool()
-	ok := pool.AppendCertsFromPEM([]byte(caRootsPEM))
-	if !ok {
-		return nil, errors.New("failed to parse CA roots")
+	pool, err := teeattestation.ParseCARoots(caRootsPEM)
+	if err != nil {
+		return nil, err
 	}
 	result, err := verifyAttestationDocument(attestation, pool, time.Now())
 	if err != nil {
@@ -155,3 +134,44 @@ func ValidateAndParseWithRoots(attestation, expectedUserData, trustedMeasurement
 		ModuleID:      result.document.ModuleID,
 	}, nil
 }
+
+// Validator is the teeattestation.Validator of AWS Nitro attestations.
+type Validator struct {
+	caRootsPEM string
+}
+
+var _ teeattestation.Validator = (*Validator)(nil)
+
+// NewValidator returns a Validator against the AWS Nitro Enclaves root certificate.
+func NewValidator() *Validator {
+	return NewValidatorWithRoots(DefaultCARoots)
+}
+
+// NewValidatorWithRoots returns a Validator against a custom CA root
+// certificate. This is primarily for testing with fake enclaves that use
+// self-signed CA roots.
+func NewValidatorWithRoots(caRootsPEM string) *Validator {
+	return &Validator{caRootsPEM: caRootsPEM}
+}
+
+func (v *Validator) Platform() teeattestation.Platform { return teeattestation.PlatformNitro }
+
+// Validate runs ValidateAndParseWithRoots and converts the Document to
+// teeattestation.Measurements, with the PCRs as registers "PCR0", "PCR1", etc.
+func (v *Validator) Validate(attestation, expectedUserData, trustedMeasurements []byte) (*teeattestation.Measurements, error) {
+	doc, err := ValidateAndParseWithRoots(attestation, expectedUserData, trustedMeasurements, v.caRootsPEM)
+	if err != nil {
+		return nil, err
+	}
+	registers := make(map[string][]byte, len(doc.PCRs))
+	for idx, value := range doc.PCRs {
+		registers[fmt.Sprintf("PCR%d", idx)] = value
+	}
+	return &teeattestation.Measurements{
+		Platform:   teeattestation.PlatformNitro,
+		Registers:  registers,
+		ReportData: doc.UserData,
+		PublicKey:  doc.PublicKey,
+		Nonce:      doc.Nonce,
+	}, nil
+}
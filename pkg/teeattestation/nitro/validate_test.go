@@ -118,3 +118,24 @@ func TestValidateAndParse_FailsOnWrongUserData(t *testing.T) {
 	require.Nil(t, parsed)
 	require.Contains(t, err.Error(), "expected user data")
 }
+
+func TestValidator(t *testing.T) {
+	fa, err := nitrofake.NewAttestor()
+	require.NoError(t, err)
+
+	userData := teeattestation.DomainHash("test-tag", []byte(`{"key":"value"}`))
+	doc, err := fa.CreateAttestation(userData, nitrofake.WithNonce([]byte("nonce")))
+	require.NoError(t, err)
+
+	v := NewValidatorWithRoots(fa.CARootsPEM())
+	assert.Equal(t, teeattestation.PlatformNitro, v.Platform())
+	m, err := v.Validate(doc, userData, fa.TrustedPCRsJSON())
+	require.NoError(t, err)
+	assert.Equal(t, teeattestation.PlatformNitro, m.Platform)
+	assert.Equal(t, userData, m.ReportData)
+	assert.Equal(t, []byte("nonce"), m.Nonce)
+	assert.Len(t, m.Registers["PCR0"], 48)
+
+	_, err = NewValidator().Validate(doc, userData, fa.TrustedPCRsJSON())
+	require.Error(t, err, "fake roots are not trusted by default")
+}
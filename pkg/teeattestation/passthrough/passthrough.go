@@ -7,10 +7,12 @@ package passthrough
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"go.opentelemetry.io/otel/metric"
 
 	"github.com/smartcontractkit/chainlink-common/pkg/beholder"
+	"github.com/smartcontractkit/chainlink-common/pkg/teeattestation"
 )
 
 // Validator accepts any attestation without verification. It implements the same
@@ -18,16 +20,31 @@ import (
 // validator is expected, and counts every call so the insecure passthrough path
 // can be alerted on if ever enabled outside test environments.
 type Validator struct {
+	platform    teeattestation.Platform
 	validations metric.Int64Counter
 }
 
-// New returns a passthrough Validator.
+var _ teeattestation.Validator = (*Validator)(nil)
+
+// New returns a passthrough Validator for teeattestation.PlatformPassthrough.
 func New() (*Validator, error) {
+	return NewForPlatform(teeattestation.PlatformPassthrough)
+}
+
+// NewForPlatform returns a passthrough Validator for a test platform, named
+// PlatformPassthrough or prefixed with "passthrough-", e.g. "passthrough-tdx".
+// The names of real platforms are rejected, so attestations claiming a real
+// platform are never accepted unverified.
+func NewForPlatform(platform teeattestation.Platform) (*Validator, error) {
+	if platform != teeattestation.PlatformPassthrough && !strings.HasPrefix(string(platform), string(teeattestation.PlatformPassthrough)+"-") {
+		return nil, fmt.Errorf("passthrough platform must be %s or prefixed with %s-: %s",
+			teeattestation.PlatformPassthrough, teeattestation.PlatformPassthrough, platform)
+	}
 	validations, err := beholder.GetMeter().Int64Counter("teeattestation_passthrough_validation_count")
 	if err != nil {
 		return nil, fmt.Errorf("failed to register passthrough validation counter: %w", err)
 	}
-	return &Validator{validations: validations}, nil
+	return &Validator{platform: platform, validations: validations}, nil
 }
 
 // ValidateAttestation accepts any attestation, recording the use.
@@ -41,3 +58,12 @@ func (v *Validator) ValidateAttestationWithRoots(_, _, _ []byte, _ string) error
 	v.validations.Add(context.Background(), 1)
 	return nil
 }
+
+func (v *Validator) Platform() teeattestation.Platform { return v.platform }
+
+// Validate accepts any attestation, recording the use. The returned
+// measurements carry no registers and echo the expected user data.
+func (v *Validator) Validate(_, expectedUserData, _ []byte) (*teeattestation.Measurements, error) {
+	v.validations.Add(context.Background(), 1)
+	return &teeattestation.Measurements{Platform: v.platform, ReportData: expectedUserData}, nil
+}
@@ -0,0 +1,22 @@
+package passthrough
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/teeattestation"
+)
+
+func TestNewForPlatform(t *testing.T) {
+	for _, p := range []teeattestation.Platform{teeattestation.PlatformPassthrough, "passthrough-tdx"} {
+		v, err := NewForPlatform(p)
+		require.NoError(t, err)
+		assert.Equal(t, p, v.Platform())
+	}
+	for _, p := range []teeattestation.Platform{teeattestation.PlatformTDX, teeattestation.PlatformSEVSNP, teeattestation.PlatformNitro, "passthroughtdx", ""} {
+		_, err := NewForPlatform(p)
+		require.Error(t, err, p)
+	}
+}
@@ -0,0 +1,320 @@
+// Package sevsnpfake provides an Attestor that produces structurally valid
+// AMD SEV-SNP attestation reports with their certificate tables. These pass
+// the local SEV-SNP validator's full validation chain (VCEK certificate
+// chain, chip ID, report signature, report data, measurement) without
+// requiring SEV-SNP hardware.
+package sevsnpfake
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	guidVCEK = uuid.MustParse("63da758d-e664-4564-adc5-f4b93be8accd")
+	guidVLEK = uuid.MustParse("a8074bc2-a25a-483e-aae6-39c045a0b8a1")
+	guidASK  = uuid.MustParse("4ab7b379-bbac-4fe4-a02f-05aef327c782")
+	guidARK  = uuid.MustParse("c0b406a4-a803-4952-9743-3fb6014cd0ae")
+
+	oidHWID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 3704, 1, 4}
+	// oidSPLs are the bootloader, TEE, SNP and microcode security patch level extensions of the VCEK.
+	oidSPLs = []asn1.ObjectIdentifier{
+		{1, 3, 6, 1, 4, 1, 3704, 1, 3, 1},
+		{1, 3, 6, 1, 4, 1, 3704, 1, 3, 2},
+		{1, 3, 6, 1, 4, 1, 3704, 1, 3, 3},
+		{1, 3, 6, 1, 4, 1, 3704, 1, 3, 8},
+	}
+)
+
+// TCB is the reported TCB version of the fake reports, and the TCB version the fake VCEK is derived for: bootloader
+// 3, TEE 0, SNP 8 and microcode 115, in the Milan layout.
+const TCB uint64 = 115<<56 | 8<<48 | 3
+
+// Attestor produces SEV-SNP attestation reports signed by a fake VCEK or
+// VLEK, whose certificates chain to a self-signed ARK through an ASK. The
+// ARK and ASK are RSA-PSS like AMD's, but 2048 bit to keep key generation
+// fast.
+type Attestor struct {
+	arkDER      []byte
+	arkCert     *x509.Certificate
+	arkKey      *rsa.PrivateKey
+	askCert     *x509.Certificate
+	askDER      []byte
+	vcekDER     []byte
+	vcekKey     *ecdsa.PrivateKey
+	vlekDER     []byte
+	vlekKey     *ecdsa.PrivateKey
+	chipID      []byte
+	measurement []byte
+}
+
+// NewAttestor generates a self-signed ARK, an ASK signed by it, a VCEK with
+// a random chip ID and a VLEK signed by the ASK, and a deterministic 48-byte
+// fake launch measurement.
+func NewAttestor() (*Attestor, error) {
+	arkKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("generate ARK key: %w", err)
+	}
+	arkTemplate := caTemplate(1, "Fake ARK")
+	arkDER, err := x509.CreateCertificate(rand.Reader, arkTemplate, arkTemplate, &arkKey.PublicKey, arkKey)
+	if err != nil {
+		return nil, fmt.Errorf("create ARK cert: %w", err)
+	}
+	arkCert, err := x509.ParseCertificate(arkDER)
+	if err != nil {
+		return nil, fmt.Errorf("parse ARK cert: %w", err)
+	}
+
+	askKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("generate ASK key: %w", err)
+	}
+	askDER, err := x509.CreateCertificate(rand.Reader, caTemplate(2, "Fake SEV"), arkCert, &askKey.PublicKey, arkKey)
+	if err != nil {
+		return nil, fmt.Errorf("create ASK cert: %w", err)
+	}
+	askCert, err := x509.ParseCertificate(askDER)
+	if err != nil {
+		return nil, fmt.Errorf("parse ASK cert: %w", err)
+	}
+
+	chipID := make([]byte, 64)
+	if _, err := rand.Read(chipID); err != nil {
+		return nil, fmt.Errorf("generate chip ID: %w", err)
+	}
+	hwID, err := asn1.Marshal(chipID)
+	if err != nil {
+		return nil, fmt.Errorf("encode chip ID: %w", err)
+	}
+	extensions := []pkix.Extension{{Id: oidHWID, Value: hwID}}
+	for i, spl := range []byte{3, 0, 8, 115} {
+		value, err := asn1.Marshal(int(spl))
+		if err != nil {
+			return nil, fmt.Errorf("encode TCB: %w", err)
+		}
+		extensions = append(extensions, pkix.Extension{Id: oidSPLs[i], Value: value})
+	}
+	vcekKey, vcekDER, err := newSigningKey(3, "SEV-VCEK", askCert, askKey, extensions)
+	if err != nil {
+		return nil, err
+	}
+	vlekKey, vlekDER, err := newSigningKey(4, "SEV-VLEK", askCert, askKey, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Attestor{
+		arkDER:      arkDER,
+		arkCert:     arkCert,
+		arkKey:      arkKey,
+		askCert:     askCert,
+		askDER:      askDER,
+		vcekDER:     vcekDER,
+		vcekKey:     vcekKey,
+		vlekDER:     vlekDER,
+		vlekKey:     vlekKey,
+		chipID:      chipID,
+		measurement: sha384Sum([]byte("fake-measurement")),
+	}, nil
+}
+
+func caTemplate(serial int64, name string) *x509.Certificate {
+	return &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-1 * time.Hour),
+		NotAfter:              time.Now().Add(10 * 365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		SignatureAlgorithm:    x509.SHA384WithRSAPSS,
+	}
+}
+
+func newSigningKey(serial int64, name string, ask *x509.Certificate, askKey *rsa.PrivateKey, extensions []pkix.Extension) (*ecdsa.PrivateKey, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generate %s key: %w", name, err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:       big.NewInt(serial),
+		Subject:            pkix.Name{CommonName: name},
+		NotBefore:          time.Now().Add(-1 * time.Hour),
+		NotAfter:           time.Now().Add(24 * time.Hour),
+		KeyUsage:           x509.KeyUsageDigitalSignature,
+		ExtraExtensions:    extensions,
+		SignatureAlgorithm: x509.SHA384WithRSAPSS,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ask, &key.PublicKey, askKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create %s cert: %w", name, err)
+	}
+	return key, der, nil
+}
+
+type reportOptions struct {
+	policy   uint64
+	tcb      uint64
+	hostData []byte
+	vlek     bool
+	chipID   []byte
+	flags    uint32
+}
+
+// Option customizes the fields of a fake attestation report.
+type Option func(*reportOptions)
+
+// WithPolicy sets the guest policy, e.g. with bit 19 set for a guest that
+// allows debugging.
+func WithPolicy(policy uint64) Option {
+	return func(o *reportOptions) { o.policy = policy }
+}
+
+// WithHostData sets the HOST_DATA field, which is at most 32 bytes.
+func WithHostData(hostData []byte) Option {
+	return func(o *reportOptions) { o.hostData = hostData }
+}
+
+// WithVLEK signs the report with the VLEK instead of the VCEK.
+func WithVLEK() Option {
+	return func(o *reportOptions) { o.vlek = true }
+}
+
+// WithReportedTCB sets the REPORTED_TCB field, instead of TCB.
+func WithReportedTCB(tcb uint64) Option {
+	return func(o *reportOptions) { o.tcb = tcb }
+}
+
+// WithChipID sets the CHIP_ID field, instead of the chip ID of the VCEK.
+func WithChipID(chipID []byte) Option {
+	return func(o *reportOptions) { o.chipID = chipID }
+}
+
+// WithMaskedChipID sets the MASK_CHIP_ID flag, and zeroes the CHIP_ID field.
+func WithMaskedChipID() Option {
+	return func(o *reportOptions) {
+		o.flags |= 1 << 0
+		o.chipID = nil
+	}
+}
+
+// CreateAttestation builds an SEV-SNP attestation report with the given
+// reportData, which is at most 64 bytes and zero padded, followed by its
+// certificate table.
+func (f *Attestor) CreateAttestation(reportData []byte, opts ...Option) ([]byte, error) {
+	if len(reportData) > 64 {
+		return nil, fmt.Errorf("report data is %d bytes, at most 64 allowed", len(reportData))
+	}
+	o := reportOptions{policy: 0x30000, tcb: TCB, chipID: f.chipID} // SMT allowed, reserved bit 17
+	for _, opt := range opts {
+		opt(&o)
+	}
+	key, keyDER, keyGUID, flags := f.vcekKey, f.vcekDER, guidVCEK, o.flags
+	if o.vlek {
+		key, keyDER, keyGUID, flags = f.vlekKey, f.vlekDER, guidVLEK, o.flags|1<<2
+	}
+
+	le := binary.LittleEndian
+	report := make([]byte, 0x4A0)
+	le.PutUint32(report[0x00:], 3) // version
+	le.PutUint64(report[0x08:], o.policy)
+	le.PutUint32(report[0x34:], 1) // signature algorithm: ECDSA P-384 with SHA-384
+	le.PutUint32(report[0x48:], flags)
+	copy(report[0x50:0x90], reportData)
+	copy(report[0x90:0xC0], f.measurement)
+	copy(report[0xC0:0xE0], o.hostData)
+	le.PutUint64(report[0x180:], o.tcb)
+	copy(report[0x1A0:0x1E0], o.chipID)
+
+	hash := sha512.Sum384(report[:0x2A0])
+	r, s, err := ecdsa.Sign(rand.Reader, key, hash[:])
+	if err != nil {
+		return nil, fmt.Errorf("ecdsa sign: %w", err)
+	}
+	r.FillBytes(report[0x2A0:0x2E8])
+	s.FillBytes(report[0x2E8:0x330])
+	slices.Reverse(report[0x2A0:0x2E8])
+	slices.Reverse(report[0x2E8:0x330])
+
+	return append(report, certTable(map[uuid.UUID][]byte{
+		keyGUID: keyDER,
+		guidASK: f.askDER,
+		guidARK: f.arkDER,
+	})...), nil
+}
+
+// certTable encodes the SNP_GET_EXT_REPORT certificate table of certs.
+func certTable(certs map[uuid.UUID][]byte) []byte {
+	le := binary.LittleEndian
+	guids := make([]uuid.UUID, 0, len(certs))
+	for guid := range certs {
+		guids = append(guids, guid)
+	}
+	slices.SortFunc(guids, func(a, b uuid.UUID) int { return slices.Compare(a[:], b[:]) })
+
+	offset := (len(guids) + 1) * 24
+	var entries, data []byte
+	for _, guid := range guids {
+		entries = append(entries, guid[:]...)
+		entries = le.AppendUint32(entries, uint32(offset+len(data))) //nolint:gosec // bounded by the certificates
+		entries = le.AppendUint32(entries, uint32(len(certs[guid]))) //nolint:gosec // bounded by the certificates
+		data = append(data, certs[guid]...)
+	}
+	entries = append(entries, make([]byte, 24)...)
+	return append(entries, data...)
+}
+
+// CARootsPEM returns the ARK certificate in PEM format.
+func (f *Attestor) CARootsPEM() string {
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: f.arkDER,
+	}))
+}
+
+// CreateCRL returns a CRL of the ARK, revoking the ASK if revokeASK is set.
+func (f *Attestor) CreateCRL(revokeASK bool) (*x509.RevocationList, error) {
+	template := &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now().Add(-1 * time.Hour),
+		NextUpdate: time.Now().Add(24 * time.Hour),
+	}
+	if revokeASK {
+		template.RevokedCertificateEntries = []x509.RevocationListEntry{{
+			SerialNumber:   f.askCert.SerialNumber,
+			RevocationTime: time.Now(),
+		}}
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, template, f.arkCert, f.arkKey)
+	if err != nil {
+		return nil, fmt.Errorf("create CRL: %w", err)
+	}
+	return x509.ParseRevocationList(der)
+}
+
+// TrustedMeasurementsJSON returns the launch measurement as a JSON object
+// matching the format expected by the attestation validator.
+func (f *Attestor) TrustedMeasurementsJSON() []byte {
+	return fmt.Appendf(nil, `{"measurement":"%s"}`, hex.EncodeToString(f.measurement))
+}
+
+func sha384Sum(data []byte) []byte {
+	h := sha512.Sum384(data)
+	return h[:]
+}
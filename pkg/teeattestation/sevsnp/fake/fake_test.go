@@ -0,0 +1,30 @@
+package sevsnpfake
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/teeattestation/sevsnp"
+)
+
+func TestAttestor_RoundTrip(t *testing.T) {
+	fa, err := NewAttestor()
+	require.NoError(t, err)
+
+	userData := []byte("test-user-data-12345")
+	attestation, err := fa.CreateAttestation(userData)
+	require.NoError(t, err)
+	require.NotEmpty(t, attestation)
+
+	err = sevsnp.ValidateAttestation(attestation, userData, fa.TrustedMeasurementsJSON(), fa.CARootsPEM())
+	require.NoError(t, err)
+}
+
+func TestAttestor_ReportDataTooLong(t *testing.T) {
+	fa, err := NewAttestor()
+	require.NoError(t, err)
+
+	_, err = fa.CreateAttestation(make([]byte, 65))
+	require.Error(t, err)
+}
@@ -0,0 +1,196 @@
+// Package sevsnp provides AMD SEV-SNP attestation report validation.
+package sevsnp
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/teeattestation"
+)
+
+// Measurements holds the trusted guest measurements for attestation
+// validation. Measurement is required; HostData is only checked when set.
+// MinTCB, when set, is the minimum reported TCB version of the platform.
+type Measurements struct {
+	Measurement teeattestation.HexBytes `json:"measurement"`
+	HostData    teeattestation.HexBytes `json:"host_data,omitempty"`
+	MinTCB      *TCB                    `json:"min_tcb,omitempty"`
+}
+
+// Report holds the validated, parsed fields of an SEV-SNP attestation
+// report. A Report is returned only after the full validation chain (VCEK or
+// VLEK certificate chain, report signature, expected report data, trusted
+// measurement) has passed.
+type Report struct {
+	// Version is the attestation report format version.
+	Version uint32
+	// GuestSVN and Policy are the guest SVN and launch policy. Guests that
+	// allow debugging have already been rejected.
+	GuestSVN uint32
+	Policy   uint64
+	// FamilyID and ImageID are supplied by the guest owner at launch.
+	FamilyID []byte
+	ImageID  []byte
+	// VMPL is the privilege level of the guest that requested the report.
+	VMPL uint32
+	// CurrentTCB, ReportedTCB, CommittedTCB and LaunchTCB are the platform
+	// TCB versions. A VCEK has already been checked to be derived for the
+	// ReportedTCB, and the ReportedTCB against the trusted minimum TCB, if
+	// any; the others are exposed unchecked.
+	CurrentTCB   uint64
+	ReportedTCB  uint64
+	CommittedTCB uint64
+	LaunchTCB    uint64
+	// TCB is the decoded ReportedTCB.
+	TCB TCB
+	// PlatformInfo describes the platform, e.g. whether SMT is enabled.
+	PlatformInfo uint64
+	// ReportData is the 64 byte REPORT_DATA of the guest. It has already been
+	// checked to equal the expectedUserData argument, zero padded.
+	ReportData []byte
+	// Measurement is the launch measurement of the guest.
+	Measurement []byte
+	// HostData is supplied by the host at launch.
+	HostData []byte
+	// IDKeyDigest and AuthorKeyDigest are the digests of the keys that signed
+	// the ID block of the guest, if any.
+	IDKeyDigest     []byte
+	AuthorKeyDigest []byte
+	// ReportID identifies the guest across migrations.
+	ReportID []byte
+	// ChipID identifies the processor, unless masked by the guest.
+	ChipID []byte
+	// SigningKey is the SPKI (DER) of the VCEK or VLEK public key that signed
+	// the report.
+	SigningKey []byte
+}
+
+// ValidateAttestation verifies an SEV-SNP attestation report against
+// expected user data and trusted measurements. The attestation is the report
+// followed by the certificate table returned by SNP_GET_EXT_REPORT, which
+// must hold the VCEK (or VLEK) and ASK certificates; the chain must lead to
+// caRootsPEM, normally the AMD Root Key (ARK) of the processor family.
+//
+// The chain is checked against crls, normally the CRL of the processor
+// family published by the AMD KDS, which callers are expected to fetch and
+// refresh. Without crls, revoked ASKs and VLEKs are accepted.
+func ValidateAttestation(attestation, expectedUserData, trustedMeasurements []byte, caRootsPEM string, crls ...*x509.RevocationList) error {
+	_, err := ValidateAndParse(attestation, expectedUserData, trustedMeasurements, caRootsPEM, crls...)
+	return err
+}
+
+// ValidateAndParse runs the same validation as ValidateAttestation and, on
+// success, returns the parsed report fields.
+func ValidateAndParse(attestation, expectedUserData, trustedMeasurements []byte, caRootsPEM string, crls ...*x509.RevocationList) (*Report, error) {
+	if attestation == nil {
+		return nil, errors.New("attestation is nil")
+	}
+	if len(expectedUserData) > reportDataSize {
+		return nil, fmt.Errorf("expected user data is longer than %d bytes", reportDataSize)
+	}
+
+	pool, err := teeattestation.ParseCARoots(caRootsPEM)
+	if err != nil {
+		return nil, err
+	}
+	r, signingCert, err := verifyReport(attestation, pool, crls, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify sev-snp attestation report: %w", err)
+	}
+
+	if !teeattestation.ReportDataMatches(r.ReportData, expectedUserData) {
+		return nil, fmt.Errorf("expected user data %x, got %x", expectedUserData, r.ReportData)
+	}
+
+	var trusted Measurements
+	if err := json.Unmarshal(trustedMeasurements, &trusted); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal trusted measurements: %w", err)
+	}
+	if len(trusted.Measurement) == 0 {
+		return nil, errors.New("trusted measurements are missing the launch measurement")
+	}
+	if !bytes.Equal(r.Measurement, trusted.Measurement) {
+		return nil, fmt.Errorf("measurement mismatch: expected %x", trusted.Measurement)
+	}
+	if len(trusted.HostData) > 0 && !bytes.Equal(r.HostData, trusted.HostData) {
+		return nil, fmt.Errorf("host data mismatch: expected %x", trusted.HostData)
+	}
+	tcb := decodeTCB(r.ReportedTCB, r.CPUIDFamily)
+	if trusted.MinTCB != nil && !tcb.AtLeast(*trusted.MinTCB) {
+		return nil, fmt.Errorf("reported TCB %+v is below the minimum %+v", tcb, *trusted.MinTCB)
+	}
+
+	signingKey, err := x509.MarshalPKIXPublicKey(signingCert.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("marshal signing key: %w", err)
+	}
+	return &Report{
+		Version:         r.Version,
+		GuestSVN:        r.GuestSVN,
+		Policy:          r.Policy,
+		FamilyID:        bytes.Clone(r.FamilyID),
+		ImageID:         bytes.Clone(r.ImageID),
+		VMPL:            r.VMPL,
+		CurrentTCB:      r.CurrentTCB,
+		ReportedTCB:     r.ReportedTCB,
+		CommittedTCB:    r.CommittedTCB,
+		LaunchTCB:       r.LaunchTCB,
+		TCB:             tcb,
+		PlatformInfo:    r.PlatformInfo,
+		ReportData:      bytes.Clone(r.ReportData),
+		Measurement:     bytes.Clone(r.Measurement),
+		HostData:        bytes.Clone(r.HostData),
+		IDKeyDigest:     bytes.Clone(r.IDKeyDigest),
+		AuthorKeyDigest: bytes.Clone(r.AuthorKeyDigest),
+		ReportID:        bytes.Clone(r.ReportID),
+		ChipID:          bytes.Clone(r.ChipID),
+		SigningKey:      signingKey,
+	}, nil
+}
+
+// Validator is the teeattestation.Validator of AMD SEV-SNP attestation
+// reports.
+type Validator struct {
+	caRootsPEM string
+	crls       []*x509.RevocationList
+}
+
+var _ teeattestation.Validator = (*Validator)(nil)
+
+// NewValidator returns a Validator against the CA roots of caRootsPEM and the
+// optional crls, see ValidateAttestation.
+func NewValidator(caRootsPEM string, crls ...*x509.RevocationList) *Validator {
+	return &Validator{caRootsPEM: caRootsPEM, crls: crls}
+}
+
+func (v *Validator) Platform() teeattestation.Platform { return teeattestation.PlatformSEVSNP }
+
+// Validate runs ValidateAndParse and converts the Report to
+// teeattestation.Measurements, with registers "MEASUREMENT", "HOST_DATA",
+// "ID_KEY_DIGEST", "AUTHOR_KEY_DIGEST", "FAMILY_ID" and "IMAGE_ID", the
+// REPORTED_TCB as TCB, and the VCEK or VLEK key as SigningKey.
+func (v *Validator) Validate(attestation, expectedUserData, trustedMeasurements []byte) (*teeattestation.Measurements, error) {
+	r, err := ValidateAndParse(attestation, expectedUserData, trustedMeasurements, v.caRootsPEM, v.crls...)
+	if err != nil {
+		return nil, err
+	}
+	return &teeattestation.Measurements{
+		Platform: teeattestation.PlatformSEVSNP,
+		Registers: map[string][]byte{
+			"MEASUREMENT":       r.Measurement,
+			"HOST_DATA":         r.HostData,
+			"ID_KEY_DIGEST":     r.IDKeyDigest,
+			"AUTHOR_KEY_DIGEST": r.AuthorKeyDigest,
+			"FAMILY_ID":         r.FamilyID,
+			"IMAGE_ID":          r.ImageID,
+		},
+		ReportData: r.ReportData,
+		TCB:        binary.LittleEndian.AppendUint64(nil, r.ReportedTCB),
+		SigningKey: r.SigningKey,
+	}, nil
+}
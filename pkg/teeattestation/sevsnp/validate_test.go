@@ -0,0 +1,285 @@
+package sevsnp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/teeattestation"
+	sevsnpfake "github.com/smartcontractkit/chainlink-common/pkg/teeattestation/sevsnp/fake"
+)
+
+func TestValidateAttestation_Attestor(t *testing.T) {
+	fa, err := sevsnpfake.NewAttestor()
+	require.NoError(t, err)
+
+	userData := teeattestation.DomainHash("test-tag", []byte(`{"key":"value"}`))
+	attestation, err := fa.CreateAttestation(userData)
+	require.NoError(t, err)
+
+	err = ValidateAttestation(attestation, userData, fa.TrustedMeasurementsJSON(), fa.CARootsPEM())
+	require.NoError(t, err)
+
+	vlek, err := fa.CreateAttestation(userData, sevsnpfake.WithVLEK())
+	require.NoError(t, err)
+	err = ValidateAttestation(vlek, userData, fa.TrustedMeasurementsJSON(), fa.CARootsPEM())
+	require.NoError(t, err)
+}
+
+func TestValidateAndParse_SurfacesReportFields(t *testing.T) {
+	fa, err := sevsnpfake.NewAttestor()
+	require.NoError(t, err)
+
+	userData := []byte("test-data")
+	hostData := []byte("host-data")
+	attestation, err := fa.CreateAttestation(userData, sevsnpfake.WithHostData(hostData))
+	require.NoError(t, err)
+
+	parsed, err := ValidateAndParse(attestation, userData, fa.TrustedMeasurementsJSON(), fa.CARootsPEM())
+	require.NoError(t, err)
+	assert.Equal(t, uint32(3), parsed.Version)
+	assert.Len(t, parsed.ReportData, reportDataSize)
+	assert.Equal(t, userData, parsed.ReportData[:len(userData)])
+	assert.Equal(t, hostData, parsed.HostData[:len(hostData)])
+	assert.Len(t, parsed.Measurement, measurementSize)
+	assert.Len(t, parsed.ChipID, chipIDSize)
+	assert.NotEmpty(t, parsed.SigningKey)
+
+	trusted := Measurements{Measurement: parsed.Measurement, HostData: parsed.HostData}
+	err = ValidateAttestation(attestation, userData, mustJSON(t, trusted), fa.CARootsPEM())
+	require.NoError(t, err)
+	trusted.HostData = []byte("other")
+	err = ValidateAttestation(attestation, userData, mustJSON(t, trusted), fa.CARootsPEM())
+	require.ErrorContains(t, err, "host data mismatch")
+}
+
+func TestValidateAttestation_WrongUserData(t *testing.T) {
+	fa, err := sevsnpfake.NewAttestor()
+	require.NoError(t, err)
+	attestation, err := fa.CreateAttestation([]byte("test-data"))
+	require.NoError(t, err)
+
+	err = ValidateAttestation(attestation, []byte("wrong"), fa.TrustedMeasurementsJSON(), fa.CARootsPEM())
+	require.ErrorContains(t, err, "expected user data")
+	err = ValidateAttestation(attestation, []byte("test-data-longer"), fa.TrustedMeasurementsJSON(), fa.CARootsPEM())
+	require.ErrorContains(t, err, "expected user data")
+}
+
+func TestValidateAttestation_WrongMeasurements(t *testing.T) {
+	fa, err := sevsnpfake.NewAttestor()
+	require.NoError(t, err)
+	attestation, err := fa.CreateAttestation([]byte("test-data"))
+	require.NoError(t, err)
+
+	for name, tc := range map[string]struct {
+		trusted string
+		err     string
+	}{
+		"measurement":         {`{"measurement":"aa"}`, "measurement mismatch"},
+		"missing measurement": {`{"host_data":"aa"}`, "missing the launch measurement"},
+		"not json":            {`nope`, "failed to unmarshal"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateAttestation(attestation, []byte("test-data"), []byte(tc.trusted), fa.CARootsPEM())
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+}
+
+func TestVerifyReport_Rejects(t *testing.T) {
+	fa, err := sevsnpfake.NewAttestor()
+	require.NoError(t, err)
+	attestation, err := fa.CreateAttestation([]byte("test-data"))
+	require.NoError(t, err)
+	debug, err := fa.CreateAttestation([]byte("test-data"), sevsnpfake.WithPolicy(0x30000|policyDebug))
+	require.NoError(t, err)
+	otherChip, err := fa.CreateAttestation([]byte("test-data"), sevsnpfake.WithChipID([]byte("other")))
+	require.NoError(t, err)
+	otherTCB, err := fa.CreateAttestation([]byte("test-data"), sevsnpfake.WithReportedTCB(sevsnpfake.TCB+1))
+	require.NoError(t, err)
+	other, err := sevsnpfake.NewAttestor()
+	require.NoError(t, err)
+
+	roots, err := teeattestation.ParseCARoots(fa.CARootsPEM())
+	require.NoError(t, err)
+	otherRoots, err := teeattestation.ParseCARoots(other.CARootsPEM())
+	require.NoError(t, err)
+
+	modified := func(offset int, f func([]byte)) []byte {
+		b := append([]byte{}, attestation...)
+		f(b[offset:])
+		return b
+	}
+	flip := func(b []byte) { b[0] ^= 0xff }
+
+	for name, tc := range map[string]struct {
+		attestation []byte
+		roots       bool
+		err         error
+	}{
+		"truncated":      {attestation: attestation[:reportSize-1], err: errShortReport},
+		"no cert table":  {attestation: attestation[:reportSize], err: errBadCertTable},
+		"bad cert table": {attestation: modified(reportSize+20, func(b []byte) { binary.LittleEndian.PutUint32(b, 1<<31) }), err: errBadCertTable},
+		"version":        {attestation: modified(offsetVersion, func(b []byte) { binary.LittleEndian.PutUint32(b, 1) }), err: errUnsupportedReportVersion},
+		"signature algo": {attestation: modified(offsetSignatureAlgo, func(b []byte) { binary.LittleEndian.PutUint32(b, 2) }), err: errUnsupportedSignatureAlgo},
+		"signing key":    {attestation: modified(offsetFlags, func(b []byte) { binary.LittleEndian.PutUint32(b, 7<<2) }), err: errUnsupportedSigningKey},
+		"missing vlek":   {attestation: modified(offsetFlags, func(b []byte) { binary.LittleEndian.PutUint32(b, signingKeyVLEK) }), err: errMissingSigningKeyCertificate},
+		"measurement":    {attestation: modified(offsetMeasurement, flip), err: errBadSignature},
+		"signature":      {attestation: modified(signedSize, flip), err: errBadSignature},
+		"chip id":        {attestation: otherChip, err: errChipIDMismatch},
+		"tcb":            {attestation: otherTCB, err: errTCBMismatch},
+		"debug":          {attestation: debug, err: errDebugGuest},
+		"untrusted root": {attestation: attestation, roots: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			pool := roots
+			if tc.roots {
+				pool = otherRoots
+			}
+			_, _, err := verifyReport(tc.attestation, pool, nil, time.Now())
+			require.Error(t, err)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestValidator(t *testing.T) {
+	fa, err := sevsnpfake.NewAttestor()
+	require.NoError(t, err)
+	userData := teeattestation.DomainHash("test-tag", []byte(`{"key":"value"}`))
+	attestation, err := fa.CreateAttestation(userData)
+	require.NoError(t, err)
+
+	v := NewValidator(fa.CARootsPEM())
+	assert.Equal(t, teeattestation.PlatformSEVSNP, v.Platform())
+	m, err := v.Validate(attestation, userData, fa.TrustedMeasurementsJSON())
+	require.NoError(t, err)
+	assert.Equal(t, teeattestation.PlatformSEVSNP, m.Platform)
+	assert.True(t, teeattestation.ReportDataMatches(m.ReportData, userData))
+	assert.Len(t, m.Registers["MEASUREMENT"], measurementSize)
+	assert.Len(t, m.Registers["HOST_DATA"], hostDataSize)
+	assert.Equal(t, binary.LittleEndian.AppendUint64(nil, sevsnpfake.TCB), m.TCB)
+	assert.NotEmpty(t, m.SigningKey)
+}
+
+func TestVerifyReport_MaskedChipID(t *testing.T) {
+	fa, err := sevsnpfake.NewAttestor()
+	require.NoError(t, err)
+	roots, err := teeattestation.ParseCARoots(fa.CARootsPEM())
+	require.NoError(t, err)
+
+	masked, err := fa.CreateAttestation([]byte("test-data"), sevsnpfake.WithMaskedChipID())
+	require.NoError(t, err)
+	r, _, err := verifyReport(masked, roots, nil, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, make([]byte, chipIDSize), r.ChipID)
+}
+
+func TestValidateAttestation_MinTCB(t *testing.T) {
+	fa, err := sevsnpfake.NewAttestor()
+	require.NoError(t, err)
+	attestation, err := fa.CreateAttestation([]byte("test-data"))
+	require.NoError(t, err)
+	measurement := Measurements{}
+	require.NoError(t, json.Unmarshal(fa.TrustedMeasurementsJSON(), &measurement))
+
+	parsed, err := ValidateAndParse(attestation, []byte("test-data"), fa.TrustedMeasurementsJSON(), fa.CARootsPEM())
+	require.NoError(t, err)
+	assert.Equal(t, TCB{Bootloader: 3, SNP: 8, Microcode: 115}, parsed.TCB)
+
+	measurement.MinTCB = &TCB{Bootloader: 3, SNP: 8, Microcode: 115}
+	require.NoError(t, ValidateAttestation(attestation, []byte("test-data"), mustJSON(t, measurement), fa.CARootsPEM()))
+	measurement.MinTCB = &TCB{Bootloader: 3, SNP: 9}
+	err = ValidateAttestation(attestation, []byte("test-data"), mustJSON(t, measurement), fa.CARootsPEM())
+	require.ErrorContains(t, err, "below the minimum")
+}
+
+func TestValidateAttestation_CRL(t *testing.T) {
+	fa, err := sevsnpfake.NewAttestor()
+	require.NoError(t, err)
+	attestation, err := fa.CreateAttestation([]byte("test-data"))
+	require.NoError(t, err)
+	other, err := sevsnpfake.NewAttestor()
+	require.NoError(t, err)
+
+	crl, err := fa.CreateCRL(false)
+	require.NoError(t, err)
+	require.NoError(t, ValidateAttestation(attestation, []byte("test-data"), fa.TrustedMeasurementsJSON(), fa.CARootsPEM(), crl))
+	forged, err := other.CreateCRL(true)
+	require.NoError(t, err)
+	err = ValidateAttestation(attestation, []byte("test-data"), fa.TrustedMeasurementsJSON(), fa.CARootsPEM(), forged)
+	require.ErrorContains(t, err, "invalid CRL", "a CRL of the same issuer name must be signed by the issuer")
+
+	revoked, err := fa.CreateCRL(true)
+	require.NoError(t, err)
+	err = ValidateAttestation(attestation, []byte("test-data"), fa.TrustedMeasurementsJSON(), fa.CARootsPEM(), revoked)
+	require.ErrorIs(t, err, teeattestation.ErrCertificateRevoked)
+	_, err = NewValidator(fa.CARootsPEM(), revoked).Validate(attestation, []byte("test-data"), fa.TrustedMeasurementsJSON())
+	require.ErrorIs(t, err, teeattestation.ErrCertificateRevoked)
+}
+
+// TestVerifyReport_Milan verifies a report captured on a Milan processor, see testdata/README.md.
+func TestVerifyReport_Milan(t *testing.T) {
+	report, err := os.ReadFile("testdata/milan_report.bin")
+	require.NoError(t, err)
+	vcek, err := os.ReadFile("testdata/milan_vcek.der")
+	require.NoError(t, err)
+	chain, err := os.ReadFile("testdata/milan_cert_chain.pem")
+	require.NoError(t, err)
+	ask, rest := pem.Decode(chain)
+	require.NotNil(t, ask)
+	roots, err := teeattestation.ParseCARoots(string(rest))
+	require.NoError(t, err)
+
+	attestation := append(report[:reportSize:reportSize], testCertTable(map[uuid.UUID][]byte{guidVCEK: vcek, guidASK: ask.Bytes})...)
+	// the VCEK is valid from 2022-09-24 to 2029-09-24
+	issued := time.Date(2023, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	// the report is of a debug guest, which is only rejected once its chain, chip ID, TCB and signature are verified
+	_, _, err = verifyReport(attestation, roots, nil, issued)
+	require.ErrorIs(t, err, errDebugGuest)
+
+	r, err := parseReport(attestation)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(2), r.Version)
+	assert.Equal(t, TCB{Bootloader: 2, SNP: 5, Microcode: 68}, decodeTCB(r.ReportedTCB, r.CPUIDFamily))
+	assert.Equal(t, []byte{1, 2, 3, 4, 5}, r.ReportData[:5])
+
+	tampered := bytes.Clone(attestation)
+	tampered[offsetReportData] ^= 0xff
+	_, _, err = verifyReport(tampered, roots, nil, issued)
+	require.ErrorIs(t, err, errBadSignature)
+	_, _, err = verifyReport(attestation, roots, nil, time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC))
+	require.Error(t, err, "the VCEK has expired")
+}
+
+// testCertTable encodes the SNP_GET_EXT_REPORT certificate table of certs.
+func testCertTable(certs map[uuid.UUID][]byte) []byte {
+	offset := (len(certs) + 1) * certTableEntrySize
+	var entries, data []byte
+	for guid, cert := range certs {
+		entries = append(entries, guid[:]...)
+		entries = binary.LittleEndian.AppendUint32(entries, uint32(offset+len(data))) //nolint:gosec // bounded by the certificates
+		entries = binary.LittleEndian.AppendUint32(entries, uint32(len(cert)))        //nolint:gosec // bounded by the certificates
+		data = append(data, cert...)
+	}
+	entries = append(entries, make([]byte, certTableEntrySize)...)
+	return append(entries, data...)
+}
+
+func mustJSON(t *testing.T, m Measurements) []byte {
+	t.Helper()
+	b, err := json.Marshal(m)
+	require.NoError(t, err)
+	return b
+}
@@ -0,0 +1,349 @@
+package sevsnp
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/teeattestation"
+)
+
+var (
+	errShortReport                  = errors.New("attestation report is truncated")
+	errUnsupportedReportVersion     = errors.New("attestation report version is less than 2")
+	errUnsupportedSignatureAlgo     = errors.New("attestation report signature algorithm is not ECDSA P-384 with SHA-384")
+	errUnsupportedSigningKey        = errors.New("attestation report signing key is neither a VCEK nor a VLEK")
+	errBadCertTable                 = errors.New("certificate table is malformed")
+	errMissingSigningKeyCertificate = errors.New("certificate table has no certificate for the signing key")
+	errBadSigningKeyAlgo            = errors.New("signing key certificate public key is not ECDSA P-384")
+	errChipIDMismatch               = errors.New("VCEK hardware ID does not match the report chip ID")
+	errTCBMismatch                  = errors.New("VCEK TCB version does not match the report reported TCB")
+	errBadSignature                 = errors.New("attestation report signature does not match signing key certificate")
+	errDebugGuest                   = errors.New("attestation report is from a guest with debugging allowed")
+)
+
+// Attestation report layout, see the AMD SEV Secure Nested Paging Firmware ABI Specification, table 23.
+const (
+	reportSize = 0x4A0
+	// signedSize is the length of the report signed by the VCEK or VLEK.
+	signedSize = 0x2A0
+	// signatureComponentSize is the length of the little-endian R and S of the signature.
+	signatureComponentSize = 72
+
+	offsetVersion         = 0x00
+	offsetGuestSVN        = 0x04
+	offsetPolicy          = 0x08
+	offsetFamilyID        = 0x10
+	offsetImageID         = 0x20
+	offsetVMPL            = 0x30
+	offsetSignatureAlgo   = 0x34
+	offsetCurrentTCB      = 0x38
+	offsetPlatformInfo    = 0x40
+	offsetFlags           = 0x48
+	offsetReportData      = 0x50
+	offsetMeasurement     = 0x90
+	offsetHostData        = 0xC0
+	offsetIDKeyDigest     = 0xE0
+	offsetAuthorKeyDigest = 0x110
+	offsetReportID        = 0x140
+	offsetReportedTCB     = 0x180
+	offsetCPUIDFamily     = 0x188
+	offsetChipID          = 0x1A0
+	offsetCommittedTCB    = 0x1E0
+	offsetLaunchTCB       = 0x1F8
+
+	reportDataSize  = 64
+	measurementSize = 48
+	hostDataSize    = 32
+	chipIDSize      = 64
+
+	signatureAlgoECDSAP384SHA384 = 1
+	// policyDebug is the DEBUG bit of the guest policy.
+	policyDebug = 1 << 19
+	// flagsSigningKey selects the SIGNING_KEY bits of the flags, and flagsMaskChipID the MASK_CHIP_ID bit.
+	flagsSigningKey     = 0b111 << 2
+	flagsMaskChipID     = 1 << 0
+	signingKeyVCEK      = 0
+	signingKeyVLEK      = 1 << 2
+	certTableEntrySize  = 24
+	maxCertTableEntries = 64
+
+	// cpuidFamilyTurin is the CPUID family of Turin processors, whose TCB versions have a different layout.
+	cpuidFamilyTurin = 0x1A
+)
+
+// Certificate table GUIDs, see the GHCB specification for SNP_GET_EXT_REPORT.
+var (
+	guidVCEK = uuid.MustParse("63da758d-e664-4564-adc5-f4b93be8accd")
+	guidVLEK = uuid.MustParse("a8074bc2-a25a-483e-aae6-39c045a0b8a1")
+	guidASK  = uuid.MustParse("4ab7b379-bbac-4fe4-a02f-05aef327c782")
+	guidARK  = uuid.MustParse("c0b406a4-a803-4952-9743-3fb6014cd0ae")
+)
+
+// oidHWID is the VCEK extension holding the chip ID.
+var oidHWID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 3704, 1, 4}
+
+// VCEK extensions holding the security patch levels of the TCB version the VCEK was derived for.
+var (
+	oidBootloaderSPL = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 3704, 1, 3, 1}
+	oidTEESPL        = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 3704, 1, 3, 2}
+	oidSNPSPL        = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 3704, 1, 3, 3}
+	oidMicrocodeSPL  = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 3704, 1, 3, 8}
+	oidFMCSPL        = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 3704, 1, 3, 9}
+)
+
+// TCB is a TCB version, i.e. the security patch levels of the firmware components of the platform.
+type TCB struct {
+	Bootloader uint8 `json:"bootloader"`
+	TEE        uint8 `json:"tee"`
+	SNP        uint8 `json:"snp"`
+	Microcode  uint8 `json:"microcode"`
+	// FMC is only set on Turin and later processors.
+	FMC uint8 `json:"fmc,omitempty"`
+}
+
+// decodeTCB decodes a TCB_VERSION of a processor of the CPUID family.
+func decodeTCB(tcb uint64, family uint8) TCB {
+	b := binary.LittleEndian.AppendUint64(nil, tcb)
+	if family >= cpuidFamilyTurin {
+		return TCB{FMC: b[0], Bootloader: b[1], TEE: b[2], SNP: b[3], Microcode: b[7]}
+	}
+	return TCB{Bootloader: b[0], TEE: b[1], SNP: b[6], Microcode: b[7]}
+}
+
+// AtLeast reports whether every security patch level of t is at least that of minimum.
+func (t TCB) AtLeast(minimum TCB) bool {
+	return t.Bootloader >= minimum.Bootloader && t.TEE >= minimum.TEE && t.SNP >= minimum.SNP &&
+		t.Microcode >= minimum.Microcode && t.FMC >= minimum.FMC
+}
+
+// report is a parsed attestation report. Slices alias the attestation.
+type report struct {
+	Version         uint32
+	GuestSVN        uint32
+	Policy          uint64
+	FamilyID        []byte
+	ImageID         []byte
+	VMPL            uint32
+	SignatureAlgo   uint32
+	CurrentTCB      uint64
+	PlatformInfo    uint64
+	Flags           uint32
+	ReportData      []byte
+	Measurement     []byte
+	HostData        []byte
+	IDKeyDigest     []byte
+	AuthorKeyDigest []byte
+	ReportID        []byte
+	ReportedTCB     uint64
+	CPUIDFamily     uint8
+	ChipID          []byte
+	CommittedTCB    uint64
+	LaunchTCB       uint64
+
+	signed    []byte
+	signature []byte
+}
+
+func parseReport(data []byte) (*report, error) {
+	if len(data) < reportSize {
+		return nil, errShortReport
+	}
+	le := binary.LittleEndian
+	field := func(offset, size int) []byte { return data[offset : offset+size : offset+size] }
+	return &report{
+		Version:         le.Uint32(data[offsetVersion:]),
+		GuestSVN:        le.Uint32(data[offsetGuestSVN:]),
+		Policy:          le.Uint64(data[offsetPolicy:]),
+		FamilyID:        field(offsetFamilyID, 16),
+		ImageID:         field(offsetImageID, 16),
+		VMPL:            le.Uint32(data[offsetVMPL:]),
+		SignatureAlgo:   le.Uint32(data[offsetSignatureAlgo:]),
+		CurrentTCB:      le.Uint64(data[offsetCurrentTCB:]),
+		PlatformInfo:    le.Uint64(data[offsetPlatformInfo:]),
+		Flags:           le.Uint32(data[offsetFlags:]),
+		ReportData:      field(offsetReportData, reportDataSize),
+		Measurement:     field(offsetMeasurement, measurementSize),
+		HostData:        field(offsetHostData, hostDataSize),
+		IDKeyDigest:     field(offsetIDKeyDigest, measurementSize),
+		AuthorKeyDigest: field(offsetAuthorKeyDigest, measurementSize),
+		ReportID:        field(offsetReportID, 32),
+		ReportedTCB:     le.Uint64(data[offsetReportedTCB:]),
+		CPUIDFamily:     data[offsetCPUIDFamily],
+		ChipID:          field(offsetChipID, chipIDSize),
+		CommittedTCB:    le.Uint64(data[offsetCommittedTCB:]),
+		LaunchTCB:       le.Uint64(data[offsetLaunchTCB:]),
+		signed:          data[:signedSize],
+		signature:       data[signedSize:reportSize],
+	}, nil
+}
+
+// parseCertTable parses the SNP_GET_EXT_REPORT certificate table, a list of (GUID, offset, length) entries terminated
+// by a zero entry, with offsets relative to the start of the table, into DER certificates by GUID.
+func parseCertTable(table []byte) (map[uuid.UUID][]byte, error) {
+	certs := make(map[uuid.UUID][]byte)
+	for i := 0; ; i++ {
+		if i == maxCertTableEntries || len(table) < (i+1)*certTableEntrySize {
+			return nil, errBadCertTable
+		}
+		entry := table[i*certTableEntrySize:]
+		guid := uuid.UUID(entry[:16])
+		offset := int(binary.LittleEndian.Uint32(entry[16:]))
+		length := int(binary.LittleEndian.Uint32(entry[20:]))
+		if guid == uuid.Nil {
+			return certs, nil
+		}
+		if offset < 0 || length < 0 || offset > len(table) || length > len(table)-offset {
+			return nil, errBadCertTable
+		}
+		certs[guid] = table[offset : offset+length]
+	}
+}
+
+// verifyReport parses an attestation report followed by its certificate table, and verifies its chain of trust: the
+// VCEK or VLEK certificate chains to roots through the table's ASK, is not revoked by crls, and signed the report. A
+// VCEK must also have been derived for the chip ID, unless masked, and for the reported TCB of the report.
+func verifyReport(data []byte, roots *x509.CertPool, crls []*x509.RevocationList, currentTime time.Time) (*report, *x509.Certificate, error) {
+	r, err := parseReport(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if r.Version < 2 {
+		return nil, nil, errUnsupportedReportVersion
+	}
+	if r.SignatureAlgo != signatureAlgoECDSAP384SHA384 {
+		return nil, nil, errUnsupportedSignatureAlgo
+	}
+	var signingKeyGUID uuid.UUID
+	switch r.Flags & flagsSigningKey {
+	case signingKeyVCEK:
+		signingKeyGUID = guidVCEK
+	case signingKeyVLEK:
+		signingKeyGUID = guidVLEK
+	default:
+		return nil, nil, errUnsupportedSigningKey
+	}
+
+	certs, err := parseCertTable(data[reportSize:])
+	if err != nil {
+		return nil, nil, err
+	}
+	leafDER, ok := certs[signingKeyGUID]
+	if !ok {
+		return nil, nil, errMissingSigningKeyCertificate
+	}
+	leaf, err := x509.ParseCertificate(leafDER)
+	if err != nil {
+		return nil, nil, err
+	}
+	intermediates := x509.NewCertPool()
+	for _, guid := range []uuid.UUID{guidASK, guidARK} {
+		if der, ok := certs[guid]; ok {
+			cert, err := x509.ParseCertificate(der)
+			if err != nil {
+				return nil, nil, err
+			}
+			intermediates.AddCert(cert)
+		}
+	}
+	chains, err := leaf.Verify(x509.VerifyOptions{
+		Intermediates: intermediates,
+		Roots:         roots,
+		CurrentTime:   currentTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := teeattestation.CheckRevocation(chains[0], crls, currentTime); err != nil {
+		return nil, nil, err
+	}
+
+	pubKey, ok := leaf.PublicKey.(*ecdsa.PublicKey)
+	if !ok || pubKey.Curve != elliptic.P384() {
+		return nil, nil, errBadSigningKeyAlgo
+	}
+	if signingKeyGUID == guidVCEK {
+		if r.Flags&flagsMaskChipID == 0 {
+			if err := checkHWID(leaf, r.ChipID); err != nil {
+				return nil, nil, err
+			}
+		}
+		if err := checkTCB(leaf, decodeTCB(r.ReportedTCB, r.CPUIDFamily)); err != nil {
+			return nil, nil, err
+		}
+	}
+	if !verifyP384(pubKey, r.signed, r.signature) {
+		return nil, nil, errBadSignature
+	}
+	if r.Policy&policyDebug != 0 {
+		return nil, nil, errDebugGuest
+	}
+	return r, leaf, nil
+}
+
+// checkHWID checks the hardware ID extension of a VCEK, when present, against the report chip ID.
+func checkHWID(vcek *x509.Certificate, chipID []byte) error {
+	for _, ext := range vcek.Extensions {
+		if !ext.Id.Equal(oidHWID) {
+			continue
+		}
+		var hwID []byte
+		if _, err := asn1.Unmarshal(ext.Value, &hwID); err != nil {
+			hwID = ext.Value
+		}
+		if !bytes.Equal(hwID, chipID) {
+			return errChipIDMismatch
+		}
+	}
+	return nil
+}
+
+// checkTCB checks the security patch level extensions of a VCEK, when present, against the reported TCB.
+func checkTCB(vcek *x509.Certificate, reported TCB) error {
+	spls := map[string]uint8{
+		oidBootloaderSPL.String(): reported.Bootloader,
+		oidTEESPL.String():        reported.TEE,
+		oidSNPSPL.String():        reported.SNP,
+		oidMicrocodeSPL.String():  reported.Microcode,
+		oidFMCSPL.String():        reported.FMC,
+	}
+	for _, ext := range vcek.Extensions {
+		want, ok := spls[ext.Id.String()]
+		if !ok {
+			continue
+		}
+		var spl int
+		if _, err := asn1.Unmarshal(ext.Value, &spl); err != nil || spl != int(want) {
+			return errTCBMismatch
+		}
+	}
+	return nil
+}
+
+// verifyP384 verifies the little-endian R and S ECDSA P-384 signature of the SHA-384 digest of data.
+func verifyP384(publicKey *ecdsa.PublicKey, data, signature []byte) bool {
+	if len(signature) < 2*signatureComponentSize {
+		return false
+	}
+	hash := sha512.Sum384(data)
+	r := new(big.Int).SetBytes(reversed(signature[:signatureComponentSize]))
+	s := new(big.Int).SetBytes(reversed(signature[signatureComponentSize : 2*signatureComponentSize]))
+	return ecdsa.Verify(publicKey, hash[:], r, s)
+}
+
+func reversed(b []byte) []byte {
+	r := slices.Clone(b)
+	slices.Reverse(r)
+	return r
+}
@@ -0,0 +1,283 @@
+// Package tdxfake provides an Attestor that produces structurally valid
+// Intel TDX v4 quotes. These quotes pass the local TDX quote validator's
+// full validation chain (PCK certificate chain, QE report and identity, attestation key,
+// quote signature, report data, MRTD/RTMRs) without requiring TDX hardware.
+package tdxfake
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// Attestor produces TDX quotes signed by a fake quoting enclave, whose PCK
+// certificate chains to a self-signed root CA.
+type Attestor struct {
+	rootCertDER    []byte
+	caCert         *x509.Certificate
+	caKey          *ecdsa.PrivateKey
+	pckSerial      *big.Int
+	pckChainPEM    []byte
+	pckKey         *ecdsa.PrivateKey
+	attestationKey *ecdsa.PrivateKey
+	mrtd           []byte
+	rtmrs          [4][]byte
+}
+
+// NewAttestor generates a self-signed P-256 root CA, an intermediate CA and
+// a PCK certificate signed by it, a quote attestation key, and deterministic
+// 48-byte fake MRTD and RTMR values.
+func NewAttestor() (*Attestor, error) {
+	rootKey, rootCert, rootCertDER, err := newCA("Fake SGX Root CA", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	caKey, caCert, caCertDER, err := newCA("Fake SGX PCK Platform CA", rootCert, rootKey)
+	if err != nil {
+		return nil, err
+	}
+
+	pckKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate PCK key: %w", err)
+	}
+	pckTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "Fake SGX PCK Certificate"},
+		NotBefore:    time.Now().Add(-1 * time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	pckCertDER, err := x509.CreateCertificate(rand.Reader, pckTemplate, caCert, &pckKey.PublicKey, caKey)
+	if err != nil {
+		return nil, fmt.Errorf("create PCK cert: %w", err)
+	}
+
+	attestationKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate attestation key: %w", err)
+	}
+
+	var pckChainPEM []byte
+	for _, der := range [][]byte{pckCertDER, caCertDER, rootCertDER} {
+		pckChainPEM = append(pckChainPEM, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	}
+	a := &Attestor{
+		rootCertDER:    rootCertDER,
+		caCert:         caCert,
+		caKey:          caKey,
+		pckSerial:      pckTemplate.SerialNumber,
+		pckChainPEM:    pckChainPEM,
+		pckKey:         pckKey,
+		attestationKey: attestationKey,
+		mrtd:           sha384Sum([]byte("fake-mrtd")),
+	}
+	for i := range a.rtmrs {
+		a.rtmrs[i] = sha384Sum(fmt.Appendf(nil, "fake-rtmr-%d", i))
+	}
+	return a, nil
+}
+
+func newCA(name string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*ecdsa.PrivateKey, *x509.Certificate, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("generate %s key: %w", name, err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-1 * time.Hour),
+		NotAfter:              time.Now().Add(10 * 365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("create %s cert: %w", name, err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("parse %s cert: %w", name, err)
+	}
+	return key, cert, der, nil
+}
+
+// QEMRSigner is the MRSIGNER of the Intel quoting enclaves, which the fake QE reports carry.
+var QEMRSigner = []byte{
+	0xdc, 0x9e, 0x2a, 0x7c, 0x6f, 0x94, 0x8f, 0x17, 0x47, 0x4e, 0x34, 0xa7, 0xfc, 0x43, 0xed, 0x03,
+	0x0f, 0x7c, 0x15, 0x63, 0xf1, 0xba, 0xbd, 0xdf, 0x63, 0x40, 0xc8, 0x2e, 0x0e, 0x54, 0xa8, 0xc5,
+}
+
+type quoteOptions struct {
+	tdAttributes uint64
+	mrConfigID   []byte
+	teeTCBSVN    []byte
+	qeMRSigner   []byte
+}
+
+// Option customizes the fields of a fake quote.
+type Option func(*quoteOptions)
+
+// WithTDAttributes sets the TDATTRIBUTES field, e.g. 1 for a debug TD.
+func WithTDAttributes(attributes uint64) Option {
+	return func(o *quoteOptions) { o.tdAttributes = attributes }
+}
+
+// WithMRConfigID sets the MRCONFIGID field, which is at most 48 bytes.
+func WithMRConfigID(mrConfigID []byte) Option {
+	return func(o *quoteOptions) { o.mrConfigID = mrConfigID }
+}
+
+// WithTEETCBSVN sets the TEE_TCB_SVN field, which is at most 16 bytes.
+func WithTEETCBSVN(svn []byte) Option {
+	return func(o *quoteOptions) { o.teeTCBSVN = svn }
+}
+
+// WithQEMRSigner sets the MRSIGNER of the QE report, instead of QEMRSigner.
+func WithQEMRSigner(mrSigner []byte) Option {
+	return func(o *quoteOptions) { o.qeMRSigner = mrSigner }
+}
+
+// CreateQuote builds a TDX v4 quote with the given reportData, which is at
+// most 64 bytes and zero padded.
+func (f *Attestor) CreateQuote(reportData []byte, opts ...Option) ([]byte, error) {
+	if len(reportData) > 64 {
+		return nil, fmt.Errorf("report data is %d bytes, at most 64 allowed", len(reportData))
+	}
+	o := quoteOptions{qeMRSigner: QEMRSigner}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	le := binary.LittleEndian
+	quote := le.AppendUint16(nil, 4)           // version
+	quote = le.AppendUint16(quote, 2)          // attestation key type: ECDSA P-256
+	quote = le.AppendUint32(quote, 0x81)       // TEE type: TDX
+	quote = append(quote, make([]byte, 40)...) // reserved, QE vendor ID, user data
+
+	quote = append(quote, padded(o.teeTCBSVN, 16)...)
+	quote = append(quote, make([]byte, 48+48+8)...) // MRSEAM, MRSIGNERSEAM, SEAM attributes
+	quote = le.AppendUint64(quote, o.tdAttributes)
+	quote = le.AppendUint64(quote, 0) // XFAM
+	quote = append(quote, f.mrtd...)
+	quote = append(quote, padded(o.mrConfigID, 48)...)
+	quote = append(quote, make([]byte, 2*48)...) // MROWNER, MROWNERCONFIG
+	for _, rtmr := range f.rtmrs {
+		quote = append(quote, rtmr...)
+	}
+	quote = append(quote, padded(reportData, 64)...)
+
+	quoteSignature, err := sign(f.attestationKey, quote)
+	if err != nil {
+		return nil, err
+	}
+	attestationKey, err := f.attestationKey.PublicKey.Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("encode attestation key: %w", err)
+	}
+	attestationKey = attestationKey[1:] // raw X || Y without the uncompressed point prefix
+	qeAuthData := []byte("fake-qe-auth-data")
+
+	qeReport := make([]byte, 384)
+	copy(qeReport[128:160], o.qeMRSigner)
+	le.PutUint16(qeReport[256:], 2) // ISVPRODID of the TD quoting enclave
+	binding := sha256.Sum256(append(append([]byte{}, attestationKey...), qeAuthData...))
+	copy(qeReport[320:], binding[:])
+	qeReportSignature, err := sign(f.pckKey, qeReport)
+	if err != nil {
+		return nil, err
+	}
+
+	qeCertData := append(qeReport, qeReportSignature...)
+	qeCertData = le.AppendUint16(qeCertData, uint16(len(qeAuthData))) //nolint:gosec // fixed size
+	qeCertData = append(qeCertData, qeAuthData...)
+	qeCertData = le.AppendUint16(qeCertData, 5)                          // PCK certificate chain
+	qeCertData = le.AppendUint32(qeCertData, uint32(len(f.pckChainPEM))) //nolint:gosec // bounded by the chain
+	qeCertData = append(qeCertData, f.pckChainPEM...)
+
+	sigData := append(quoteSignature, attestationKey...)
+	sigData = le.AppendUint16(sigData, 6)                       // QE report certification data
+	sigData = le.AppendUint32(sigData, uint32(len(qeCertData))) //nolint:gosec // bounded by the chain
+	sigData = append(sigData, qeCertData...)
+
+	quote = le.AppendUint32(quote, uint32(len(sigData))) //nolint:gosec // bounded by the chain
+	return append(quote, sigData...), nil
+}
+
+// CARootsPEM returns the root CA certificate in PEM format.
+func (f *Attestor) CARootsPEM() string {
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: f.rootCertDER,
+	}))
+}
+
+// CreateCRL returns a CRL of the PCK platform CA, revoking the PCK certificate if revokePCK is set.
+func (f *Attestor) CreateCRL(revokePCK bool) (*x509.RevocationList, error) {
+	template := &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now().Add(-1 * time.Hour),
+		NextUpdate: time.Now().Add(24 * time.Hour),
+	}
+	if revokePCK {
+		template.RevokedCertificateEntries = []x509.RevocationListEntry{{
+			SerialNumber:   f.pckSerial,
+			RevocationTime: time.Now(),
+		}}
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, template, f.caCert, f.caKey)
+	if err != nil {
+		return nil, fmt.Errorf("create CRL: %w", err)
+	}
+	return x509.ParseRevocationList(der)
+}
+
+// TrustedMeasurementsJSON returns the MRTD and RTMR values as a JSON object
+// matching the format expected by the quote validator.
+func (f *Attestor) TrustedMeasurementsJSON() []byte {
+	return fmt.Appendf(nil, `{"mrtd":"%s","rtmr0":"%s","rtmr1":"%s","rtmr2":"%s","rtmr3":"%s"}`,
+		hex.EncodeToString(f.mrtd),
+		hex.EncodeToString(f.rtmrs[0]),
+		hex.EncodeToString(f.rtmrs[1]),
+		hex.EncodeToString(f.rtmrs[2]),
+		hex.EncodeToString(f.rtmrs[3]),
+	)
+}
+
+// sign returns the raw r || s ECDSA P-256 signature of the SHA-256 digest of data.
+func sign(key *ecdsa.PrivateKey, data []byte) ([]byte, error) {
+	hash := sha256.Sum256(data)
+	r, s, err := ecdsa.Sign(rand.Reader, key, hash[:])
+	if err != nil {
+		return nil, fmt.Errorf("ecdsa sign: %w", err)
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+	return signature, nil
+}
+
+func padded(b []byte, size int) []byte {
+	p := make([]byte, size)
+	copy(p, b)
+	return p
+}
+
+func sha384Sum(data []byte) []byte {
+	h := sha512.Sum384(data)
+	return h[:]
+}
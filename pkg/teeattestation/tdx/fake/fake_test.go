@@ -0,0 +1,30 @@
+package tdxfake
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/teeattestation/tdx"
+)
+
+func TestAttestor_RoundTrip(t *testing.T) {
+	fa, err := NewAttestor()
+	require.NoError(t, err)
+
+	userData := []byte("test-user-data-12345")
+	quote, err := fa.CreateQuote(userData)
+	require.NoError(t, err)
+	require.NotEmpty(t, quote)
+
+	err = tdx.ValidateAttestation(quote, userData, fa.TrustedMeasurementsJSON(), fa.CARootsPEM())
+	require.NoError(t, err)
+}
+
+func TestAttestor_ReportDataTooLong(t *testing.T) {
+	fa, err := NewAttestor()
+	require.NoError(t, err)
+
+	_, err = fa.CreateQuote(make([]byte, 65))
+	require.Error(t, err)
+}
@@ -0,0 +1,194 @@
+// Package tdx provides Intel TDX quote validation.
+package tdx
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/teeattestation"
+)
+
+// Measurements holds the trusted TD measurements for attestation validation.
+// MRTD is required; the RTMRs are only checked when set. MinTEETCBSVN, when
+// set, is the minimum TEE_TCB_SVN of the TDX module, compared per component.
+type Measurements struct {
+	MRTD  teeattestation.HexBytes `json:"mrtd"`
+	RTMR0 teeattestation.HexBytes `json:"rtmr0,omitempty"`
+	RTMR1 teeattestation.HexBytes `json:"rtmr1,omitempty"`
+	RTMR2 teeattestation.HexBytes `json:"rtmr2,omitempty"`
+	RTMR3 teeattestation.HexBytes `json:"rtmr3,omitempty"`
+
+	MinTEETCBSVN teeattestation.HexBytes `json:"min_tee_tcb_svn,omitempty"`
+}
+
+// Quote holds the validated, parsed fields of a TDX quote. A Quote is
+// returned only after the full validation chain (PCK certificate chain, QE
+// report, quote signature, expected report data, trusted MRTD/RTMRs) has
+// passed.
+type Quote struct {
+	// TEETCBSVN is the TCB SVN of the TDX module. It has already been checked
+	// against the trusted minimum, if any.
+	TEETCBSVN []byte
+	// MRSEAM and MRSignerSEAM measure the TDX module and its signer.
+	MRSEAM       []byte
+	MRSignerSEAM []byte
+	// TDAttributes and XFAM are the TD attributes and its extended features.
+	// Debug TDs have already been rejected.
+	TDAttributes uint64
+	XFAM         uint64
+	// MRTD is the build-time measurement of the TD.
+	MRTD []byte
+	// MRConfigID, MROwner and MROwnerConfig are set by the host at launch.
+	// They are exposed unchecked.
+	MRConfigID    []byte
+	MROwner       []byte
+	MROwnerConfig []byte
+	// RTMRs are the runtime-extendable measurement registers.
+	RTMRs [4][]byte
+	// ReportData is the 64 byte REPORTDATA of the TD. It has already been
+	// checked to equal the expectedUserData argument, zero padded.
+	ReportData []byte
+	// PCKPublicKey is the SPKI (DER) of the PCK certificate public key, which
+	// identifies the platform.
+	PCKPublicKey []byte
+}
+
+// ValidateAttestation verifies an Intel TDX v4 quote against expected user
+// data and trusted measurements. The PCK certificate chain embedded in the
+// quote must chain to caRootsPEM, normally the Intel SGX Provisioning
+// Certification Root CA, and the QE report must be of the Intel TD quoting
+// enclave.
+//
+// The chain is checked against crls, normally the root CA and PCK CRLs of the
+// Intel PCS, which callers are expected to fetch and refresh. Without crls,
+// revoked PCK certificates are accepted. The TCB status of the platform is
+// only checked against the trusted MinTEETCBSVN.
+func ValidateAttestation(attestation, expectedUserData, trustedMeasurements []byte, caRootsPEM string, crls ...*x509.RevocationList) error {
+	_, err := ValidateAndParse(attestation, expectedUserData, trustedMeasurements, caRootsPEM, crls...)
+	return err
+}
+
+// ValidateAndParse runs the same validation as ValidateAttestation and, on
+// success, returns the parsed quote fields.
+func ValidateAndParse(attestation, expectedUserData, trustedMeasurements []byte, caRootsPEM string, crls ...*x509.RevocationList) (*Quote, error) {
+	if attestation == nil {
+		return nil, errors.New("attestation is nil")
+	}
+	if len(expectedUserData) > reportDataSize {
+		return nil, fmt.Errorf("expected user data is longer than %d bytes", reportDataSize)
+	}
+
+	pool, err := teeattestation.ParseCARoots(caRootsPEM)
+	if err != nil {
+		return nil, err
+	}
+	q, pck, err := verifyQuote(attestation, pool, crls, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify tdx quote: %w", err)
+	}
+
+	if !teeattestation.ReportDataMatches(q.body.ReportData, expectedUserData) {
+		return nil, fmt.Errorf("expected user data %x, got %x", expectedUserData, q.body.ReportData)
+	}
+
+	var trusted Measurements
+	if err := json.Unmarshal(trustedMeasurements, &trusted); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal trusted measurements: %w", err)
+	}
+	if len(trusted.MRTD) == 0 {
+		return nil, errors.New("trusted measurements are missing MRTD")
+	}
+	if !bytes.Equal(q.body.MRTD, trusted.MRTD) {
+		return nil, fmt.Errorf("MRTD mismatch: expected %x", trusted.MRTD)
+	}
+	for i, rtmr := range [4][]byte{trusted.RTMR0, trusted.RTMR1, trusted.RTMR2, trusted.RTMR3} {
+		if len(rtmr) > 0 && !bytes.Equal(q.body.RTMRs[i], rtmr) {
+			return nil, fmt.Errorf("RTMR%d mismatch: expected %x", i, rtmr)
+		}
+	}
+	if len(trusted.MinTEETCBSVN) > 0 && !svnAtLeast(q.body.TEETCBSVN, trusted.MinTEETCBSVN) {
+		return nil, fmt.Errorf("TEE TCB SVN %x is below the minimum %x", q.body.TEETCBSVN, trusted.MinTEETCBSVN)
+	}
+
+	pckPublicKey, err := x509.MarshalPKIXPublicKey(pck.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("marshal PCK public key: %w", err)
+	}
+	b := q.body
+	return &Quote{
+		TEETCBSVN:     bytes.Clone(b.TEETCBSVN),
+		MRSEAM:        bytes.Clone(b.MRSEAM),
+		MRSignerSEAM:  bytes.Clone(b.MRSignerSEAM),
+		TDAttributes:  b.TDAttributes,
+		XFAM:          b.XFAM,
+		MRTD:          bytes.Clone(b.MRTD),
+		MRConfigID:    bytes.Clone(b.MRConfigID),
+		MROwner:       bytes.Clone(b.MROwner),
+		MROwnerConfig: bytes.Clone(b.MROwnerConfig),
+		RTMRs:         [4][]byte{bytes.Clone(b.RTMRs[0]), bytes.Clone(b.RTMRs[1]), bytes.Clone(b.RTMRs[2]), bytes.Clone(b.RTMRs[3])},
+		ReportData:    bytes.Clone(b.ReportData),
+		PCKPublicKey:  pckPublicKey,
+	}, nil
+}
+
+// svnAtLeast reports whether every component of svn is at least that of minimum.
+func svnAtLeast(svn, minimum []byte) bool {
+	if len(minimum) > len(svn) {
+		return false
+	}
+	for i, m := range minimum {
+		if svn[i] < m {
+			return false
+		}
+	}
+	return true
+}
+
+// Validator is the teeattestation.Validator of Intel TDX quotes.
+type Validator struct {
+	caRootsPEM string
+	crls       []*x509.RevocationList
+}
+
+var _ teeattestation.Validator = (*Validator)(nil)
+
+// NewValidator returns a Validator against the CA roots of caRootsPEM and the
+// optional crls, see ValidateAttestation.
+func NewValidator(caRootsPEM string, crls ...*x509.RevocationList) *Validator {
+	return &Validator{caRootsPEM: caRootsPEM, crls: crls}
+}
+
+func (v *Validator) Platform() teeattestation.Platform { return teeattestation.PlatformTDX }
+
+// Validate runs ValidateAndParse and converts the Quote to
+// teeattestation.Measurements, with registers "MRTD", "RTMR0".."RTMR3",
+// "MRSEAM", "MRSIGNERSEAM", "MRCONFIGID", "MROWNER" and "MROWNERCONFIG", the
+// TEE_TCB_SVN as TCB, and the PCK key as SigningKey.
+func (v *Validator) Validate(attestation, expectedUserData, trustedMeasurements []byte) (*teeattestation.Measurements, error) {
+	q, err := ValidateAndParse(attestation, expectedUserData, trustedMeasurements, v.caRootsPEM, v.crls...)
+	if err != nil {
+		return nil, err
+	}
+	registers := map[string][]byte{
+		"MRTD":          q.MRTD,
+		"MRSEAM":        q.MRSEAM,
+		"MRSIGNERSEAM":  q.MRSignerSEAM,
+		"MRCONFIGID":    q.MRConfigID,
+		"MROWNER":       q.MROwner,
+		"MROWNERCONFIG": q.MROwnerConfig,
+	}
+	for i, rtmr := range q.RTMRs {
+		registers[fmt.Sprintf("RTMR%d", i)] = rtmr
+	}
+	return &teeattestation.Measurements{
+		Platform:   teeattestation.PlatformTDX,
+		Registers:  registers,
+		ReportData: q.ReportData,
+		TCB:        q.TEETCBSVN,
+		SigningKey: q.PCKPublicKey,
+	}, nil
+}
@@ -0,0 +1,240 @@
+package tdx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/teeattestation"
+	tdxfake "github.com/smartcontractkit/chainlink-common/pkg/teeattestation/tdx/fake"
+)
+
+func TestValidateAttestation_Attestor(t *testing.T) {
+	fa, err := tdxfake.NewAttestor()
+	require.NoError(t, err)
+
+	userData := teeattestation.DomainHash("test-tag", []byte(`{"key":"value"}`))
+	quote, err := fa.CreateQuote(userData)
+	require.NoError(t, err)
+
+	err = ValidateAttestation(quote, userData, fa.TrustedMeasurementsJSON(), fa.CARootsPEM())
+	require.NoError(t, err)
+}
+
+func TestValidateAndParse_SurfacesQuoteFields(t *testing.T) {
+	fa, err := tdxfake.NewAttestor()
+	require.NoError(t, err)
+
+	userData := []byte("test-data")
+	quote, err := fa.CreateQuote(userData, tdxfake.WithMRConfigID([]byte("config")))
+	require.NoError(t, err)
+
+	parsed, err := ValidateAndParse(quote, userData, fa.TrustedMeasurementsJSON(), fa.CARootsPEM())
+	require.NoError(t, err)
+	assert.Len(t, parsed.ReportData, reportDataSize)
+	assert.Equal(t, userData, parsed.ReportData[:len(userData)])
+	assert.Equal(t, []byte("config"), parsed.MRConfigID[:len("config")])
+	assert.Len(t, parsed.MRTD, measurementSize)
+	assert.NotEmpty(t, parsed.PCKPublicKey)
+}
+
+func TestValidateAttestation_WrongUserData(t *testing.T) {
+	fa, err := tdxfake.NewAttestor()
+	require.NoError(t, err)
+	quote, err := fa.CreateQuote([]byte("test-data"))
+	require.NoError(t, err)
+
+	err = ValidateAttestation(quote, []byte("wrong"), fa.TrustedMeasurementsJSON(), fa.CARootsPEM())
+	require.ErrorContains(t, err, "expected user data")
+	err = ValidateAttestation(quote, make([]byte, reportDataSize+1), fa.TrustedMeasurementsJSON(), fa.CARootsPEM())
+	require.ErrorContains(t, err, "longer than")
+}
+
+func TestValidateAttestation_WrongMeasurements(t *testing.T) {
+	fa, err := tdxfake.NewAttestor()
+	require.NoError(t, err)
+	quote, err := fa.CreateQuote([]byte("test-data"))
+	require.NoError(t, err)
+
+	for name, tc := range map[string]struct {
+		trusted string
+		err     string
+	}{
+		"mrtd":         {`{"mrtd":"aa"}`, "MRTD mismatch"},
+		"missing mrtd": {`{"rtmr0":"aa"}`, "missing MRTD"},
+		"not json":     {`nope`, "failed to unmarshal"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateAttestation(quote, []byte("test-data"), []byte(tc.trusted), fa.CARootsPEM())
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+
+	parsed, err := ValidateAndParse(quote, []byte("test-data"), fa.TrustedMeasurementsJSON(), fa.CARootsPEM())
+	require.NoError(t, err)
+	trusted := Measurements{MRTD: parsed.MRTD, RTMR2: []byte{0xaa}}
+	err = ValidateAttestation(quote, []byte("test-data"), mustJSON(t, trusted), fa.CARootsPEM())
+	require.ErrorContains(t, err, "RTMR2 mismatch")
+
+	trusted.RTMR2 = nil
+	err = ValidateAttestation(quote, []byte("test-data"), mustJSON(t, trusted), fa.CARootsPEM())
+	require.NoError(t, err, "unset RTMRs are not checked")
+}
+
+func TestVerifyQuote_Rejects(t *testing.T) {
+	fa, err := tdxfake.NewAttestor()
+	require.NoError(t, err)
+	quote, err := fa.CreateQuote([]byte("test-data"))
+	require.NoError(t, err)
+	debug, err := fa.CreateQuote([]byte("test-data"), tdxfake.WithTDAttributes(tdAttributesDebug))
+	require.NoError(t, err)
+	otherQE, err := fa.CreateQuote([]byte("test-data"), tdxfake.WithQEMRSigner([]byte("other")))
+	require.NoError(t, err)
+	other, err := tdxfake.NewAttestor()
+	require.NoError(t, err)
+
+	roots, err := teeattestation.ParseCARoots(fa.CARootsPEM())
+	require.NoError(t, err)
+	otherRoots, err := teeattestation.ParseCARoots(other.CARootsPEM())
+	require.NoError(t, err)
+
+	modified := func(offset int, f func([]byte)) []byte {
+		b := append([]byte{}, quote...)
+		f(b[offset:])
+		return b
+	}
+	flip := func(b []byte) { b[0] ^= 0xff }
+
+	for name, tc := range map[string]struct {
+		quote []byte
+		roots bool
+		err   error
+	}{
+		"truncated":      {quote: quote[:signedSize+10], err: errShortQuote},
+		"version":        {quote: modified(0, func(b []byte) { binary.LittleEndian.PutUint16(b, 3) }), err: errUnsupportedQuoteVersion},
+		"key type":       {quote: modified(2, func(b []byte) { binary.LittleEndian.PutUint16(b, 3) }), err: errUnsupportedAttestationKeyType},
+		"tee type":       {quote: modified(4, func(b []byte) { binary.LittleEndian.PutUint32(b, 0) }), err: errNotTDXQuote},
+		"body":           {quote: modified(quoteHeaderSize+136, flip), err: errBadQuoteSignature},
+		"quote sig":      {quote: modified(signedSize+4, flip), err: errBadQuoteSignature},
+		"attest key":     {quote: modified(signedSize+4+signatureSize, flip), err: errBadQEReportData},
+		"qe report":      {quote: modified(signedSize+4+signatureSize+publicKeySize+6, flip), err: errBadQEReportSignature},
+		"qe identity":    {quote: otherQE, err: errBadQEIdentity},
+		"debug":          {quote: debug, err: errDebugTD},
+		"untrusted root": {quote: quote, roots: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			pool := roots
+			if tc.roots {
+				pool = otherRoots
+			}
+			_, _, err := verifyQuote(tc.quote, pool, nil, time.Now())
+			require.Error(t, err)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+			}
+		})
+	}
+}
+
+func TestValidator(t *testing.T) {
+	fa, err := tdxfake.NewAttestor()
+	require.NoError(t, err)
+	userData := teeattestation.DomainHash("test-tag", []byte(`{"key":"value"}`))
+	quote, err := fa.CreateQuote(userData)
+	require.NoError(t, err)
+
+	v := NewValidator(fa.CARootsPEM())
+	assert.Equal(t, teeattestation.PlatformTDX, v.Platform())
+	m, err := v.Validate(quote, userData, fa.TrustedMeasurementsJSON())
+	require.NoError(t, err)
+	assert.Equal(t, teeattestation.PlatformTDX, m.Platform)
+	assert.True(t, teeattestation.ReportDataMatches(m.ReportData, userData))
+	for _, name := range []string{"MRTD", "RTMR0", "RTMR1", "RTMR2", "RTMR3", "MRSEAM", "MRCONFIGID"} {
+		assert.Len(t, m.Registers[name], measurementSize, name)
+	}
+	assert.Len(t, m.TCB, 16)
+	assert.NotEmpty(t, m.SigningKey)
+}
+
+func TestValidateAttestation_MinTEETCBSVN(t *testing.T) {
+	fa, err := tdxfake.NewAttestor()
+	require.NoError(t, err)
+	svn := []byte{3, 1, 2}
+	quote, err := fa.CreateQuote([]byte("test-data"), tdxfake.WithTEETCBSVN(svn))
+	require.NoError(t, err)
+	var trusted Measurements
+	require.NoError(t, json.Unmarshal(fa.TrustedMeasurementsJSON(), &trusted))
+
+	trusted.MinTEETCBSVN = svn
+	require.NoError(t, ValidateAttestation(quote, []byte("test-data"), mustJSON(t, trusted), fa.CARootsPEM()))
+	trusted.MinTEETCBSVN = []byte{2, 1, 3}
+	err = ValidateAttestation(quote, []byte("test-data"), mustJSON(t, trusted), fa.CARootsPEM())
+	require.ErrorContains(t, err, "below the minimum", "every component must be at least the minimum")
+}
+
+func TestValidateAttestation_CRL(t *testing.T) {
+	fa, err := tdxfake.NewAttestor()
+	require.NoError(t, err)
+	quote, err := fa.CreateQuote([]byte("test-data"))
+	require.NoError(t, err)
+
+	crl, err := fa.CreateCRL(false)
+	require.NoError(t, err)
+	require.NoError(t, ValidateAttestation(quote, []byte("test-data"), fa.TrustedMeasurementsJSON(), fa.CARootsPEM(), crl))
+
+	revoked, err := fa.CreateCRL(true)
+	require.NoError(t, err)
+	err = ValidateAttestation(quote, []byte("test-data"), fa.TrustedMeasurementsJSON(), fa.CARootsPEM(), revoked)
+	require.ErrorIs(t, err, teeattestation.ErrCertificateRevoked)
+	_, err = NewValidator(fa.CARootsPEM(), revoked).Validate(quote, []byte("test-data"), fa.TrustedMeasurementsJSON())
+	require.ErrorIs(t, err, teeattestation.ErrCertificateRevoked)
+}
+
+// TestVerifyQuote_SapphireRapids verifies a quote captured on a Sapphire Rapids processor, see testdata/README.md.
+func TestVerifyQuote_SapphireRapids(t *testing.T) {
+	quote, err := os.ReadFile("testdata/tdx_prod_quote_SPR_E4.dat")
+	require.NoError(t, err)
+	root, err := os.ReadFile("testdata/intel_sgx_root_ca.pem")
+	require.NoError(t, err)
+	roots, err := teeattestation.ParseCARoots(string(root))
+	require.NoError(t, err)
+	// the PCK certificate is valid from 2022-09-20 to 2029-09-20
+	issued := time.Date(2023, time.July, 1, 0, 0, 0, 0, time.UTC)
+
+	q, pck, err := verifyQuote(quote, roots, nil, issued)
+	require.NoError(t, err)
+	assert.Equal(t, "Intel SGX PCK Certificate", pck.Subject.CommonName)
+	assert.Equal(t, qeMRSigner, q.qeReport[qeMRSignerOffset:qeMRSignerOffset+len(qeMRSigner)])
+	assert.Equal(t, uint16(qeISVProdIDTD), binary.LittleEndian.Uint16(q.qeReport[qeISVProdIDOffset:]))
+	assert.Equal(t, "6363b8043668a3ad953278e10389574d326c6749fb78aa810ecd9336923db86f22fc00b8dcd404bc10d5e119d7215cbb", hex.EncodeToString(q.body.MRTD))
+	assert.Equal(t, "6c62dec1b8191749a31dab490be532a35944dea47caef1f980863993d9899545eb7406a38d1eed313b987a467dacead6f0c87a6d766c66f6f29f8acb281f1113", hex.EncodeToString(q.body.ReportData))
+	assert.Zero(t, q.body.TDAttributes&tdAttributesDebug)
+
+	tampered := bytes.Clone(quote)
+	tampered[signedSize-1] ^= 0xff
+	_, _, err = verifyQuote(tampered, roots, nil, issued)
+	require.ErrorIs(t, err, errBadQuoteSignature)
+	_, _, err = verifyQuote(quote, roots, nil, time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC))
+	require.Error(t, err, "the PCK certificate has expired")
+
+	fa, err := tdxfake.NewAttestor()
+	require.NoError(t, err)
+	otherRoots, err := teeattestation.ParseCARoots(fa.CARootsPEM())
+	require.NoError(t, err)
+	_, _, err = verifyQuote(quote, otherRoots, nil, issued)
+	require.Error(t, err, "the PCK certificate doesn't chain to other roots")
+}
+
+func mustJSON(t *testing.T, m Measurements) []byte {
+	t.Helper()
+	b, err := json.Marshal(m)
+	require.NoError(t, err)
+	return b
+}
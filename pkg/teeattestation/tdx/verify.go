@@ -0,0 +1,299 @@
+package tdx
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/teeattestation"
+)
+
+var (
+	errShortQuote                    = errors.New("quote is truncated")
+	errUnsupportedQuoteVersion       = errors.New("quote version is not 4")
+	errUnsupportedAttestationKeyType = errors.New("quote attestation key type is not ECDSA P-256")
+	errNotTDXQuote                   = errors.New("quote TEE type is not TDX")
+	errUnsupportedCertificationData  = errors.New("quote certification data is not a QE report")
+	errUnsupportedQECertification    = errors.New("QE report certification data is not a PCK certificate chain")
+	errBadPCKChain                   = errors.New("PCK certificate chain is empty or malformed")
+	errBadPCKPublicKey               = errors.New("PCK certificate public key is not ECDSA P-256")
+	errBadAttestationKey             = errors.New("quote attestation key is not a P-256 point")
+	errBadQEReportSignature          = errors.New("QE report signature does not match PCK certificate")
+	errBadQEReportData               = errors.New("QE report data does not bind the attestation key")
+	errBadQEIdentity                 = errors.New("QE report is not of the Intel TD quoting enclave")
+	errBadQuoteSignature             = errors.New("quote signature does not match attestation key")
+	errDebugTD                       = errors.New("quote is from a debug TD")
+)
+
+// Quote v4 layout, see the Intel TDX DCAP Quoting Library API, appendix A.
+const (
+	quoteVersion4               = 4
+	attestationKeyTypeECDSAP256 = 2
+	teeTypeTDX                  = 0x81
+	certDataTypePCKChain        = 5
+	certDataTypeQEReport        = 6
+
+	quoteHeaderSize   = 48
+	tdReportBodySize  = 584
+	sgxReportBodySize = 384
+	signatureSize     = 64
+	publicKeySize     = 64
+	measurementSize   = 48
+	reportDataSize    = 64
+
+	// signedSize is the length of the header and TD report body signed by the attestation key.
+	signedSize = quoteHeaderSize + tdReportBodySize
+	// qeReportDataOffset is the offset of REPORTDATA in an SGX report body, and qeMRSignerOffset and
+	// qeISVProdIDOffset those of MRSIGNER and ISVPRODID.
+	qeReportDataOffset = 320
+	qeMRSignerOffset   = 128
+	qeISVProdIDOffset  = 256
+	// qeISVProdIDTD is the ISVPRODID of the TD quoting enclave.
+	qeISVProdIDTD = 2
+
+	// tdAttributesDebug is the DEBUG bit of TDATTRIBUTES.
+	tdAttributesDebug = 1 << 0
+)
+
+// qeMRSigner is the MRSIGNER of the Intel quoting enclaves, as published in the QE identity of the Intel PCS.
+var qeMRSigner = []byte{
+	0xdc, 0x9e, 0x2a, 0x7c, 0x6f, 0x94, 0x8f, 0x17, 0x47, 0x4e, 0x34, 0xa7, 0xfc, 0x43, 0xed, 0x03,
+	0x0f, 0x7c, 0x15, 0x63, 0xf1, 0xba, 0xbd, 0xdf, 0x63, 0x40, 0xc8, 0x2e, 0x0e, 0x54, 0xa8, 0xc5,
+}
+
+// tdReport is the TD quote body. Slices alias the quote.
+type tdReport struct {
+	TEETCBSVN      []byte
+	MRSEAM         []byte
+	MRSignerSEAM   []byte
+	SEAMAttributes uint64
+	TDAttributes   uint64
+	XFAM           uint64
+	MRTD           []byte
+	MRConfigID     []byte
+	MROwner        []byte
+	MROwnerConfig  []byte
+	RTMRs          [4][]byte
+	ReportData     []byte
+}
+
+type quote struct {
+	signed            []byte
+	body              tdReport
+	signature         []byte
+	attestationKey    []byte
+	qeReport          []byte
+	qeReportSignature []byte
+	qeAuthData        []byte
+	pckChain          []byte
+}
+
+// quoteReader reads the little-endian fields of a quote.
+type quoteReader struct {
+	b   []byte
+	err error
+}
+
+func (r *quoteReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || len(r.b) < n {
+		r.err = errShortQuote
+		return nil
+	}
+	v := r.b[:n:n]
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *quoteReader) uint16() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.LittleEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *quoteReader) uint32() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.LittleEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (r *quoteReader) uint64() uint64 {
+	if b := r.bytes(8); b != nil {
+		return binary.LittleEndian.Uint64(b)
+	}
+	return 0
+}
+
+func parseQuote(data []byte) (*quote, error) {
+	r := &quoteReader{b: data}
+	version := r.uint16()
+	keyType := r.uint16()
+	teeType := r.uint32()
+	r.bytes(quoteHeaderSize - 8) // reserved, QE vendor ID and user data
+	if r.err != nil {
+		return nil, r.err
+	}
+	if version != quoteVersion4 {
+		return nil, errUnsupportedQuoteVersion
+	}
+	if keyType != attestationKeyTypeECDSAP256 {
+		return nil, errUnsupportedAttestationKeyType
+	}
+	if teeType != teeTypeTDX {
+		return nil, errNotTDXQuote
+	}
+
+	q := &quote{signed: data[:min(len(data), signedSize)]}
+	b := &q.body
+	b.TEETCBSVN = r.bytes(16)
+	b.MRSEAM = r.bytes(measurementSize)
+	b.MRSignerSEAM = r.bytes(measurementSize)
+	b.SEAMAttributes = r.uint64()
+	b.TDAttributes = r.uint64()
+	b.XFAM = r.uint64()
+	b.MRTD = r.bytes(measurementSize)
+	b.MRConfigID = r.bytes(measurementSize)
+	b.MROwner = r.bytes(measurementSize)
+	b.MROwnerConfig = r.bytes(measurementSize)
+	for i := range b.RTMRs {
+		b.RTMRs[i] = r.bytes(measurementSize)
+	}
+	b.ReportData = r.bytes(reportDataSize)
+
+	sigData := &quoteReader{b: r.bytes(int(r.uint32()))}
+	if r.err != nil {
+		return nil, r.err
+	}
+	q.signature = sigData.bytes(signatureSize)
+	q.attestationKey = sigData.bytes(publicKeySize)
+	certType := sigData.uint16()
+	certData := &quoteReader{b: sigData.bytes(int(sigData.uint32()))}
+	if sigData.err != nil {
+		return nil, sigData.err
+	}
+	if certType != certDataTypeQEReport {
+		return nil, errUnsupportedCertificationData
+	}
+
+	q.qeReport = certData.bytes(sgxReportBodySize)
+	q.qeReportSignature = certData.bytes(signatureSize)
+	q.qeAuthData = certData.bytes(int(certData.uint16()))
+	qeCertType := certData.uint16()
+	q.pckChain = certData.bytes(int(certData.uint32()))
+	if certData.err != nil {
+		return nil, certData.err
+	}
+	if qeCertType != certDataTypePCKChain {
+		return nil, errUnsupportedQECertification
+	}
+	return q, nil
+}
+
+// verifyQuote parses a TDX quote and verifies its chain of trust: the PCK certificate chains to roots and is not
+// revoked by crls, the PCK key signed the QE report of the Intel TD quoting enclave, the QE report binds the
+// attestation key, and the attestation key signed the quote. TCB status evaluation against the TCB info and QE
+// identity collateral of the Intel PCS, beyond the identity of the quoting enclave, is left to the caller.
+func verifyQuote(data []byte, roots *x509.CertPool, crls []*x509.RevocationList, currentTime time.Time) (*quote, *x509.Certificate, error) {
+	q, err := parseQuote(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pck, intermediates, err := parsePCKChain(q.pckChain)
+	if err != nil {
+		return nil, nil, err
+	}
+	chains, err := pck.Verify(x509.VerifyOptions{
+		Intermediates: intermediates,
+		Roots:         roots,
+		CurrentTime:   currentTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := teeattestation.CheckRevocation(chains[0], crls, currentTime); err != nil {
+		return nil, nil, err
+	}
+	pckKey, ok := pck.PublicKey.(*ecdsa.PublicKey)
+	if !ok || pckKey.Curve != elliptic.P256() {
+		return nil, nil, errBadPCKPublicKey
+	}
+	if !verifyP256(pckKey, q.qeReport, q.qeReportSignature) {
+		return nil, nil, errBadQEReportSignature
+	}
+	if !bytes.Equal(q.qeReport[qeMRSignerOffset:qeMRSignerOffset+sha256.Size], qeMRSigner) ||
+		binary.LittleEndian.Uint16(q.qeReport[qeISVProdIDOffset:]) != qeISVProdIDTD {
+		return nil, nil, errBadQEIdentity
+	}
+
+	// The QE binds the attestation key by SHA256(attestation key || QE auth data) in its REPORTDATA, zero padded.
+	binding := sha256.Sum256(append(bytes.Clone(q.attestationKey), q.qeAuthData...))
+	qeReportData := q.qeReport[qeReportDataOffset : qeReportDataOffset+reportDataSize]
+	if !bytes.Equal(qeReportData[:sha256.Size], binding[:]) || !bytes.Equal(qeReportData[sha256.Size:], make([]byte, reportDataSize-sha256.Size)) {
+		return nil, nil, errBadQEReportData
+	}
+
+	attestationKey, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), append([]byte{4}, q.attestationKey...))
+	if err != nil {
+		return nil, nil, errBadAttestationKey
+	}
+	if !verifyP256(attestationKey, q.signed, q.signature) {
+		return nil, nil, errBadQuoteSignature
+	}
+	if q.body.TDAttributes&tdAttributesDebug != 0 {
+		return nil, nil, errDebugTD
+	}
+	return q, pck, nil
+}
+
+// parsePCKChain parses the PEM PCK certificate chain, leaf first.
+func parsePCKChain(chain []byte) (*x509.Certificate, *x509.CertPool, error) {
+	var certs []*x509.Certificate
+	for rest := bytes.TrimRight(chain, "\x00"); ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %w", errBadPCKChain, err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, nil, errBadPCKChain
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	return certs[0], intermediates, nil
+}
+
+// verifyP256 verifies the raw r || s ECDSA P-256 signature of the SHA-256 digest of data.
+func verifyP256(publicKey *ecdsa.PublicKey, data, signature []byte) bool {
+	if len(signature) != signatureSize {
+		return false
+	}
+	hash := sha256.Sum256(data)
+	r := new(big.Int).SetBytes(signature[:signatureSize/2])
+	s := new(big.Int).SetBytes(signature[signatureSize/2:])
+	return ecdsa.Verify(publicKey, hash[:], r, s)
+}
@@ -0,0 +1,205 @@
+package teeattestation
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Platform identifies the TEE platform an attestation was produced by.
+type Platform string
+
+const (
+	PlatformNitro       Platform = "nitro"
+	PlatformTDX         Platform = "tdx"
+	PlatformSEVSNP      Platform = "sev-snp"
+	PlatformPassthrough Platform = "passthrough"
+)
+
+// Measurements holds the parsed fields of a verified attestation in a platform-agnostic form. Registers are keyed by
+// the platform's own names: "PCR0".."PCR31" for Nitro, "MRTD", "RTMR0".."RTMR3" etc. for TDX, and "MEASUREMENT",
+// "HOST_DATA" etc. for SEV-SNP.
+type Measurements struct {
+	Platform Platform
+	// Registers holds every measurement register of the attestation. The trusted registers have already been checked;
+	// the others are exposed unchecked.
+	Registers map[string][]byte
+	// ReportData is the data bound into the attestation by the enclave. It has already been checked against the
+	// expected user data, which it may extend with zero padding (e.g. the 64 byte REPORTDATA of TDX and SEV-SNP).
+	ReportData []byte
+	// PublicKey is the enclave-supplied identity key, for platforms that carry one separately (nil otherwise).
+	PublicKey []byte
+	// Nonce is the attestation nonce, for platforms that carry one separately (nil otherwise). It is not checked.
+	Nonce []byte
+	// TCB is the platform TCB version the attestation was produced at, for platforms that carry one (nil otherwise):
+	// the 16 byte TEE_TCB_SVN for TDX, and the 8 byte little-endian REPORTED_TCB for SEV-SNP. It has already been
+	// checked against the minimum TCB of the trusted measurements, if any.
+	TCB []byte
+	// SigningKey is the SPKI (DER) of the platform key the attestation chains to, for platforms that carry one (nil
+	// otherwise): the PCK key for TDX, and the VCEK or VLEK key for SEV-SNP. It identifies the platform.
+	SigningKey []byte
+}
+
+// Validator validates attestations of a single TEE platform.
+type Validator interface {
+	// Platform returns the platform validated.
+	Platform() Platform
+	// Validate verifies attestation against the expected user data and the platform-specific JSON encoding of the
+	// trusted measurements, and returns the parsed measurements.
+	Validate(attestation, expectedUserData, trustedMeasurements []byte) (*Measurements, error)
+}
+
+// ErrUnknownPlatform is returned for a platform without a registered Validator.
+var ErrUnknownPlatform = errors.New("no validator registered for platform")
+
+// Registry selects a Validator by Platform.
+type Registry struct {
+	mu         sync.RWMutex
+	validators map[Platform]Validator
+}
+
+// NewRegistry returns a Registry of validators. Each platform may only be registered once.
+func NewRegistry(validators ...Validator) (*Registry, error) {
+	r := &Registry{validators: make(map[Platform]Validator)}
+	for _, v := range validators {
+		if err := r.Register(v); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Register adds v for v.Platform(). It fails if the platform already has a Validator.
+func (r *Registry) Register(v Validator) error {
+	p := v.Platform()
+	if p == "" {
+		return errors.New("validator platform is empty")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.validators[p]; ok {
+		return fmt.Errorf("validator already registered for platform %s", p)
+	}
+	r.validators[p] = v
+	return nil
+}
+
+// Get returns the Validator registered for p.
+func (r *Registry) Get(p Platform) (Validator, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	v, ok := r.validators[p]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownPlatform, p)
+	}
+	return v, nil
+}
+
+// Platforms returns the registered platforms in sorted order.
+func (r *Registry) Platforms() []Platform {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	platforms := make([]Platform, 0, len(r.validators))
+	for p := range r.validators {
+		platforms = append(platforms, p)
+	}
+	slices.Sort(platforms)
+	return platforms
+}
+
+// Validate validates attestation with the Validator registered for p.
+func (r *Registry) Validate(p Platform, attestation, expectedUserData, trustedMeasurements []byte) (*Measurements, error) {
+	v, err := r.Get(p)
+	if err != nil {
+		return nil, err
+	}
+	return v.Validate(attestation, expectedUserData, trustedMeasurements)
+}
+
+// HexBytes is a custom type that unmarshals hex strings into a byte slice
+// and marshals byte slices back to hex strings. This allows parsing trusted
+// measurements, which use hex byte strings in JSON.
+type HexBytes []byte
+
+func (h *HexBytes) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("HexBytes: cannot unmarshal JSON into string: %w", err)
+	}
+
+	decoded, err := hex.DecodeString(s)
+	if err != nil {
+		return fmt.Errorf("HexBytes: failed to decode hex string '%s': %w", s, err)
+	}
+	*h = decoded
+	return nil
+}
+
+func (h HexBytes) MarshalJSON() ([]byte, error) {
+	s := hex.EncodeToString(h)
+	return json.Marshal(s)
+}
+
+// ReportDataMatches reports whether the fixed-size reportData of a TDX or SEV-SNP report binds expectedUserData,
+// i.e. equals it followed by zero padding.
+func ReportDataMatches(reportData, expectedUserData []byte) bool {
+	if len(expectedUserData) > len(reportData) {
+		return false
+	}
+	for i, b := range reportData {
+		var want byte
+		if i < len(expectedUserData) {
+			want = expectedUserData[i]
+		}
+		if b != want {
+			return false
+		}
+	}
+	return true
+}
+
+// ErrCertificateRevoked is returned for a certificate revoked by a CRL.
+var ErrCertificateRevoked = errors.New("certificate is revoked")
+
+// CheckRevocation checks the certificates of a verified chain, leaf first, against crls. CRLs of issuers outside the
+// chain are ignored. The others must be signed by their issuer, and must not be past their next update.
+func CheckRevocation(chain []*x509.Certificate, crls []*x509.RevocationList, currentTime time.Time) error {
+	for _, crl := range crls {
+		i := slices.IndexFunc(chain, func(c *x509.Certificate) bool { return bytes.Equal(c.RawSubject, crl.RawIssuer) })
+		if i < 0 {
+			continue
+		}
+		if err := crl.CheckSignatureFrom(chain[i]); err != nil {
+			return fmt.Errorf("invalid CRL of %s: %w", crl.Issuer, err)
+		}
+		if !crl.NextUpdate.IsZero() && currentTime.After(crl.NextUpdate) {
+			return fmt.Errorf("CRL of %s expired at %s", crl.Issuer, crl.NextUpdate)
+		}
+		for _, cert := range chain[:i] {
+			if !bytes.Equal(cert.RawIssuer, crl.RawIssuer) {
+				continue
+			}
+			for _, entry := range crl.RevokedCertificateEntries {
+				if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+					return fmt.Errorf("%w: %s", ErrCertificateRevoked, cert.Subject)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ParseCARoots parses a PEM bundle of trusted root certificates.
+func ParseCARoots(caRootsPEM string) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(caRootsPEM)) {
+		return nil, errors.New("failed to parse CA roots")
+	}
+	return pool, nil
+}
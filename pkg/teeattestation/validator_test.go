@@ -0,0 +1,116 @@
+package teeattestation
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubValidator struct {
+	platform Platform
+}
+
+func (s stubValidator) Platform() Platform { return s.platform }
+
+func (s stubValidator) Validate(_, expectedUserData, _ []byte) (*Measurements, error) {
+	return &Measurements{Platform: s.platform, ReportData: expectedUserData}, nil
+}
+
+func TestRegistry(t *testing.T) {
+	r, err := NewRegistry(stubValidator{PlatformTDX}, stubValidator{PlatformNitro})
+	require.NoError(t, err)
+	assert.Equal(t, []Platform{PlatformNitro, PlatformTDX}, r.Platforms())
+
+	m, err := r.Validate(PlatformTDX, nil, []byte("data"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, PlatformTDX, m.Platform)
+
+	_, err = r.Get(PlatformSEVSNP)
+	require.ErrorIs(t, err, ErrUnknownPlatform)
+	_, err = r.Validate(PlatformSEVSNP, nil, nil, nil)
+	require.ErrorIs(t, err, ErrUnknownPlatform)
+
+	require.NoError(t, r.Register(stubValidator{PlatformSEVSNP}))
+	_, err = r.Get(PlatformSEVSNP)
+	require.NoError(t, err)
+
+	require.ErrorContains(t, r.Register(stubValidator{PlatformTDX}), "already registered")
+	require.Error(t, r.Register(stubValidator{}))
+	_, err = NewRegistry(stubValidator{PlatformTDX}, stubValidator{PlatformTDX})
+	require.Error(t, err)
+}
+
+func TestReportDataMatches(t *testing.T) {
+	reportData := make([]byte, 64)
+	copy(reportData, "data")
+	assert.True(t, ReportDataMatches(reportData, []byte("data")))
+	assert.True(t, ReportDataMatches(reportData, append([]byte("data"), 0, 0)))
+	assert.False(t, ReportDataMatches(reportData, []byte("dat")))
+	assert.False(t, ReportDataMatches(reportData, []byte("other")))
+	assert.False(t, ReportDataMatches(reportData, make([]byte, 65)))
+}
+
+func TestHexBytes(t *testing.T) {
+	var h HexBytes
+	require.NoError(t, h.UnmarshalJSON([]byte(`"0aff"`)))
+	assert.Equal(t, HexBytes{0x0a, 0xff}, h)
+	b, err := h.MarshalJSON()
+	require.NoError(t, err)
+	assert.JSONEq(t, `"0aff"`, string(b))
+	require.Error(t, h.UnmarshalJSON([]byte(`"zz"`)))
+}
+
+func TestCheckRevocation(t *testing.T) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+	leafDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "leaf"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}, ca, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	leaf, err := x509.ParseCertificate(leafDER)
+	require.NoError(t, err)
+	chain := []*x509.Certificate{leaf, ca}
+
+	crl := func(nextUpdate time.Time, revoked ...*big.Int) *x509.RevocationList {
+		template := &x509.RevocationList{Number: big.NewInt(1), ThisUpdate: time.Now().Add(-time.Hour), NextUpdate: nextUpdate}
+		for _, serial := range revoked {
+			template.RevokedCertificateEntries = append(template.RevokedCertificateEntries, x509.RevocationListEntry{SerialNumber: serial, RevocationTime: time.Now()})
+		}
+		der, err := x509.CreateRevocationList(rand.Reader, template, ca, caKey)
+		require.NoError(t, err)
+		l, err := x509.ParseRevocationList(der)
+		require.NoError(t, err)
+		return l
+	}
+	now := time.Now()
+	require.NoError(t, CheckRevocation(chain, nil, now))
+	require.NoError(t, CheckRevocation(chain, []*x509.RevocationList{crl(now.Add(time.Hour), big.NewInt(3))}, now))
+	require.ErrorIs(t, CheckRevocation(chain, []*x509.RevocationList{crl(now.Add(time.Hour), big.NewInt(2))}, now), ErrCertificateRevoked)
+	require.ErrorContains(t, CheckRevocation(chain, []*x509.RevocationList{crl(now.Add(-time.Minute))}, now), "expired")
+	require.NoError(t, CheckRevocation(chain[:1], []*x509.RevocationList{crl(now.Add(time.Hour), big.NewInt(2))}, now),
+		"CRLs of issuers outside the chain are ignored")
+}
@@ -2,6 +2,7 @@ package dontime
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"google.golang.org/protobuf/proto"
@@ -21,6 +22,7 @@ const (
 	defaultBatchSize            = 10000
 	defaultExecutionRemovalTime = 20 * time.Minute // 2x CRE workflow time limit
 	defaultMinTimeIncrease      = time.Millisecond
+	closePersistTimeout         = 5 * time.Second
 )
 
 var _ core.OCR3ReportingPluginFactory = &Factory{}
@@ -29,13 +31,17 @@ type Factory struct {
 	store *Store
 	lggr  logger.Logger
 
+	stopCh services.StopChan
+	wg     sync.WaitGroup
+
 	services.StateMachine
 }
 
 func NewFactory(s *Store, lggr logger.Logger) (*Factory, error) {
 	return &Factory{
-		store: s,
-		lggr:  logger.Named(lggr, "OCR3DonTimeFactory"),
+		store:  s,
+		lggr:   logger.Named(lggr, "OCR3DonTimeFactory"),
+		stopCh: make(services.StopChan),
 	}, nil
 }
 
@@ -100,16 +106,48 @@ func (o *Factory) NewReportingPlugin(_ context.Context, config ocr3types.Reporti
 
 func (o *Factory) Start(ctx context.Context) error {
 	return o.StartOnce("DonTimePlugin", func() error {
+		if o.store.stateStore != nil {
+			o.wg.Add(1)
+			go o.persistLoop()
+		}
 		return nil
 	})
 }
 
 func (o *Factory) Close() error {
 	return o.StopOnce("DonTimePlugin", func() error {
+		close(o.stopCh)
+		o.wg.Wait()
 		return nil
 	})
 }
 
+// persistLoop saves the changes of a persistent Store every DefaultPersistInterval, and once more when stopped. It only
+// runs between Start and Close, so changes made while the Factory is not started are saved once it is.
+// A failure to save is not fatal: the changes are saved by the next attempt.
+func (o *Factory) persistLoop() {
+	defer o.wg.Done()
+	ticker := time.NewTicker(DefaultPersistInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-o.stopCh:
+			ctx, cancel := context.WithTimeout(context.Background(), closePersistTimeout)
+			defer cancel()
+			if err := o.store.persist(ctx); err != nil {
+				o.lggr.Errorw("Failed to persist DON time state on close", "err", err)
+			}
+			return
+		case <-ticker.C:
+			ctx, cancel := o.stopCh.CtxWithTimeout(DefaultPersistInterval)
+			if err := o.store.persist(ctx); err != nil {
+				o.lggr.Errorw("Failed to persist DON time state", "err", err)
+			}
+			cancel()
+		}
+	}
+}
+
 func (o *Factory) Name() string { return o.lggr.Name() }
 
 func (o *Factory) HealthReport() map[string]error {
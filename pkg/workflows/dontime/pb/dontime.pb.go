@@ -26,8 +26,10 @@ type Observation struct {
 	Timestamp            int64                  `protobuf:"varint,1,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
 	Requests             map[string]int64       `protobuf:"bytes,2,rep,name=requests,proto3" json:"requests,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
 	LimitByBatchSizeFlag bool                   `protobuf:"varint,4,opt,name=limit_by_batch_size_flag,json=limitByBatchSizeFlag,proto3" json:"limit_by_batch_size_flag,omitempty"`
-	unknownFields        protoimpl.UnknownFields
-	sizeCache            protoimpl.SizeCache
+	// The latest DON time the node has seen finalised, which the outcome's DON time must not precede.
+	LastFinalizedTimestamp int64 `protobuf:"varint,5,opt,name=last_finalized_timestamp,json=lastFinalizedTimestamp,proto3" json:"last_finalized_timestamp,omitempty"`
+	unknownFields          protoimpl.UnknownFields
+	sizeCache              protoimpl.SizeCache
 }
 
 func (x *Observation) Reset() {
@@ -81,6 +83,13 @@ func (x *Observation) GetLimitByBatchSizeFlag() bool {
 	return false
 }
 
+func (x *Observation) GetLastFinalizedTimestamp() int64 {
+	if x != nil {
+		return x.LastFinalizedTimestamp
+	}
+	return 0
+}
+
 type ObservedDonTimes struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Timestamps    []int64                `protobuf:"varint,1,rep,packed,name=timestamps,proto3" json:"timestamps,omitempty"`
@@ -181,11 +190,12 @@ var File_dontime_proto protoreflect.FileDescriptor
 
 const file_dontime_proto_rawDesc = "" +
 	"\n" +
-	"\rdontime.proto\"\xde\x01\n" +
+	"\rdontime.proto\"\x98\x02\n" +
 	"\vObservation\x12\x1c\n" +
 	"\ttimestamp\x18\x01 \x01(\x03R\ttimestamp\x126\n" +
 	"\brequests\x18\x02 \x03(\v2\x1a.Observation.RequestsEntryR\brequests\x126\n" +
-	"\x18limit_by_batch_size_flag\x18\x04 \x01(\bR\x14limitByBatchSizeFlag\x1a;\n" +
+	"\x18limit_by_batch_size_flag\x18\x04 \x01(\bR\x14limitByBatchSizeFlag\x128\n" +
+	"\x18last_finalized_timestamp\x18\x05 \x01(\x03R\x16lastFinalizedTimestamp\x1a;\n" +
 	"\rRequestsEntry\x12\x10\n" +
 	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
 	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01J\x04\b\x03\x10\x04\"2\n" +
@@ -0,0 +1,182 @@
+// Package pgstore provides a Postgres backed [dontime.StateStore], so that DON time requests and observed DON times
+// survive a restart of the node.
+package pgstore
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/sqlutil"
+	"github.com/smartcontractkit/chainlink-common/pkg/workflows/dontime"
+)
+
+// DefaultSchema is the Postgres schema of the tables used by a Store, unless configured otherwise.
+const DefaultSchema = "cre"
+
+// Config holds optional configuration for a Store.
+type Config struct {
+	// Schema is the Postgres schema containing the tables. Defaults to DefaultSchema.
+	Schema string
+}
+
+func (c Config) tables() (requests, donTimes, lastObserved string) {
+	schema := c.Schema
+	if schema == "" {
+		schema = DefaultSchema
+	}
+	schema = pq.QuoteIdentifier(schema)
+	return schema + ".dontime_requests", schema + ".dontime_don_times", schema + ".dontime_last_observed"
+}
+
+// TablesSQL returns the statements which create the tables used by a Store with this Config. Production databases
+// should apply them via migrations.
+func (c Config) TablesSQL() string {
+	requests, donTimes, lastObserved := c.tables()
+	schema, _, _ := strings.Cut(requests, ".")
+	return `
+CREATE SCHEMA IF NOT EXISTS ` + schema + `;
+CREATE TABLE IF NOT EXISTS ` + requests + ` (
+	workflow_execution_id TEXT PRIMARY KEY,
+	seq_num BIGINT NOT NULL,
+	expires_at_ms BIGINT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS ` + donTimes + ` (
+	workflow_execution_id TEXT NOT NULL,
+	seq_num BIGINT NOT NULL,
+	don_time BIGINT NOT NULL,
+	PRIMARY KEY (workflow_execution_id, seq_num)
+);
+CREATE TABLE IF NOT EXISTS ` + lastObserved + ` (
+	id INTEGER PRIMARY KEY,
+	don_time BIGINT NOT NULL
+);`
+}
+
+// Schema creates the tables used by a Store in DefaultSchema. Production databases should apply it via migrations.
+var Schema = Config{}.TablesSQL()
+
+var _ dontime.StateStore = (*Store)(nil)
+
+// Store is a Postgres backed dontime.StateStore. Each DON time of an execution is stored as a row, keyed by its
+// sequence number. The state is that of a single node: nodes of a DON must not share their tables.
+type Store struct {
+	ds sqlutil.DataSource
+
+	requests, donTimes, lastObserved string
+}
+
+// New returns a new Store backed by ds, with tables in DefaultSchema. The tables from Schema must already exist.
+func New(ds sqlutil.DataSource) *Store {
+	return Config{}.New(ds)
+}
+
+// New returns a new Store backed by ds. The tables from TablesSQL must already exist.
+func (c Config) New(ds sqlutil.DataSource) *Store {
+	s := &Store{ds: ds}
+	s.requests, s.donTimes, s.lastObserved = c.tables()
+	return s
+}
+
+type requestRow struct {
+	WorkflowExecutionID string `db:"workflow_execution_id"`
+	SeqNum              int64  `db:"seq_num"`
+	ExpiresAtMs         int64  `db:"expires_at_ms"`
+}
+
+type donTimeRow struct {
+	WorkflowExecutionID string `db:"workflow_execution_id"`
+	SeqNum              int64  `db:"seq_num"`
+	DonTime             int64  `db:"don_time"`
+}
+
+func (s *Store) Load(ctx context.Context) (dontime.State, error) {
+	var (
+		qRequests     = `SELECT workflow_execution_id, seq_num, expires_at_ms FROM ` + s.requests
+		qDonTimes     = `SELECT workflow_execution_id, seq_num, don_time FROM ` + s.donTimes + ` ORDER BY workflow_execution_id, seq_num`
+		qLastObserved = `SELECT COALESCE(MAX(don_time), 0) FROM ` + s.lastObserved
+	)
+	state := dontime.State{DonTimes: make(map[string][]int64)}
+
+	var requests []requestRow
+	if err := s.ds.SelectContext(ctx, &requests, qRequests); err != nil {
+		return dontime.State{}, fmt.Errorf("failed to load DON time requests: %w", err)
+	}
+	for _, r := range requests {
+		state.Requests = append(state.Requests, dontime.PendingRequest{
+			WorkflowExecutionID: r.WorkflowExecutionID,
+			SeqNum:              int(r.SeqNum),
+			ExpiresAt:           time.UnixMilli(r.ExpiresAtMs),
+		})
+	}
+
+	var donTimes []donTimeRow
+	if err := s.ds.SelectContext(ctx, &donTimes, qDonTimes); err != nil {
+		return dontime.State{}, fmt.Errorf("failed to load DON times: %w", err)
+	}
+	for _, r := range donTimes {
+		times := state.DonTimes[r.WorkflowExecutionID]
+		if int64(len(times)) != r.SeqNum {
+			// A gap in the sequence can't be served, so only the DON times before it are restored.
+			continue
+		}
+		state.DonTimes[r.WorkflowExecutionID] = append(times, r.DonTime)
+	}
+
+	if err := s.ds.GetContext(ctx, &state.LastObservedDonTime, qLastObserved); err != nil {
+		return dontime.State{}, fmt.Errorf("failed to load last observed DON time: %w", err)
+	}
+	return state, nil
+}
+
+func (s *Store) Save(ctx context.Context, update dontime.StateUpdate) error {
+	var (
+		qDeleteRequest  = `DELETE FROM ` + s.requests + ` WHERE workflow_execution_id = $1`
+		qInsertRequest  = `INSERT INTO ` + s.requests + ` (workflow_execution_id, seq_num, expires_at_ms) VALUES ($1, $2, $3)`
+		qDeleteDonTimes = `DELETE FROM ` + s.donTimes + ` WHERE workflow_execution_id = $1`
+		qInsertDonTime  = `INSERT INTO ` + s.donTimes + ` (workflow_execution_id, seq_num, don_time) VALUES ($1, $2, $3)`
+		qLastObserved   = `INSERT INTO ` + s.lastObserved + ` AS t (id, don_time) VALUES (1, $1)
+ON CONFLICT (id) DO UPDATE SET don_time = GREATEST(t.don_time, EXCLUDED.don_time)`
+	)
+	return sqlutil.TransactDataSource(ctx, s.ds, nil, func(tx sqlutil.DataSource) error {
+		for _, executionID := range update.DeletedRequests {
+			if _, err := tx.ExecContext(ctx, qDeleteRequest, executionID); err != nil {
+				return fmt.Errorf("failed to delete request %s: %w", executionID, err)
+			}
+		}
+		for _, r := range update.SavedRequests {
+			if _, err := tx.ExecContext(ctx, qDeleteRequest, r.WorkflowExecutionID); err != nil {
+				return fmt.Errorf("failed to replace request %s: %w", r.WorkflowExecutionID, err)
+			}
+			if _, err := tx.ExecContext(ctx, qInsertRequest, r.WorkflowExecutionID, r.SeqNum, r.ExpiresAt.UnixMilli()); err != nil {
+				return fmt.Errorf("failed to insert request %s: %w", r.WorkflowExecutionID, err)
+			}
+		}
+
+		for _, executionID := range update.DeletedDonTimes {
+			if _, err := tx.ExecContext(ctx, qDeleteDonTimes, executionID); err != nil {
+				return fmt.Errorf("failed to delete DON times of %s: %w", executionID, err)
+			}
+		}
+		for _, executionID := range slices.Sorted(maps.Keys(update.SavedDonTimes)) {
+			if _, err := tx.ExecContext(ctx, qDeleteDonTimes, executionID); err != nil {
+				return fmt.Errorf("failed to replace DON times of %s: %w", executionID, err)
+			}
+			for seqNum, donTime := range update.SavedDonTimes[executionID] {
+				if _, err := tx.ExecContext(ctx, qInsertDonTime, executionID, seqNum, donTime); err != nil {
+					return fmt.Errorf("failed to insert DON time %d of %s: %w", seqNum, executionID, err)
+				}
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, qLastObserved, update.LastObservedDonTime); err != nil {
+			return fmt.Errorf("failed to update last observed DON time: %w", err)
+		}
+		return nil
+	})
+}
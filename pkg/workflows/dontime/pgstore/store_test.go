@@ -0,0 +1,67 @@
+package pgstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/sqlutil/sqltest"
+	"github.com/smartcontractkit/chainlink-common/pkg/workflows/dontime"
+)
+
+func TestStore(t *testing.T) {
+	db := sqltest.NewDB(t, sqltest.TestURL(t))
+	_, err := db.ExecContext(t.Context(), Schema)
+	require.NoError(t, err)
+	s := New(db)
+	ctx := t.Context()
+	expiresAt := time.UnixMilli(time.Now().Add(time.Minute).UnixMilli())
+
+	state, err := s.Load(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, state.Requests)
+	assert.Empty(t, state.DonTimes)
+	assert.Zero(t, state.LastObservedDonTime)
+
+	require.NoError(t, s.Save(ctx, dontime.StateUpdate{
+		SavedRequests: []dontime.PendingRequest{
+			{WorkflowExecutionID: "workflow-1", SeqNum: 1, ExpiresAt: expiresAt},
+			{WorkflowExecutionID: "workflow-2", SeqNum: 0, ExpiresAt: expiresAt},
+		},
+		SavedDonTimes: map[string][]int64{
+			"workflow-1": {100},
+			"workflow-3": {100, 200},
+		},
+		LastObservedDonTime: 200,
+	}))
+
+	require.NoError(t, s.Save(ctx, dontime.StateUpdate{
+		SavedRequests:   []dontime.PendingRequest{{WorkflowExecutionID: "workflow-1", SeqNum: 2, ExpiresAt: expiresAt}},
+		DeletedRequests: []string{"workflow-2"},
+		SavedDonTimes:   map[string][]int64{"workflow-1": {100, 300}},
+		DeletedDonTimes: []string{"workflow-3"},
+		// The last observed DON time never goes backward.
+		LastObservedDonTime: 150,
+	}))
+
+	state, err = s.Load(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []dontime.PendingRequest{{WorkflowExecutionID: "workflow-1", SeqNum: 2, ExpiresAt: expiresAt}}, state.Requests)
+	assert.Equal(t, map[string][]int64{"workflow-1": {100, 300}}, state.DonTimes)
+	assert.Equal(t, int64(200), state.LastObservedDonTime)
+}
+
+func TestConfig_Schema(t *testing.T) {
+	db := sqltest.NewDB(t, sqltest.TestURL(t))
+	cfg := Config{Schema: "dontime_test"}
+	_, err := db.ExecContext(t.Context(), cfg.TablesSQL())
+	require.NoError(t, err)
+	s := cfg.New(db)
+
+	require.NoError(t, s.Save(t.Context(), dontime.StateUpdate{LastObservedDonTime: 100}))
+	var donTime int64
+	require.NoError(t, db.GetContext(t.Context(), &donTime, `SELECT don_time FROM dontime_test.dontime_last_observed`))
+	assert.Equal(t, int64(100), donTime)
+}
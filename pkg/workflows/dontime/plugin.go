@@ -196,9 +196,10 @@ func (p *Plugin) Observation(ctx context.Context, outctx ocr3types.OutcomeContex
 	p.metrics.observationBatchOverflow.Record(ctx, int64(overflowCount))
 
 	observation := &pb.Observation{
-		Timestamp:            time.Now().UTC().UnixMilli(),
-		Requests:             requests,
-		LimitByBatchSizeFlag: true,
+		Timestamp:              time.Now().UTC().UnixMilli(),
+		Requests:               requests,
+		LimitByBatchSizeFlag:   true,
+		LastFinalizedTimestamp: p.store.GetLastObservedDonTime(),
 	}
 
 	return proto.MarshalOptions{Deterministic: true}.Marshal(observation)
@@ -220,6 +221,7 @@ func (p *Plugin) Outcome(ctx context.Context, outctx ocr3types.OutcomeContext, _
 		OffsetFromMedian int64
 	}
 	var timestampNodePairs []timestampNodePair
+	var lastFinalizedTimestamps []int64
 	limitByBatchSizeFlagEnabled := true
 
 	prevOutcome := &pb.Outcome{}
@@ -257,6 +259,7 @@ func (p *Plugin) Outcome(ctx context.Context, outctx ocr3types.OutcomeContext, _
 		}
 
 		timestampNodePairs = append(timestampNodePairs, timestampNodePair{Timestamp: observation.Timestamp, NodeID: idx})
+		lastFinalizedTimestamps = append(lastFinalizedTimestamps, observation.LastFinalizedTimestamp)
 	}
 	if len(timestampNodePairs) == 0 {
 		return nil, errors.New("no observation contains a valid timestamp")
@@ -279,10 +282,15 @@ func (p *Plugin) Outcome(ctx context.Context, outctx ocr3types.OutcomeContext, _
 
 	outcome := prevOutcome
 
-	// Compare with prior outcome to ensure DON time never goes backward.
-	if donTime < outcome.Timestamp+p.minTimeIncrease {
-		p.lggr.Infow("DON Time incremented by minimum time increase to ensure time progression", "minTimeIncrease", p.minTimeIncrease)
-		donTime = outcome.Timestamp + p.minTimeIncrease
+	// Compare with prior outcome to ensure DON time never goes backward. The prior outcome is lost when the DON
+	// restarts or is reconfigured, so the DON time must also follow the DON times finalised before.
+	previousDonTime := max(outcome.Timestamp, finalizedDonTime(lastFinalizedTimestamps, p.config.F))
+	if donTime < previousDonTime+p.minTimeIncrease {
+		p.lggr.Infow("DON Time incremented by minimum time increase to ensure time progression",
+			"minTimeIncrease", p.minTimeIncrease,
+			"previousDonTime", previousDonTime,
+		)
+		donTime = previousDonTime + p.minTimeIncrease
 	}
 
 	p.lggr.Infow("New DON Time", "donTime", donTime)
@@ -341,6 +349,16 @@ func (p *Plugin) Outcome(ctx context.Context, outctx ocr3types.OutcomeContext, _
 	return outcomeBytes, err
 }
 
+// finalizedDonTime returns the latest DON time that at least f+1 nodes have seen finalised, so that at least one
+// honest node vouches for it and faulty nodes can't push the DON time forward.
+func finalizedDonTime(lastFinalizedTimestamps []int64, f int) int64 {
+	if len(lastFinalizedTimestamps) <= f {
+		return 0
+	}
+	sorted := slices.Sorted(slices.Values(lastFinalizedTimestamps))
+	return sorted[len(sorted)-1-f]
+}
+
 func (p *Plugin) Reports(_ context.Context, _ uint64, outcome ocr3types.Outcome) ([]ocr3types.ReportPlus[[]byte], error) {
 	allOraclesTransmitNow := &ocr3types.TransmissionSchedule{
 		Transmitters:       make([]commontypes.OracleID, p.config.N),
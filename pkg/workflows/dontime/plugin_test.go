@@ -614,6 +614,61 @@ func TestPlugin_Outcome_TrimByBatchSize(t *testing.T) {
 	})
 }
 
+func TestPlugin_Outcome_FollowsFinalizedDonTime(t *testing.T) {
+	lggr := logger.Test(t)
+	store := NewStore(DefaultRequestTimeout)
+	config, offchainCfg := newTestPluginConfig(t), newTestPluginOffchainConfig(t)
+	ctx := t.Context()
+
+	plugin, err := NewPlugin(store, config, offchainCfg, lggr)
+	require.NoError(t, err)
+
+	query, err := plugin.Query(ctx, ocr3types.OutcomeContext{PreviousOutcome: []byte("")})
+	require.NoError(t, err)
+
+	timestamp := time.Now().UnixMilli()
+	outcomeFor := func(lastFinalized ...int64) *pb.Outcome {
+		aos := make([]types.AttributedObservation, len(lastFinalized))
+		for i, finalized := range lastFinalized {
+			rawObs, err := proto.Marshal(&pb.Observation{
+				Timestamp:              timestamp,
+				Requests:               map[string]int64{},
+				LastFinalizedTimestamp: finalized,
+			})
+			require.NoError(t, err)
+			aos[i] = types.AttributedObservation{Observation: rawObs, Observer: commontypes.OracleID(i)}
+		}
+
+		// The previous outcome was lost, e.g. to a restart of the DON.
+		outcome, err := plugin.Outcome(ctx, ocr3types.OutcomeContext{PreviousOutcome: []byte("")}, query, aos)
+		require.NoError(t, err)
+		outcomeProto := &pb.Outcome{}
+		require.NoError(t, proto.Unmarshal(outcome, outcomeProto))
+		return outcomeProto
+	}
+
+	t.Run("does not precede a DON time finalised by f+1 nodes", func(t *testing.T) {
+		finalized := timestamp + time.Minute.Milliseconds()
+		outcome := outcomeFor(0, finalized, finalized, finalized+1000)
+		require.Equal(t, finalized+defaultMinTimeIncrease.Milliseconds(), outcome.Timestamp)
+	})
+
+	t.Run("ignores a DON time only f nodes finalised", func(t *testing.T) {
+		outcome := outcomeFor(0, 0, 0, timestamp+time.Hour.Milliseconds())
+		require.Equal(t, timestamp, outcome.Timestamp)
+	})
+
+	t.Run("observes the store's last observed DON time", func(t *testing.T) {
+		store.setLastObservedDonTime(timestamp)
+		observation, err := plugin.Observation(ctx, ocr3types.OutcomeContext{PreviousOutcome: []byte("")}, query)
+		require.NoError(t, err)
+
+		obsProto := &pb.Observation{}
+		require.NoError(t, proto.Unmarshal(observation, obsProto))
+		require.Equal(t, timestamp, obsProto.LastFinalizedTimestamp)
+	})
+}
+
 func TestPlugin_ExpiredRequest(t *testing.T) {
 	lggr := logger.Test(t)
 	store := NewStore(0)
@@ -18,6 +18,8 @@ type Request struct {
 
 	expiryTimer *time.Timer
 	respondOnce sync.Once
+	// restored is set on requests restored from a StateStore, which no execution is waiting on yet.
+	restored bool
 }
 
 func (r *Request) ID() string {
@@ -0,0 +1,42 @@
+package dontime
+
+import (
+	"context"
+	"time"
+)
+
+// StateStore persists the state of a Store, so that pending requests and observed DON times survive a restart of
+// the node. See pgstore for a Postgres backed implementation.
+type StateStore interface {
+	// Load returns the persisted state.
+	Load(ctx context.Context) (State, error)
+	// Save applies update to the persisted state.
+	Save(ctx context.Context, update StateUpdate) error
+}
+
+// State is the persisted state of a Store.
+type State struct {
+	Requests []PendingRequest
+	// DonTimes maps workflow execution IDs to their DON times, in sequence order.
+	DonTimes            map[string][]int64
+	LastObservedDonTime int64
+}
+
+// PendingRequest is a persisted DON time request.
+type PendingRequest struct {
+	WorkflowExecutionID string
+	SeqNum              int
+	ExpiresAt           time.Time
+}
+
+// StateUpdate holds the changes made to a Store since its state was last saved.
+type StateUpdate struct {
+	// SavedRequests replace the persisted requests of their executions.
+	SavedRequests   []PendingRequest
+	DeletedRequests []string
+	// SavedDonTimes replace the persisted DON times of their executions.
+	SavedDonTimes   map[string][]int64
+	DeletedDonTimes []string
+	// LastObservedDonTime never decreases.
+	LastObservedDonTime int64
+}
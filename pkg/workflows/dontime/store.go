@@ -1,9 +1,11 @@
 package dontime
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"maps"
+	"slices"
 	"sync"
 	"time"
 )
@@ -15,6 +17,10 @@ import (
 // much faster when waiting on the response channel.
 var DefaultRequestTimeout = 10 * time.Minute
 
+// DefaultPersistInterval is how often the Factory saves the changes of a persistent Store. Changes made between saves
+// are batched into a single StateStore.Save.
+var DefaultPersistInterval = time.Second
+
 type Store struct {
 	requests       map[string]*Request // Maps workflow execution ID to request
 	requestTimeout time.Duration
@@ -24,6 +30,14 @@ type Store struct {
 	donTimes            map[string][]int64
 	lastObservedDonTime int64
 	mu                  sync.Mutex
+
+	// stateStore, if set, persists the requests and DON times whose execution IDs are marked dirty.
+	stateStore    StateStore
+	dirtyRequests map[string]struct{}
+	dirtyDonTimes map[string]struct{}
+	// savedLastObservedDonTime is the last observed DON time of the last successful save.
+	savedLastObservedDonTime int64
+	persistMu                sync.Mutex
 }
 
 func NewStore(requestTimeout time.Duration) *Store {
@@ -36,6 +50,52 @@ func NewStore(requestTimeout time.Duration) *Store {
 	}
 }
 
+// NewPersistentStore returns a Store that persists its requests and DON times to stateStore, restoring those saved
+// before a restart. Restored requests are observed by the plugin until they expire, and are taken over by the
+// execution requesting DON time again.
+//
+// Changes are saved in the background by the Factory every DefaultPersistInterval, and when it is closed, so that
+// reports are never delayed by the StateStore. Nothing is saved unless the Store is passed to NewFactory and the Factory
+// is started, and nothing is saved after it is closed. Changes made since the last save, such as requests made between
+// reports, are lost if the node crashes; their executions request DON time again when they resume.
+//
+// The state is local to the node and is not replicated: every node of the DON persists its own Store, and a node
+// whose state is lost catches up on the DON times from the next outcome.
+func NewPersistentStore(ctx context.Context, requestTimeout time.Duration, stateStore StateStore) (*Store, error) {
+	state, err := stateStore.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load DON time state: %w", err)
+	}
+
+	s := NewStore(requestTimeout)
+	s.stateStore = stateStore
+	s.dirtyRequests = make(map[string]struct{})
+	s.dirtyDonTimes = make(map[string]struct{})
+	s.lastObservedDonTime = state.LastObservedDonTime
+	s.savedLastObservedDonTime = state.LastObservedDonTime
+	maps.Copy(s.donTimes, state.DonTimes)
+
+	now := time.Now()
+	for _, pending := range state.Requests {
+		executionID := pending.WorkflowExecutionID
+		if !pending.ExpiresAt.After(now) {
+			s.dirtyRequests[executionID] = struct{}{}
+			continue
+		}
+		s.requests[executionID] = &Request{
+			ExpiresAt:           pending.ExpiresAt,
+			CallbackCh:          make(chan Response, 1),
+			WorkflowExecutionID: executionID,
+			SeqNum:              pending.SeqNum,
+			restored:            true,
+			expiryTimer: time.AfterFunc(time.Until(pending.ExpiresAt), func() {
+				s.expireRequest(executionID)
+			}),
+		}
+	}
+	return s, nil
+}
+
 func (s *Store) GetRequest(executionID string) *Request {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -68,7 +128,10 @@ func (s *Store) RequestDonTime(executionID string, seqNum int) <-chan Response {
 	// Submit request and return channel
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if _, alreadyExists := s.requests[executionID]; alreadyExists {
+	if existing, alreadyExists := s.requests[executionID]; alreadyExists && existing.restored {
+		// The execution resumed after a restart, and takes over the request restored for it.
+		s.removeRequestLocked(executionID)
+	} else if alreadyExists {
 		ch <- Response{
 			WorkflowExecutionID: executionID,
 			SeqNum:              seqNum,
@@ -91,6 +154,7 @@ func (s *Store) RequestDonTime(executionID string, seqNum int) <-chan Response {
 			s.expireRequest(executionID)
 		}),
 	}
+	s.markRequestDirtyLocked(executionID)
 	return ch
 }
 
@@ -111,6 +175,7 @@ func (s *Store) removeRequestLocked(executionID string) *Request {
 	}
 	delete(s.requests, executionID)
 	req.stopExpiry()
+	s.markRequestDirtyLocked(executionID)
 	return req
 }
 
@@ -145,17 +210,24 @@ func (s *Store) setDonTimes(executionID string, donTimes []int64) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.donTimes[executionID] = donTimes
+	s.markDonTimesDirtyLocked(executionID)
 }
 
 func (s *Store) replaceDonTimes(donTimes map[string][]int64) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	for executionID, times := range donTimes {
+		if !slices.Equal(s.donTimes[executionID], times) {
+			s.markDonTimesDirtyLocked(executionID)
+		}
+	}
 	maps.Copy(s.donTimes, donTimes)
 
 	for executionID := range s.donTimes {
 		if _, ok := donTimes[executionID]; !ok {
 			delete(s.donTimes, executionID)
+			s.markDonTimesDirtyLocked(executionID)
 			s.removeRequestLocked(executionID)
 		}
 	}
@@ -167,18 +239,84 @@ func (s *Store) GetLastObservedDonTime() int64 {
 	return s.lastObservedDonTime
 }
 
+// setLastObservedDonTime records a finalised DON time. The last observed DON time never goes backward, so that the
+// plugin never observes a DON time earlier than one finalised before.
 func (s *Store) setLastObservedDonTime(observedDonTime int64) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.lastObservedDonTime = observedDonTime
+	s.lastObservedDonTime = max(s.lastObservedDonTime, observedDonTime)
 }
 
 func (s *Store) deleteExecutionID(executionID string) {
 	s.mu.Lock()
 	req := s.removeRequestLocked(executionID)
 	delete(s.donTimes, executionID)
+	s.markDonTimesDirtyLocked(executionID)
 	s.mu.Unlock()
 	if req != nil {
 		req.SendResponse(Response{WorkflowExecutionID: executionID, Err: errors.New("execution removed from consensus outcome")})
 	}
 }
+
+func (s *Store) markRequestDirtyLocked(executionID string) {
+	if s.stateStore != nil {
+		s.dirtyRequests[executionID] = struct{}{}
+	}
+}
+
+func (s *Store) markDonTimesDirtyLocked(executionID string) {
+	if s.stateStore != nil {
+		s.dirtyDonTimes[executionID] = struct{}{}
+	}
+}
+
+// persist saves the changes made since it was last called to the StateStore, if any. Changes that fail to save are
+// saved by the next call.
+func (s *Store) persist(ctx context.Context) error {
+	if s.stateStore == nil {
+		return nil
+	}
+	s.persistMu.Lock()
+	defer s.persistMu.Unlock()
+
+	s.mu.Lock()
+	if len(s.dirtyRequests) == 0 && len(s.dirtyDonTimes) == 0 && s.lastObservedDonTime == s.savedLastObservedDonTime {
+		s.mu.Unlock()
+		return nil
+	}
+	update := StateUpdate{
+		SavedDonTimes:       make(map[string][]int64),
+		LastObservedDonTime: s.lastObservedDonTime,
+	}
+	for _, executionID := range slices.Sorted(maps.Keys(s.dirtyRequests)) {
+		if req, ok := s.requests[executionID]; ok {
+			update.SavedRequests = append(update.SavedRequests, PendingRequest{
+				WorkflowExecutionID: executionID,
+				SeqNum:              req.SeqNum,
+				ExpiresAt:           req.ExpiresAt,
+			})
+		} else {
+			update.DeletedRequests = append(update.DeletedRequests, executionID)
+		}
+	}
+	for _, executionID := range slices.Sorted(maps.Keys(s.dirtyDonTimes)) {
+		if times, ok := s.donTimes[executionID]; ok {
+			update.SavedDonTimes[executionID] = slices.Clone(times)
+		} else {
+			update.DeletedDonTimes = append(update.DeletedDonTimes, executionID)
+		}
+	}
+	dirtyRequests, dirtyDonTimes := s.dirtyRequests, s.dirtyDonTimes
+	s.dirtyRequests, s.dirtyDonTimes = make(map[string]struct{}), make(map[string]struct{})
+	s.mu.Unlock()
+
+	if err := s.stateStore.Save(ctx, update); err != nil {
+		s.mu.Lock()
+		maps.Copy(s.dirtyRequests, dirtyRequests)
+		maps.Copy(s.dirtyDonTimes, dirtyDonTimes)
+		s.mu.Unlock()
+		return fmt.Errorf("failed to save DON time state: %w", err)
+	}
+	s.savedLastObservedDonTime = update.LastObservedDonTime
+	return nil
+}
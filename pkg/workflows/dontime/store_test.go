@@ -1,10 +1,18 @@
 package dontime
 
 import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
 )
 
 func TestStore_RequestExpiresWithoutPlugin(t *testing.T) {
@@ -24,3 +32,150 @@ func TestStore_RequestExpiresWithoutPlugin(t *testing.T) {
 
 	require.Nil(t, store.GetRequest(executionID))
 }
+
+// memStateStore is an in-memory StateStore.
+type memStateStore struct {
+	mu      sync.Mutex
+	state   State
+	saveErr error
+	saves   int
+}
+
+func (m *memStateStore) Load(context.Context) (State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return State{
+		Requests:            slices.Clone(m.state.Requests),
+		DonTimes:            maps.Clone(m.state.DonTimes),
+		LastObservedDonTime: m.state.LastObservedDonTime,
+	}, nil
+}
+
+func (m *memStateStore) Save(_ context.Context, update StateUpdate) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.saveErr != nil {
+		return m.saveErr
+	}
+	m.saves++
+	if m.state.DonTimes == nil {
+		m.state.DonTimes = make(map[string][]int64)
+	}
+	for _, executionID := range update.DeletedRequests {
+		m.state.Requests = slices.DeleteFunc(m.state.Requests, func(r PendingRequest) bool { return r.WorkflowExecutionID == executionID })
+	}
+	for _, req := range update.SavedRequests {
+		m.state.Requests = slices.DeleteFunc(m.state.Requests, func(r PendingRequest) bool { return r.WorkflowExecutionID == req.WorkflowExecutionID })
+		m.state.Requests = append(m.state.Requests, req)
+	}
+	for _, executionID := range update.DeletedDonTimes {
+		delete(m.state.DonTimes, executionID)
+	}
+	maps.Copy(m.state.DonTimes, update.SavedDonTimes)
+	m.state.LastObservedDonTime = max(m.state.LastObservedDonTime, update.LastObservedDonTime)
+	return nil
+}
+
+func TestStore_Persistence(t *testing.T) {
+	ctx := t.Context()
+	stateStore := &memStateStore{}
+	store, err := NewPersistentStore(ctx, DefaultRequestTimeout, stateStore)
+	require.NoError(t, err)
+
+	_ = store.RequestDonTime("workflow-1", 1)
+	_ = store.RequestDonTime("workflow-2", 0)
+	store.RemoveRequest("workflow-2")
+	store.replaceDonTimes(map[string][]int64{"workflow-1": {100}, "workflow-3": {100, 200}})
+	store.setLastObservedDonTime(200)
+	require.NoError(t, store.persist(ctx))
+
+	require.Len(t, stateStore.state.Requests, 1)
+	assert.Equal(t, "workflow-1", stateStore.state.Requests[0].WorkflowExecutionID)
+	assert.Equal(t, 1, stateStore.state.Requests[0].SeqNum)
+	assert.Equal(t, map[string][]int64{"workflow-1": {100}, "workflow-3": {100, 200}}, stateStore.state.DonTimes)
+
+	t.Run("retries changes that failed to save", func(t *testing.T) {
+		stateStore.saveErr = errors.New("unavailable")
+		store.deleteExecutionID("workflow-3")
+		require.Error(t, store.persist(ctx))
+		assert.Contains(t, stateStore.state.DonTimes, "workflow-3")
+
+		stateStore.saveErr = nil
+		require.NoError(t, store.persist(ctx))
+		assert.NotContains(t, stateStore.state.DonTimes, "workflow-3")
+	})
+
+	t.Run("restores the state after a restart", func(t *testing.T) {
+		restarted, err := NewPersistentStore(ctx, DefaultRequestTimeout, stateStore)
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(200), restarted.GetLastObservedDonTime())
+		assert.Equal(t, int64(100), *restarted.GetDonTimeForSeqNum("workflow-1", 0))
+		restored := restarted.GetRequest("workflow-1")
+		require.NotNil(t, restored)
+		assert.Equal(t, 1, restored.SeqNum)
+
+		// The resumed execution takes over the restored request.
+		ch := restarted.RequestDonTime("workflow-1", 1)
+		require.NotSame(t, restored, restarted.GetRequest("workflow-1"))
+		restarted.GetRequest("workflow-1").SendResponse(Response{WorkflowExecutionID: "workflow-1", SeqNum: 1, Timestamp: 300})
+		resp := <-ch
+		require.NoError(t, resp.Err)
+		assert.Equal(t, int64(300), resp.Timestamp)
+	})
+
+	t.Run("drops expired requests", func(t *testing.T) {
+		stateStore.state.Requests[0].ExpiresAt = time.Now()
+		restarted, err := NewPersistentStore(ctx, DefaultRequestTimeout, stateStore)
+		require.NoError(t, err)
+
+		assert.Nil(t, restarted.GetRequest("workflow-1"))
+		require.NoError(t, restarted.persist(ctx))
+		assert.Empty(t, stateStore.state.Requests)
+	})
+}
+
+func TestStore_PersistSkipsUnchangedState(t *testing.T) {
+	ctx := t.Context()
+	stateStore := &memStateStore{}
+	store, err := NewPersistentStore(ctx, DefaultRequestTimeout, stateStore)
+	require.NoError(t, err)
+
+	require.NoError(t, store.persist(ctx))
+	assert.Zero(t, stateStore.saves)
+
+	_ = store.RequestDonTime("workflow-1", 0)
+	store.setLastObservedDonTime(100)
+	require.NoError(t, store.persist(ctx))
+	require.NoError(t, store.persist(ctx))
+	assert.Equal(t, 1, stateStore.saves)
+}
+
+func TestFactory_PersistsStoreInBackground(t *testing.T) {
+	ctx := t.Context()
+	stateStore := &memStateStore{}
+	store, err := NewPersistentStore(ctx, DefaultRequestTimeout, stateStore)
+	require.NoError(t, err)
+	factory, err := NewFactory(store, logger.Test(t))
+	require.NoError(t, err)
+	require.NoError(t, factory.Start(ctx))
+
+	_ = store.RequestDonTime("workflow-1", 0)
+	require.Eventually(t, func() bool {
+		stateStore.mu.Lock()
+		defer stateStore.mu.Unlock()
+		return len(stateStore.state.Requests) == 1
+	}, 5*DefaultPersistInterval, 10*time.Millisecond)
+
+	// Changes made since the last save are saved on close.
+	store.setLastObservedDonTime(100)
+	require.NoError(t, factory.Close())
+	assert.Equal(t, int64(100), stateStore.state.LastObservedDonTime)
+}
+
+func TestStore_LastObservedDonTimeIsMonotonic(t *testing.T) {
+	store := NewStore(DefaultRequestTimeout)
+	store.setLastObservedDonTime(200)
+	store.setLastObservedDonTime(100)
+	assert.Equal(t, int64(200), store.GetLastObservedDonTime())
+}
@@ -37,6 +37,7 @@ type execution[T any] struct {
 	nodeSeed             int64
 	donLogCount          uint32
 	nodeLogCount         uint32
+	profiler             *executionProfiler
 
 	usedCallbackIDs map[string]bool
 }
@@ -64,10 +65,13 @@ func (e *execution[T]) callCapAsync(ctx context.Context, req *sdkpb.CapabilityRe
 	ch := make(chan *sdkpb.CapabilityResponse, 1)
 	e.capabilityResponses[req.CallbackId] = ch
 
+	profiler := e.profiler
 	go func() {
 		defer free()
 
+		start := time.Now()
 		resp, err := e.executor.CallCapability(ctx, req)
+		profiler.recordCapabilityCall(req.Id, time.Since(start))
 
 		if err != nil {
 			errString := err.Error()
@@ -142,10 +146,13 @@ func (e *execution[T]) getSecretsAsync(ctx context.Context, req *sdkpb.GetSecret
 	ch := make(chan *secretsResponse, 1)
 	e.secretsResponses[req.CallbackId] = ch
 
+	profiler := e.profiler
 	go func() {
 		defer free()
 
+		start := time.Now()
 		resp, err := e.executor.GetSecrets(ctx, req)
+		profiler.recordGetSecrets(time.Since(start))
 		sr := &secretsResponse{responses: resp, err: err}
 
 		select {
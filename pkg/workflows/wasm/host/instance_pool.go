@@ -0,0 +1,167 @@
+package host
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bytecodealliance/wasmtime-go/v48"
+)
+
+// pooledInstance is a linked and instantiated module whose _start has not run yet. Its host functions are bound to
+// exec, which runWasm fills in for the execution that takes the instance.
+type pooledInstance[O any] struct {
+	store    *wasmtime.Store
+	instance *wasmtime.Instance
+	exec     *execution[O]
+}
+
+// memorySize returns the size of the instance's linear memory, in bytes.
+func (p *pooledInstance[O]) memorySize() int64 {
+	return int64(p.instance.GetExport(p.store, memoryExportName).Memory().DataSize(p.store)) //nolint:gosec // bounded by the memory limit
+}
+
+// instancePool keeps up to size pre-instantiated instances of a module ready while the module is started, so that
+// an execution only has to bind its request, limits and seeds to the store before calling _start.
+//
+// A guest can't be rewound once its _start ran, so the snapshot an instance is reset to is a fresh instantiation:
+// every execution consumes its instance and the pool instantiates a replacement in the background. The memory limit
+// is only known in the context of an execution, so the pool starts filling after the first execution and
+// instantiates under the most recent execution's limit. Instances whose memory exceeds the limit of the execution
+// taking them are discarded.
+type instancePool[O any] struct {
+	m     *module
+	link  linkFn[O]
+	ready chan *pooledInstance[O]
+
+	memoryLimit atomic.Int64
+	limitKnown  chan struct{}
+	limitOnce   sync.Once
+
+	mu     sync.Mutex
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// newInstancePool returns a pool of size instances linked with link, or nil if size is not positive.
+func newInstancePool[O any](m *module, link linkFn[O], size int) *instancePool[O] {
+	if size <= 0 {
+		return nil
+	}
+	return &instancePool[O]{
+		m:          m,
+		link:       link,
+		ready:      make(chan *pooledInstance[O], size),
+		limitKnown: make(chan struct{}),
+	}
+}
+
+// start starts instantiating instances in the background.
+func (p *instancePool[O]) start() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopCh != nil {
+		return
+	}
+	stopCh := make(chan struct{})
+	p.stopCh = stopCh
+	p.wg.Go(func() { p.fill(stopCh) })
+}
+
+// stop stops instantiating instances and closes the ready ones.
+func (p *instancePool[O]) stop() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopCh == nil {
+		return
+	}
+	close(p.stopCh)
+	p.stopCh = nil
+	p.wg.Wait()
+	for {
+		select {
+		case inst := <-p.ready:
+			inst.store.Close()
+		default:
+			return
+		}
+	}
+}
+
+// get returns a ready instance whose memory fits in memoryLimit, or nil if there is none and the caller must
+// instantiate the module itself.
+func (p *instancePool[O]) get(memoryLimit int64) *pooledInstance[O] {
+	if p == nil {
+		return nil
+	}
+	p.memoryLimit.Store(memoryLimit)
+	p.limitOnce.Do(func() { close(p.limitKnown) })
+
+	select {
+	case inst := <-p.ready:
+		if inst.memorySize() <= memoryLimit {
+			p.m.metrics.IncInstancePoolHit()
+			return inst
+		}
+		inst.store.Close()
+	default:
+	}
+	p.m.metrics.IncInstancePoolMiss()
+	return nil
+}
+
+func (p *instancePool[O]) fill(stopCh chan struct{}) {
+	select {
+	case <-stopCh:
+		return
+	case <-p.limitKnown:
+	}
+
+	for {
+		inst, err := p.instantiate()
+		if err != nil {
+			// Executions instantiate the module themselves, and report the error if it persists.
+			p.m.cfg.Logger.Errorw("failed to pre-instantiate module, disabling instance pool", "err", err)
+			return
+		}
+
+		select {
+		case <-stopCh:
+			inst.store.Close()
+			return
+		case p.ready <- inst:
+		}
+	}
+}
+
+// instantiate links and instantiates the module in a new store, with the fuel, memory and epoch limits of an
+// execution in case the module runs code on instantiation.
+func (p *instancePool[O]) instantiate() (inst *pooledInstance[O], err error) {
+	store := wasmtime.NewStore(p.m.engine)
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic during wasm instantiation: %v", r)
+		}
+		if err != nil {
+			store.Close()
+		}
+	}()
+
+	if err = configureStore(store, p.m.cfg, p.memoryLimit.Load(), *p.m.cfg.Timeout); err != nil {
+		return nil, err
+	}
+
+	exec := &execution[O]{module: p.m}
+	instance, err := p.link(context.Background(), p.m, store, exec)
+	if err != nil {
+		return nil, fmt.Errorf("error linking wasm: %w", err)
+	}
+	return &pooledInstance[O]{store: store, instance: instance, exec: exec}, nil
+}
@@ -15,7 +15,7 @@ import (
 	"os"
 	"regexp"
 	"strings"
-	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/andybalholm/brotli"
@@ -25,6 +25,7 @@ import (
 	"github.com/smartcontractkit/chainlink-common/pkg/workflows/host"
 
 	"github.com/smartcontractkit/chainlink-common/pkg/config"
+	"github.com/smartcontractkit/chainlink-common/pkg/contexts"
 	"github.com/smartcontractkit/chainlink-common/pkg/custmsg"
 	"github.com/smartcontractkit/chainlink-common/pkg/logger"
 	"github.com/smartcontractkit/chainlink-common/pkg/settings"
@@ -124,6 +125,20 @@ type ModuleConfig struct {
 	// the provided seed to ensure deterministic behavior.
 	Determinism *DeterminismConfig
 
+	// ModuleCache caches the compiled module across NewModule calls. Defaults to a process-wide cache, stored on disk
+	// in the user cache directory.
+	ModuleCache *ModuleCache
+
+	// InstancePoolSize is the number of pre-instantiated instances Execute keeps ready while the module is started.
+	// Legacy DAG modules, and modules with a zero InstancePoolSize, are instantiated on every execution.
+	InstancePoolSize int
+
+	// Profiler, if set, receives the profile of every Execute call once the guest returns. Profiling wraps every host
+	// function, and reads the store's fuel on every host call. Legacy DAG modules are not profiled.
+	Profiler func(ctx context.Context, profile *ExecutionProfile)
+	// WorkflowID is the ExecutionProfile.WorkflowID of executions whose context has no CRE workflow. It is optional.
+	WorkflowID string
+
 	// guestStdoutFile and guestStderrFile are the paths the WASM guest's stdout/stderr are
 	// redirected to. They always default to os.DevNull so the guest can never write to the
 	// host's own stdout/stderr; unexported so callers outside this package can't override
@@ -146,16 +161,16 @@ type ModuleV2 = host.Module
 type ExecutionHelper = host.ExecutionHelper
 
 type module struct {
-	engine  *wasmtime.Engine
-	module  *wasmtime.Module
-	wconfig *wasmtime.Config
+	engine       *wasmtime.Engine
+	sharedEngine *sharedEngine
+	module       *wasmtime.Module
 
 	cfg *ModuleConfig
 
 	metrics moduleMetrics
 
-	wg     sync.WaitGroup
-	stopCh chan struct{}
+	// started records whether the module holds a reference on its shared engine's epoch ticker.
+	started atomic.Bool
 
 	v2ImportName string
 
@@ -169,6 +184,9 @@ type module struct {
 	// implementation (e.g. one that panics) without going through a real
 	// compiled wasm binary.
 	linkV2 linkFn[*sdkpb.ExecutionResult]
+
+	// pool holds pre-instantiated instances for Execute. It is nil unless InstancePoolSize is set.
+	pool *instancePool[*sdkpb.ExecutionResult]
 }
 
 var _ ModuleV1 = (*module)(nil)
@@ -408,22 +426,14 @@ func NewModule(ctx context.Context, modCfg *ModuleConfig, binary []byte, opts ..
 }
 
 func newModule(modCfg *ModuleConfig, binary []byte, metrics moduleMetrics) (*module, error) {
-	cfg := wasmtime.NewConfig()
-	cfg.SetEpochInterruption(true)
-	if modCfg.InitialFuel > 0 {
-		cfg.SetConsumeFuel(true)
-	}
-	if err := cfg.CacheConfigLoadDefault(); err != nil {
-		modCfg.Logger.Errorw("failed to load cache config, continuing without cache", "error", err)
+	if modCfg.ModuleCache == nil {
+		modCfg.ModuleCache = defaultModuleCache(modCfg.Logger)
 	}
-	cfg.SetCraneliftOptLevel(wasmtime.OptLevelSpeedAndSize)
-	SetUnwinding(cfg) // Handled differently based on host OS.
+	engine := getSharedEngine(engineSettingsOf(modCfg), modCfg.TickInterval)
 
-	engine := wasmtime.NewEngineWithConfig(cfg)
-
-	mod, err := wasmtime.NewModule(engine, binary)
+	mod, err := modCfg.ModuleCache.getModule(engine, binary)
 	if err != nil {
-		return nil, fmt.Errorf("error creating wasmtime module: %w", err)
+		return nil, err
 	}
 
 	// Every host callback reaches the guest through its exported linear memory,
@@ -452,25 +462,41 @@ func newModule(modCfg *ModuleConfig, binary []byte, metrics moduleMetrics) (*mod
 
 	modCfg.SdkLabeler(v2ImportName)
 
-	return &module{
-		engine:        engine,
+	m := &module{
+		engine:        engine.engine,
+		sharedEngine:  engine,
 		module:        mod,
-		wconfig:       cfg,
 		cfg:           modCfg,
 		metrics:       metrics,
-		stopCh:        make(chan struct{}),
 		v2ImportName:  v2ImportName,
 		callCapParams: callCapParams,
 		linkV2:        linkNoDAG,
-	}, nil
+	}
+	if !m.IsLegacyDAG() {
+		// Resolve linkV2 on every instantiation, so the pool links the same host functions as Execute.
+		m.pool = newInstancePool(m, func(ctx context.Context, m *module, store *wasmtime.Store, exec *execution[*sdkpb.ExecutionResult]) (*wasmtime.Instance, error) {
+			return m.linkV2(ctx, m, store, exec)
+		}, modCfg.InstancePoolSize)
+	}
+	return m, nil
 }
 
 func linkNoDAG(_ context.Context, m *module, store *wasmtime.Store, exec *execution[*sdkpb.ExecutionResult]) (*wasmtime.Instance, error) {
-	linker, err := newWasiLinker(exec, m.engine)
+	linker, err := newWasiLinker(m, exec)
 	if err != nil {
 		return nil, err
 	}
 
+	// Recorded and replayed executions read WASI randomness from their helper, which the guest runtime is seeded from.
+	if source, ok := exec.executor.(wasiRandomSource); ok {
+		if err = linker.FuncWrap(
+			"wasi_snapshot_preview1",
+			"random_get",
+			hostFunc(m, exec, "random_get", createTracedRandomGet(source))); err != nil {
+			return nil, fmt.Errorf("error wrapping random_get func: %w", err)
+		}
+	}
+
 	if err = linker.FuncWrap(
 		"env",
 		m.v2ImportName,
@@ -482,9 +508,9 @@ func linkNoDAG(_ context.Context, m *module, store *wasmtime.Store, exec *execut
 	if err = linker.FuncWrap(
 		"env",
 		"send_response",
-		createSendResponseFn(logger, exec, func() *sdkpb.ExecutionResult {
+		hostFunc(m, exec, "send_response", createSendResponseFn(logger, exec, func() *sdkpb.ExecutionResult {
 			return &sdkpb.ExecutionResult{}
-		}),
+		})),
 	); err != nil {
 		return nil, fmt.Errorf("error wrapping sendResponse func: %w", err)
 	}
@@ -492,7 +518,7 @@ func linkNoDAG(_ context.Context, m *module, store *wasmtime.Store, exec *execut
 	if err = linker.FuncWrap(
 		"env",
 		"call_capability",
-		createCallCapFn(logger, exec, m.callCapParams),
+		hostFunc(m, exec, "call_capability", createCallCapFn(logger, exec, m.callCapParams)),
 	); err != nil {
 		return nil, fmt.Errorf("error wrapping callcap func: %w", err)
 	}
@@ -500,7 +526,7 @@ func linkNoDAG(_ context.Context, m *module, store *wasmtime.Store, exec *execut
 	if err = linker.FuncWrap(
 		"env",
 		"await_capabilities",
-		createAwaitCapsFn(logger, exec),
+		hostFunc(m, exec, "await_capabilities", createAwaitCapsFn(logger, exec)),
 	); err != nil {
 		return nil, fmt.Errorf("error wrapping awaitcaps func: %w", err)
 	}
@@ -508,7 +534,7 @@ func linkNoDAG(_ context.Context, m *module, store *wasmtime.Store, exec *execut
 	if err = linker.FuncWrap(
 		"env",
 		"get_secrets",
-		createGetSecretsFn(logger, exec),
+		hostFunc(m, exec, "get_secrets", createGetSecretsFn(logger, exec)),
 	); err != nil {
 		return nil, fmt.Errorf("error wrapping get_secrets func: %w", err)
 	}
@@ -516,7 +542,7 @@ func linkNoDAG(_ context.Context, m *module, store *wasmtime.Store, exec *execut
 	if err = linker.FuncWrap(
 		"env",
 		"await_secrets",
-		createAwaitSecretsFn(logger, exec),
+		hostFunc(m, exec, "await_secrets", createAwaitSecretsFn(logger, exec)),
 	); err != nil {
 		return nil, fmt.Errorf("error wrapping await_secrets func: %w", err)
 	}
@@ -524,7 +550,7 @@ func linkNoDAG(_ context.Context, m *module, store *wasmtime.Store, exec *execut
 	if err = linker.FuncWrap(
 		"env",
 		"log",
-		exec.log,
+		hostFunc(m, exec, "log", exec.log),
 	); err != nil {
 		return nil, fmt.Errorf("error wrapping log func: %w", err)
 	}
@@ -532,7 +558,7 @@ func linkNoDAG(_ context.Context, m *module, store *wasmtime.Store, exec *execut
 	if err = linker.FuncWrap(
 		"env",
 		"emit_metric",
-		exec.emitMetric,
+		hostFunc(m, exec, "emit_metric", exec.emitMetric),
 	); err != nil {
 		return nil, fmt.Errorf("error wrapping emit_metric func: %w", err)
 	}
@@ -540,21 +566,21 @@ func linkNoDAG(_ context.Context, m *module, store *wasmtime.Store, exec *execut
 	if err = linker.FuncWrap(
 		"env",
 		"switch_modes",
-		exec.switchModes); err != nil {
+		hostFunc(m, exec, "switch_modes", exec.switchModes)); err != nil {
 		return nil, fmt.Errorf("error wrapping switchModes func: %w", err)
 	}
 
 	if err = linker.FuncWrap(
 		"env",
 		"random_seed",
-		exec.getSeed); err != nil {
+		hostFunc(m, exec, "random_seed", exec.getSeed)); err != nil {
 		return nil, fmt.Errorf("error wrapping getSeed func: %w", err)
 	}
 
 	if err = linker.FuncWrap(
 		"env",
 		"now",
-		exec.now); err != nil {
+		hostFunc(m, exec, "now", exec.now)); err != nil {
 		return nil, fmt.Errorf("error wrapping get_time func: %w", err)
 	}
 
@@ -608,27 +634,22 @@ func linkLegacyDAG(ctx context.Context, m *module, store *wasmtime.Store, exec *
 	return linker.Instantiate(store, m.module)
 }
 
+// Start starts incrementing the epoch of the module's shared engine every TickInterval, which enforces the
+// execution timeouts, and starts filling the instance pool, if any.
 func (m *module) Start() {
-	m.wg.Go(func() {
-		ticker := time.NewTicker(m.cfg.TickInterval)
-		for {
-			select {
-			case <-m.stopCh:
-				return
-			case <-ticker.C:
-				m.engine.IncrementEpoch()
-			}
-		}
-	})
+	if m.started.CompareAndSwap(false, true) {
+		m.sharedEngine.acquire()
+		m.pool.start()
+	}
 }
 
+// Close releases the module's shared engine and pre-instantiated instances. The engine and the compiled module are
+// shared with other modules, and are not closed.
 func (m *module) Close() {
-	close(m.stopCh)
-	m.wg.Wait()
-
-	m.engine.Close()
-	m.module.Close()
-	m.wconfig.Close()
+	if m.started.CompareAndSwap(true, false) {
+		m.pool.stop()
+		m.sharedEngine.release()
+	}
 }
 
 func (m *module) IsLegacyDAG() bool {
@@ -657,7 +678,7 @@ func (m *module) Execute(ctx context.Context, req *sdkpb.ExecuteRequest, executo
 	case *sdkpb.ExecuteRequest_PreHook:
 		timeout = *m.cfg.PrehookTimeout
 	}
-	return runWasm(ctx, m, req, setMaxResponseSize, m.linkV2, executor, timeout)
+	return runWasm(ctx, m, req, setMaxResponseSize, m.linkV2, m.pool, executor, timeout)
 }
 
 // Run is deprecated, use execute instead
@@ -683,7 +704,7 @@ func (m *module) Run(ctx context.Context, request *wasmdagpb.Request) (*wasmdagp
 		}
 	}
 
-	return runWasm(ctx, m, request, setMaxResponseSize, linkLegacyDAG, nil, *m.cfg.Timeout)
+	return runWasm(ctx, m, request, setMaxResponseSize, linkLegacyDAG, nil, nil, *m.cfg.Timeout)
 }
 
 // callStart looks up and invokes the wasm module's _start function, but
@@ -714,6 +735,7 @@ func runWasm[I, O proto.Message](
 	request I,
 	setMaxResponseSize func(i I, maxSize uint64),
 	linkWasm linkFn[O],
+	pool *instancePool[O],
 	helper ExecutionHelper,
 	maxTimeout time.Duration,
 ) (O, error) {
@@ -732,10 +754,6 @@ func runWasm[I, O proto.Message](
 
 	defer cancel()
 
-	store := wasmtime.NewStore(m.engine)
-
-	defer store.Close()
-
 	maxResponseSizeBytes, err := m.cfg.MaxResponseSizeLimiter.Limit(ctx)
 	if err != nil {
 		return o, fmt.Errorf("failed to get response size limit: %w", err)
@@ -748,6 +766,30 @@ func runWasm[I, O proto.Message](
 
 	reqstr := base64.StdEncoding.EncodeToString(reqpb)
 
+	// Limit memory to max memory megabytes per instance.
+	maxMemoryBytes, err := m.cfg.MemoryLimiter.Limit(ctx)
+	if err != nil {
+		return o, fmt.Errorf("failed to get memory limit: %w", err)
+	}
+	memoryLimit := int64(maxMemoryBytes/config.MByte) * int64(math.Pow(10, 6))
+
+	// A pooled instance is already linked to its exec, which is bound to this execution below.
+	var store *wasmtime.Store
+	var instance *wasmtime.Instance
+	// Recorded and replayed executions link their own random_get, so they never take a pooled instance.
+	var exec *execution[O]
+	var pooled *pooledInstance[O]
+	if _, traced := helper.(wasiRandomSource); !traced {
+		pooled = pool.get(memoryLimit)
+	}
+	if pooled != nil {
+		store, instance, exec = pooled.store, pooled.instance, pooled.exec
+	} else {
+		store, exec = wasmtime.NewStore(m.engine), &execution[O]{}
+	}
+
+	defer store.Close()
+
 	wasi := wasmtime.NewWasiConfig()
 	if err := wasi.SetStdoutFile(m.cfg.guestStdoutFile); err != nil {
 		return o, fmt.Errorf("error setting guest stdout file: %w", err)
@@ -761,57 +803,67 @@ func runWasm[I, O proto.Message](
 
 	store.SetWasi(wasi)
 
-	if m.cfg.InitialFuel > 0 {
-		err = store.SetFuel(m.cfg.InitialFuel)
-		if err != nil {
-			return o, fmt.Errorf("error setting fuel: %w", err)
-		}
-	}
-
-	// Limit memory to max memory megabytes per instance.
-	maxMemoryBytes, err := m.cfg.MemoryLimiter.Limit(ctx)
-	if err != nil {
-		return o, fmt.Errorf("failed to get memory limit: %w", err)
+	if err = configureStore(store, m.cfg, memoryLimit, maxTimeout); err != nil {
+		return o, err
 	}
-	store.Limiter(
-		int64(maxMemoryBytes/config.MByte)*int64(math.Pow(10, 6)),
-		-1, // tableElements, -1 == default
-		1,  // instances
-		1,  // tables
-		1,  // memories
-	)
-
-	deadline := maxTimeout / m.cfg.TickInterval
-	store.SetEpochDeadline(uint64(deadline))
 
 	h := fnv.New64a()
+	var executionId string
 	if helper != nil {
-		executionId := helper.GetWorkflowExecutionID()
+		executionId = helper.GetWorkflowExecutionID()
 		_, _ = h.Write([]byte(executionId))
 	}
 
 	donSeed := int64(h.Sum64())
 
-	exec := &execution[O]{
-		ctx:                 ctxWithTimeout,
-		capabilityResponses: map[int32]<-chan *sdkpb.CapabilityResponse{},
-		secretsResponses:    map[int32]<-chan *secretsResponse{},
-		usedCallbackIDs:     map[string]bool{},
-		pendingCallsLimiter: m.cfg.PendingCallsLimiter,
-		module:              m,
-		executor:            helper,
-		donSeed:             donSeed,
-		nodeSeed:            int64(rand.Uint64()),
+	// Helpers recording or replaying the execution choose the node seed, so that a replay reproduces node mode randomness.
+	nodeSeed := int64(rand.Uint64())
+	if seeder, ok := helper.(nodeSeeder); ok {
+		nodeSeed = seeder.NodeSeed()
+	}
+
+	exec.ctx = ctxWithTimeout
+	exec.capabilityResponses = map[int32]<-chan *sdkpb.CapabilityResponse{}
+	exec.secretsResponses = map[int32]<-chan *secretsResponse{}
+	exec.usedCallbackIDs = map[string]bool{}
+	exec.pendingCallsLimiter = m.cfg.PendingCallsLimiter
+	exec.module = m
+	exec.executor = helper
+	exec.donSeed = donSeed
+	exec.nodeSeed = nodeSeed
+	exec.timeFetcher = newTimeFetcher(ctxWithTimeout, helper)
+	exec.timeFetcher.Start()
+	exec.profiler = nil
+	if m.cfg.Profiler != nil && helper != nil {
+		workflowID := contexts.CREValue(ctx).Workflow
+		if workflowID == "" {
+			workflowID = m.cfg.WorkflowID
+		}
+		exec.profiler = newExecutionProfiler(workflowID, executionId, func() (uint64, bool) {
+			if m.cfg.InitialFuel == 0 {
+				return 0, false
+			}
+			fuel, err := store.GetFuel()
+			return fuel, err == nil
+		})
 	}
 
-	instance, err := linkWasm(ctxWithTimeout, m, store, exec)
-	if err != nil {
-		return o, fmt.Errorf("error linking wasm: %w", err)
+	if instance == nil {
+		instance, err = linkWasm(ctxWithTimeout, m, store, exec)
+		if err != nil {
+			return o, fmt.Errorf("error linking wasm: %w", err)
+		}
 	}
 
 	startTime := time.Now()
+	exec.profiler.start()
 	_, err = callStart(m, instance, store)
 	executionDuration := time.Since(startTime)
+	if exec.profiler != nil {
+		profile := exec.profiler.finish()
+		m.metrics.RecordExecutionProfile(ctx, profile)
+		m.cfg.Profiler(ctx, profile)
+	}
 
 	// The error codes below are only returned by the v1 legacy DAG workflow.
 	switch {
@@ -847,6 +899,29 @@ func runWasm[I, O proto.Message](
 	return o, err
 }
 
+// configureStore sets the fuel, memory and epoch deadline limits of an execution on store. It is called again on a
+// pooled instance's store, since the limits of the execution taking it may differ from those it was instantiated
+// under.
+func configureStore(store *wasmtime.Store, cfg *ModuleConfig, memoryLimit int64, maxTimeout time.Duration) error {
+	if cfg.InitialFuel > 0 {
+		if err := store.SetFuel(cfg.InitialFuel); err != nil {
+			return fmt.Errorf("error setting fuel: %w", err)
+		}
+	}
+
+	store.Limiter(
+		memoryLimit,
+		-1, // tableElements, -1 == default
+		1,  // instances
+		1,  // tables
+		1,  // memories
+	)
+
+	deadline := maxTimeout / cfg.TickInterval
+	store.SetEpochDeadline(uint64(deadline))
+	return nil
+}
+
 func containsCode(err error, code int) bool {
 	if err == nil {
 		return false
@@ -0,0 +1,499 @@
+package host
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bytecodealliance/wasmtime-go/v48"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"golang.org/x/sync/singleflight"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/beholder"
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+)
+
+const (
+	defaultModuleCacheMaxMemoryBytes = 256 << 20 // 256 MiB
+	defaultModuleCacheMaxDiskBytes   = 2 << 30   // 2 GiB
+
+	// compiledModuleVersion is part of every cache key, so artifacts of another wasmtime release are never loaded.
+	compiledModuleVersion = "wasmtime-go/v48"
+	compiledModuleExt     = ".cwasm"
+)
+
+// compiledModuleMagic prefixes the files of the on-disk store, followed by the SHA-256 of the serialized module.
+var compiledModuleMagic = []byte("CLCWASM1")
+
+// engineSettings are the wasmtime.Config settings that vary between modules. Modules compiled with different
+// settings are incompatible, so they are part of the cache key.
+type engineSettings struct {
+	consumeFuel bool
+}
+
+func engineSettingsOf(cfg *ModuleConfig) engineSettings {
+	return engineSettings{
+		consumeFuel: cfg.InitialFuel > 0,
+	}
+}
+
+func (s engineSettings) newConfig() *wasmtime.Config {
+	cfg := wasmtime.NewConfig()
+	cfg.SetEpochInterruption(true)
+	cfg.SetConsumeFuel(s.consumeFuel)
+	cfg.SetCraneliftOptLevel(wasmtime.OptLevelSpeedAndSize)
+	SetUnwinding(cfg) // Handled differently based on host OS.
+	return cfg
+}
+
+func (s engineSettings) String() string {
+	return fmt.Sprintf("fuel=%t", s.consumeFuel)
+}
+
+// sharedEngine is a process-wide wasmtime.Engine. Its epoch is incremented every tickInterval while any started
+// module uses it.
+type sharedEngine struct {
+	engine       *wasmtime.Engine
+	settings     engineSettings
+	tickInterval time.Duration
+
+	mu     sync.Mutex
+	refs   int
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+type sharedEngineKey struct {
+	settings     engineSettings
+	tickInterval time.Duration
+}
+
+var sharedEngines = struct {
+	sync.Mutex
+	engines map[sharedEngineKey]*sharedEngine
+}{engines: make(map[sharedEngineKey]*sharedEngine)}
+
+// getSharedEngine returns the engine for settings and tickInterval, creating it on first use. Engines are never
+// closed, and there is only one per distinct configuration.
+func getSharedEngine(settings engineSettings, tickInterval time.Duration) *sharedEngine {
+	key := sharedEngineKey{settings: settings, tickInterval: tickInterval}
+	sharedEngines.Lock()
+	defer sharedEngines.Unlock()
+	e, ok := sharedEngines.engines[key]
+	if !ok {
+		e = &sharedEngine{
+			engine:       wasmtime.NewEngineWithConfig(settings.newConfig()),
+			settings:     settings,
+			tickInterval: tickInterval,
+		}
+		sharedEngines.engines[key] = e
+	}
+	return e
+}
+
+// acquire starts incrementing the epoch, if it is not already.
+func (e *sharedEngine) acquire() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.refs++; e.refs > 1 {
+		return
+	}
+	stopCh := make(chan struct{})
+	e.stopCh = stopCh
+	e.wg.Go(func() {
+		ticker := time.NewTicker(e.tickInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-ticker.C:
+				e.engine.IncrementEpoch()
+			}
+		}
+	})
+}
+
+// release stops incrementing the epoch once no started module uses the engine.
+func (e *sharedEngine) release() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.refs--; e.refs > 0 {
+		return
+	}
+	close(e.stopCh)
+	e.wg.Wait()
+}
+
+// ModuleCacheConfig configures a ModuleCache.
+type ModuleCacheConfig struct {
+	// MaxMemoryBytes bounds the serialized size of the compiled modules held in memory. Defaults to 256 MiB.
+	MaxMemoryBytes int64
+	// Dir is the directory of the on-disk store of serialized modules, which survives restarts. The store is disabled
+	// if empty. Serialized modules are loaded as trusted native code, so Dir must only be writable by the node.
+	Dir string
+	// MaxDiskBytes bounds the size of the on-disk store. Defaults to 2 GiB.
+	MaxDiskBytes int64
+	Logger       logger.Logger
+}
+
+// New returns a ModuleCache, creating Dir if necessary.
+func (c ModuleCacheConfig) New() (*ModuleCache, error) {
+	if c.Logger == nil {
+		return nil, errors.New("must provide logger")
+	}
+	if c.MaxMemoryBytes <= 0 {
+		c.MaxMemoryBytes = defaultModuleCacheMaxMemoryBytes
+	}
+	if c.MaxDiskBytes <= 0 {
+		c.MaxDiskBytes = defaultModuleCacheMaxDiskBytes
+	}
+	if c.Dir != "" {
+		if err := os.MkdirAll(c.Dir, 0o700); err != nil {
+			return nil, fmt.Errorf("failed to create module cache dir: %w", err)
+		}
+	}
+	metrics, err := newModuleCacheMetrics()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create module cache metrics: %w", err)
+	}
+	return &ModuleCache{
+		cfg:     c,
+		lggr:    logger.Named(c.Logger, "ModuleCache"),
+		metrics: metrics,
+		modules: make(map[moduleCacheKey]*list.Element),
+	}, nil
+}
+
+// ModuleCache caches compiled wasmtime modules by binary hash and engine settings, so modules are only compiled by
+// Cranelift once. Compiled modules are held in an in-memory LRU and, if configured, in an on-disk store of serialized
+// modules that survives restarts. It is safe for concurrent use.
+type ModuleCache struct {
+	cfg     ModuleCacheConfig
+	lggr    logger.Logger
+	metrics *moduleCacheMetrics
+	group   singleflight.Group
+
+	mu          sync.Mutex
+	modules     map[moduleCacheKey]*list.Element // of *moduleCacheEntry
+	lru         list.List                        // most recently used first
+	memoryBytes int64
+}
+
+type moduleCacheKey struct {
+	engine *wasmtime.Engine
+	// sum is the SHA-256 of the binary and engine settings.
+	sum [sha256.Size]byte
+}
+
+type moduleCacheEntry struct {
+	key    moduleCacheKey
+	module *wasmtime.Module
+	size   int64
+}
+
+var defaultModuleCacheOnce struct {
+	sync.Once
+	cache *ModuleCache
+}
+
+// defaultModuleCache returns the process-wide ModuleCache, whose on-disk store is in the user cache directory, like
+// the wasmtime cache it replaces. It is created with the logger of the first caller, and is only held in memory if
+// the store cannot be created.
+func defaultModuleCache(lggr logger.Logger) *ModuleCache {
+	defaultModuleCacheOnce.Do(func() {
+		dir, err := os.UserCacheDir()
+		if err == nil {
+			dir = filepath.Join(dir, "chainlink", "wasm-modules")
+			if defaultModuleCacheOnce.cache, err = (ModuleCacheConfig{Dir: dir, Logger: lggr}).New(); err == nil {
+				return
+			}
+		}
+		lggr.Errorw("Failed to create the on-disk module cache, caching compiled modules in memory only", "err", err)
+		if defaultModuleCacheOnce.cache, err = (ModuleCacheConfig{Logger: lggr}).New(); err != nil {
+			panic(fmt.Errorf("failed to create default module cache: %w", err))
+		}
+	})
+	return defaultModuleCacheOnce.cache
+}
+
+// getModule returns the compiled module of binary for engine, from memory, from disk, or by compiling it.
+func (c *ModuleCache) getModule(engine *sharedEngine, binary []byte) (*wasmtime.Module, error) {
+	key := moduleCacheKey{engine: engine.engine, sum: moduleSum(engine.settings, binary)}
+	if mod := c.getMemory(key); mod != nil {
+		c.metrics.hit("memory")
+		return mod, nil
+	}
+	v, err, _ := c.group.Do(fmt.Sprintf("%p/%x", engine.engine, key.sum), func() (any, error) {
+		if mod := c.getMemory(key); mod != nil {
+			c.metrics.hit("memory")
+			return mod, nil
+		}
+		if mod, size := c.loadDisk(engine.engine, key.sum); mod != nil {
+			c.metrics.hit("disk")
+			c.putMemory(key, mod, size)
+			return mod, nil
+		}
+		c.metrics.miss()
+
+		start := time.Now()
+		mod, err := wasmtime.NewModule(engine.engine, binary)
+		if err != nil {
+			return nil, fmt.Errorf("error creating wasmtime module: %w", err)
+		}
+		c.metrics.compiled(time.Since(start))
+
+		serialized, err := mod.Serialize()
+		if err != nil {
+			c.lggr.Errorw("Failed to serialize compiled module, caching it in memory only", "err", err)
+			c.putMemory(key, mod, int64(len(binary)))
+			return mod, nil
+		}
+		c.putMemory(key, mod, int64(len(serialized)))
+		c.storeDisk(key.sum, serialized)
+		return mod, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*wasmtime.Module), nil
+}
+
+func moduleSum(settings engineSettings, binary []byte) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write([]byte(compiledModuleVersion))
+	h.Write([]byte{0})
+	h.Write([]byte(settings.String()))
+	h.Write([]byte{0})
+	h.Write(binary)
+	var sum [sha256.Size]byte
+	h.Sum(sum[:0])
+	return sum
+}
+
+func (c *ModuleCache) getMemory(key moduleCacheKey) *wasmtime.Module {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.modules[key]
+	if !ok {
+		return nil
+	}
+	c.lru.MoveToFront(e)
+	return e.Value.(*moduleCacheEntry).module
+}
+
+// putMemory adds mod, evicting the least recently used modules beyond MaxMemoryBytes. Evicted modules are released
+// by the garbage collector once no module uses them.
+func (c *ModuleCache) putMemory(key moduleCacheKey, mod *wasmtime.Module, size int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.modules[key]; ok {
+		return
+	}
+	c.modules[key] = c.lru.PushFront(&moduleCacheEntry{key: key, module: mod, size: size})
+	c.memoryBytes += size
+	for c.memoryBytes > c.cfg.MaxMemoryBytes && c.lru.Len() > 1 {
+		entry := c.lru.Remove(c.lru.Back()).(*moduleCacheEntry)
+		delete(c.modules, entry.key)
+		c.memoryBytes -= entry.size
+		c.metrics.evicted("memory")
+	}
+	c.metrics.memoryBytes.Record(context.Background(), c.memoryBytes)
+}
+
+func (c *ModuleCache) diskPath(sum [sha256.Size]byte) string {
+	return filepath.Join(c.cfg.Dir, hex.EncodeToString(sum[:])+compiledModuleExt)
+}
+
+// loadDisk returns the deserialized module of sum from the on-disk store, if present and valid. Invalid files, e.g.
+// corrupted or rejected by wasmtime, are removed.
+func (c *ModuleCache) loadDisk(engine *wasmtime.Engine, sum [sha256.Size]byte) (*wasmtime.Module, int64) {
+	if c.cfg.Dir == "" {
+		return nil, 0
+	}
+	path := c.diskPath(sum)
+	b, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			c.lggr.Errorw("Failed to read compiled module", "path", path, "err", err)
+		}
+		return nil, 0
+	}
+	serialized, ok := bytes.CutPrefix(b, compiledModuleMagic)
+	if !ok || len(serialized) < sha256.Size {
+		c.removeDisk(path, "invalid header")
+		return nil, 0
+	}
+	checksum, serialized := serialized[:sha256.Size], serialized[sha256.Size:]
+	if got := sha256.Sum256(serialized); !bytes.Equal(got[:], checksum) {
+		c.removeDisk(path, "checksum mismatch")
+		return nil, 0
+	}
+	mod, err := wasmtime.NewModuleDeserialize(engine, serialized)
+	if err != nil {
+		c.removeDisk(path, err.Error())
+		return nil, 0
+	}
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err != nil {
+		c.lggr.Warnw("Failed to touch compiled module", "path", path, "err", err)
+	}
+	return mod, int64(len(serialized))
+}
+
+func (c *ModuleCache) removeDisk(path, reason string) {
+	c.lggr.Warnw("Removing invalid compiled module", "path", path, "reason", reason)
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		c.lggr.Errorw("Failed to remove invalid compiled module", "path", path, "err", err)
+	}
+}
+
+// storeDisk writes serialized to the on-disk store, and then prunes it to MaxDiskBytes.
+func (c *ModuleCache) storeDisk(sum [sha256.Size]byte, serialized []byte) {
+	if c.cfg.Dir == "" {
+		return
+	}
+	if int64(len(serialized)) > c.cfg.MaxDiskBytes {
+		return
+	}
+	checksum := sha256.Sum256(serialized)
+	tmp, err := os.CreateTemp(c.cfg.Dir, "tmp-*")
+	if err != nil {
+		c.lggr.Errorw("Failed to create compiled module file", "err", err)
+		return
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // no-op after the rename
+	for _, b := range [][]byte{compiledModuleMagic, checksum[:], serialized} {
+		if _, err = tmp.Write(b); err != nil {
+			break
+		}
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.diskPath(sum))
+	}
+	if err != nil {
+		c.lggr.Errorw("Failed to write compiled module", "err", err)
+		return
+	}
+	c.pruneDisk()
+}
+
+// pruneDisk removes the least recently used files of the on-disk store beyond MaxDiskBytes.
+func (c *ModuleCache) pruneDisk() {
+	entries, err := os.ReadDir(c.cfg.Dir)
+	if err != nil {
+		c.lggr.Errorw("Failed to list compiled modules", "err", err)
+		return
+	}
+	type file struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+	var files []file
+	var total int64
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), compiledModuleExt) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, file{path: filepath.Join(c.cfg.Dir, e.Name()), size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+	}
+	slices.SortFunc(files, func(a, b file) int { return a.modTime.Compare(b.modTime) })
+	for _, f := range files {
+		if total <= c.cfg.MaxDiskBytes {
+			break
+		}
+		if err := os.Remove(f.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			c.lggr.Errorw("Failed to remove compiled module", "path", f.path, "err", err)
+			continue
+		}
+		total -= f.size
+		c.metrics.evicted("disk")
+	}
+	c.metrics.diskBytes.Record(context.Background(), total)
+}
+
+type moduleCacheMetrics struct {
+	hits            metric.Int64Counter
+	misses          metric.Int64Counter
+	evictions       metric.Int64Counter
+	compileDuration metric.Float64Histogram
+	memoryBytes     metric.Int64Gauge
+	diskBytes       metric.Int64Gauge
+}
+
+func newModuleCacheMetrics() (*moduleCacheMetrics, error) {
+	meter := beholder.GetMeter()
+	var m moduleCacheMetrics
+	var err error
+	if m.hits, err = meter.Int64Counter("platform_wasm_module_cache_hits_total",
+		metric.WithDescription("the total number of compiled modules loaded from the cache, by tier"),
+	); err != nil {
+		return nil, err
+	}
+	if m.misses, err = meter.Int64Counter("platform_wasm_module_cache_misses_total",
+		metric.WithDescription("the total number of modules compiled because they were not cached"),
+	); err != nil {
+		return nil, err
+	}
+	if m.evictions, err = meter.Int64Counter("platform_wasm_module_cache_evictions_total",
+		metric.WithDescription("the total number of compiled modules evicted from the cache, by tier"),
+	); err != nil {
+		return nil, err
+	}
+	if m.compileDuration, err = meter.Float64Histogram("platform_wasm_module_compile_duration_seconds",
+		metric.WithDescription("the time taken to compile a module"),
+		metric.WithUnit("s"),
+	); err != nil {
+		return nil, err
+	}
+	if m.memoryBytes, err = meter.Int64Gauge("platform_wasm_module_cache_memory_bytes",
+		metric.WithDescription("the serialized size of the compiled modules held in memory"),
+		metric.WithUnit("By"),
+	); err != nil {
+		return nil, err
+	}
+	if m.diskBytes, err = meter.Int64Gauge("platform_wasm_module_cache_disk_bytes",
+		metric.WithDescription("the size of the on-disk store of compiled modules"),
+		metric.WithUnit("By"),
+	); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (m *moduleCacheMetrics) hit(tier string) {
+	m.hits.Add(context.Background(), 1, metric.WithAttributes(attribute.String("tier", tier)))
+}
+
+func (m *moduleCacheMetrics) miss() {
+	m.misses.Add(context.Background(), 1)
+}
+
+func (m *moduleCacheMetrics) evicted(tier string) {
+	m.evictions.Add(context.Background(), 1, metric.WithAttributes(attribute.String("tier", tier)))
+}
+
+func (m *moduleCacheMetrics) compiled(d time.Duration) {
+	m.compileDuration.Record(context.Background(), d.Seconds())
+}
@@ -0,0 +1,138 @@
+package host
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bytecodealliance/wasmtime-go/v48"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/logger"
+)
+
+func testWasm(t *testing.T, name string) []byte {
+	t.Helper()
+	b, err := wasmtime.Wat2Wasm(`(module $` + name + ` (memory (export "memory") 1))`)
+	require.NoError(t, err)
+	return b
+}
+
+func diskModules(t *testing.T, dir string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*"+compiledModuleExt))
+	require.NoError(t, err)
+	return files
+}
+
+func TestModuleCache_Memory(t *testing.T) {
+	c, err := ModuleCacheConfig{Logger: logger.Test(t)}.New()
+	require.NoError(t, err)
+	engine := getSharedEngine(engineSettings{}, defaultTickInterval)
+	fuelEngine := getSharedEngine(engineSettings{consumeFuel: true}, defaultTickInterval)
+
+	first, err := c.getModule(engine, testWasm(t, "a"))
+	require.NoError(t, err)
+	second, err := c.getModule(engine, testWasm(t, "a"))
+	require.NoError(t, err)
+	assert.Same(t, first, second, "the compiled module is reused")
+
+	other, err := c.getModule(fuelEngine, testWasm(t, "a"))
+	require.NoError(t, err)
+	assert.NotSame(t, first, other, "engine settings are part of the key")
+	assert.Len(t, c.modules, 2)
+
+	_, err = c.getModule(engine, []byte("not wasm"))
+	require.ErrorContains(t, err, "error creating wasmtime module")
+}
+
+func TestModuleCache_MaxMemoryBytes(t *testing.T) {
+	c, err := ModuleCacheConfig{Logger: logger.Test(t), MaxMemoryBytes: 1}.New()
+	require.NoError(t, err)
+	engine := getSharedEngine(engineSettings{}, defaultTickInterval)
+
+	_, err = c.getModule(engine, testWasm(t, "a"))
+	require.NoError(t, err)
+	b := testWasm(t, "b")
+	_, err = c.getModule(engine, b)
+	require.NoError(t, err)
+
+	require.Len(t, c.modules, 1, "the least recently used module is evicted")
+	_, ok := c.modules[moduleCacheKey{engine: engine.engine, sum: moduleSum(engine.settings, b)}]
+	assert.True(t, ok)
+}
+
+func TestModuleCache_Disk(t *testing.T) {
+	dir := t.TempDir()
+	c, err := ModuleCacheConfig{Logger: logger.Test(t), Dir: dir}.New()
+	require.NoError(t, err)
+	engine := getSharedEngine(engineSettings{}, defaultTickInterval)
+	binary := testWasm(t, "a")
+
+	_, err = c.getModule(engine, binary)
+	require.NoError(t, err)
+	require.Len(t, diskModules(t, dir), 1)
+
+	// a new cache, e.g. after a restart, loads the serialized module
+	restarted, err := ModuleCacheConfig{Logger: logger.Test(t), Dir: dir}.New()
+	require.NoError(t, err)
+	sum := moduleSum(engine.settings, binary)
+	mod, size := restarted.loadDisk(engine.engine, sum)
+	require.NotNil(t, mod)
+	assert.Positive(t, size)
+
+	// corrupted modules are removed
+	path := restarted.diskPath(sum)
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	b[len(b)-1] ^= 0xff
+	require.NoError(t, os.WriteFile(path, b, 0o600))
+	mod, _ = restarted.loadDisk(engine.engine, sum)
+	assert.Nil(t, mod)
+	assert.Empty(t, diskModules(t, dir))
+
+	// and recompiled
+	_, err = restarted.getModule(engine, binary)
+	require.NoError(t, err)
+	assert.Len(t, diskModules(t, dir), 1)
+}
+
+func TestModuleCache_MaxDiskBytes(t *testing.T) {
+	dir := t.TempDir()
+	engine := getSharedEngine(engineSettings{}, defaultTickInterval)
+	c, err := ModuleCacheConfig{Logger: logger.Test(t), Dir: dir}.New()
+	require.NoError(t, err)
+	_, err = c.getModule(engine, testWasm(t, "a"))
+	require.NoError(t, err)
+	files := diskModules(t, dir)
+	require.Len(t, files, 1)
+	info, err := os.Stat(files[0])
+	require.NoError(t, err)
+
+	// room for one module only
+	c.cfg.MaxDiskBytes = info.Size() + info.Size()/2
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(files[0], old, old))
+	_, err = c.getModule(engine, testWasm(t, "b"))
+	require.NoError(t, err)
+
+	remaining := diskModules(t, dir)
+	require.Len(t, remaining, 1, "the least recently used module is removed")
+	assert.NotEqual(t, files[0], remaining[0])
+}
+
+func TestSharedEngine(t *testing.T) {
+	settings := engineSettings{consumeFuel: true}
+	e := getSharedEngine(settings, time.Millisecond)
+	assert.Same(t, e, getSharedEngine(settings, time.Millisecond))
+	assert.NotSame(t, e, getSharedEngine(settings, 2*time.Millisecond), "engines tick at their module's interval")
+
+	e.acquire()
+	e.acquire()
+	e.release()
+	assert.Equal(t, 1, e.refs, "the ticker runs while any started module uses the engine")
+	e.release()
+	assert.Equal(t, 0, e.refs)
+}
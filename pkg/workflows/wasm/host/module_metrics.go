@@ -3,6 +3,7 @@ package host
 import (
 	"context"
 
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/metric"
 
 	"github.com/smartcontractkit/chainlink-common/pkg/beholder"
@@ -11,10 +12,21 @@ import (
 // moduleMetrics records host-side metrics for wasm module execution.
 type moduleMetrics interface {
 	IncHostFnPanicRecovered()
+	IncInstancePoolHit()
+	IncInstancePoolMiss()
+	RecordExecutionProfile(ctx context.Context, profile *ExecutionProfile)
 }
 
 type moduleMetricsImpl struct {
 	hostFnPanicRecoveredCount metric.Int64Counter
+	instancePoolHits          metric.Int64Counter
+	instancePoolMisses        metric.Int64Counter
+	fuelConsumed              metric.Int64Counter
+	hostCallFuelConsumed      metric.Int64Counter
+	guestTime                 metric.Float64Histogram
+	hostCallTime              metric.Float64Histogram
+	capabilityCallTime        metric.Float64Histogram
+	getSecretsTime            metric.Float64Histogram
 }
 
 var _ moduleMetrics = &moduleMetricsImpl{}
@@ -27,8 +39,76 @@ func newModuleMetrics() (moduleMetrics, error) {
 		return nil, err
 	}
 
+	instancePoolHits, err := beholder.GetMeter().Int64Counter("platform_wasm_instance_pool_hits_total",
+		metric.WithDescription("the total number of executions that ran on a pre-instantiated instance"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	instancePoolMisses, err := beholder.GetMeter().Int64Counter("platform_wasm_instance_pool_misses_total",
+		metric.WithDescription("the total number of executions of a pooled module that had to instantiate it"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	fuelConsumed, err := beholder.GetMeter().Int64Counter("platform_wasm_fuel_consumed_total",
+		metric.WithDescription("the total fuel consumed by profiled executions"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	hostCallFuelConsumed, err := beholder.GetMeter().Int64Counter("platform_wasm_host_call_fuel_consumed_total",
+		metric.WithDescription("the total fuel consumed by profiled executions before each host call"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	guestTime, err := beholder.GetMeter().Float64Histogram("platform_wasm_guest_time_seconds",
+		metric.WithDescription("the time profiled executions spent running guest code, excluding host calls"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	hostCallTime, err := beholder.GetMeter().Float64Histogram("platform_wasm_host_call_time_seconds",
+		metric.WithDescription("the time profiled executions spent in each host function"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	capabilityCallTime, err := beholder.GetMeter().Float64Histogram("platform_wasm_capability_call_time_seconds",
+		metric.WithDescription("the duration of the capability calls of profiled executions"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	getSecretsTime, err := beholder.GetMeter().Float64Histogram("platform_wasm_get_secrets_time_seconds",
+		metric.WithDescription("the duration of the secrets lookups of profiled executions"),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	return &moduleMetricsImpl{
 		hostFnPanicRecoveredCount: hostFnPanicRecoveredTotal,
+		instancePoolHits:          instancePoolHits,
+		instancePoolMisses:        instancePoolMisses,
+		fuelConsumed:              fuelConsumed,
+		hostCallFuelConsumed:      hostCallFuelConsumed,
+		guestTime:                 guestTime,
+		hostCallTime:              hostCallTime,
+		capabilityCallTime:        capabilityCallTime,
+		getSecretsTime:            getSecretsTime,
 	}, nil
 }
 
@@ -38,3 +118,37 @@ func newModuleMetrics() (moduleMetrics, error) {
 func (m *moduleMetricsImpl) IncHostFnPanicRecovered() {
 	m.hostFnPanicRecoveredCount.Add(context.Background(), 1)
 }
+
+// IncInstancePoolHit records an execution that took a pre-instantiated
+// instance from the module's instance pool.
+func (m *moduleMetricsImpl) IncInstancePoolHit() {
+	m.instancePoolHits.Add(context.Background(), 1)
+}
+
+// IncInstancePoolMiss records an execution of a module with an instance pool
+// that found the pool empty and instantiated the module itself.
+func (m *moduleMetricsImpl) IncInstancePoolMiss() {
+	m.instancePoolMisses.Add(context.Background(), 1)
+}
+
+// RecordExecutionProfile records the fuel and time of a profiled execution,
+// keyed by workflow ID. Host call metrics are recorded once per host
+// function, with the totals of the execution.
+func (m *moduleMetricsImpl) RecordExecutionProfile(ctx context.Context, profile *ExecutionProfile) {
+	workflow := attribute.String("workflow_id", profile.WorkflowID)
+	m.fuelConsumed.Add(ctx, int64(profile.FuelConsumed), metric.WithAttributes(workflow)) //nolint:gosec // fuel fits in int64
+	m.guestTime.Record(ctx, profile.GuestTime.Seconds(), metric.WithAttributes(workflow))
+	for name, hc := range profile.HostCalls {
+		attrs := metric.WithAttributes(workflow, attribute.String("host_call", name))
+		m.hostCallFuelConsumed.Add(ctx, int64(hc.Fuel), attrs) //nolint:gosec // fuel fits in int64
+		if name != profileEndOfExecution {
+			m.hostCallTime.Record(ctx, hc.Time.Seconds(), attrs)
+		}
+	}
+	for id, c := range profile.Capabilities {
+		m.capabilityCallTime.Record(ctx, c.Time.Seconds(), metric.WithAttributes(workflow, attribute.String("capability_id", id)))
+	}
+	if profile.Secrets.Calls > 0 {
+		m.getSecretsTime.Record(ctx, profile.Secrets.Time.Seconds(), metric.WithAttributes(workflow))
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"math"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -691,12 +692,24 @@ func Test_CallAwaitRace(t *testing.T) {
 
 type fakeModuleMetrics struct {
 	hostFnPanicRecoveredCount int
+	instancePoolHits          atomic.Int64
+	instancePoolMisses        atomic.Int64
 }
 
 func (f *fakeModuleMetrics) IncHostFnPanicRecovered() {
 	f.hostFnPanicRecoveredCount++
 }
 
+func (f *fakeModuleMetrics) IncInstancePoolHit() {
+	f.instancePoolHits.Add(1)
+}
+
+func (f *fakeModuleMetrics) IncInstancePoolMiss() {
+	f.instancePoolMisses.Add(1)
+}
+
+func (f *fakeModuleMetrics) RecordExecutionProfile(context.Context, *ExecutionProfile) {}
+
 func Test_Integration_Execute_RecoversHostFunctionPanic(t *testing.T) {
 	lggr, logs := logger.TestObserved(t, zapcore.ErrorLevel)
 	cfg := defaultNoDAGModCfg(t)
@@ -736,3 +749,53 @@ func Test_Integration_Execute_RecoversHostFunctionPanic(t *testing.T) {
 	assert.Equal(t, zapcore.ErrorLevel, logs.AllUntimed()[0].Level)
 	assert.Equal(t, "panic during wasm execution", logs.AllUntimed()[0].Message)
 }
+
+func Test_Integration_Execute_InstancePool(t *testing.T) {
+	cfg := defaultNoDAGModCfg(t)
+	cfg.InstancePoolSize = 2
+	m := makeTestModuleByName(t, testPath, "config", cfg, true)
+	require.NotNil(t, m.pool)
+
+	metrics := &fakeModuleMetrics{}
+	m.metrics = metrics
+
+	m.Start()
+	defer m.Close()
+
+	execute := func() {
+		mockExecutionHelper := mocks.NewMockExecutionHelper(t)
+		mockExecutionHelper.EXPECT().GetWorkflowExecutionID().Return("id")
+		mockExecutionHelper.EXPECT().GetNodeTime().RunAndReturn(func() time.Time {
+			return time.Now()
+		}).Maybe()
+
+		result := runWithBasicTriggerWithModule(t, mockExecutionHelper, m)
+		require.ElementsMatch(t, anyTestConfig, result.GetValue().GetBytesValue())
+	}
+
+	// The pool fills once the first execution established the memory limit.
+	execute()
+	assert.Equal(t, int64(1), metrics.instancePoolMisses.Load())
+
+	for range 3 {
+		require.Eventually(t, func() bool { return len(m.pool.ready) == cap(m.pool.ready) }, 10*time.Second, 10*time.Millisecond)
+		execute()
+	}
+	assert.Equal(t, int64(3), metrics.instancePoolHits.Load())
+	assert.Equal(t, int64(1), metrics.instancePoolMisses.Load())
+
+	m.Close()
+	assert.Empty(t, m.pool.ready)
+}
+
+func Test_InstancePool_LegacyDAGIsNotPooled(t *testing.T) {
+	wasmBytes, err := wasmtime.Wat2Wasm(`(module (memory (export "memory") 1))`)
+	require.NoError(t, err)
+
+	cfg := defaultNoDAGModCfg(t)
+	cfg.InstancePoolSize = 2
+	m, err := NewModule(t.Context(), cfg, wasmBytes)
+	require.NoError(t, err)
+	require.True(t, m.IsLegacyDAG())
+	assert.Nil(t, m.pool)
+}
@@ -0,0 +1,345 @@
+package host
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// profileEndOfExecution is the pseudo host call that ends the guest's last run, when _start returns or the guest
+// exits through WASI.
+const profileEndOfExecution = "(end)"
+
+// ExecutionProfile is the profile of an execution, recorded when ModuleConfig.Profiler is set.
+type ExecutionProfile struct {
+	WorkflowID          string
+	WorkflowExecutionID string
+	Start               time.Time
+	Duration            time.Duration
+
+	// FuelConsumed is the fuel the guest consumed. It is zero unless fuel is metered, i.e. InitialFuel is set.
+	FuelConsumed uint64
+	// GuestTime is the wall time the guest ran, excluding host calls. The guest runs on a single thread, so it
+	// approximates the guest's CPU time. WASI functions implemented by wasmtime, such as fd_write, are not profiled,
+	// so the time spent in them is included.
+	GuestTime time.Duration
+
+	// HostCalls profiles the calls of each host function, by import name. The guest run ending when the execution
+	// ends is recorded under "(end)". Of the WASI functions, only poll_oneoff and clock_time_get are profiled, and
+	// random_get when the execution is recorded or replayed.
+	HostCalls map[string]*HostCallProfile
+	// Capabilities profiles the ExecutionHelper.CallCapability calls, by capability ID.
+	Capabilities map[string]*CallProfile
+	// Secrets profiles the ExecutionHelper.GetSecrets calls.
+	Secrets CallProfile
+}
+
+// HostCallProfile profiles the calls of a host function.
+type HostCallProfile struct {
+	Calls int
+	// Fuel and GuestTime are consumed by the guest between the previous host call and this one.
+	Fuel      uint64
+	GuestTime time.Duration
+	// Time is spent in the host function. Calls awaiting capabilities or secrets include the time blocked on them.
+	Time time.Duration
+}
+
+// CallProfile profiles the calls made to the ExecutionHelper. Calls run concurrently, so their Time may add up to
+// more than the execution's.
+type CallProfile struct {
+	Calls int
+	Time  time.Duration
+}
+
+// executionProfiler records an ExecutionProfile. Host calls are made by the single guest thread, but capability and
+// secrets calls are recorded concurrently. Its methods are no-ops on a nil executionProfiler.
+type executionProfiler struct {
+	// fuel returns the fuel remaining in the store, and false if fuel is not metered.
+	fuel func() (uint64, bool)
+
+	mu       sync.Mutex
+	profile  ExecutionProfile
+	lastFuel uint64
+	lastTime time.Time
+}
+
+func newExecutionProfiler(workflowID, executionID string, fuel func() (uint64, bool)) *executionProfiler {
+	return &executionProfiler{
+		fuel: fuel,
+		profile: ExecutionProfile{
+			WorkflowID:          workflowID,
+			WorkflowExecutionID: executionID,
+			HostCalls:           map[string]*HostCallProfile{},
+			Capabilities:        map[string]*CallProfile{},
+		},
+	}
+}
+
+// start marks the start of the guest's first run.
+func (p *executionProfiler) start() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.profile.Start = time.Now()
+	p.lastTime = p.profile.Start
+	p.lastFuel, _ = p.fuel()
+}
+
+// enterHostCall ends the guest's current run with a call of the host function name, and returns the func that
+// resumes the guest when the call returns.
+func (p *executionProfiler) enterHostCall(name string) (exit func()) {
+	if p == nil {
+		return func() {}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	entered := p.endGuestRun(name)
+	return func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.lastTime = time.Now()
+		p.profile.HostCalls[name].Time += p.lastTime.Sub(entered)
+	}
+}
+
+// endGuestRun attributes the fuel and time consumed since the guest last resumed to the host call name, and
+// returns the time it ended.
+func (p *executionProfiler) endGuestRun(name string) time.Time {
+	now := time.Now()
+	hc, ok := p.profile.HostCalls[name]
+	if !ok {
+		hc = &HostCallProfile{}
+		p.profile.HostCalls[name] = hc
+	}
+	hc.Calls++
+	hc.GuestTime += now.Sub(p.lastTime)
+	p.profile.GuestTime += now.Sub(p.lastTime)
+	if fuel, ok := p.fuel(); ok && fuel <= p.lastFuel {
+		hc.Fuel += p.lastFuel - fuel
+		p.profile.FuelConsumed += p.lastFuel - fuel
+		p.lastFuel = fuel
+	}
+	return now
+}
+
+func (p *executionProfiler) recordCapabilityCall(capabilityID string, d time.Duration) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	c, ok := p.profile.Capabilities[capabilityID]
+	if !ok {
+		c = &CallProfile{}
+		p.profile.Capabilities[capabilityID] = c
+	}
+	c.Calls++
+	c.Time += d
+}
+
+func (p *executionProfiler) recordGetSecrets(d time.Duration) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.profile.Secrets.Calls++
+	p.profile.Secrets.Time += d
+}
+
+// finish ends the guest's last run and returns the profile. Capability and secrets calls still running are not
+// included.
+func (p *executionProfiler) finish() *ExecutionProfile {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	end := p.endGuestRun(profileEndOfExecution)
+	p.profile.Duration = end.Sub(p.profile.Start)
+
+	profile := p.profile
+	profile.HostCalls = make(map[string]*HostCallProfile, len(p.profile.HostCalls))
+	for name, hc := range p.profile.HostCalls {
+		c := *hc
+		profile.HostCalls[name] = &c
+	}
+	profile.Capabilities = make(map[string]*CallProfile, len(p.profile.Capabilities))
+	for id, cp := range p.profile.Capabilities {
+		c := *cp
+		profile.Capabilities[id] = &c
+	}
+	return &profile
+}
+
+// profiledHostFunc wraps the host function fn, of any signature Linker.FuncWrap accepts, to record its calls in the
+// profile of the execution running it.
+func profiledHostFunc[T any](exec *execution[T], name string, fn any) any {
+	v := reflect.ValueOf(fn)
+	return reflect.MakeFunc(v.Type(), func(args []reflect.Value) []reflect.Value {
+		defer exec.profiler.enterHostCall(name)()
+		return v.Call(args)
+	}).Interface()
+}
+
+// hostFunc returns fn, wrapped to be profiled if the module is.
+func hostFunc[T any](m *module, exec *execution[T], name string, fn any) any {
+	if m.cfg.Profiler == nil {
+		return fn
+	}
+	return profiledHostFunc(exec, name, fn)
+}
+
+// WritePprof writes the profile in the gzipped protobuf format of pprof. Each sample has the fuel, wall time and
+// number of calls of a guest run before a host call, a host call, or a capability or secrets call.
+func (p *ExecutionProfile) WritePprof(w io.Writer) error {
+	b := newPprofBuilder("fuel", "count", "wall", "nanoseconds", "calls", "count")
+
+	for _, name := range sortedKeys(p.HostCalls) {
+		hc := p.HostCalls[name]
+		b.sample([]string{"guest before " + name, "guest"}, int64(hc.Fuel), hc.GuestTime.Nanoseconds(), int64(hc.Calls)) //nolint:gosec // fuel fits in int64
+		if name != profileEndOfExecution {
+			b.sample([]string{"host " + name, "host"}, 0, hc.Time.Nanoseconds(), int64(hc.Calls))
+		}
+	}
+	for _, id := range sortedKeys(p.Capabilities) {
+		c := p.Capabilities[id]
+		b.sample([]string{"CallCapability " + id, "CallCapability"}, 0, c.Time.Nanoseconds(), int64(c.Calls))
+	}
+	if p.Secrets.Calls > 0 {
+		b.sample([]string{"GetSecrets"}, 0, p.Secrets.Time.Nanoseconds(), int64(p.Secrets.Calls))
+	}
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(b.build(p.Start, p.Duration)); err != nil {
+		return fmt.Errorf("failed to write profile: %w", err)
+	}
+	return zw.Close()
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// pprofBuilder encodes a pprof profile.proto, in which every function has a location of the same ID.
+type pprofBuilder struct {
+	sampleTypes []int64
+	samples     []byte
+	strings     []string
+	stringIDs   map[string]int64
+	functionIDs map[string]uint64
+	functions   []string
+}
+
+// newPprofBuilder returns a pprofBuilder of samples with values of the given type and unit pairs.
+func newPprofBuilder(typeUnits ...string) *pprofBuilder {
+	b := &pprofBuilder{strings: []string{""}, stringIDs: map[string]int64{"": 0}, functionIDs: map[string]uint64{}}
+	for _, s := range typeUnits {
+		b.sampleTypes = append(b.sampleTypes, b.str(s))
+	}
+	return b
+}
+
+func (b *pprofBuilder) str(s string) int64 {
+	id, ok := b.stringIDs[s]
+	if !ok {
+		id = int64(len(b.strings))
+		b.strings = append(b.strings, s)
+		b.stringIDs[s] = id
+	}
+	return id
+}
+
+// sample adds a sample with the stack of functions, leaf first.
+func (b *pprofBuilder) sample(stack []string, values ...int64) {
+	var locations, vals []byte
+	for _, name := range stack {
+		id, ok := b.functionIDs[name]
+		if !ok {
+			b.functions = append(b.functions, name)
+			id = uint64(len(b.functions))
+			b.functionIDs[name] = id
+		}
+		locations = protowire.AppendVarint(locations, id)
+	}
+	for _, v := range values {
+		vals = protowire.AppendVarint(vals, uint64(v)) //nolint:gosec // int64 values are encoded as varints
+	}
+	var sample []byte
+	sample = protowire.AppendTag(sample, 1, protowire.BytesType) // location_id
+	sample = protowire.AppendBytes(sample, locations)
+	sample = protowire.AppendTag(sample, 2, protowire.BytesType) // value
+	sample = protowire.AppendBytes(sample, vals)
+
+	b.samples = protowire.AppendTag(b.samples, 2, protowire.BytesType) // Profile.sample
+	b.samples = protowire.AppendBytes(b.samples, sample)
+}
+
+func (b *pprofBuilder) build(start time.Time, duration time.Duration) []byte {
+	var out []byte
+	valueType := func(field protowire.Number, typ, unit int64) {
+		var vt []byte
+		vt = protowire.AppendTag(vt, 1, protowire.VarintType)
+		vt = protowire.AppendVarint(vt, uint64(typ)) //nolint:gosec // string table index
+		vt = protowire.AppendTag(vt, 2, protowire.VarintType)
+		vt = protowire.AppendVarint(vt, uint64(unit)) //nolint:gosec // string table index
+		out = protowire.AppendTag(out, field, protowire.BytesType)
+		out = protowire.AppendBytes(out, vt)
+	}
+	for i := 0; i+1 < len(b.sampleTypes); i += 2 {
+		valueType(1, b.sampleTypes[i], b.sampleTypes[i+1]) // Profile.sample_type
+	}
+	out = append(out, b.samples...)
+
+	for i, name := range b.functions {
+		id := uint64(i + 1)
+		nameID := b.str(name)
+
+		var line []byte
+		line = protowire.AppendTag(line, 1, protowire.VarintType) // function_id
+		line = protowire.AppendVarint(line, id)
+		var location []byte
+		location = protowire.AppendTag(location, 1, protowire.VarintType) // id
+		location = protowire.AppendVarint(location, id)
+		location = protowire.AppendTag(location, 4, protowire.BytesType) // line
+		location = protowire.AppendBytes(location, line)
+		out = protowire.AppendTag(out, 4, protowire.BytesType) // Profile.location
+		out = protowire.AppendBytes(out, location)
+
+		// Profile.function
+		var function []byte
+		function = protowire.AppendTag(function, 1, protowire.VarintType)
+		function = protowire.AppendVarint(function, id)
+		function = protowire.AppendTag(function, 2, protowire.VarintType)
+		function = protowire.AppendVarint(function, uint64(nameID)) //nolint:gosec // string table index
+		function = protowire.AppendTag(function, 3, protowire.VarintType)
+		function = protowire.AppendVarint(function, uint64(nameID)) //nolint:gosec // string table index
+		out = protowire.AppendTag(out, 5, protowire.BytesType)
+		out = protowire.AppendBytes(out, function)
+	}
+
+	// Intern the period type before the string table is written.
+	periodType, periodUnit := b.str("wall"), b.str("nanoseconds")
+	for _, s := range b.strings {
+		out = protowire.AppendTag(out, 6, protowire.BytesType) // Profile.string_table
+		out = protowire.AppendString(out, s)
+	}
+
+	// Profile.time_nanos, duration_nanos and period_type.
+	out = protowire.AppendTag(out, 9, protowire.VarintType)
+	out = protowire.AppendVarint(out, uint64(start.UnixNano())) //nolint:gosec // timestamps after 1970
+	out = protowire.AppendTag(out, 10, protowire.VarintType)
+	out = protowire.AppendVarint(out, uint64(duration.Nanoseconds())) //nolint:gosec // non-negative
+	valueType(11, periodType, periodUnit)
+	return out
+}
@@ -0,0 +1,113 @@
+package host
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/smartcontractkit/chainlink-common/pkg/capabilities/v2/protoc/pkg/test_capabilities/basictrigger"
+	"github.com/smartcontractkit/chainlink-common/pkg/workflows/host/mocks"
+)
+
+func TestExecutionProfiler(t *testing.T) {
+	fuel := uint64(1000)
+	p := newExecutionProfiler("workflow", "execution", func() (uint64, bool) { return fuel, true })
+	p.start()
+
+	fuel -= 100
+	exit := p.enterHostCall("call_capability")
+	p.recordCapabilityCall("basic-test-action@1.0.0", 2*time.Millisecond)
+	exit()
+	fuel -= 30
+	p.enterHostCall("call_capability")()
+	p.recordGetSecrets(time.Millisecond)
+	fuel -= 20
+	profile := p.finish()
+
+	assert.Equal(t, "workflow", profile.WorkflowID)
+	assert.Equal(t, "execution", profile.WorkflowExecutionID)
+	assert.Equal(t, uint64(150), profile.FuelConsumed)
+	require.Contains(t, profile.HostCalls, "call_capability")
+	assert.Equal(t, 2, profile.HostCalls["call_capability"].Calls)
+	assert.Equal(t, uint64(130), profile.HostCalls["call_capability"].Fuel)
+	assert.Equal(t, uint64(20), profile.HostCalls[profileEndOfExecution].Fuel)
+	assert.Equal(t, CallProfile{Calls: 1, Time: 2 * time.Millisecond}, *profile.Capabilities["basic-test-action@1.0.0"])
+	assert.Equal(t, CallProfile{Calls: 1, Time: time.Millisecond}, profile.Secrets)
+	assert.LessOrEqual(t, profile.GuestTime, profile.Duration)
+
+	t.Run("returns a copy", func(t *testing.T) {
+		p.recordCapabilityCall("basic-test-action@1.0.0", time.Millisecond)
+		assert.Equal(t, 1, profile.Capabilities["basic-test-action@1.0.0"].Calls)
+	})
+
+	t.Run("is a no-op when nil", func(t *testing.T) {
+		var p *executionProfiler
+		p.start()
+		p.enterHostCall("log")()
+		p.recordCapabilityCall("basic-test-action@1.0.0", time.Millisecond)
+		p.recordGetSecrets(time.Millisecond)
+	})
+
+	t.Run("writes pprof", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, profile.WritePprof(&buf))
+
+		zr, err := gzip.NewReader(&buf)
+		require.NoError(t, err)
+		raw, err := io.ReadAll(zr)
+		require.NoError(t, err)
+		for _, name := range []string{"fuel", "guest before call_capability", "host call_capability", "CallCapability basic-test-action@1.0.0", "GetSecrets"} {
+			assert.Contains(t, string(raw), name)
+		}
+	})
+}
+
+func Test_Integration_Execute_Profiler(t *testing.T) {
+	cfg := defaultNoDAGModCfg(t)
+	cfg.InitialFuel = 100_000_000_000
+	cfg.WorkflowID = "workflow"
+	var mu sync.Mutex
+	var profiles []*ExecutionProfile
+	cfg.Profiler = func(_ context.Context, profile *ExecutionProfile) {
+		mu.Lock()
+		defer mu.Unlock()
+		profiles = append(profiles, profile)
+	}
+	m := makeTestModuleByName(t, testPath, "random", cfg, true)
+
+	m.Start()
+	defer m.Close()
+
+	mockExecutionHelper := mocks.NewMockExecutionHelper(t)
+	mockExecutionHelper.EXPECT().GetWorkflowExecutionID().Return("id")
+	mockExecutionHelper.EXPECT().GetNodeTime().RunAndReturn(func() time.Time {
+		return time.Now()
+	}).Maybe()
+	mockExecutionHelper.EXPECT().GetDONTime().RunAndReturn(func() (time.Time, error) {
+		return time.Now(), nil
+	}).Maybe()
+	mockExecutionHelper.EXPECT().CallCapability(mock.Anything, mock.Anything).RunAndReturn(setupNodeCallAndConsensusCall(t, 150))
+
+	trigger := &basictrigger.Outputs{CoolOutput: anyTestTriggerValue}
+	_, err := m.Execute(t.Context(), triggerExecuteRequest(t, 0, trigger), mockExecutionHelper)
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(t, profiles, 1)
+	profile := profiles[0]
+	assert.Equal(t, "workflow", profile.WorkflowID, "the context has no CRE workflow")
+	assert.Equal(t, "id", profile.WorkflowExecutionID)
+	assert.Positive(t, profile.FuelConsumed)
+	require.Contains(t, profile.HostCalls, "call_capability")
+	assert.Positive(t, profile.HostCalls["call_capability"].Calls)
+	assert.NotEmpty(t, profile.Capabilities)
+}
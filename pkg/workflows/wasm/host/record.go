@@ -0,0 +1,352 @@
+package host
+
+import (
+	"bufio"
+	"context"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	caperrors "github.com/smartcontractkit/chainlink-common/pkg/capabilities/errors"
+	sdkpb "github.com/smartcontractkit/chainlink-protos/cre/go/sdk"
+	wfpb "github.com/smartcontractkit/chainlink-protos/workflows/go/v2"
+)
+
+// traceVersion is the version of the trace format written by RecordingExecutionHelper.
+const traceVersion = 1
+
+// TraceEventKind identifies the host interaction a TraceEvent records.
+type TraceEventKind string
+
+const (
+	TraceEventCallCapability TraceEventKind = "call_capability"
+	TraceEventGetSecrets     TraceEventKind = "get_secrets"
+	TraceEventGetNodeTime    TraceEventKind = "get_node_time"
+	TraceEventGetDONTime     TraceEventKind = "get_don_time"
+	TraceEventEmitUserLog    TraceEventKind = "emit_user_log"
+	TraceEventEmitUserMetric TraceEventKind = "emit_user_metric"
+	// TraceEventRandomGet records the bytes read by the guest with WASI random_get.
+	TraceEventRandomGet TraceEventKind = "random_get"
+	// TraceEventResult records the outcome of the execution, as passed to RecordingExecutionHelper.Finish.
+	TraceEventResult TraceEventKind = "result"
+)
+
+// ordered reports whether events of kind are made synchronously by the guest, and so happen in the same order in
+// every run. Capability calls and secrets lookups run concurrently, and are matched by callback ID instead.
+func (k TraceEventKind) ordered() bool {
+	switch k {
+	case TraceEventGetNodeTime, TraceEventGetDONTime, TraceEventEmitUserLog, TraceEventEmitUserMetric, TraceEventRandomGet:
+		return true
+	}
+	return false
+}
+
+// TraceHeader describes a recorded execution.
+type TraceHeader struct {
+	Version             int    `json:"version"`
+	WorkflowExecutionID string `json:"workflow_execution_id"`
+	// Request is the protojson encoded sdk.ExecuteRequest.
+	Request json.RawMessage `json:"request"`
+	// DeterminismSeed is the DeterminismConfig seed of the recorded module, if any.
+	DeterminismSeed *int64 `json:"determinism_seed,omitempty"`
+	// NodeSeed is the seed of the execution's node mode randomness.
+	NodeSeed int64 `json:"node_seed"`
+	// SecretValues reports whether the trace holds the values of secrets, rather than their digests.
+	SecretValues bool `json:"secret_values,omitempty"`
+}
+
+// TraceEvent is a host interaction of a recorded execution. Protobuf requests and responses are protojson encoded,
+// and lists of them are JSON arrays.
+type TraceEvent struct {
+	Seq        int             `json:"seq"`
+	Kind       TraceEventKind  `json:"kind"`
+	CallbackID int32           `json:"callback_id,omitempty"`
+	Request    json.RawMessage `json:"request,omitempty"`
+	Response   json.RawMessage `json:"response,omitempty"`
+	Time       time.Time       `json:"time,omitzero"`
+	Log        string          `json:"log,omitempty"`
+	Random     []byte          `json:"random,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	// CapabilityError is set if Error is a serialized capabilities/errors.Error.
+	CapabilityError bool `json:"capability_error,omitempty"`
+}
+
+// Trace is a recorded execution, read with ReadTrace.
+type Trace struct {
+	Header TraceHeader
+	// Events are the host interactions, ordered by Seq.
+	Events []TraceEvent
+	// Result is the outcome of the execution, or nil if the recording was not finished.
+	Result *TraceEvent
+}
+
+// ReadTrace reads a trace written by RecordingExecutionHelper: a JSON header line followed by one JSON line per event.
+func ReadTrace(r io.Reader) (*Trace, error) {
+	dec := json.NewDecoder(r)
+	t := &Trace{}
+	if err := dec.Decode(&t.Header); err != nil {
+		return nil, fmt.Errorf("failed to read trace header: %w", err)
+	}
+	if t.Header.Version != traceVersion {
+		return nil, fmt.Errorf("unsupported trace version %d", t.Header.Version)
+	}
+	for {
+		var event TraceEvent
+		if err := dec.Decode(&event); errors.Is(err, io.EOF) {
+			return t, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to read trace event %d: %w", len(t.Events), err)
+		}
+		if event.Kind == TraceEventResult {
+			t.Result = &event
+			continue
+		}
+		t.Events = append(t.Events, event)
+	}
+}
+
+// RecordingExecutionHelper is an ExecutionHelper that writes every host interaction of an execution to a trace, which
+// Replay can run the workflow against. It must be the helper passed to Execute, so that the module takes the node
+// mode random seed from it. Fetches are made through capabilities, and are recorded as capability calls.
+//
+// The bytes the guest reads with WASI random_get, which seed its runtime, are recorded too. Secret values are recorded as
+// their SHA-256 digests, unless WithSecretValues is set.
+type RecordingExecutionHelper struct {
+	ExecutionHelper
+
+	nodeSeed     int64
+	secretValues bool
+
+	mu       sync.Mutex
+	w        *bufio.Writer
+	enc      *json.Encoder
+	seq      int
+	writeErr error
+}
+
+var _ ExecutionHelper = (*RecordingExecutionHelper)(nil)
+
+// RecordingOption configures a RecordingExecutionHelper.
+type RecordingOption func(*RecordingExecutionHelper)
+
+// WithSecretValues records the values of the secrets looked up by the execution, instead of their digests, so that a
+// replay returns them to the workflow.
+//
+// WARNING: the trace then holds the secrets in plaintext, and must be stored and shared as carefully as the secrets
+// themselves. Only use it to debug executions whose host calls or result depend on secret values.
+func WithSecretValues() RecordingOption {
+	return func(r *RecordingExecutionHelper) { r.secretValues = true }
+}
+
+// NewRecordingExecutionHelper returns a RecordingExecutionHelper that records the execution of request, by a
+// module configured with determinism, to w.
+func NewRecordingExecutionHelper(inner ExecutionHelper, w io.Writer, request *sdkpb.ExecuteRequest, determinism *DeterminismConfig, opts ...RecordingOption) (*RecordingExecutionHelper, error) {
+	req, err := protojson.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	bw := bufio.NewWriter(w)
+	r := &RecordingExecutionHelper{
+		ExecutionHelper: inner,
+		nodeSeed:        int64(rand.Uint64()), //nolint:gosec // matches the node seed of unrecorded executions
+		w:               bw,
+		enc:             json.NewEncoder(bw),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	header := TraceHeader{
+		Version:             traceVersion,
+		WorkflowExecutionID: inner.GetWorkflowExecutionID(),
+		Request:             req,
+		NodeSeed:            r.nodeSeed,
+		SecretValues:        r.secretValues,
+	}
+	if determinism != nil {
+		header.DeterminismSeed = &determinism.Seed
+	}
+	if err := r.enc.Encode(header); err != nil {
+		return nil, fmt.Errorf("failed to write trace header: %w", err)
+	}
+	return r, nil
+}
+
+// NodeSeed returns the seed of the execution's node mode randomness.
+func (r *RecordingExecutionHelper) NodeSeed() int64 {
+	return r.nodeSeed
+}
+
+// RandomGet fills b with random bytes for the guest's WASI random_get, and records them.
+func (r *RecordingExecutionHelper) RandomGet(b []byte) error {
+	if _, err := crand.Read(b); err != nil {
+		return err
+	}
+	r.record(TraceEvent{Kind: TraceEventRandomGet, Random: slices.Clone(b)})
+	return nil
+}
+
+func (r *RecordingExecutionHelper) CallCapability(ctx context.Context, request *sdkpb.CapabilityRequest) (*sdkpb.CapabilityResponse, error) {
+	resp, err := r.ExecutionHelper.CallCapability(ctx, request)
+	event := TraceEvent{Kind: TraceEventCallCapability, CallbackID: request.CallbackId}
+	event.Request = marshalTraceProto(request)
+	if resp != nil {
+		event.Response = marshalTraceProto(resp)
+	}
+	setTraceError(&event, err)
+	r.record(event)
+	return resp, err
+}
+
+// GetSecrets records the secrets with their values replaced by their SHA-256 digests, unless WithSecretValues is set.
+// A replay then returns the digests to the workflow, so it only matches the recording if the workflow's host calls and
+// result don't depend on the secret values.
+func (r *RecordingExecutionHelper) GetSecrets(ctx context.Context, request *sdkpb.GetSecretsRequest) ([]*sdkpb.SecretResponse, error) {
+	resp, err := r.ExecutionHelper.GetSecrets(ctx, request)
+	event := TraceEvent{Kind: TraceEventGetSecrets, CallbackID: request.CallbackId}
+	event.Request = marshalTraceProto(request)
+	if resp != nil {
+		recorded := resp
+		if !r.secretValues {
+			recorded = redactSecrets(resp)
+		}
+		event.Response = marshalTraceProtos(recorded)
+	}
+	setTraceError(&event, err)
+	r.record(event)
+	return resp, err
+}
+
+func (r *RecordingExecutionHelper) GetNodeTime() time.Time {
+	t := r.ExecutionHelper.GetNodeTime()
+	r.record(TraceEvent{Kind: TraceEventGetNodeTime, Time: t})
+	return t
+}
+
+func (r *RecordingExecutionHelper) GetDONTime() (time.Time, error) {
+	t, err := r.ExecutionHelper.GetDONTime()
+	event := TraceEvent{Kind: TraceEventGetDONTime, Time: t}
+	setTraceError(&event, err)
+	r.record(event)
+	return t, err
+}
+
+func (r *RecordingExecutionHelper) EmitUserLog(log string) error {
+	err := r.ExecutionHelper.EmitUserLog(log)
+	event := TraceEvent{Kind: TraceEventEmitUserLog, Log: log}
+	setTraceError(&event, err)
+	r.record(event)
+	return err
+}
+
+func (r *RecordingExecutionHelper) EmitUserMetric(ctx context.Context, metric *wfpb.WorkflowUserMetric) error {
+	err := r.ExecutionHelper.EmitUserMetric(ctx, metric)
+	event := TraceEvent{Kind: TraceEventEmitUserMetric, Request: marshalTraceProto(metric)}
+	setTraceError(&event, err)
+	r.record(event)
+	return err
+}
+
+// Finish records the outcome of the execution and flushes the trace. It returns the first error writing the trace.
+func (r *RecordingExecutionHelper) Finish(result *sdkpb.ExecutionResult, err error) error {
+	event := TraceEvent{Kind: TraceEventResult}
+	if result != nil {
+		event.Response = marshalTraceProto(result)
+	}
+	setTraceError(&event, err)
+	r.record(event)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.writeErr == nil {
+		r.writeErr = r.w.Flush()
+	}
+	return r.writeErr
+}
+
+func (r *RecordingExecutionHelper) record(event TraceEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event.Seq = r.seq
+	r.seq++
+	if r.writeErr == nil {
+		r.writeErr = r.enc.Encode(event)
+	}
+}
+
+// redactSecrets returns copies of resp with the secret values replaced by their SHA-256 digests.
+func redactSecrets(resp []*sdkpb.SecretResponse) []*sdkpb.SecretResponse {
+	redacted := make([]*sdkpb.SecretResponse, len(resp))
+	for i, r := range resp {
+		redacted[i] = proto.Clone(r).(*sdkpb.SecretResponse)
+		if secret := redacted[i].GetSecret(); secret != nil {
+			sum := sha256.Sum256([]byte(secret.Value))
+			secret.Value = "sha256:" + hex.EncodeToString(sum[:])
+		}
+	}
+	return redacted
+}
+
+// nodeSeeder is implemented by execution helpers that choose the seed of node mode randomness, such as the recording
+// and replaying helpers.
+type nodeSeeder interface {
+	NodeSeed() int64
+}
+
+// wasiRandomSource is implemented by execution helpers that provide the bytes the guest reads with WASI random_get,
+// such as the recording and replaying helpers. Other executions read them from the OS.
+type wasiRandomSource interface {
+	RandomGet(b []byte) error
+}
+
+func setTraceError(event *TraceEvent, err error) {
+	if err == nil {
+		return
+	}
+	if capErr, ok := errors.AsType[caperrors.Error](err); ok {
+		event.Error = capErr.SerializeToString()
+		event.CapabilityError = true
+		return
+	}
+	event.Error = err.Error()
+}
+
+// traceError returns the error recorded in event, if any.
+func traceError(event *TraceEvent) error {
+	switch {
+	case event.Error == "":
+		return nil
+	case event.CapabilityError:
+		return caperrors.DeserializeErrorFromString(event.Error)
+	default:
+		return errors.New(event.Error)
+	}
+}
+
+func marshalTraceProto(m proto.Message) json.RawMessage {
+	b, err := protojson.Marshal(m)
+	if err != nil {
+		// Only invalid UTF-8 in string fields fails to encode; record it as null rather than dropping the event.
+		return json.RawMessage("null")
+	}
+	return b
+}
+
+func marshalTraceProtos[M proto.Message](ms []M) json.RawMessage {
+	list := make([]json.RawMessage, len(ms))
+	for i, m := range ms {
+		list[i] = marshalTraceProto(m)
+	}
+	b, _ := json.Marshal(list) // always valid JSON
+	return b
+}
@@ -0,0 +1,267 @@
+package host
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+
+	caperrors "github.com/smartcontractkit/chainlink-common/pkg/capabilities/errors"
+	"github.com/smartcontractkit/chainlink-common/pkg/capabilities/v2/protoc/pkg/test_capabilities/basictrigger"
+	"github.com/smartcontractkit/chainlink-common/pkg/workflows/host/mocks"
+	sdkpb "github.com/smartcontractkit/chainlink-protos/cre/go/sdk"
+)
+
+func TestRecordingExecutionHelper(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 6, time.UTC)
+	capErr := caperrors.NewPublicUserError(errors.New("bad input"), caperrors.InvalidArgument)
+	capReq := &sdkpb.CapabilityRequest{Id: "basic-test-action@1.0.0", Method: "PerformAction", CallbackId: 1}
+	capResp := &sdkpb.CapabilityResponse{Response: &sdkpb.CapabilityResponse_Error{Error: "failed"}}
+	secretsReq := &sdkpb.GetSecretsRequest{Requests: []*sdkpb.SecretRequest{{Id: "Foo"}}, CallbackId: 2}
+	secretsResp := []*sdkpb.SecretResponse{{Response: &sdkpb.SecretResponse_Secret{Secret: &sdkpb.Secret{Value: "Bar"}}}}
+
+	inner := mocks.NewMockExecutionHelper(t)
+	inner.EXPECT().GetWorkflowExecutionID().Return("id")
+	inner.EXPECT().CallCapability(mock.Anything, mock.Anything).RunAndReturn(func(_ context.Context, req *sdkpb.CapabilityRequest) (*sdkpb.CapabilityResponse, error) {
+		if req.CallbackId == 1 {
+			return capResp, nil
+		}
+		return nil, capErr
+	})
+	inner.EXPECT().GetSecrets(mock.Anything, mock.Anything).Return(secretsResp, nil)
+	inner.EXPECT().GetNodeTime().Return(now)
+	inner.EXPECT().GetDONTime().Return(time.Time{}, errors.New("no consensus"))
+	inner.EXPECT().EmitUserLog("hello").Return(nil)
+
+	request := &sdkpb.ExecuteRequest{Config: []byte("config")}
+	var buf bytes.Buffer
+	recorder, err := NewRecordingExecutionHelper(inner, &buf, request, &DeterminismConfig{Seed: 42})
+	require.NoError(t, err)
+
+	_, err = recorder.CallCapability(t.Context(), capReq)
+	require.NoError(t, err)
+	_, err = recorder.CallCapability(t.Context(), &sdkpb.CapabilityRequest{Id: "basic-test-action@1.0.0", CallbackId: 3})
+	require.Error(t, err)
+	_, err = recorder.GetSecrets(t.Context(), secretsReq)
+	require.NoError(t, err)
+	assert.Equal(t, now, recorder.GetNodeTime())
+	_, err = recorder.GetDONTime()
+	require.Error(t, err)
+	require.NoError(t, recorder.EmitUserLog("hello"))
+	result := &sdkpb.ExecutionResult{Result: &sdkpb.ExecutionResult_Error{Error: "done"}}
+	require.NoError(t, recorder.Finish(result, nil))
+
+	trace, err := ReadTrace(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, "id", trace.Header.WorkflowExecutionID)
+	assert.Equal(t, int64(42), *trace.Header.DeterminismSeed)
+	assert.Equal(t, recorder.NodeSeed(), trace.Header.NodeSeed)
+	require.Len(t, trace.Events, 6)
+	require.NotNil(t, trace.Result)
+
+	recordedErr, ok := errors.AsType[caperrors.Error](traceError(&trace.Events[1]))
+	require.True(t, ok)
+	assert.True(t, capErr.Equals(recordedErr))
+	assert.Equal(t, now, trace.Events[3].Time.UTC())
+	assert.EqualError(t, traceError(&trace.Events[4]), "no consensus")
+
+	t.Run("replays the recorded interactions", func(t *testing.T) {
+		replay := newReplayExecutionHelper(trace, func() {})
+		assert.Equal(t, "id", replay.GetWorkflowExecutionID())
+		assert.Equal(t, trace.Header.NodeSeed, replay.NodeSeed())
+
+		resp, err := replay.CallCapability(t.Context(), capReq)
+		require.NoError(t, err)
+		assert.True(t, proto.Equal(capResp, resp))
+		secrets, err := replay.GetSecrets(t.Context(), secretsReq)
+		require.NoError(t, err)
+		require.Len(t, secrets, 1)
+		sum := sha256.Sum256([]byte("Bar"))
+		assert.Equal(t, "sha256:"+hex.EncodeToString(sum[:]), secrets[0].GetSecret().GetValue(), "secret values are redacted by default")
+		assert.Equal(t, "Bar", secretsResp[0].GetSecret().GetValue(), "the recorded response is not modified")
+		_, err = replay.CallCapability(t.Context(), &sdkpb.CapabilityRequest{Id: "basic-test-action@1.0.0", CallbackId: 3})
+		_, ok := errors.AsType[caperrors.Error](err)
+		assert.True(t, ok)
+		assert.Equal(t, now, replay.GetNodeTime().UTC())
+		_, err = replay.GetDONTime()
+		assert.EqualError(t, err, "no consensus")
+		require.NoError(t, replay.EmitUserLog("hello"))
+
+		replay.finish(result, nil)
+		assert.Nil(t, replay.divergence)
+	})
+
+	t.Run("records secret values if enabled", func(t *testing.T) {
+		inner := mocks.NewMockExecutionHelper(t)
+		inner.EXPECT().GetWorkflowExecutionID().Return("id")
+		inner.EXPECT().GetSecrets(mock.Anything, mock.Anything).Return(secretsResp, nil)
+
+		var buf bytes.Buffer
+		recorder, err := NewRecordingExecutionHelper(inner, &buf, request, nil, WithSecretValues())
+		require.NoError(t, err)
+		_, err = recorder.GetSecrets(t.Context(), secretsReq)
+		require.NoError(t, err)
+		require.NoError(t, recorder.Finish(nil, nil))
+
+		trace, err := ReadTrace(&buf)
+		require.NoError(t, err)
+		assert.True(t, trace.Header.SecretValues)
+		secrets, err := newReplayExecutionHelper(trace, func() {}).GetSecrets(t.Context(), secretsReq)
+		require.NoError(t, err)
+		require.Len(t, secrets, 1)
+		assert.True(t, proto.Equal(secretsResp[0], secrets[0]))
+	})
+
+	t.Run("replays random_get bytes", func(t *testing.T) {
+		inner := mocks.NewMockExecutionHelper(t)
+		inner.EXPECT().GetWorkflowExecutionID().Return("id")
+
+		var buf bytes.Buffer
+		recorder, err := NewRecordingExecutionHelper(inner, &buf, request, nil)
+		require.NoError(t, err)
+		recorded := make([]byte, 16)
+		require.NoError(t, recorder.RandomGet(recorded))
+		require.NoError(t, recorder.Finish(nil, nil))
+
+		trace, err := ReadTrace(&buf)
+		require.NoError(t, err)
+		require.Len(t, trace.Events, 1)
+		assert.Equal(t, TraceEventRandomGet, trace.Events[0].Kind)
+
+		replay := newReplayExecutionHelper(trace, func() {})
+		replayed := make([]byte, 16)
+		require.NoError(t, replay.RandomGet(replayed))
+		assert.Equal(t, recorded, replayed)
+
+		var divergence *Divergence
+		require.ErrorAs(t, newReplayExecutionHelper(trace, func() {}).RandomGet(make([]byte, 8)), &divergence)
+	})
+
+	t.Run("flags a different request", func(t *testing.T) {
+		canceled := false
+		replay := newReplayExecutionHelper(trace, func() { canceled = true })
+
+		_, err := replay.CallCapability(t.Context(), &sdkpb.CapabilityRequest{Id: "basic-test-action@1.0.0", Method: "Other", CallbackId: 1})
+		var divergence *Divergence
+		require.ErrorAs(t, err, &divergence)
+		assert.Equal(t, 0, divergence.Event.Seq)
+		assert.True(t, canceled)
+
+		// Later divergences don't replace the first.
+		require.Error(t, replay.EmitUserLog("hello"))
+		assert.Same(t, divergence, replay.divergence)
+	})
+
+	t.Run("flags ordered interactions out of order", func(t *testing.T) {
+		replay := newReplayExecutionHelper(trace, func() {})
+
+		_, err := replay.GetDONTime()
+		var divergence *Divergence
+		require.ErrorAs(t, err, &divergence)
+		assert.Equal(t, TraceEventGetNodeTime, divergence.Event.Kind)
+	})
+
+	t.Run("flags interactions that were not replayed", func(t *testing.T) {
+		replay := newReplayExecutionHelper(trace, func() {})
+
+		replay.finish(result, nil)
+		require.NotNil(t, replay.divergence)
+		assert.Equal(t, 0, replay.divergence.Event.Seq)
+		assert.Equal(t, "not replayed", replay.divergence.Reason)
+	})
+}
+
+func Test_sameError(t *testing.T) {
+	capErr := caperrors.NewPublicUserError(errors.New("bad input"), caperrors.InvalidArgument)
+	for _, tt := range []struct {
+		name     string
+		err      error
+		recorded error
+		same     bool
+	}{
+		{"both nil", nil, nil, true},
+		{"unexpected error", errors.New("failed"), nil, false},
+		{"missing error", nil, errors.New("failed"), false},
+		{"same message", errors.New("failed"), errors.New("failed"), true},
+		{"different message", errors.New("failed"), errors.New("other"), false},
+		{"capability error with another message", caperrors.NewPublicUserError(errors.New("bad input 2"), caperrors.InvalidArgument), capErr, true},
+		{"capability error with another code", caperrors.NewPublicUserError(errors.New("bad input"), caperrors.NotFound), capErr, false},
+		{"capability error recorded as plain", capErr, errors.New(capErr.Error()), false},
+		{"wrapped cancellation", fmt.Errorf("execution stopped: %w", context.Canceled), errors.New("call failed: context canceled"), true},
+		{"cancellation recorded as deadline", context.Canceled, errors.New(context.DeadlineExceeded.Error()), false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.same, sameError(tt.err, tt.recorded))
+		})
+	}
+}
+
+func Test_Integration_RecordAndReplay(t *testing.T) {
+	m := makeTestModuleByName(t, testPath, "random", nil, true)
+	binary, err := os.ReadFile(filepath.Join(testPath, "random", "test.wasm"))
+	require.NoError(t, err)
+
+	m.Start()
+	defer m.Close()
+
+	inner := mocks.NewMockExecutionHelper(t)
+	inner.EXPECT().GetWorkflowExecutionID().Return("id")
+	inner.EXPECT().GetNodeTime().RunAndReturn(func() time.Time {
+		return time.Now()
+	}).Maybe()
+	inner.EXPECT().GetDONTime().RunAndReturn(func() (time.Time, error) {
+		return time.Now(), nil
+	}).Maybe()
+	inner.EXPECT().CallCapability(mock.Anything, mock.Anything).RunAndReturn(setupNodeCallAndConsensusCall(t, 150))
+
+	triggerPayload, err := anypb.New(&basictrigger.Outputs{CoolOutput: "trigger1"})
+	require.NoError(t, err)
+	request := &sdkpb.ExecuteRequest{
+		Request: &sdkpb.ExecuteRequest_Trigger{Trigger: &sdkpb.Trigger{Payload: triggerPayload}},
+	}
+
+	var buf bytes.Buffer
+	recorder, err := NewRecordingExecutionHelper(inner, &buf, request, m.cfg.Determinism)
+	require.NoError(t, err)
+	result, err := m.Execute(t.Context(), proto.Clone(request).(*sdkpb.ExecuteRequest), recorder)
+	require.NoError(t, recorder.Finish(result, err))
+	require.NoError(t, err)
+
+	trace, err := ReadTrace(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+
+	t.Run("matches the recording", func(t *testing.T) {
+		modCfg := defaultNoDAGModCfg(t)
+		determinism := &DeterminismConfig{Seed: 1}
+		modCfg.Determinism = determinism
+		replayed, err := Replay(t.Context(), modCfg, binary, trace)
+		require.NoError(t, err)
+		assert.Same(t, determinism, modCfg.Determinism, "the caller's config is not modified")
+		require.NoError(t, replayed.Err)
+		assert.Nil(t, replayed.Divergence)
+		assert.True(t, proto.Equal(result, replayed.Result))
+	})
+
+	t.Run("flags a different result", func(t *testing.T) {
+		tampered, err := ReadTrace(bytes.NewReader(buf.Bytes()))
+		require.NoError(t, err)
+		tampered.Result.Response = []byte(`{"error":"tampered"}`)
+
+		replayed, err := Replay(t.Context(), defaultNoDAGModCfg(t), binary, tampered)
+		require.NoError(t, err)
+		require.NotNil(t, replayed.Divergence)
+		assert.Equal(t, TraceEventResult, replayed.Divergence.Event.Kind)
+	})
+}
@@ -0,0 +1,334 @@
+package host
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	caperrors "github.com/smartcontractkit/chainlink-common/pkg/capabilities/errors"
+	sdkpb "github.com/smartcontractkit/chainlink-protos/cre/go/sdk"
+	wfpb "github.com/smartcontractkit/chainlink-protos/workflows/go/v2"
+)
+
+// Divergence is the first point where a replay differs from its trace.
+type Divergence struct {
+	// Event is the recorded event the replay diverged from. It is nil if the replay made a host call the trace has no
+	// event for.
+	Event *TraceEvent
+	// Reason describes the difference.
+	Reason string
+}
+
+func (d *Divergence) Error() string {
+	if d.Event == nil {
+		return "replay diverged: " + d.Reason
+	}
+	return fmt.Sprintf("replay diverged at event %d (%s): %s", d.Event.Seq, d.Event.Kind, d.Reason)
+}
+
+// ReplayResult is the outcome of a replayed execution.
+type ReplayResult struct {
+	Result *sdkpb.ExecutionResult
+	Err    error
+	// Divergence is the first difference from the trace, or nil if the replay matched it.
+	Divergence *Divergence
+}
+
+// Replay executes the request of trace on a new module of binary, configured like modCfg, answering the host calls
+// from the trace rather than live, and reports the first divergence from the recorded execution. The module's
+// Determinism is set from the trace, and the guest reads the recorded bytes with WASI random_get, so its runtime is
+// seeded as it was when recorded. Unless the trace holds secret values (see WithSecretValues), the workflow is given
+// the digests of its secrets.
+//
+// Replay stops the execution at the first divergence.
+func Replay(ctx context.Context, modCfg *ModuleConfig, binary []byte, trace *Trace) (*ReplayResult, error) {
+	cfg := *modCfg
+	cfg.Determinism = nil
+	if seed := trace.Header.DeterminismSeed; seed != nil {
+		cfg.Determinism = &DeterminismConfig{Seed: *seed}
+	}
+	m, err := NewModule(ctx, &cfg, binary)
+	if err != nil {
+		return nil, fmt.Errorf("could not instantiate module: %w", err)
+	}
+	m.Start()
+	defer m.Close()
+
+	req := &sdkpb.ExecuteRequest{}
+	if err = protojson.Unmarshal(trace.Header.Request, req); err != nil {
+		return nil, fmt.Errorf("failed to decode recorded request: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	helper := newReplayExecutionHelper(trace, cancel)
+
+	result, err := m.Execute(ctx, req, helper)
+	helper.finish(result, err)
+	return &ReplayResult{Result: result, Err: err, Divergence: helper.divergence}, nil
+}
+
+// replayExecutionHelper answers host calls from a trace. Ordered events are consumed in sequence, and capability
+// calls and secrets lookups by callback ID.
+type replayExecutionHelper struct {
+	trace  *Trace
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	ordered    []*TraceEvent
+	byCallback map[replayCallbackKey]*TraceEvent
+	divergence *Divergence
+}
+
+type replayCallbackKey struct {
+	kind       TraceEventKind
+	callbackID int32
+}
+
+var _ ExecutionHelper = (*replayExecutionHelper)(nil)
+
+func newReplayExecutionHelper(trace *Trace, cancel context.CancelFunc) *replayExecutionHelper {
+	h := &replayExecutionHelper{
+		trace:      trace,
+		cancel:     cancel,
+		byCallback: make(map[replayCallbackKey]*TraceEvent),
+	}
+	for i := range trace.Events {
+		event := &trace.Events[i]
+		if event.Kind.ordered() {
+			h.ordered = append(h.ordered, event)
+		} else {
+			h.byCallback[replayCallbackKey{kind: event.Kind, callbackID: event.CallbackID}] = event
+		}
+	}
+	return h
+}
+
+func (h *replayExecutionHelper) NodeSeed() int64 {
+	return h.trace.Header.NodeSeed
+}
+
+func (h *replayExecutionHelper) GetWorkflowExecutionID() string {
+	return h.trace.Header.WorkflowExecutionID
+}
+
+func (h *replayExecutionHelper) CallCapability(_ context.Context, request *sdkpb.CapabilityRequest) (*sdkpb.CapabilityResponse, error) {
+	event, err := h.takeCallback(TraceEventCallCapability, request.CallbackId, request)
+	if err != nil {
+		return nil, err
+	}
+	var resp *sdkpb.CapabilityResponse
+	if len(event.Response) > 0 {
+		resp = &sdkpb.CapabilityResponse{}
+		if err := protojson.Unmarshal(event.Response, resp); err != nil {
+			return nil, h.diverge(event, fmt.Sprintf("failed to decode recorded response: %v", err))
+		}
+	}
+	return resp, traceError(event)
+}
+
+func (h *replayExecutionHelper) GetSecrets(_ context.Context, request *sdkpb.GetSecretsRequest) ([]*sdkpb.SecretResponse, error) {
+	event, err := h.takeCallback(TraceEventGetSecrets, request.CallbackId, request)
+	if err != nil {
+		return nil, err
+	}
+	var resp []*sdkpb.SecretResponse
+	if len(event.Response) > 0 {
+		var list []json.RawMessage
+		if err := json.Unmarshal(event.Response, &list); err != nil {
+			return nil, h.diverge(event, fmt.Sprintf("failed to decode recorded response: %v", err))
+		}
+		for _, raw := range list {
+			secret := &sdkpb.SecretResponse{}
+			if err := protojson.Unmarshal(raw, secret); err != nil {
+				return nil, h.diverge(event, fmt.Sprintf("failed to decode recorded response: %v", err))
+			}
+			resp = append(resp, secret)
+		}
+	}
+	return resp, traceError(event)
+}
+
+func (h *replayExecutionHelper) GetNodeTime() time.Time {
+	event, err := h.takeOrdered(TraceEventGetNodeTime)
+	if err != nil {
+		return time.Time{}
+	}
+	return event.Time
+}
+
+func (h *replayExecutionHelper) GetDONTime() (time.Time, error) {
+	event, err := h.takeOrdered(TraceEventGetDONTime)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return event.Time, traceError(event)
+}
+
+// RandomGet fills b with the bytes the recorded guest read with WASI random_get.
+func (h *replayExecutionHelper) RandomGet(b []byte) error {
+	event, err := h.takeOrdered(TraceEventRandomGet)
+	if err != nil {
+		return err
+	}
+	if len(event.Random) != len(b) {
+		return h.diverge(event, fmt.Sprintf("read %d random bytes, recorded %d", len(b), len(event.Random)))
+	}
+	copy(b, event.Random)
+	return nil
+}
+
+func (h *replayExecutionHelper) EmitUserLog(log string) error {
+	event, err := h.takeOrdered(TraceEventEmitUserLog)
+	if err != nil {
+		return err
+	}
+	if event.Log != log {
+		return h.diverge(event, fmt.Sprintf("log %q, recorded %q", log, event.Log))
+	}
+	return traceError(event)
+}
+
+func (h *replayExecutionHelper) EmitUserMetric(_ context.Context, metric *wfpb.WorkflowUserMetric) error {
+	event, err := h.takeOrdered(TraceEventEmitUserMetric)
+	if err != nil {
+		return err
+	}
+	if err := h.compareRequest(event, metric, &wfpb.WorkflowUserMetric{}); err != nil {
+		return err
+	}
+	return traceError(event)
+}
+
+// takeCallback returns the recorded event of kind for callbackID, after checking that it was made with request.
+func (h *replayExecutionHelper) takeCallback(kind TraceEventKind, callbackID int32, request proto.Message) (*TraceEvent, error) {
+	h.mu.Lock()
+	key := replayCallbackKey{kind: kind, callbackID: callbackID}
+	event, ok := h.byCallback[key]
+	delete(h.byCallback, key)
+	h.mu.Unlock()
+
+	if !ok {
+		return nil, h.diverge(nil, fmt.Sprintf("unrecorded %s with callback ID %d", kind, callbackID))
+	}
+	if err := h.compareRequest(event, request, request.ProtoReflect().New().Interface()); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// takeOrdered returns the next ordered event, after checking that it is of kind.
+func (h *replayExecutionHelper) takeOrdered(kind TraceEventKind) (*TraceEvent, error) {
+	h.mu.Lock()
+	if len(h.ordered) == 0 {
+		h.mu.Unlock()
+		return nil, h.diverge(nil, fmt.Sprintf("unrecorded %s after the last recorded event", kind))
+	}
+	event := h.ordered[0]
+	h.ordered = h.ordered[1:]
+	h.mu.Unlock()
+
+	if event.Kind != kind {
+		return nil, h.diverge(event, fmt.Sprintf("got %s", kind))
+	}
+	return event, nil
+}
+
+// compareRequest checks that request equals the request recorded in event, decoded into recorded.
+func (h *replayExecutionHelper) compareRequest(event *TraceEvent, request, recorded proto.Message) error {
+	if err := protojson.Unmarshal(event.Request, recorded); err != nil {
+		return h.diverge(event, fmt.Sprintf("failed to decode recorded request: %v", err))
+	}
+	if !proto.Equal(request, recorded) {
+		return h.diverge(event, fmt.Sprintf("request %s, recorded %s", marshalTraceProto(request), event.Request))
+	}
+	return nil
+}
+
+// diverge records the divergence, if it is the first, and stops the execution.
+func (h *replayExecutionHelper) diverge(event *TraceEvent, reason string) *Divergence {
+	d := &Divergence{Event: event, Reason: reason}
+	h.mu.Lock()
+	if h.divergence == nil {
+		h.divergence = d
+	}
+	h.mu.Unlock()
+	h.cancel()
+	return d
+}
+
+// finish checks that the execution made every recorded host call and had the recorded outcome.
+func (h *replayExecutionHelper) finish(result *sdkpb.ExecutionResult, err error) {
+	h.mu.Lock()
+	diverged := h.divergence != nil
+	var missed *TraceEvent
+	if len(h.ordered) > 0 {
+		missed = h.ordered[0]
+	}
+	for _, event := range h.byCallback {
+		if missed == nil || event.Seq < missed.Seq {
+			missed = event
+		}
+	}
+	h.mu.Unlock()
+
+	switch {
+	case diverged:
+	case missed != nil:
+		h.diverge(missed, "not replayed")
+	case h.trace.Result != nil:
+		h.compareResult(result, err)
+	}
+}
+
+func (h *replayExecutionHelper) compareResult(result *sdkpb.ExecutionResult, err error) {
+	event := h.trace.Result
+	if recordedErr := traceError(event); !sameError(err, recordedErr) {
+		h.diverge(event, fmt.Sprintf("error %v, recorded %v", err, recordedErr))
+		return
+	}
+	if len(event.Response) == 0 {
+		if result != nil {
+			h.diverge(event, fmt.Sprintf("result %s, recorded none", marshalTraceProto(result)))
+		}
+		return
+	}
+	recorded := &sdkpb.ExecutionResult{}
+	if unmarshalErr := protojson.Unmarshal(event.Response, recorded); unmarshalErr != nil {
+		h.diverge(event, fmt.Sprintf("failed to decode recorded result: %v", unmarshalErr))
+		return
+	}
+	if !proto.Equal(result, recorded) {
+		h.diverge(event, fmt.Sprintf("result %s, recorded %s", marshalTraceProto(result), event.Response))
+	}
+}
+
+// sameError reports whether err matches the recorded error. Capability errors match on their code, origin and
+// visibility, and context cancellations on their cause, since their messages may differ between runs; other errors
+// match on their message.
+func sameError(err, recorded error) bool {
+	if err == nil || recorded == nil {
+		return err == nil && recorded == nil
+	}
+	capErr, isCapErr := errors.AsType[caperrors.Error](err)
+	recordedCapErr, recordedIsCapErr := errors.AsType[caperrors.Error](recorded)
+	if isCapErr || recordedIsCapErr {
+		return isCapErr && recordedIsCapErr &&
+			capErr.Code() == recordedCapErr.Code() &&
+			capErr.Origin() == recordedCapErr.Origin() &&
+			capErr.Visibility() == recordedCapErr.Visibility()
+	}
+	for _, cause := range []error{context.Canceled, context.DeadlineExceeded} {
+		if errors.Is(err, cause) {
+			return strings.Contains(recorded.Error(), cause.Error())
+		}
+	}
+	return err.Error() == recorded.Error()
+}
@@ -19,8 +19,8 @@ var (
 	tick     = 100 * time.Millisecond
 )
 
-func newWasiLinker[T any](exec *execution[T], engine *wasmtime.Engine) (*wasmtime.Linker, error) {
-	linker := wasmtime.NewLinker(engine)
+func newWasiLinker[T any](m *module, exec *execution[T]) (*wasmtime.Linker, error) {
+	linker := wasmtime.NewLinker(m.engine)
 	linker.AllowShadowing(true)
 
 	err := linker.DefineWasi()
@@ -31,19 +31,16 @@ func newWasiLinker[T any](exec *execution[T], engine *wasmtime.Engine) (*wasmtim
 	err = linker.FuncWrap(
 		"wasi_snapshot_preview1",
 		"poll_oneoff",
-		exec.pollOneoff,
+		hostFunc(m, exec, "poll_oneoff", exec.pollOneoff),
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	exec.timeFetcher = newTimeFetcher(exec.ctx, exec.executor)
-	exec.timeFetcher.Start()
-
 	err = linker.FuncWrap(
 		"wasi_snapshot_preview1",
 		"clock_time_get",
-		exec.clockTimeGet,
+		hostFunc(m, exec, "clock_time_get", exec.clockTimeGet),
 	)
 	if err != nil {
 		return nil, err
@@ -282,6 +279,25 @@ func createRandomGet(cfg *ModuleConfig) func(caller *wasmtime.Caller, buf, bufLe
 	}
 }
 
+// createTracedRandomGet returns a WASI random_get which reads the random bytes from source, so that the bytes read by
+// a recorded execution are replayed.
+func createTracedRandomGet(source wasiRandomSource) func(caller *wasmtime.Caller, buf, bufLen int32) int32 {
+	return func(caller *wasmtime.Caller, buf, bufLen int32) int32 {
+		// bufLen is guest-controlled; a negative value would panic make below.
+		if bufLen < 0 || int(bufLen) > len(wasmMemoryAccessor(caller)) {
+			return ErrnoInval
+		}
+		randOutput := make([]byte, bufLen)
+		if err := source.RandomGet(randOutput); err != nil {
+			return ErrnoFault
+		}
+		if n := wasmWrite(caller, randOutput, buf, bufLen); n != int64(len(randOutput)) {
+			return ErrnoFault
+		}
+		return ErrnoSuccess
+	}
+}
+
 func getSlot(events []byte, i int32) ([]byte, error) {
 	offset := i * eventsLen
 